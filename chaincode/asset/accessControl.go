@@ -0,0 +1,129 @@
+package asset
+
+import (
+	"fmt"
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+type Operation = string
+
+const (
+	eRegisterAuthorityOperation Operation = "eRegisterAuthorityOperation" /// creation of root and intermediate certificate authorities
+	eRecallOperation            Operation = "eRecallOperation"
+	/// operations of the token contract, which passes their names to CheckSubmitterRole
+	eMintOperation   Operation = "eMintOperation"
+	eFreezeOperation Operation = "eFreezeOperation" /// freezing and unfreezing of tokens
+)
+
+const (
+	/// attribute granted by the organization CAs, e.g. with fabric-ca-client register --id.attrs 'role=regulator:ecert'
+	roleAttribute = "role"
+
+	requiredRoleKeyPrefix = "requiredRole."
+)
+
+/// roles required until the administrator configures otherwise
+var defaultRequiredRoles = map[Operation]string{
+	eRegisterAuthorityOperation: "issuer",
+	eRecallOperation:            "regulator",
+	eMintOperation:              "issuer",
+	eFreezeOperation:            "regulator",
+}
+
+/// Signed by the administrator, an empty Role lifts the restriction
+type OperationRoleChange struct {
+	Operation Operation `json:"Operation"`
+	Role      string    `json:"Role"`
+	Signature string    `json:"Signature"`
+}
+
+func isOperation(
+	iOperation Operation,
+) bool {
+	_, ok := defaultRequiredRoles[iOperation]
+	return ok
+}
+
+func getRequiredRole(
+	iCtx contractapi.TransactionContextInterface,
+	iOperation Operation,
+) (string, error) {
+	role, err := getConfigValue(iCtx, requiredRoleKeyPrefix+iOperation)
+	if err != nil {
+		return "", err
+	}
+
+	if role == nil {
+		return defaultRequiredRoles[iOperation], nil
+	}
+
+	return string(role), nil
+}
+
+/// checks the Fabric identity submitting the transaction, on top of the payload signatures
+/// which only prove who approved the operation. In strict access control mode, an operation without
+/// required role is refused
+func CheckSubmitterRole(
+	iCtx contractapi.TransactionContextInterface,
+	iOperation Operation,
+) error {
+	role, err := getRequiredRole(iCtx, iOperation)
+	if err != nil {
+		return err
+	}
+
+	if role == "" {
+		isStrict, err := IsStrictModeEnabled(iCtx, strictAccessControlMode)
+		if err != nil {
+			return err
+		}
+
+		if isStrict {
+			return fmt.Errorf("no role is configured for %s", iOperation)
+		}
+		return nil
+	}
+
+	err = iCtx.GetClientIdentity().AssertAttributeValue(roleAttribute, role)
+	if err != nil {
+		return fmt.Errorf("submitter must have the attribute %s=%s: %v", roleAttribute, role, err)
+	}
+
+	return nil
+}
+
+/// iSignature is the administrator's signature of the OperationRoleChange
+func (c *MaterialContract) SetRequiredRole(
+	iCtx contractapi.TransactionContextInterface,
+	iOperation Operation,
+	iRole string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	if !isOperation(iOperation) {
+		return nil, fmt.Errorf("invalid operation %s", iOperation)
+	}
+
+	change := OperationRoleChange{
+		Operation: iOperation,
+		Role:      iRole,
+	}
+	err := verifyAdministratorSignature(iCtx, &change, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putConfigValue(iCtx, requiredRoleKeyPrefix+iOperation, []byte(iRole)))
+}
+
+func (c *MaterialContract) GetRequiredRole(
+	iCtx contractapi.TransactionContextInterface,
+	iOperation Operation,
+) (string, error) {
+	if !isOperation(iOperation) {
+		return "", fmt.Errorf("invalid operation %s", iOperation)
+	}
+
+	return getRequiredRole(iCtx, iOperation)
+}
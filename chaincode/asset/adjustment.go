@@ -0,0 +1,263 @@
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/shopspring/decimal"
+)
+
+type AdjustmentReason = string
+
+const (
+	eShrinkage  AdjustmentReason = "eShrinkage"
+	eDamage     AdjustmentReason = "eDamage"
+	eSampling   AdjustmentReason = "eSampling"
+	eCorrection AdjustmentReason = "eCorrection"
+)
+
+const adjustmentObjectType = "adjustment"
+
+/// Signed by the owner of the material. Delta must be negative except for corrections. Sequence is the number of previous adjustments
+/// of the material so that a signed adjustment cannot be applied twice
+type AdjustmentRequest struct {
+	NodeId     string           `json:"NodeId"`
+	Sequence   int              `json:"Sequence"`
+	Delta      string           `json:"Delta"`
+	ReasonCode AdjustmentReason `json:"ReasonCode"`
+	Signature  string           `json:"Signature"`
+}
+
+type QuantityAdjustment struct {
+	AdjustmentRequest
+	AdjustedTime time.Time `json:"AdjustedTime"`
+	TxId         string    `json:"TxId"`
+}
+
+func isAdjustmentReason(
+	iReasonCode string,
+) bool {
+	switch iReasonCode {
+	case eShrinkage, eDamage, eSampling, eCorrection:
+		return true
+	default:
+		return false
+	}
+}
+
+func getAdjustments(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) ([]QuantityAdjustment, error) {
+	iterator, err := iCtx.GetStub().GetStateByPartialCompositeKey(adjustmentObjectType, []string{iNodeId})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	adjustments := []QuantityAdjustment{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var adjustment QuantityAdjustment
+		err = json.Unmarshal(kv.Value, &adjustment)
+		if err != nil {
+			return nil, err
+		}
+
+		adjustments = append(adjustments, adjustment)
+	}
+
+	return adjustments, nil
+}
+
+/// the quantity of the material once all of its adjustments are applied
+func getEffectiveQuantity(
+	iCtx contractapi.TransactionContextInterface,
+	iMaterial *Material,
+) (decimal.Decimal, error) {
+	quantity, err := decimal.NewFromString(iMaterial.Quantity)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	adjustments, err := getAdjustments(iCtx, iMaterial.Id)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	for _, adjustment := range adjustments {
+		delta, err := decimal.NewFromString(adjustment.Delta)
+		if err != nil {
+			return decimal.Decimal{}, err
+		}
+		quantity = quantity.Add(delta)
+	}
+
+	return quantity, nil
+}
+
+/// sum of the adjustments which increased the quantity of the material, such quantity does not come from
+/// any input so mass balance reports it
+func getPositiveAdjustment(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) (decimal.Decimal, error) {
+	adjustments, err := getAdjustments(iCtx, iNodeId)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	total := decimal.NewFromInt(0)
+	for _, adjustment := range adjustments {
+		delta, err := decimal.NewFromString(adjustment.Delta)
+		if err != nil {
+			return decimal.Decimal{}, err
+		}
+
+		if delta.IsPositive() {
+			total = total.Add(delta)
+		}
+	}
+
+	return total, nil
+}
+
+/// iSignature is the owner's signature of the AdjustmentRequest
+/// shrinkage, damage and sampling can only decrease the quantity, only corrections can increase it
+func (c *MaterialContract) AdjustMaterialQuantity(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iDelta string,
+	iReasonCode string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	if !isAdjustmentReason(iReasonCode) {
+		return nil, fmt.Errorf("unknown reason code %s", iReasonCode)
+	}
+
+	delta, err := decimal.NewFromString(iDelta)
+	if err != nil {
+		return nil, err
+	}
+
+	if delta.IsZero() {
+		return nil, fmt.Errorf("delta cannot be zero")
+	}
+
+	if delta.IsPositive() && iReasonCode != eCorrection {
+		return nil, fmt.Errorf("delta of %s must be negative, only %s can increase the quantity", iReasonCode, eCorrection)
+	}
+
+	material, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	if material.IsFinalized {
+		return nil, fmt.Errorf("node is already finalized")
+	}
+
+	err = checkNoPendingOffer(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	adjustments, err := getAdjustments(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	quantity, err := getEffectiveQuantity(iCtx, material)
+	if err != nil {
+		return nil, err
+	}
+
+	if quantity.Add(delta).IsNegative() {
+		return nil, fmt.Errorf("adjusted quantity cannot be negative")
+	}
+
+	reserved, err := getReservedQuantity(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	if quantity.Add(delta).LessThan(reserved) {
+		return nil, fmt.Errorf("adjusted quantity cannot be less than the reserved quantity")
+	}
+
+	request := AdjustmentRequest{
+		NodeId:     iNodeId,
+		Sequence:   len(adjustments),
+		Delta:      delta.String(),
+		ReasonCode: iReasonCode,
+	}
+	err = graph.VerifyPayload(material.OwnerPublicKey, &request, iSignature)
+	if err != nil {
+		return nil, err
+	}
+	request.Signature = iSignature
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	adjustment := QuantityAdjustment{
+		AdjustmentRequest: request,
+		AdjustedTime:      transactionTime,
+		TxId:              iCtx.GetStub().GetTxID(),
+	}
+	adjustmentJson, err := json.Marshal(adjustment)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(adjustmentObjectType, []string{iNodeId, fmt.Sprintf("%010d", request.Sequence)})
+	if err != nil {
+		return nil, err
+	}
+
+	err = iCtx.GetStub().PutState(key, adjustmentJson)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putAuditEntry(iCtx, iNodeId, eAdjusted, fmt.Sprintf("%s %s", request.Delta, iReasonCode)))
+}
+
+func (c *MaterialContract) GetMaterialAdjustments(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) ([]QuantityAdjustment, error) {
+	_, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	return getAdjustments(iCtx, iNodeId)
+}
+
+/// returns the quantity of the material once all of its adjustments are applied
+func (c *MaterialContract) GetMaterialQuantity(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) (string, error) {
+	material, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return "", err
+	}
+
+	quantity, err := getEffectiveQuantity(iCtx, material)
+	if err != nil {
+		return "", err
+	}
+
+	return quantity.String(), nil
+}
@@ -1,6 +1,7 @@
 package asset
 
 import (
+	"encoding/json"
 	"fmt"
 	"sig_chain/chaincode/graph"
 	"time"
@@ -17,13 +18,55 @@ const (
 	eCertificateAuthority NodeType = "eCertificateAuthority"
 	eData                 NodeType = "eData"
 	eHash                 NodeType = "eHash"
+	eCustody              NodeType = "eCustody"
+	eSensorReading        NodeType = "eSensorReading"
+	eAttestation          NodeType = "eAttestation"
 )
 
+/// returns an empty node of iType, so that nodes read from the ledger can be unmarshalled into their own type
+func MakeNode(
+	iType NodeType,
+) (graph.NodeI, error) {
+	switch iType {
+	case eMaterial:
+		return &Material{}, nil
+	case eCertificate:
+		return &Certificate{}, nil
+	case eCertificateAuthority:
+		return &CertificateAuthority{}, nil
+	case eData:
+		return &QualityRecord{}, nil
+	case eCustody:
+		return &CustodyEvent{}, nil
+	case eSensorReading:
+		return &SensorReading{}, nil
+	case eAttestation:
+		return &Attestation{}, nil
+	default:
+		if graph.IsGenericNodeType(iType) {
+			return &graph.GenericNode{}, nil
+		}
+		return nil, fmt.Errorf("unknown node type %s", iType)
+	}
+}
+
 type Material struct {
 	graph.NodeHeader
-	Name     string `json:"Name"`
-	Unit     string `json:"Unit"`
-	Quantity string `json:"Quantity"`
+	Name              string            `json:"Name"`
+	Unit              string            `json:"Unit"`
+	Quantity          string            `json:"Quantity"`
+	LotNumber         string            `json:"LotNumber"`
+	BatchNumber       string            `json:"BatchNumber"`
+	ExpiryDate        time.Time         `json:"ExpiryDate"`  /// zero if the material does not expire, in UTC at a whole second
+	Composition       map[string]string `json:"Composition"` /// component material name -> percentage
+	TemplateId        string            `json:"TemplateId"`  /// empty if the material does not follow a product template
+	Attributes        map[string]string `json:"Attributes"`
+	Gtin              string            `json:"Gtin"`              /// GS1 trade item number, empty if unknown
+	Sscc              string            `json:"Sscc"`              /// GS1 serial shipping container code of the logistic unit, empty if unknown
+	Grade             string            `json:"Grade"`             /// empty if the material is not graded
+	SerialNumber      string            `json:"SerialNumber"`      /// only set for individually serialized items
+	PrivateCollection string            `json:"PrivateCollection"` /// set with PrivateDataHash for confidentially transferred materials
+	PrivateDataHash   string            `json:"PrivateDataHash"`   /// the body of the material is then empty and kept in PrivateCollection
 }
 
 func (m *Material) GetHeader() graph.NodeHeader {
@@ -33,88 +76,354 @@ func (m *Material) SetHeader(iHeader graph.NodeHeader) {
 	m.NodeHeader = iHeader
 }
 
-type CertificateAuthority struct {
-	RevokedCertificateIds []string `json:"RevokedCertificateIds"`
-	RootId                string   `json:"RootId"` /// Easier to trace since the node only stores hash of the issuer
-}
-
-type Certificate struct {
-	IssueTime  time.Time `json:"IssueTime"`
-	ExpiryTime time.Time `json:"ExpiryTime"`
-	Signature  string    `json:"Signature"`
-	IssuerId   string    `json:"IssuerId"` /// Easier to trace since the node only stores hash of the issuer
-}
-
 type MaterialContract struct {
 	contractapi.Contract
 }
 
+/// Everything needed to create a material, Signature is the owner's signature of the resulting node
+type MaterialSpec struct {
+	NodeId         string            `json:"NodeId"`
+	Name           string            `json:"Name"`
+	Unit           string            `json:"Unit"`
+	Quantity       string            `json:"Quantity"`
+	LotNumber      string            `json:"LotNumber"`
+	BatchNumber    string            `json:"BatchNumber"`
+	ExpiryDate     time.Time         `json:"ExpiryDate"`
+	TemplateId     string            `json:"TemplateId"`
+	Attributes     map[string]string `json:"Attributes"`
+	Gtin           string            `json:"Gtin"`
+	Sscc           string            `json:"Sscc"`
+	Grade          string            `json:"Grade"`
+	OwnerPublicKey string            `json:"OwnerPublicKey"`
+	CreatedTime    time.Time         `json:"CreatedTime"`
+	Signature      string            `json:"Signature"`
+}
+
 func (c *MaterialContract) CreateMaterial(
 	iCtx contractapi.TransactionContextInterface,
 	iNodeId string,
 	iName string,
 	iUnit string,
 	iQuantity string,
+	iLotNumber string,
+	iBatchNumber string,
+	iExpiryDate time.Time,
+	iTemplateId string,
+	iAttributes string,
+	iGtin string,
+	iSscc string,
+	iGrade string,
 	iOwnerPublicKey string,
 	iCreatedTime time.Time,
 	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	attributes := map[string]string{}
+	if iAttributes != "" {
+		err := json.Unmarshal([]byte(iAttributes), &attributes)
+		if err != nil {
+			return nil, fmt.Errorf("attributes must be a json object of strings: %v", err)
+		}
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, c.createMaterial(iCtx, &MaterialSpec{
+		NodeId:         iNodeId,
+		Name:           iName,
+		Unit:           iUnit,
+		Quantity:       iQuantity,
+		LotNumber:      iLotNumber,
+		BatchNumber:    iBatchNumber,
+		ExpiryDate:     iExpiryDate,
+		TemplateId:     iTemplateId,
+		Attributes:     attributes,
+		Gtin:           iGtin,
+		Sscc:           iSscc,
+		Grade:          iGrade,
+		OwnerPublicKey: iOwnerPublicKey,
+		CreatedTime:    iCreatedTime,
+		Signature:      iSignature,
+	}))
+}
+
+/// creates every material of iSpecs in a single transaction, none are created if one of them fails
+func (c *MaterialContract) CreateMaterials(
+	iCtx contractapi.TransactionContextInterface,
+	iSpecs []MaterialSpec,
+) (*graph.TransactionReceipt, error) {
+	if len(iSpecs) == 0 {
+		return nil, fmt.Errorf("material specs cannot be empty")
+	}
+
+	/// the ledger does not return the writes of the current transaction so duplicates must be caught here
+	nodeIds := map[string]bool{}
+	for i := range iSpecs {
+		if nodeIds[iSpecs[i].NodeId] {
+			return nil, fmt.Errorf("node id %s is used more than once", iSpecs[i].NodeId)
+		}
+		nodeIds[iSpecs[i].NodeId] = true
+
+		err := c.createMaterial(iCtx, &iSpecs[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to create material %s: %v", iSpecs[i].NodeId, err)
+		}
+	}
+
+	return graph.MakeTransactionReceipt(iCtx)
+}
+
+func (c *MaterialContract) createMaterial(
+	iCtx contractapi.TransactionContextInterface,
+	iSpec *MaterialSpec,
 ) error {
-	quantity, err := decimal.NewFromString(iQuantity)
+	quantity, err := decimal.NewFromString(iSpec.Quantity)
 	if err != nil {
 		return err
 	}
 
-	transactionTime, err := iCtx.GetStub().GetTxTimestamp()
+	createdTime, err := getOperationTime(iCtx, iSpec.CreatedTime)
 	if err != nil {
 		return err
 	}
 
-	timeDiff := transactionTime.Seconds - iCreatedTime.Unix()
-	if timeDiff < 0 {
-		timeDiff = -timeDiff
+	err = checkQueryTime("expiry date", iSpec.ExpiryDate)
+	if err != nil {
+		return err
 	}
 
-	if timeDiff > 3600 {
-		return fmt.Errorf("Timestamp does not match with transaction's timestamp")
+	attributes := iSpec.Attributes
+	if attributes == nil {
+		attributes = map[string]string{}
+	}
+
+	if iSpec.Gtin != "" {
+		err = validateGtin(iSpec.Gtin)
+		if err != nil {
+			return err
+		}
+	}
+
+	if iSpec.Sscc != "" {
+		err = validateSscc(iSpec.Sscc)
+		if err != nil {
+			return err
+		}
 	}
 
 	graphContract := graph.GraphContract{}
 	nodeHeader := graph.MakeNodeHeader(
-		iNodeId,
+		iSpec.NodeId,
+		eMaterial,
 		false,
-		map[string]bool{},
-		map[string]bool{},
-		iOwnerPublicKey,
-		iCreatedTime,
-		iSignature,
+		graph.MakeHashSet(),
+		graph.MakeHashSet(),
+		iSpec.OwnerPublicKey,
+		createdTime,
+		iSpec.Signature,
 	)
+
+	err = setTimeSource(iCtx, &nodeHeader)
+	if err != nil {
+		return err
+	}
 	material := MakeMaterial(
-		iName,
-		iUnit,
+		iSpec.Name,
+		iSpec.Unit,
 		quantity.String(),
+		iSpec.LotNumber,
+		iSpec.BatchNumber,
+		iSpec.ExpiryDate,
+		map[string]string{iSpec.Name: "100"},
+		iSpec.TemplateId,
+		attributes,
+		iSpec.Gtin,
+		iSpec.Sscc,
+		iSpec.Grade,
+		"",
 		nodeHeader,
 	)
 
-	return graphContract.CreateNode(
+	err = checkProductDefinition(iCtx, &material)
+	if err != nil {
+		return err
+	}
+
+	err = graphContract.CreateNode(
 		iCtx,
 		&material,
 	)
+	if err != nil {
+		return err
+	}
+
+	err = putMaterialIndexes(iCtx, &material)
+	if err != nil {
+		return err
+	}
+
+	return putAuditEntry(iCtx, iSpec.NodeId, eCreated, "")
+}
+
+func getTransactionTime(
+	iCtx contractapi.TransactionContextInterface,
+) (time.Time, error) {
+	transactionTime, err := iCtx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(transactionTime.Seconds, int64(transactionTime.Nanos)).UTC(), nil
+}
+
+/// iTime must be within the configured clock drift tolerance of the transaction's timestamp
+func checkTransactionTime(
+	iCtx contractapi.TransactionContextInterface,
+	iTime time.Time,
+) error {
+	transactionTime, err := iCtx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+
+	tolerance, err := getClockDriftTolerance(iCtx)
+	if err != nil {
+		return err
+	}
+
+	timeDiff := transactionTime.Seconds - iTime.Unix()
+	if timeDiff < 0 {
+		timeDiff = -timeDiff
+	}
+
+	if timeDiff > tolerance {
+		return fmt.Errorf("Timestamp does not match with transaction's timestamp")
+	}
+
+	return nil
+}
+
+/// iTime can be any time in the past, but not further in the future than the clock drift tolerance
+func checkNotInFuture(
+	iCtx contractapi.TransactionContextInterface,
+	iTime time.Time,
+) error {
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return err
+	}
+
+	tolerance, err := getClockDriftTolerance(iCtx)
+	if err != nil {
+		return err
+	}
+
+	if iTime.After(transactionTime.Add(time.Duration(tolerance) * time.Second)) {
+		return fmt.Errorf("time cannot be in the future")
+	}
+
+	return nil
+}
+
+/// returns the time to record for an operation: iTime once checked against the transaction's timestamp,
+/// or the transaction's timestamp itself if the channel does not rely on client times, iTime is then ignored.
+/// Created times are queried, so iTime must be in UTC at a whole second and the timestamp is truncated to the
+/// second, see formatQueryTime. The nodes created at that time go through setTimeSource
+func getOperationTime(
+	iCtx contractapi.TransactionContextInterface,
+	iTime time.Time,
+) (time.Time, error) {
+	useTransactionTime, err := getUseTransactionTime(iCtx)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if useTransactionTime {
+		transactionTime, err := getTransactionTime(iCtx)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		return transactionTime.Truncate(time.Second), nil
+	}
+
+	err = checkQueryTime("created time", iTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	err = checkTransactionTime(iCtx, iTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return iTime, nil
+}
+
+/// owners cannot know the timestamp of the transaction when they sign its nodes, so when the channel uses it the
+/// nodes are marked IsTransactionTime and signed without their created time, see graph.GetSignedHeader
+func setTimeSource(
+	iCtx contractapi.TransactionContextInterface,
+	iHeader *graph.NodeHeader,
+) error {
+	useTransactionTime, err := getUseTransactionTime(iCtx)
+	if err != nil {
+		return err
+	}
+
+	iHeader.IsTransactionTime = useTransactionTime
+	return nil
 }
 
 func MakeMaterial(
 	iName string,
 	iUnit string,
 	iQuantity string,
+	iLotNumber string,
+	iBatchNumber string,
+	iExpiryDate time.Time,
+	iComposition map[string]string,
+	iTemplateId string,
+	iAttributes map[string]string,
+	iGtin string,
+	iSscc string,
+	iGrade string,
+	iSerialNumber string,
 	iHeader graph.NodeHeader,
 ) Material {
 	return Material{
-		NodeHeader: iHeader,
-		Name:       iName,
-		Unit:       iUnit,
-		Quantity:   iQuantity,
+		NodeHeader:   iHeader,
+		Name:         iName,
+		Unit:         iUnit,
+		Quantity:     iQuantity,
+		LotNumber:    iLotNumber,
+		BatchNumber:  iBatchNumber,
+		ExpiryDate:   iExpiryDate,
+		Composition:  iComposition,
+		TemplateId:   iTemplateId,
+		Attributes:   iAttributes,
+		Gtin:         iGtin,
+		Sscc:         iSscc,
+		Grade:        iGrade,
+		SerialNumber: iSerialNumber,
 	}
 }
 
+func areAttributesEqual(
+	iAttributes map[string]string,
+	iOtherAttributes map[string]string,
+) bool {
+	if len(iAttributes) != len(iOtherAttributes) {
+		return false
+	}
+
+	for name, value := range iAttributes {
+		otherValue, ok := iOtherAttributes[name]
+		if !ok || otherValue != value {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (c *MaterialContract) GetMaterial(
 	iCtx contractapi.TransactionContextInterface,
 	iNodeId string,
@@ -129,163 +438,481 @@ func (c *MaterialContract) GetMaterial(
 	return &material, nil
 }
 
+/// iSignature is the current owner's signature of the finalized node, which carries iNewOwnerPublicKey in its
+/// NewOwnerPublicKey. iNewOwnerMspId is the organization whose peers endorse updates of the new node, it is
+/// required when iNewOwnerPublicKey is a bare key. An optional price can be passed in the transient map, see
+/// TransferPrice
 func (c *MaterialContract) TransferMaterial(
 	iCtx contractapi.TransactionContextInterface,
 	iNodeId string,
 	iNewNodeId string,
 	iNewOwnerPublicKey string,
+	iNewOwnerMspId string,
 	iSignature string,
 	iNewNodeSignature string,
 	iTransferTime time.Time,
-) error {
+) (*graph.TransactionReceipt, error) {
 	graphContract := graph.GraphContract{}
 
 	var material Material
 	err := graphContract.GetNode(iCtx, iNodeId, &material)
+	if err != nil {
+		return nil, err
+	}
+
+	transferTime, err := getOperationTime(iCtx, iTransferTime)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkNoPendingOffer(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putTransferPrice(iCtx, iNodeId, iNewNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, c.transferMaterial(
+		iCtx,
+		eTransfer,
+		&material,
+		iNewNodeId,
+		iNewOwnerPublicKey,
+		iNewOwnerMspId,
+		iSignature,
+		iNewNodeSignature,
+		transferTime,
+		"",
+	))
+}
+
+/// shared by single-shot transfers, two-phase transfers and returns, iMaterial must be loaded from the ledger.
+/// The new node is transferred confidentially if iCollection is not empty
+func (c *MaterialContract) transferMaterial(
+	iCtx contractapi.TransactionContextInterface,
+	iKind DerivationKind,
+	iMaterial *Material,
+	iNewNodeId string,
+	iNewOwnerPublicKey string,
+	iNewOwnerMspId string,
+	iSignature string,
+	iNewNodeSignature string,
+	iTransferTime time.Time,
+	iCollection string,
+) error {
+	err := checkNotExpired(iCtx, iMaterial)
 	if err != nil {
 		return err
 	}
 
-	transactionTime, err := iCtx.GetStub().GetTxTimestamp()
+	err = checkOfferNotWithdrawn(iCtx, iMaterial.Id, iNewNodeId)
 	if err != nil {
 		return err
 	}
 
-	timeDiff := transactionTime.Seconds - iTransferTime.Unix()
-	if timeDiff < 0 {
-		timeDiff = -timeDiff
+	quantity, err := getEffectiveQuantity(iCtx, iMaterial)
+	if err != nil {
+		return err
 	}
 
-	if timeDiff > 3600 {
-		return fmt.Errorf("Timestamp does not match with transaction's timestamp")
+	err = checkReservations(iCtx, iMaterial.Id, []allocation{{iNewOwnerPublicKey, quantity}})
+	if err != nil {
+		return err
+	}
+
+	err = checkRequiredCertifications(iCtx, iMaterial, iNewOwnerPublicKey)
+	if err != nil {
+		return err
+	}
+
+	grade, err := getEffectiveGrade(iCtx, iMaterial)
+	if err != nil {
+		return err
+	}
+
+	material := *iMaterial
+	newMaterial := material
+	newMaterial.Quantity = quantity.String()
+	newMaterial.Grade = grade
+
+	newHeader := newMaterial.GetHeader()
+	err = setTimeSource(iCtx, &newHeader)
+	if err != nil {
+		return err
 	}
+	newMaterial.SetHeader(newHeader)
 
-	return graphContract.TransferNodeOwnership(
+	if iCollection != "" {
+		err = putConfidentialMaterial(iCtx, iCollection, &material, &newMaterial)
+		if err != nil {
+			return err
+		}
+	}
+
+	graphContract := graph.GraphContract{}
+	err = graphContract.TransferNodeOwnership(
 		iCtx,
-		iNodeId,
+		material.Id,
 		&material,
+		&newMaterial,
 		iNewNodeId,
 		iTransferTime,
 		iNewOwnerPublicKey,
+		iNewOwnerMspId,
 		iSignature,
 		iNewNodeSignature,
 	)
+	if err != nil {
+		return err
+	}
+
+	err = putMaterialIndexes(iCtx, &newMaterial)
+	if err != nil {
+		return err
+	}
+
+	err = settleTransfer(iCtx, material.Id, iNewNodeId)
+	if err != nil {
+		return err
+	}
+
+	return putDerivation(
+		iCtx,
+		iKind,
+		[]string{material.Id},
+		[]string{iNewNodeId},
+		"0",
+		material.Unit,
+	)
 }
 
-/// iSignature is the signature for the final finalized node
+/// iSignature is the signature for the finalized parent node
 /// iNewNodeSignatures are the signatures for the new split nodes
-/*
+/// iWaste is the quantity lost during the split, in the unit of the parent
 func (c *MaterialContract) SplitMaterial(
 	iCtx contractapi.TransactionContextInterface,
 	iNodeId string,
-	iSplitQuantities []decimal.Decimal,
+	iSplitQuantities []string,
+	iWaste string,
 	iNewNodeIds []string,
 	iNewNodeOwnerPublicKeys []string,
+	iCreatedTime time.Time,
 	iSignature string,
 	iNewNodeSignatures []string,
-) error {
+) (*graph.TransactionReceipt, error) {
+	if len(iSplitQuantities) == 0 {
+		return nil, fmt.Errorf("cannot have empty split quantities")
+	}
+
 	if len(iSplitQuantities) != len(iNewNodeIds) {
-		return fmt.Errorf("mismatch new node ids and split quantities")
+		return nil, fmt.Errorf("mismatch new node ids and split quantities")
 	}
 
 	if len(iSplitQuantities) != len(iNewNodeOwnerPublicKeys) {
-		return fmt.Errorf("mismatch owner public keys and split quantities")
+		return nil, fmt.Errorf("mismatch owner public keys and split quantities")
+	}
+
+	if len(iSplitQuantities) != len(iNewNodeSignatures) {
+		return nil, fmt.Errorf("mismatch signatures and split quantities")
+	}
+
+	createdTime, err := getOperationTime(iCtx, iCreatedTime)
+	if err != nil {
+		return nil, err
 	}
 
 	parentMaterial, err := c.GetMaterial(iCtx, iNodeId)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	err = checkNoPendingOffer(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
 	}
 
-	var total decimal.Decimal
-	for _, quantity := range iSplitQuantities {
+	parentQuantity, err := getEffectiveQuantity(iCtx, parentMaterial)
+	if err != nil {
+		return nil, err
+	}
+
+	parentGrade, err := getEffectiveGrade(iCtx, parentMaterial)
+	if err != nil {
+		return nil, err
+	}
+
+	waste, err := decimal.NewFromString(iWaste)
+	if err != nil {
+		return nil, err
+	}
+
+	if waste.IsNegative() {
+		return nil, fmt.Errorf("waste cannot be negative")
+	}
+
+	total := waste
+	children := []graph.NodeI{}
+	allocations := []allocation{}
+	for i, quantityString := range iSplitQuantities {
+		quantity, err := decimal.NewFromString(quantityString)
+		if err != nil {
+			return nil, err
+		}
+
+		if !quantity.IsPositive() {
+			return nil, fmt.Errorf("split quantities must be positive")
+		}
 		total = total.Add(quantity)
+		allocations = append(allocations, allocation{iNewNodeOwnerPublicKeys[i], quantity})
+
+		nodeHeader := graph.MakeNodeHeader(
+			iNewNodeIds[i],
+			eMaterial,
+			false,
+			graph.MakeHashSet(),
+			graph.MakeHashSet(),
+			iNewNodeOwnerPublicKeys[i],
+			createdTime,
+			iNewNodeSignatures[i],
+		)
+
+		err = setTimeSource(iCtx, &nodeHeader)
+		if err != nil {
+			return nil, err
+		}
+		material := MakeMaterial(
+			parentMaterial.Name,
+			parentMaterial.Unit,
+			quantity.String(),
+			parentMaterial.LotNumber,
+			parentMaterial.BatchNumber,
+			parentMaterial.ExpiryDate,
+			getComposition(parentMaterial),
+			parentMaterial.TemplateId,
+			parentMaterial.Attributes,
+			parentMaterial.Gtin,
+			"", /// split materials are new logistic units
+			parentGrade,
+			"",
+			nodeHeader,
+		)
+		children = append(children, &material)
 	}
 
-	if !total.Equals(parentMaterial.Quantity) {
-		return fmt.Errorf("incorrect quantities")
+	if !total.Equal(parentQuantity) {
+		return nil, fmt.Errorf("incorrect quantities")
 	}
 
-	if len(iSplitQuantities) == 0 {
-		return fmt.Errorf("cannot have empty split quantities")
+	err = checkReservations(iCtx, iNodeId, allocations)
+	if err != nil {
+		return nil, err
 	}
 
-	splitMaterial := []interface{}{}
-	nodeType := []string{}
-	for _, quantity := range iSplitQuantities {
-		material := Material{
-			Name:     parentMaterial.Name,
-			Unit:     parentMaterial.Unit,
-			Quantity: quantity,
+	graphContract := graph.GraphContract{}
+	err = graphContract.CreateDerivedNodes(
+		iCtx,
+		[]string{iNodeId},
+		[]graph.NodeI{&Material{}},
+		[]string{iSignature},
+		children,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, child := range children {
+		err = putMaterialIndexes(iCtx, child.(*Material))
+		if err != nil {
+			return nil, err
 		}
-		splitMaterial = append(splitMaterial, material)
-		nodeType = append(nodeType, eMaterial)
 	}
 
-	typedGraphContract := TypedGraphContract{}
-	err = typedGraphContract.CreateReferencedTypedNodesAndFinalize(
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putDerivation(
 		iCtx,
-		iNodeId,
+		eSplit,
+		[]string{iNodeId},
 		iNewNodeIds,
-		nodeType,
-		splitMaterial,
-		iNewNodeOwnerPublicKeys,
-		iSignature,
-		iNewNodeSignatures,
-	)
-	return err
+		waste.String(),
+		parentMaterial.Unit,
+	))
 }
 
-/// TODO: Add support for merge and consuming materials
-/*
+/// iSignatures are the signatures for the finalized merged nodes
+/// iNewNodeSignature is the signature for the new node
+/// quantities are converted to iUnit through the unit registry before being summed
 func (c *MaterialContract) MergeMaterials(
 	iCtx contractapi.TransactionContextInterface,
 	iNodeIds []string,
 	iSignatures []string,
 	iNewNodeId string,
+	iUnit string,
 	iNewOwnerPublicKey string,
-) error {
+	iCreatedTime time.Time,
+	iNewNodeSignature string,
+) (*graph.TransactionReceipt, error) {
 	if len(iNodeIds) == 0 {
-		return fmt.Errorf("input node ids cannot be empty")
+		return nil, fmt.Errorf("input node ids cannot be empty")
 	}
-	unit := ""
-	name := ""
-	quantity := decimal.NewFromInt(0)
 
 	if len(iNodeIds) != len(iSignatures) {
-		return fmt.Errorf("mismatch node ids and signatures")
+		return nil, fmt.Errorf("mismatch node ids and signatures")
 	}
 
+	createdTime, err := getOperationTime(iCtx, iCreatedTime)
+	if err != nil {
+		return nil, err
+	}
+
+	name := ""
+	lotNumber := ""
+	batchNumber := ""
+	expiryDate := time.Time{}
+	templateId := ""
+	attributes := map[string]string{}
+	gtin := ""
+	grade := ""
+	quantity := decimal.NewFromInt(0)
+	parts := []compositionPart{}
+	parents := []graph.NodeI{}
 	for _, nodeId := range iNodeIds {
 		material, err := c.GetMaterial(iCtx, nodeId)
 		if err != nil {
-			return err
-		}
-
-		if unit != "" && material.Unit != unit {
-			return fmt.Errorf("Materials must have same unit")
+			return nil, err
 		}
 
 		if name != "" && material.Name != name {
-			return fmt.Errorf("Materials must have same name")
+			return nil, fmt.Errorf("Materials must have same name")
 		}
-		unit = material.Unit
 		name = material.Name
-		quantity = quantity.Add(material.Quantity)
+
+		/// lots of different grades must be downgraded to the same grade before being merged
+		materialGrade, err := getEffectiveGrade(iCtx, material)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(parents) > 0 && materialGrade != grade {
+			return nil, fmt.Errorf("Materials must have same grade")
+		}
+		grade = materialGrade
+
+		/// lot and batch numbers are only kept if every merged material shares them
+		if len(parents) == 0 {
+			lotNumber = material.LotNumber
+			batchNumber = material.BatchNumber
+			templateId = material.TemplateId
+			attributes = material.Attributes
+			gtin = material.Gtin
+		}
+		if material.LotNumber != lotNumber {
+			lotNumber = ""
+		}
+		if material.BatchNumber != batchNumber {
+			batchNumber = ""
+		}
+		if material.Gtin != gtin {
+			gtin = ""
+		}
+
+		/// same for the template, which only holds if the attributes are identical too
+		if material.TemplateId != templateId || !areAttributesEqual(material.Attributes, attributes) {
+			templateId = ""
+			attributes = map[string]string{}
+		}
+
+		/// the merged material expires with its earliest expiring input
+		if !material.ExpiryDate.IsZero() && (expiryDate.IsZero() || material.ExpiryDate.Before(expiryDate)) {
+			expiryDate = material.ExpiryDate
+		}
+
+		materialQuantity, err := getEffectiveQuantity(iCtx, material)
+		if err != nil {
+			return nil, err
+		}
+
+		err = checkReservations(iCtx, nodeId, []allocation{{iNewOwnerPublicKey, materialQuantity}})
+		if err != nil {
+			return nil, err
+		}
+
+		err = checkNoPendingOffer(iCtx, nodeId)
+		if err != nil {
+			return nil, err
+		}
+
+		convertedQuantity, err := convertQuantity(iCtx, materialQuantity, material.Unit, iUnit)
+		if err != nil {
+			return nil, err
+		}
+		quantity = quantity.Add(convertedQuantity)
+		parts = append(parts, compositionPart{getComposition(material), convertedQuantity})
+
+		parents = append(parents, &Material{})
 	}
 
-	err := c.CreateMaterial(
-		iCtx,
+	composition, err := mixCompositions(parts)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeHeader := graph.MakeNodeHeader(
 		iNewNodeId,
-		name,
-		unit,
-		quantity,
+		eMaterial,
+		false,
+		graph.MakeHashSet(),
+		graph.MakeHashSet(),
 		iNewOwnerPublicKey,
+		createdTime,
+		iNewNodeSignature,
 	)
+
+	err = setTimeSource(iCtx, &nodeHeader)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	material := MakeMaterial(
+		name,
+		iUnit,
+		quantity.String(),
+		lotNumber,
+		batchNumber,
+		expiryDate,
+		composition,
+		templateId,
+		attributes,
+		gtin,
+		"",
+		grade,
+		"",
+		nodeHeader,
+	)
+
+	graphContract := graph.GraphContract{}
+	err = graphContract.CreateDerivedNodes(
+		iCtx,
+		iNodeIds,
+		parents,
+		iSignatures,
+		[]graph.NodeI{&material},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putMaterialIndexes(iCtx, &material)
+	if err != nil {
+		return nil, err
 	}
 
-	return err
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putDerivation(
+		iCtx,
+		eMerge,
+		iNodeIds,
+		[]string{iNewNodeId},
+		"0",
+		iUnit,
+	))
 }
-*/
@@ -0,0 +1,107 @@
+package asset_test
+
+import (
+	"sig_chain/pkg/client"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// splits iNodeId of iOwner into children of iChildOwners, each signing its own child
+func (l *testLedger) splitMaterial(
+	iNodeId string,
+	iOwner client.Signer,
+	iQuantities []string,
+	iWaste string,
+	iChildIds []string,
+	iChildOwners []client.Signer,
+) error {
+	ownerPublicKeys := []string{}
+	signatures := []string{}
+	for i, childId := range iChildIds {
+		child := makeTestMaterial(childId, iQuantities[i], iChildOwners[i], iNodeId)
+		ownerPublicKeys = append(ownerPublicKeys, iChildOwners[i].GetPublicKey())
+		signatures = append(signatures, signNode(l.t, iChildOwners[i], &child))
+	}
+	signature := signFinalization(l.t, iOwner, *l.getMaterial(iNodeId), iChildIds...)
+
+	return l.submit(func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.SplitMaterial(iCtx, iNodeId, iQuantities, iWaste, iChildIds, ownerPublicKeys, testTime, signature, signatures)
+		return err
+	})
+}
+
+func TestSplitSignatures(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	bob := makeTestSigner(t)
+	l.createMaterial("m1", "10", alice)
+
+	err := l.splitMaterial("m1", alice, []string{"4", "5"}, "0", []string{"m2", "m3"}, []client.Signer{alice, bob})
+	if err == nil {
+		t.Fatal("split whose quantities do not add up succeeded")
+	}
+
+	child := makeTestMaterial("m3", "6", alice, "m1")
+	l.mustFail("split with a child signed by another owner", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.SplitMaterial(
+			iCtx,
+			"m1",
+			[]string{"4", "6"},
+			"0",
+			[]string{"m2", "m3"},
+			[]string{alice.GetPublicKey(), bob.GetPublicKey()},
+			testTime,
+			signFinalization(t, alice, *l.getMaterial("m1"), "m2", "m3"),
+			[]string{signNode(t, alice, &child), signNode(t, alice, &child)},
+		)
+		return err
+	})
+
+	err = l.splitMaterial("m1", alice, []string{"4", "6"}, "0", []string{"m2", "m3"}, []client.Signer{alice, bob})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !l.getMaterial("m1").IsFinalized {
+		t.Fatal("split material is not finalized")
+	}
+	if l.getMaterial("m3").OwnerPublicKey != bob.GetPublicKey() {
+		t.Fatal("m3 is not owned by its owner")
+	}
+	for _, nodeId := range []string{"m1", "m2", "m3"} {
+		l.checkNodeSignature(nodeId)
+	}
+}
+
+func TestMergeSignatures(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	bob := makeTestSigner(t)
+	l.createMaterial("m1", "5", alice)
+	l.createMaterial("m2", "5", bob)
+
+	merged := makeTestMaterial("m3", "10", alice, "m1", "m2")
+	mergedSignature := signNode(t, alice, &merged)
+	signatures := []string{
+		signFinalization(t, alice, *l.getMaterial("m1"), "m3"),
+		signFinalization(t, bob, *l.getMaterial("m2"), "m3"),
+	}
+
+	l.mustFail("merge without the signature of every input owner", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.MergeMaterials(iCtx, []string{"m1", "m2"}, []string{signatures[0], signatures[0]}, "m3", "kg", alice.GetPublicKey(), testTime, mergedSignature)
+		return err
+	})
+
+	l.mustSubmit("merge", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.MergeMaterials(iCtx, []string{"m1", "m2"}, signatures, "m3", "kg", alice.GetPublicKey(), testTime, mergedSignature)
+		return err
+	})
+
+	if l.getMaterial("m3").Quantity != "10" {
+		t.Fatalf("merged quantity is %s", l.getMaterial("m3").Quantity)
+	}
+	for _, nodeId := range []string{"m1", "m2", "m3"} {
+		l.checkNodeSignature(nodeId)
+	}
+}
@@ -0,0 +1,156 @@
+package asset
+
+import (
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+type AttestationSubject = string
+
+const (
+	eMaterialSubject AttestationSubject = "eMaterialSubject"
+	eFacilitySubject AttestationSubject = "eFacilitySubject" /// SubjectId is a registered GLN
+)
+
+const attestationObjectType = "attestation"
+
+/// Node owned by an independent auditor, records a spot-check of a material or a facility.
+/// Attestations of a material point back to it through their previous node hashes like quality records
+type Attestation struct {
+	graph.NodeHeader
+	SubjectType AttestationSubject `json:"SubjectType"`
+	SubjectId   string             `json:"SubjectId"`
+	Scope       string             `json:"Scope"` /// what was audited, e.g. "storage conditions"
+	Findings    string             `json:"Findings"`
+	AuditTime   time.Time          `json:"AuditTime"`
+}
+
+func (a *Attestation) GetHeader() graph.NodeHeader {
+	return a.NodeHeader
+}
+func (a *Attestation) SetHeader(iHeader graph.NodeHeader) {
+	a.NodeHeader = iHeader
+}
+
+func getAttestations(
+	iCtx contractapi.TransactionContextInterface,
+	iSubjectId string,
+) ([]Attestation, error) {
+	attestationIds, err := getIndexedIds(iCtx, attestationObjectType, []string{iSubjectId})
+	if err != nil {
+		return nil, err
+	}
+
+	graphContract := graph.GraphContract{}
+	attestations := []Attestation{}
+	for _, attestationId := range attestationIds {
+		var attestation Attestation
+		err = graphContract.GetNode(iCtx, attestationId, &attestation)
+		if err != nil {
+			return nil, err
+		}
+
+		attestations = append(attestations, attestation)
+	}
+
+	return attestations, nil
+}
+
+/// the auditor cannot be the owner of the material or of the facility it attests.
+/// iSignature is the auditor's signature of the attestation node
+func (c *MaterialContract) CreateAttestation(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iSubjectType string,
+	iSubjectId string,
+	iScope string,
+	iFindings string,
+	iAuditTime time.Time,
+	iAuditorPublicKey string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	if iScope == "" {
+		return nil, fmt.Errorf("scope cannot be empty")
+	}
+
+	err := checkNotInFuture(iCtx, iAuditTime)
+	if err != nil {
+		return nil, err
+	}
+
+	previousNodeHashedIds := graph.MakeHashSet()
+	subjectOwnerPublicKey := ""
+	switch iSubjectType {
+	case eMaterialSubject:
+		material, err := c.GetMaterial(iCtx, iSubjectId)
+		if err != nil {
+			return nil, err
+		}
+		subjectOwnerPublicKey = material.OwnerPublicKey
+		previousNodeHashedIds = previousNodeHashedIds.Add(graph.HashId(iSubjectId))
+	case eFacilitySubject:
+		registration, err := c.GetOwnerByGln(iCtx, iSubjectId)
+		if err != nil {
+			return nil, err
+		}
+		subjectOwnerPublicKey = registration.OwnerPublicKey
+	default:
+		return nil, fmt.Errorf("unknown subject type %s", iSubjectType)
+	}
+
+	if subjectOwnerPublicKey == iAuditorPublicKey {
+		return nil, fmt.Errorf("auditor cannot attest its own %s", iSubjectId)
+	}
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	graphContract := graph.GraphContract{}
+	nodeHeader := graph.MakeNodeHeader(
+		iNodeId,
+		eAttestation,
+		true,
+		previousNodeHashedIds,
+		graph.MakeHashSet(),
+		iAuditorPublicKey,
+		transactionTime,
+		iSignature,
+	)
+	attestation := Attestation{
+		NodeHeader:  nodeHeader,
+		SubjectType: iSubjectType,
+		SubjectId:   iSubjectId,
+		Scope:       iScope,
+		Findings:    iFindings,
+		AuditTime:   iAuditTime,
+	}
+
+	err = graphContract.CreateNode(iCtx, &attestation)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putIndex(iCtx, attestationObjectType, []string{iSubjectId, iNodeId})
+	if err != nil {
+		return nil, err
+	}
+
+	if iSubjectType != eMaterialSubject {
+		return graph.MakeTransactionReceipt(iCtx)
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putAuditEntry(iCtx, iSubjectId, eAttested, fmt.Sprintf("%s by %s", iNodeId, ownerFingerprint(iAuditorPublicKey))))
+}
+
+/// iSubjectId is either a material id or a GLN
+func (c *MaterialContract) GetAttestations(
+	iCtx contractapi.TransactionContextInterface,
+	iSubjectId string,
+) ([]Attestation, error) {
+	return getAttestations(iCtx, iSubjectId)
+}
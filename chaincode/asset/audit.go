@@ -0,0 +1,183 @@
+package asset
+
+import (
+	"encoding/json"
+	"sig_chain/chaincode/graph"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+type AuditEvent = string
+
+const (
+	eNodeUpdated         AuditEvent = "eNodeUpdated"
+	eCreated             AuditEvent = "eCreated"
+	eDerived             AuditEvent = "eDerived"
+	eAdjusted            AuditEvent = "eAdjusted"
+	eReserved            AuditEvent = "eReserved"
+	eReservationReleased AuditEvent = "eReservationReleased"
+	eTransferOffered     AuditEvent = "eTransferOffered"
+	eTransferCancelled   AuditEvent = "eTransferCancelled"
+	eTransferRejected    AuditEvent = "eTransferRejected"
+	eRecalled            AuditEvent = "eRecalled"
+	eDocumentAttached    AuditEvent = "eDocumentAttached"
+	eQualityRecorded     AuditEvent = "eQualityRecorded"
+	eCustodyRecorded     AuditEvent = "eCustodyRecorded"
+	eSensorRecorded      AuditEvent = "eSensorRecorded"
+	eDowngraded          AuditEvent = "eDowngraded"
+	eCertified           AuditEvent = "eCertified"
+	eCertificateRevoked  AuditEvent = "eCertificateRevoked"
+	eCertificateRenewed  AuditEvent = "eCertificateRenewed"
+	eAttested            AuditEvent = "eAttested"
+	ePersonalDataSet     AuditEvent = "ePersonalDataSet"
+	ePersonalDataPurged  AuditEvent = "ePersonalDataPurged"
+)
+
+const auditObjectType = "audit"
+
+/// the submitter fields identify the Fabric identity which submitted the transaction, they are empty
+/// for eNodeUpdated entries
+type AuditEntry struct {
+	NodeId               string     `json:"NodeId"`
+	Event                AuditEvent `json:"Event"`
+	Detail               string     `json:"Detail"`
+	TxId                 string     `json:"TxId"`
+	Timestamp            time.Time  `json:"Timestamp"`
+	SubmitterMspId       string     `json:"SubmitterMspId"`
+	SubmitterFingerprint string     `json:"SubmitterFingerprint"`
+}
+
+/// Material is only set for eNodeUpdated entries, which come from the key history of the node
+type MaterialHistoryEntry struct {
+	AuditEntry
+	Material *Material `json:"Material,omitempty" metadata:",optional"`
+	IsDelete bool      `json:"IsDelete"`
+}
+
+/// must be called by every operation modifying a material or its records
+func putAuditEntry(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iEvent AuditEvent,
+	iDetail string,
+) error {
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return err
+	}
+
+	submitter, err := graph.MakeTransactionSubmitter(iCtx)
+	if err != nil {
+		return err
+	}
+
+	entry := AuditEntry{
+		NodeId:               iNodeId,
+		Event:                iEvent,
+		Detail:               iDetail,
+		TxId:                 iCtx.GetStub().GetTxID(),
+		Timestamp:            transactionTime,
+		SubmitterMspId:       submitter.MspId,
+		SubmitterFingerprint: submitter.Fingerprint,
+	}
+	entryJson, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(auditObjectType, []string{iNodeId, entry.TxId, iEvent})
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().PutState(key, entryJson)
+}
+
+func getAuditEntries(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) ([]AuditEntry, error) {
+	iterator, err := iCtx.GetStub().GetStateByPartialCompositeKey(auditObjectType, []string{iNodeId})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	entries := []AuditEntry{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var entry AuditEntry
+		err = json.Unmarshal(kv.Value, &entry)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+/// returns every version of the node along with the audit entries of the material, oldest first
+func (c *MaterialContract) GetMaterialHistory(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) ([]MaterialHistoryEntry, error) {
+	iterator, err := iCtx.GetStub().GetHistoryForKey(iNodeId)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	history := []MaterialHistoryEntry{}
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		entry := MaterialHistoryEntry{
+			AuditEntry: AuditEntry{
+				NodeId:    iNodeId,
+				Event:     eNodeUpdated,
+				TxId:      modification.TxId,
+				Timestamp: time.Unix(modification.Timestamp.Seconds, int64(modification.Timestamp.Nanos)).UTC(),
+			},
+			IsDelete: modification.IsDelete,
+		}
+
+		if !modification.IsDelete {
+			var material Material
+			err = json.Unmarshal(modification.Value, &material)
+			if err != nil {
+				return nil, err
+			}
+			entry.Material = &material
+		}
+
+		history = append(history, entry)
+	}
+
+	auditEntries, err := getAuditEntries(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, auditEntry := range auditEntries {
+		history = append(history, MaterialHistoryEntry{
+			AuditEntry: auditEntry,
+		})
+	}
+
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Timestamp.Before(history[j].Timestamp)
+	})
+
+	return history, nil
+}
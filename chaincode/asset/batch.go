@@ -0,0 +1,183 @@
+package asset
+
+import (
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+type BatchOperationType = string
+
+const (
+	eCreateBatchOperation   BatchOperationType = "eCreateBatchOperation"
+	eEdgeBatchOperation     BatchOperationType = "eEdgeBatchOperation" /// between generic nodes, see GraphContract.CreateGenericEdge
+	eTransferBatchOperation BatchOperationType = "eTransferBatchOperation"
+	eAttachBatchOperation   BatchOperationType = "eAttachBatchOperation"  /// of a document
+	eCertifyBatchOperation  BatchOperationType = "eCertifyBatchOperation" /// attachment of a certificate
+)
+
+/// Parameters of GraphContract.CreateGenericEdge
+type EdgeSpec struct {
+	NodeId            string `json:"NodeId"`
+	Signature         string `json:"Signature"`
+	NextNodeId        string `json:"NextNodeId"`
+	NextNodeSignature string `json:"NextNodeSignature"`
+}
+
+/// Parameters of TransferMaterial
+type TransferSpec struct {
+	NodeId            string    `json:"NodeId"`
+	NewNodeId         string    `json:"NewNodeId"`
+	NewOwnerPublicKey string    `json:"NewOwnerPublicKey"`
+	NewOwnerMspId     string    `json:"NewOwnerMspId,omitempty" metadata:",optional"`
+	Signature         string    `json:"Signature"`
+	NewNodeSignature  string    `json:"NewNodeSignature"`
+	TransferTime      time.Time `json:"TransferTime"`
+}
+
+/// Parameters of AttachDocument
+type DocumentSpec struct {
+	NodeId    string `json:"NodeId"`
+	DocHash   string `json:"DocHash"`
+	DocType   string `json:"DocType"`
+	Uri       string `json:"Uri"`
+	Signature string `json:"Signature"`
+}
+
+/// Parameters of CertificateContract.AttachCertificateToMaterial
+type CertificationSpec struct {
+	CertificateId string `json:"CertificateId"`
+	MaterialId    string `json:"MaterialId"`
+	Signature     string `json:"Signature"`
+}
+
+/// only the spec matching Type is used
+type BatchOperation struct {
+	Type          BatchOperationType `json:"Type"`
+	Create        *MaterialSpec      `json:"Create,omitempty" metadata:",optional"`
+	Edge          *EdgeSpec          `json:"Edge,omitempty" metadata:",optional"`
+	Transfer      *TransferSpec      `json:"Transfer,omitempty" metadata:",optional"`
+	Attach        *DocumentSpec      `json:"Attach,omitempty" metadata:",optional"`
+	Certification *CertificationSpec `json:"Certification,omitempty" metadata:",optional"`
+}
+
+/// returns an error if the spec matching the type of iOperation is missing
+func checkBatchOperationSpec(
+	iOperation *BatchOperation,
+) error {
+	isMissing := false
+	switch iOperation.Type {
+	case eCreateBatchOperation:
+		isMissing = iOperation.Create == nil
+	case eEdgeBatchOperation:
+		isMissing = iOperation.Edge == nil
+	case eTransferBatchOperation:
+		isMissing = iOperation.Transfer == nil
+	case eAttachBatchOperation:
+		isMissing = iOperation.Attach == nil
+	case eCertifyBatchOperation:
+		isMissing = iOperation.Certification == nil
+	default:
+		return fmt.Errorf("invalid operation type %s", iOperation.Type)
+	}
+
+	if isMissing {
+		return fmt.Errorf("operation %s is missing its parameters", iOperation.Type)
+	}
+
+	return nil
+}
+
+/// operations may use the nodes of the previous ones, see batchStub. The transient price, settlement and the
+/// certificate log event can only be used once per transaction, so there can be at most one transfer and one
+/// certification
+func checkBatchOperations(
+	iOperations []BatchOperation,
+) error {
+	if len(iOperations) == 0 {
+		return fmt.Errorf("operations cannot be empty")
+	}
+
+	counts := map[BatchOperationType]int{}
+	for i := range iOperations {
+		err := checkBatchOperationSpec(&iOperations[i])
+		if err != nil {
+			return fmt.Errorf("operation %d: %v", i, err)
+		}
+
+		counts[iOperations[i].Type]++
+	}
+
+	if counts[eTransferBatchOperation] > 1 {
+		return fmt.Errorf("there can be at most one transfer")
+	}
+
+	if counts[eCertifyBatchOperation] > 1 {
+		return fmt.Errorf("there can be at most one certification")
+	}
+
+	return nil
+}
+
+func (c *MaterialContract) executeBatchOperation(
+	iCtx contractapi.TransactionContextInterface,
+	iOperation *BatchOperation,
+) error {
+	/// the receipts of the operations are replaced by the receipt of the whole batch
+	var err error
+	switch iOperation.Type {
+	case eCreateBatchOperation:
+		err = c.createMaterial(iCtx, iOperation.Create)
+	case eEdgeBatchOperation:
+		graphContract := graph.GraphContract{}
+		edge := iOperation.Edge
+		_, err = graphContract.CreateGenericEdge(iCtx, edge.NodeId, edge.Signature, edge.NextNodeId, edge.NextNodeSignature)
+	case eTransferBatchOperation:
+		transfer := iOperation.Transfer
+		_, err = c.TransferMaterial(
+			iCtx,
+			transfer.NodeId,
+			transfer.NewNodeId,
+			transfer.NewOwnerPublicKey,
+			transfer.NewOwnerMspId,
+			transfer.Signature,
+			transfer.NewNodeSignature,
+			transfer.TransferTime,
+		)
+	case eAttachBatchOperation:
+		document := iOperation.Attach
+		_, err = c.AttachDocument(iCtx, document.NodeId, document.DocHash, document.DocType, document.Uri, document.Signature)
+	default:
+		certificateContract := CertificateContract{}
+		certification := iOperation.Certification
+		_, err = certificateContract.AttachCertificateToMaterial(iCtx, certification.CertificateId, certification.MaterialId, certification.Signature)
+	}
+
+	return err
+}
+
+/// applies iOperations in order within a single transaction, none are applied if one of them fails.
+/// Operations are signed as if they were submitted on their own, one after the other, so that an operation
+/// can depend on the previous ones, e.g. a material created then transferred
+func (c *MaterialContract) Execute(
+	iCtx contractapi.TransactionContextInterface,
+	iOperations []BatchOperation,
+) (*graph.TransactionReceipt, error) {
+	err := checkBatchOperations(iOperations)
+	if err != nil {
+		return nil, err
+	}
+
+	batchCtx := makeBatchContext(iCtx)
+	for i := range iOperations {
+		err = c.executeBatchOperation(batchCtx, &iOperations[i])
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s) failed: %v", i, iOperations[i].Type, err)
+		}
+		batchCtx.endOperation()
+	}
+
+	return graph.MakeTransactionReceipt(iCtx)
+}
@@ -0,0 +1,186 @@
+package asset
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+)
+
+/// the ledger does not return the writes of the current transaction, the stub of a batch returns the writes of
+/// its previous operations so that an operation can use the nodes the previous ones created or changed, as if
+/// they were committed. The writes of the operation being executed are not returned, as they would not be if
+/// it was submitted on its own. Paginated and rich queries, private data and key histories only see the ledger
+type batchStub struct {
+	shim.ChaincodeStubInterface
+	previousWrites map[string][]byte /// nil for deleted keys
+	writes         map[string][]byte /// of the operation being executed
+}
+
+type batchContext struct {
+	contractapi.TransactionContextInterface
+	stub *batchStub
+}
+
+func makeBatchContext(
+	iCtx contractapi.TransactionContextInterface,
+) *batchContext {
+	return &batchContext{
+		TransactionContextInterface: iCtx,
+		stub: &batchStub{
+			ChaincodeStubInterface: iCtx.GetStub(),
+			previousWrites:         map[string][]byte{},
+			writes:                 map[string][]byte{},
+		},
+	}
+}
+
+func (c *batchContext) GetStub() shim.ChaincodeStubInterface {
+	return c.stub
+}
+
+/// makes the writes of the operation which was executed visible to the next ones
+func (c *batchContext) endOperation() {
+	for key, value := range c.stub.writes {
+		c.stub.previousWrites[key] = value
+	}
+	c.stub.writes = map[string][]byte{}
+}
+
+func (s *batchStub) GetState(
+	iKey string,
+) ([]byte, error) {
+	if value, ok := s.previousWrites[iKey]; ok {
+		return value, nil
+	}
+
+	return s.ChaincodeStubInterface.GetState(iKey)
+}
+
+func (s *batchStub) PutState(
+	iKey string,
+	iValue []byte,
+) error {
+	err := s.ChaincodeStubInterface.PutState(iKey, iValue)
+	if err != nil {
+		return err
+	}
+
+	/// Fabric stores empty values as deletions
+	if len(iValue) == 0 {
+		s.writes[iKey] = nil
+	} else {
+		s.writes[iKey] = append([]byte{}, iValue...)
+	}
+	return nil
+}
+
+func (s *batchStub) DelState(
+	iKey string,
+) error {
+	err := s.ChaincodeStubInterface.DelState(iKey)
+	if err != nil {
+		return err
+	}
+
+	s.writes[iKey] = nil
+	return nil
+}
+
+/// iIterator merged with the previous writes whose keys iIsInRange, in the order of their keys
+func (s *batchStub) mergePreviousWrites(
+	iIterator shim.StateQueryIteratorInterface,
+	iIsInRange func(iKey string) bool,
+) (shim.StateQueryIteratorInterface, error) {
+	defer iIterator.Close()
+
+	values := map[string][]byte{}
+	for iIterator.HasNext() {
+		kv, err := iIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		values[kv.Key] = kv.Value
+	}
+
+	for key, value := range s.previousWrites {
+		if !iIsInRange(key) {
+			continue
+		}
+
+		if value == nil {
+			delete(values, key)
+		} else {
+			values[key] = value
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]*queryresult.KV, 0, len(keys))
+	for _, key := range keys {
+		kvs = append(kvs, &queryresult.KV{Key: key, Value: values[key]})
+	}
+
+	return &batchStateIterator{kvs: kvs}, nil
+}
+
+/// iEndKey is excluded, an empty iEndKey is the end of the simple keys
+func (s *batchStub) GetStateByRange(
+	iStartKey string,
+	iEndKey string,
+) (shim.StateQueryIteratorInterface, error) {
+	iterator, err := s.ChaincodeStubInterface.GetStateByRange(iStartKey, iEndKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.mergePreviousWrites(iterator, func(iKey string) bool {
+		/// composite keys start with the null character, which range queries skip
+		return !strings.HasPrefix(iKey, "\x00") && iKey >= iStartKey && (iEndKey == "" || iKey < iEndKey)
+	})
+}
+
+func (s *batchStub) GetStateByPartialCompositeKey(
+	iObjectType string,
+	iAttributes []string,
+) (shim.StateQueryIteratorInterface, error) {
+	prefix, err := s.CreateCompositeKey(iObjectType, iAttributes)
+	if err != nil {
+		return nil, err
+	}
+
+	iterator, err := s.ChaincodeStubInterface.GetStateByPartialCompositeKey(iObjectType, iAttributes)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.mergePreviousWrites(iterator, func(iKey string) bool {
+		return strings.HasPrefix(iKey, prefix)
+	})
+}
+
+type batchStateIterator struct {
+	kvs   []*queryresult.KV
+	index int
+}
+
+func (i *batchStateIterator) HasNext() bool {
+	return i.index < len(i.kvs)
+}
+
+func (i *batchStateIterator) Next() (*queryresult.KV, error) {
+	kv := i.kvs[i.index]
+	i.index++
+	return kv, nil
+}
+
+func (i *batchStateIterator) Close() error {
+	return nil
+}
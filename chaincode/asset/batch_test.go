@@ -0,0 +1,80 @@
+package asset_test
+
+import (
+	"sig_chain/chaincode/asset"
+	"sig_chain/pkg/client"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// operations creating iNodeId for iOwner and transferring it to iNewOwner as iNewNodeId, signed by iSender
+func makeCreateAndTransfer(
+	t *testing.T,
+	iNodeId string,
+	iOwner client.Signer,
+	iSender client.Signer,
+	iNewNodeId string,
+	iNewOwner client.Signer,
+) []asset.BatchOperation {
+	material := makeTestMaterial(iNodeId, "10", iOwner)
+	newMaterial := makeTransferredMaterial(material, iNewNodeId, iNewOwner.GetPublicKey())
+	return []asset.BatchOperation{
+		{
+			Type: "eCreateBatchOperation",
+			Create: &asset.MaterialSpec{
+				NodeId:         iNodeId,
+				Name:           "flour",
+				Unit:           "kg",
+				Quantity:       "10",
+				OwnerPublicKey: iOwner.GetPublicKey(),
+				CreatedTime:    testTime,
+				Signature:      signNode(t, iOwner, &material),
+			},
+		},
+		{
+			Type: "eTransferBatchOperation",
+			Transfer: &asset.TransferSpec{
+				NodeId:            iNodeId,
+				NewNodeId:         iNewNodeId,
+				NewOwnerPublicKey: iNewOwner.GetPublicKey(),
+				NewOwnerMspId:     "Org1MSP",
+				Signature:         signTransfer(t, iSender, material, iNewNodeId, iNewOwner.GetPublicKey()),
+				NewNodeSignature:  signNode(t, iNewOwner, &newMaterial),
+				TransferTime:      testTime,
+			},
+		},
+	}
+}
+
+func TestExecuteDependentOperations(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	bob := makeTestSigner(t)
+
+	/// the transfer fails, so the material is not created either
+	operations := makeCreateAndTransfer(t, "m1", alice, bob, "m2", bob)
+	l.mustFail("create and transfer signed by the receiver", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.Execute(iCtx, operations)
+		return err
+	})
+	l.mustFail("get m1", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.GetMaterial(iCtx, "m1")
+		return err
+	})
+
+	operations = makeCreateAndTransfer(t, "m1", alice, alice, "m2", bob)
+	l.mustSubmit("create and transfer", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.Execute(iCtx, operations)
+		return err
+	})
+
+	if !l.getMaterial("m1").IsFinalized {
+		t.Fatal("m1 is not finalized by its transfer")
+	}
+	if l.getMaterial("m2").OwnerPublicKey != bob.GetPublicKey() {
+		t.Fatal("m2 is not owned by the receiver")
+	}
+	l.checkNodeSignature("m1")
+	l.checkNodeSignature("m2")
+}
@@ -0,0 +1,141 @@
+package asset
+
+import (
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const bootstrappedKey = "bootstrapped"
+
+/// Signature is the authority's signature of its node, as in CreateCertificateAuthority
+type RootAuthoritySpec struct {
+	NodeId         string    `json:"NodeId"`
+	OwnerPublicKey string    `json:"OwnerPublicKey"`
+	CreatedTime    time.Time `json:"CreatedTime"`
+	Signature      string    `json:"Signature"`
+}
+
+/// Signed by the administrator being configured, which approves every root authority listed
+type BootstrapConfig struct {
+	AdminPublicKey      string               `json:"AdminPublicKey"`
+	UseTransactionTime  bool                 `json:"UseTransactionTime"`
+	ClockDriftTolerance int64                `json:"ClockDriftTolerance"` /// in seconds, the default tolerance if omitted
+	RootAuthorities     []RootAuthoritySpec  `json:"RootAuthorities"`
+	TrustedRoots        []graph.TrustedRoots `json:"TrustedRoots"`
+	AdminMspIds         []string             `json:"AdminMspIds"` /// MSPs whose admins can change settings with SetConfig
+	Signature           string               `json:"Signature"`
+}
+
+/// meant to be the init transaction of the chaincode, it can only be run once and before any administrator
+/// is configured, by a channel admin of one of the admin MSPs being configured. Writes of a transaction cannot be read back before it is committed, so root authorities
+/// are created with the default time configuration.
+/// iSignature is the administrator's signature of the BootstrapConfig
+func (c *MaterialContract) InitLedger(
+	iCtx contractapi.TransactionContextInterface,
+	iConfig BootstrapConfig,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	bootstrapped, err := getConfigValue(iCtx, bootstrappedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if bootstrapped != nil {
+		return nil, fmt.Errorf("ledger is already bootstrapped")
+	}
+
+	currentAdmin, err := c.GetAdministrator(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	if currentAdmin != "" {
+		return nil, fmt.Errorf("administrator is already configured")
+	}
+
+	if iConfig.AdminPublicKey == "" {
+		return nil, fmt.Errorf("admin public key cannot be empty")
+	}
+
+	if iConfig.ClockDriftTolerance < 0 {
+		return nil, fmt.Errorf("tolerance cannot be negative")
+	}
+
+	adminMspIds, err := normalizeConfigValue(eMspIdListConfig, strings.Join(iConfig.AdminMspIds, ","))
+	if err != nil {
+		return nil, fmt.Errorf("invalid admin msp ids: %v", err)
+	}
+
+	/// anyone could otherwise take over a ledger deployed without its init transaction
+	err = checkAdminSubmitter(iCtx, adminMspIds)
+	if err != nil {
+		return nil, err
+	}
+
+	iConfig.Signature = ""
+	err = graph.VerifyPayload(iConfig.AdminPublicKey, &iConfig, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	/// a zero tolerance rejects every client time, an omitted tolerance is the default one
+	clockDriftTolerance := iConfig.ClockDriftTolerance
+	if clockDriftTolerance == 0 {
+		clockDriftTolerance = defaultClockDriftTolerance
+	}
+
+	err = putConfigValue(iCtx, adminPublicKeyKey, []byte(iConfig.AdminPublicKey))
+	if err != nil {
+		return nil, err
+	}
+
+	err = putConfigValue(iCtx, adminMspIdsKey, []byte(adminMspIds))
+	if err != nil {
+		return nil, err
+	}
+
+	err = putConfigValue(iCtx, useTransactionTimeKey, []byte(strconv.FormatBool(iConfig.UseTransactionTime)))
+	if err != nil {
+		return nil, err
+	}
+
+	err = putConfigValue(iCtx, clockDriftToleranceKey, []byte(strconv.FormatInt(clockDriftTolerance, 10)))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range iConfig.TrustedRoots {
+		err = graph.PutTrustedRoots(iCtx, &iConfig.TrustedRoots[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	logEntries := []CertificateLogEntry{}
+	for _, root := range iConfig.RootAuthorities {
+		err = createRootCertificateAuthority(iCtx, root.NodeId, root.OwnerPublicKey, root.CreatedTime, root.Signature)
+		if err != nil {
+			return nil, err
+		}
+
+		logEntries = append(logEntries, CertificateLogEntry{
+			Event:       eLogAuthorityCreated,
+			NodeId:      root.NodeId,
+			AuthorityId: root.NodeId,
+		})
+	}
+
+	if len(logEntries) > 0 {
+		err = appendCertificateLogEntries(iCtx, logEntries)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putConfigValue(iCtx, bootstrappedKey, []byte("true")))
+}
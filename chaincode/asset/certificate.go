@@ -0,0 +1,819 @@
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	certifiesObjectType              = "certifies"
+	requiredCertificationsObjectType = "requiredCertifications"
+)
+
+/// Root authorities are approved by the administrator, intermediate authorities by their parent
+type CertificateAuthority struct {
+	graph.NodeHeader
+	RootId   string `json:"RootId"`   /// Easier to trace since the node only stores hash of the issuer
+	ParentId string `json:"ParentId"` /// empty for root authorities
+}
+
+func (a *CertificateAuthority) GetHeader() graph.NodeHeader {
+	return a.NodeHeader
+}
+func (a *CertificateAuthority) SetHeader(iHeader graph.NodeHeader) {
+	a.NodeHeader = iHeader
+}
+
+/// The node is signed by its issuer, SubjectId is the material it certifies.
+/// A certificate also covers every material derived from its subject.
+/// The node is finalized once it is renewed and points to its renewal
+type Certificate struct {
+	graph.NodeHeader
+	CertificateType       string            `json:"CertificateType"` /// e.g. "organic"
+	SubjectId             string            `json:"SubjectId"`
+	IssueTime             time.Time         `json:"IssueTime"`
+	ExpiryTime            time.Time         `json:"ExpiryTime"`
+	IssuerId              string            `json:"IssuerId"`              /// Easier to trace since the node only stores hash of the issuer
+	PreviousCertificateId string            `json:"PreviousCertificateId"` /// the renewed certificate, empty for the first one
+	Scope                 CertificateScope  `json:"Scope"`
+	CoIssuerIds           []string          `json:"CoIssuerIds"` /// authorities which must also sign the certificate, see CoSignCertificate
+	Claims                map[string]string `json:"Claims"`      /// checked against the ClaimSchema of the type
+	Credential            string            `json:"Credential"`  /// JWT encoded W3C verifiable credential the certificate was imported from, empty otherwise
+}
+
+func (c *Certificate) GetHeader() graph.NodeHeader {
+	return c.NodeHeader
+}
+func (c *Certificate) SetHeader(iHeader graph.NodeHeader) {
+	c.NodeHeader = iHeader
+}
+
+/// Signed by the owner, materials are only transferred to it if they hold certificates of every type
+type CertificationRequirement struct {
+	OwnerPublicKey   string   `json:"OwnerPublicKey"`
+	CertificateTypes []string `json:"CertificateTypes"`
+	Signature        string   `json:"Signature"`
+}
+
+/// returns the certificates whose subject is iMaterialId or which are attached to it
+func getCertificates(
+	iCtx contractapi.TransactionContextInterface,
+	iMaterialId string,
+) ([]Certificate, error) {
+	certificateIds, err := getIndexedIds(iCtx, certifiesObjectType, []string{iMaterialId})
+	if err != nil {
+		return nil, err
+	}
+
+	graphContract := graph.GraphContract{}
+	certificates := []Certificate{}
+	for _, certificateId := range certificateIds {
+		var certificate Certificate
+		err = graphContract.GetNode(iCtx, certificateId, &certificate)
+		if err != nil {
+			return nil, err
+		}
+
+		certificates = append(certificates, certificate)
+	}
+
+	return certificates, nil
+}
+
+/// returns nil if iCertificate is valid at iTime, the reason it is not otherwise
+func checkCertificate(
+	iCtx contractapi.TransactionContextInterface,
+	iCertificate *Certificate,
+	iTime time.Time,
+) error {
+	if iTime.Before(iCertificate.IssueTime) {
+		return fmt.Errorf("certificate %s is not valid yet", iCertificate.Id)
+	}
+
+	if !iTime.Before(iCertificate.ExpiryTime) {
+		return fmt.Errorf("certificate %s is expired", iCertificate.Id)
+	}
+
+	err := checkIssuerChain(iCtx, iCertificate.Id, iCertificate.IssuerId, iTime)
+	if err != nil {
+		return err
+	}
+
+	for _, coIssuerId := range iCertificate.CoIssuerIds {
+		err = checkCoSignature(iCtx, iCertificate, coIssuerId)
+		if err != nil {
+			return err
+		}
+
+		err = checkIssuerChain(iCtx, iCertificate.Id, coIssuerId, iTime)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/// walks up from iIssuerId to its root, every authority must be revoked by none of its ancestors at iTime
+func checkIssuerChain(
+	iCtx contractapi.TransactionContextInterface,
+	iCertificateId string,
+	iIssuerId string,
+	iTime time.Time,
+) error {
+	graphContract := graph.GraphContract{}
+	childId := iCertificateId
+	authorityId := iIssuerId
+	rootId := ""
+	visited := map[string]bool{}
+	for authorityId != "" {
+		if visited[authorityId] {
+			return fmt.Errorf("certificate authority chain of %s has a cycle", iCertificateId)
+		}
+		visited[authorityId] = true
+
+		var authority CertificateAuthority
+		err := graphContract.GetNode(iCtx, authorityId, &authority)
+		if err != nil {
+			return err
+		}
+
+		if authority.Type != eCertificateAuthority {
+			return fmt.Errorf("node %s is not a certificate authority", authorityId)
+		}
+
+		if rootId != "" && authority.RootId != rootId {
+			return fmt.Errorf("certificate authority %s does not belong to root %s", authorityId, rootId)
+		}
+		rootId = authority.RootId
+
+		revocation, err := getRevocation(iCtx, authorityId, childId)
+		if err != nil {
+			return err
+		}
+
+		if revocation != nil && !iTime.Before(revocation.EffectiveTime) {
+			return fmt.Errorf("%s is revoked by %s since %s: %s", childId, authorityId, revocation.EffectiveTime.Format(time.RFC3339), revocation.ReasonCode)
+		}
+
+		if authority.ParentId == "" && authority.RootId != authorityId {
+			return fmt.Errorf("certificate authority %s is not a root", authorityId)
+		}
+
+		childId = authorityId
+		authorityId = authority.ParentId
+	}
+
+	return nil
+}
+
+/// the product template of iMaterial, if any, must allow iCertificateType
+func checkCertificateTypeAllowed(
+	iCtx contractapi.TransactionContextInterface,
+	iMaterial *Material,
+	iCertificateType string,
+) error {
+	if iMaterial.TemplateId == "" {
+		return nil
+	}
+
+	product, err := getProductDefinition(iCtx, iMaterial.TemplateId)
+	if err != nil {
+		return err
+	}
+
+	if product == nil || len(product.AllowedCertifications) == 0 {
+		return nil
+	}
+
+	for _, allowedType := range product.AllowedCertifications {
+		if allowedType == iCertificateType {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("certificate type %s is not allowed for product %s", iCertificateType, product.TemplateId)
+}
+
+func getCertificateStatus(
+	iCtx contractapi.TransactionContextInterface,
+	iCertificate *Certificate,
+	iTime time.Time,
+) CertificateStatus {
+	status := CertificateStatus{
+		CertificateId: iCertificate.Id,
+		IsValid:       true,
+	}
+
+	err := checkCertificate(iCtx, iCertificate, iTime)
+	if err != nil {
+		status.IsValid = false
+		status.Reason = err.Error()
+	}
+
+	return status
+}
+
+/// returns nil if iOwnerPublicKey has no requirement
+func getCertificationRequirement(
+	iCtx contractapi.TransactionContextInterface,
+	iOwnerPublicKey string,
+) (*CertificationRequirement, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(requiredCertificationsObjectType, []string{ownerFingerprint(iOwnerPublicKey)})
+	if err != nil {
+		return nil, err
+	}
+
+	requirementJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if requirementJson == nil {
+		return nil, nil
+	}
+
+	var requirement CertificationRequirement
+	err = json.Unmarshal(requirementJson, &requirement)
+	if err != nil {
+		return nil, err
+	}
+
+	return &requirement, nil
+}
+
+/// iMaterial, or one of its ancestors, must hold a valid certificate of every type required by its
+/// product template and by iNewOwnerPublicKey
+func checkRequiredCertifications(
+	iCtx contractapi.TransactionContextInterface,
+	iMaterial *Material,
+	iNewOwnerPublicKey string,
+) error {
+	requiredTypes := []string{}
+	if iMaterial.TemplateId != "" {
+		product, err := getProductDefinition(iCtx, iMaterial.TemplateId)
+		if err != nil {
+			return err
+		}
+
+		if product != nil {
+			requiredTypes = append(requiredTypes, product.RequiredCertifications...)
+		}
+	}
+
+	requirement, err := getCertificationRequirement(iCtx, iNewOwnerPublicKey)
+	if err != nil {
+		return err
+	}
+
+	if requirement != nil {
+		requiredTypes = append(requiredTypes, requirement.CertificateTypes...)
+	}
+
+	if len(requiredTypes) == 0 {
+		return nil
+	}
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return err
+	}
+
+	lineageIds, err := getLineageIds(iCtx, iMaterial.Id)
+	if err != nil {
+		return err
+	}
+
+	validTypes := map[string]bool{}
+	invalidReasons := map[string]error{}
+	for _, nodeId := range lineageIds {
+		certificates, err := getCertificates(iCtx, nodeId)
+		if err != nil {
+			return err
+		}
+
+		for i := range certificates {
+			err = checkCertificate(iCtx, &certificates[i], transactionTime)
+			if err == nil {
+				validTypes[certificates[i].CertificateType] = true
+			} else {
+				invalidReasons[certificates[i].CertificateType] = err
+			}
+		}
+	}
+
+	for _, requiredType := range requiredTypes {
+		if validTypes[requiredType] {
+			continue
+		}
+
+		if reason, ok := invalidReasons[requiredType]; ok {
+			return fmt.Errorf("material has no valid %s certificate: %v", requiredType, reason)
+		}
+
+		return fmt.Errorf("material has no valid %s certificate", requiredType)
+	}
+
+	return nil
+}
+
+/// iSignature is the owner's signature of the CertificationRequirement, an empty list removes the requirement
+func (c *MaterialContract) SetRequiredCertifications(
+	iCtx contractapi.TransactionContextInterface,
+	iOwnerPublicKey string,
+	iCertificateTypes []string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	requirement := CertificationRequirement{
+		OwnerPublicKey:   iOwnerPublicKey,
+		CertificateTypes: iCertificateTypes,
+	}
+	err := graph.VerifyPayload(iOwnerPublicKey, &requirement, iSignature)
+	if err != nil {
+		return nil, err
+	}
+	requirement.Signature = iSignature
+
+	key, err := iCtx.GetStub().CreateCompositeKey(requiredCertificationsObjectType, []string{ownerFingerprint(iOwnerPublicKey)})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(iCertificateTypes) == 0 {
+		return graph.MakeTransactionReceiptIfSucceeded(iCtx, iCtx.GetStub().DelState(key))
+	}
+
+	requirementJson, err := json.Marshal(requirement)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, iCtx.GetStub().PutState(key, requirementJson))
+}
+
+func (c *MaterialContract) GetRequiredCertifications(
+	iCtx contractapi.TransactionContextInterface,
+	iOwnerPublicKey string,
+) ([]string, error) {
+	requirement, err := getCertificationRequirement(iCtx, iOwnerPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if requirement == nil {
+		return []string{}, nil
+	}
+
+	return requirement.CertificateTypes, nil
+}
+
+/// Signed by the administrator for root authorities or by the parent authority for intermediate ones,
+/// allows OwnerPublicKey to issue certificates
+type CertificateAuthorityApproval struct {
+	NodeId         string `json:"NodeId"`
+	OwnerPublicKey string `json:"OwnerPublicKey"`
+	Signature      string `json:"Signature"`
+}
+
+/// Signed by the issuer of the certificate, extends it to a material other than its subject
+type CertificateAttachment struct {
+	CertificateId string `json:"CertificateId"`
+	MaterialId    string `json:"MaterialId"`
+	Signature     string `json:"Signature"`
+}
+
+/// Result of VerifyCertificate, Reason is empty if the certificate is valid
+type CertificateStatus struct {
+	CertificateId string `json:"CertificateId"`
+	IsValid       bool   `json:"IsValid"`
+	Reason        string `json:"Reason"`
+}
+
+type CertificateContract struct {
+	contractapi.Contract
+}
+
+/// the caller is responsible for the approval of the authority and for logging its creation
+func createRootCertificateAuthority(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iOwnerPublicKey string,
+	iCreatedTime time.Time,
+	iSignature string,
+) error {
+	createdTime, err := getOperationTime(iCtx, iCreatedTime)
+	if err != nil {
+		return err
+	}
+
+	graphContract := graph.GraphContract{}
+	nodeHeader := graph.MakeNodeHeader(
+		iNodeId,
+		eCertificateAuthority,
+		false,
+		graph.MakeHashSet(),
+		graph.MakeHashSet(),
+		iOwnerPublicKey,
+		createdTime,
+		iSignature,
+	)
+
+	err = setTimeSource(iCtx, &nodeHeader)
+	if err != nil {
+		return err
+	}
+	authority := CertificateAuthority{
+		NodeHeader: nodeHeader,
+		RootId:     iNodeId,
+		ParentId:   "",
+	}
+
+	return graphContract.CreateNode(iCtx, &authority)
+}
+
+/// iSignature is the authority's signature of its node, iAdminSignature is the administrator's
+/// signature of the CertificateAuthorityApproval
+func (c *CertificateContract) CreateCertificateAuthority(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iOwnerPublicKey string,
+	iCreatedTime time.Time,
+	iSignature string,
+	iAdminSignature string,
+) (*graph.TransactionReceipt, error) {
+	err := CheckSubmitterRole(iCtx, eRegisterAuthorityOperation)
+	if err != nil {
+		return nil, err
+	}
+
+	approval := CertificateAuthorityApproval{
+		NodeId:         iNodeId,
+		OwnerPublicKey: iOwnerPublicKey,
+	}
+	err = verifyAdministratorSignature(iCtx, &approval, iAdminSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	err = createRootCertificateAuthority(iCtx, iNodeId, iOwnerPublicKey, iCreatedTime, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, appendCertificateLog(iCtx, eLogAuthorityCreated, iNodeId, iNodeId, ""))
+}
+
+/// iSignature is the authority's signature of its node, iParentSignature is the parent authority's
+/// signature of the CertificateAuthorityApproval
+func (c *CertificateContract) CreateIntermediateCertificateAuthority(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iParentId string,
+	iOwnerPublicKey string,
+	iCreatedTime time.Time,
+	iSignature string,
+	iParentSignature string,
+) (*graph.TransactionReceipt, error) {
+	err := CheckSubmitterRole(iCtx, eRegisterAuthorityOperation)
+	if err != nil {
+		return nil, err
+	}
+
+	parent, err := c.GetCertificateAuthority(iCtx, iParentId)
+	if err != nil {
+		return nil, err
+	}
+
+	approval := CertificateAuthorityApproval{
+		NodeId:         iNodeId,
+		OwnerPublicKey: iOwnerPublicKey,
+	}
+	err = graph.VerifyPayload(parent.OwnerPublicKey, &approval, iParentSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	createdTime, err := getOperationTime(iCtx, iCreatedTime)
+	if err != nil {
+		return nil, err
+	}
+
+	graphContract := graph.GraphContract{}
+	nodeHeader := graph.MakeNodeHeader(
+		iNodeId,
+		eCertificateAuthority,
+		false,
+		graph.MakeHashSet(graph.HashId(iParentId)),
+		graph.MakeHashSet(),
+		iOwnerPublicKey,
+		createdTime,
+		iSignature,
+	)
+
+	err = setTimeSource(iCtx, &nodeHeader)
+	if err != nil {
+		return nil, err
+	}
+	authority := CertificateAuthority{
+		NodeHeader: nodeHeader,
+		RootId:     parent.RootId,
+		ParentId:   iParentId,
+	}
+
+	err = graphContract.CreateNode(iCtx, &authority)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, appendCertificateLog(iCtx, eLogAuthorityCreated, iNodeId, iParentId, ""))
+}
+
+func (c *CertificateContract) GetCertificateAuthority(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) (*CertificateAuthority, error) {
+	graphContract := graph.GraphContract{}
+	var authority CertificateAuthority
+	err := graphContract.GetNode(iCtx, iNodeId, &authority)
+	if err != nil {
+		return nil, err
+	}
+
+	if authority.Type != eCertificateAuthority {
+		return nil, fmt.Errorf("node %s is not a certificate authority", iNodeId)
+	}
+
+	return &authority, nil
+}
+
+/// iScope is an optional json CertificateScope, iClaims is a json map checked against the ClaimSchema of the type,
+/// the certificate is only valid once every authority of iCoIssuerIds co-signed it,
+/// iSignature is the issuer's signature of the certificate node
+func (c *CertificateContract) IssueCertificate(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iIssuerId string,
+	iSubjectId string,
+	iCertificateType string,
+	iIssueTime time.Time,
+	iExpiryTime time.Time,
+	iScope string,
+	iClaims string,
+	iCoIssuerIds []string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	if iCertificateType == "" {
+		return nil, fmt.Errorf("certificate type cannot be empty")
+	}
+
+	err := checkQueryTime("expiry time", iExpiryTime)
+	if err != nil {
+		return nil, err
+	}
+
+	if !iExpiryTime.After(iIssueTime) {
+		return nil, fmt.Errorf("expiry time must be after issue time")
+	}
+
+	issuer, err := c.GetCertificateAuthority(iCtx, iIssuerId)
+	if err != nil {
+		return nil, err
+	}
+
+	coIssuers := map[string]bool{iIssuerId: true}
+	for _, coIssuerId := range iCoIssuerIds {
+		if coIssuers[coIssuerId] {
+			return nil, fmt.Errorf("authority %s is used more than once", coIssuerId)
+		}
+		coIssuers[coIssuerId] = true
+
+		_, err = c.GetCertificateAuthority(iCtx, coIssuerId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	materialContract := MaterialContract{}
+	subject, err := materialContract.GetMaterial(iCtx, iSubjectId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkCertificateTypeAllowed(iCtx, subject, iCertificateType)
+	if err != nil {
+		return nil, err
+	}
+
+	scope, err := parseCertificateScope(iScope)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := map[string]string{}
+	if iClaims != "" {
+		err = json.Unmarshal([]byte(iClaims), &claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = checkClaims(iCtx, iIssuerId, iCertificateType, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	graphContract := graph.GraphContract{}
+	nodeHeader := graph.MakeNodeHeader(
+		iNodeId,
+		eCertificate,
+		false,
+		graph.MakeHashSet(graph.HashId(iIssuerId)),
+		graph.MakeHashSet(),
+		issuer.OwnerPublicKey,
+		transactionTime,
+		iSignature,
+	)
+	certificate := Certificate{
+		NodeHeader:      nodeHeader,
+		CertificateType: iCertificateType,
+		SubjectId:       iSubjectId,
+		IssueTime:       iIssueTime,
+		ExpiryTime:      iExpiryTime,
+		IssuerId:        iIssuerId,
+		Scope:           scope,
+		Claims:          claims,
+		CoIssuerIds:     iCoIssuerIds,
+	}
+
+	err = checkCertificateRestrictions(iCtx, &certificate, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	err = graphContract.CreateNode(iCtx, &certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putCertification(iCtx, iNodeId, iSubjectId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putAuditEntry(iCtx, iSubjectId, eCertified, fmt.Sprintf("%s %s by %s", iCertificateType, iNodeId, iIssuerId))
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, appendCertificateLog(iCtx, eLogIssued, iNodeId, iIssuerId, fmt.Sprintf("%s for %s", iCertificateType, iSubjectId)))
+}
+
+func (c *CertificateContract) GetCertificate(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) (*Certificate, error) {
+	graphContract := graph.GraphContract{}
+	var certificate Certificate
+	err := graphContract.GetNode(iCtx, iNodeId, &certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	if certificate.Type != eCertificate {
+		return nil, fmt.Errorf("node %s is not a certificate", iNodeId)
+	}
+
+	return &certificate, nil
+}
+
+/// checks the certificate against the transaction's timestamp and the revocations along its issuer chain
+func (c *CertificateContract) VerifyCertificate(
+	iCtx contractapi.TransactionContextInterface,
+	iCertificateId string,
+) (*CertificateStatus, error) {
+	certificate, err := c.GetCertificate(iCtx, iCertificateId)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := getCertificateStatus(iCtx, certificate, transactionTime)
+	return &status, nil
+}
+
+/// iSignature is the issuer's signature of the CertificateAttachment, the certificate must be valid,
+/// allowed for the material and the material must be in its scope
+func (c *CertificateContract) AttachCertificateToMaterial(
+	iCtx contractapi.TransactionContextInterface,
+	iCertificateId string,
+	iMaterialId string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	certificate, err := c.GetCertificate(iCtx, iCertificateId)
+	if err != nil {
+		return nil, err
+	}
+
+	materialContract := MaterialContract{}
+	material, err := materialContract.GetMaterial(iCtx, iMaterialId)
+	if err != nil {
+		return nil, err
+	}
+
+	certificateIds, err := getIndexedIds(iCtx, certifiesObjectType, []string{iMaterialId})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, certificateId := range certificateIds {
+		if certificateId == iCertificateId {
+			return nil, fmt.Errorf("certificate %s is already attached to %s", iCertificateId, iMaterialId)
+		}
+	}
+
+	issuer, err := c.GetCertificateAuthority(iCtx, certificate.IssuerId)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment := CertificateAttachment{
+		CertificateId: iCertificateId,
+		MaterialId:    iMaterialId,
+	}
+	err = graph.VerifyPayload(issuer.OwnerPublicKey, &attachment, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkCertificate(iCtx, certificate, transactionTime)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkCertificateTypeAllowed(iCtx, material, certificate.CertificateType)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkCertificateRestrictions(iCtx, certificate, material)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putCertification(iCtx, iCertificateId, iMaterialId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putAuditEntry(iCtx, iMaterialId, eCertified, fmt.Sprintf("%s %s by %s", certificate.CertificateType, iCertificateId, certificate.IssuerId))
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, appendCertificateLog(iCtx, eLogAttached, iCertificateId, certificate.IssuerId, iMaterialId))
+}
+
+/// returns the certificates attached to the material or to one of its ancestors
+func (c *MaterialContract) GetMaterialCertificates(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) ([]Certificate, error) {
+	_, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	lineageIds, err := getLineageIds(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	certificates := []Certificate{}
+	for _, nodeId := range lineageIds {
+		nodeCertificates, err := getCertificates(iCtx, nodeId)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, nodeCertificates...)
+	}
+
+	return certificates, nil
+}
@@ -0,0 +1,210 @@
+package asset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+type CertificateLogEvent = string
+
+const (
+	eLogAuthorityCreated CertificateLogEvent = "eLogAuthorityCreated"
+	eLogIssued           CertificateLogEvent = "eLogIssued"
+	eLogRenewed          CertificateLogEvent = "eLogRenewed"
+	eLogAttached         CertificateLogEvent = "eLogAttached"
+	eLogRevoked          CertificateLogEvent = "eLogRevoked"
+	eLogAuthorityRevoked CertificateLogEvent = "eLogAuthorityRevoked"
+)
+
+const (
+	certificateLogObjectType = "certificateLog"
+	certificateLogHeadKey    = "certificateLogHead"
+	certificateLogEventName  = "CertificateLog"
+
+	maxCertificateLogPageSize = 1000
+)
+
+/// Hash covers the entry with an empty Hash, and PreviousHash is the Hash of the previous entry,
+/// so that rewriting an entry breaks every entry after it
+type CertificateLogEntry struct {
+	Sequence     int64               `json:"Sequence"`
+	Event        CertificateLogEvent `json:"Event"`
+	NodeId       string              `json:"NodeId"`      /// the certificate or authority the event is about
+	AuthorityId  string              `json:"AuthorityId"` /// the authority responsible for the event
+	Detail       string              `json:"Detail"`
+	TxId         string              `json:"TxId"`
+	Timestamp    time.Time           `json:"Timestamp"`
+	PreviousHash string              `json:"PreviousHash"`
+	Hash         string              `json:"Hash"`
+}
+
+/// Sequence is the number of entries in the log
+type certificateLogHead struct {
+	Sequence int64  `json:"Sequence"`
+	Hash     string `json:"Hash"`
+}
+
+func getCertificateLogHead(
+	iCtx contractapi.TransactionContextInterface,
+) (*certificateLogHead, error) {
+	headJson, err := getConfigValue(iCtx, certificateLogHeadKey)
+	if err != nil {
+		return nil, err
+	}
+
+	head := certificateLogHead{}
+	if headJson == nil {
+		return &head, nil
+	}
+
+	err = json.Unmarshal(headJson, &head)
+	if err != nil {
+		return nil, err
+	}
+
+	return &head, nil
+}
+
+func getCertificateLogKey(
+	iCtx contractapi.TransactionContextInterface,
+	iSequence int64,
+) (string, error) {
+	return iCtx.GetStub().CreateCompositeKey(certificateLogObjectType, []string{fmt.Sprintf("%020d", iSequence)})
+}
+
+/// appends an entry and emits it as a chaincode event, must be called at most once per transaction
+/// since the head written by the transaction cannot be read back before it is committed
+func appendCertificateLog(
+	iCtx contractapi.TransactionContextInterface,
+	iEvent CertificateLogEvent,
+	iNodeId string,
+	iAuthorityId string,
+	iDetail string,
+) error {
+	return appendCertificateLogEntries(iCtx, []CertificateLogEntry{
+		{
+			Event:       iEvent,
+			NodeId:      iNodeId,
+			AuthorityId: iAuthorityId,
+			Detail:      iDetail,
+		},
+	})
+}
+
+/// for transactions producing several entries, only Event, NodeId, AuthorityId and Detail of iEntries are used.
+/// The entries are emitted together as a single chaincode event
+func appendCertificateLogEntries(
+	iCtx contractapi.TransactionContextInterface,
+	iEntries []CertificateLogEntry,
+) error {
+	head, err := getCertificateLogHead(iCtx)
+	if err != nil {
+		return err
+	}
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return err
+	}
+
+	entries := []CertificateLogEntry{}
+	for _, entry := range iEntries {
+		entry.Sequence = head.Sequence
+		entry.TxId = iCtx.GetStub().GetTxID()
+		entry.Timestamp = transactionTime
+		entry.PreviousHash = head.Hash
+		entry.Hash = ""
+
+		entryJson, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256(entryJson)
+		entry.Hash = hex.EncodeToString(hash[:])
+
+		entryJson, err = json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+
+		key, err := getCertificateLogKey(iCtx, entry.Sequence)
+		if err != nil {
+			return err
+		}
+
+		err = iCtx.GetStub().PutState(key, entryJson)
+		if err != nil {
+			return err
+		}
+
+		head.Sequence = entry.Sequence + 1
+		head.Hash = entry.Hash
+		entries = append(entries, entry)
+	}
+
+	headJson, err := json.Marshal(head)
+	if err != nil {
+		return err
+	}
+
+	err = putConfigValue(iCtx, certificateLogHeadKey, headJson)
+	if err != nil {
+		return err
+	}
+
+	entriesJson, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().SetEvent(certificateLogEventName, entriesJson)
+}
+
+/// returns at most iPageSize entries starting at iFromSequence, monitors can resume from the
+/// sequence following the last entry they received and check the hash chain
+func (c *CertificateContract) GetCertificateLog(
+	iCtx contractapi.TransactionContextInterface,
+	iFromSequence int64,
+	iPageSize int64,
+) ([]CertificateLogEntry, error) {
+	if iFromSequence < 0 {
+		return nil, fmt.Errorf("sequence cannot be negative")
+	}
+
+	if iPageSize <= 0 || iPageSize > maxCertificateLogPageSize {
+		return nil, fmt.Errorf("page size must be between 1 and %d", maxCertificateLogPageSize)
+	}
+
+	head, err := getCertificateLogHead(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []CertificateLogEntry{}
+	for sequence := iFromSequence; sequence < head.Sequence && sequence < iFromSequence+iPageSize; sequence++ {
+		key, err := getCertificateLogKey(iCtx, sequence)
+		if err != nil {
+			return nil, err
+		}
+
+		entryJson, err := iCtx.GetStub().GetState(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from ledger: %v", err)
+		}
+
+		var entry CertificateLogEntry
+		err = json.Unmarshal(entryJson, &entry)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
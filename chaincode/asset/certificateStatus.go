@@ -0,0 +1,191 @@
+package asset
+
+import (
+	"encoding/json"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+type CertificateStatusCode = string
+
+const (
+	eStatusGood    CertificateStatusCode = "eStatusGood"
+	eStatusRevoked CertificateStatusCode = "eStatusRevoked" /// by its issuer or through one of the authorities above it
+	eStatusExpired CertificateStatusCode = "eStatusExpired"
+	eStatusInvalid CertificateStatusCode = "eStatusInvalid" /// not valid yet or missing a co-signature
+	eStatusUnknown CertificateStatusCode = "eStatusUnknown" /// no certificate with this id
+)
+
+/// Result of CheckCertificateStatus, the issuer fields are empty if the status is eStatusUnknown
+type CertificateStatusReport struct {
+	CertificateId    string                `json:"CertificateId"`
+	Status           CertificateStatusCode `json:"Status"`
+	Reason           string                `json:"Reason"`
+	CertificateType  string                `json:"CertificateType"`
+	SubjectId        string                `json:"SubjectId"`
+	ExpiryTime       time.Time             `json:"ExpiryTime"`
+	IssuerId         string                `json:"IssuerId"`
+	IssuerPublicKey  string                `json:"IssuerPublicKey"`
+	RootAuthorityId  string                `json:"RootAuthorityId"`
+	RevocationReason RevocationReason      `json:"RevocationReason"`
+	RevocationTime   time.Time             `json:"RevocationTime"` /// effective time of the revocation by the issuer
+	CheckedTime      time.Time             `json:"CheckedTime"`
+}
+
+/// single evaluation meant for point of sale apps, unknown certificates are reported rather than failing
+func (c *CertificateContract) CheckCertificateStatus(
+	iCtx contractapi.TransactionContextInterface,
+	iCertificateId string,
+) (*CertificateStatusReport, error) {
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := CertificateStatusReport{
+		CertificateId: iCertificateId,
+		Status:        eStatusUnknown,
+		CheckedTime:   transactionTime,
+	}
+
+	graphContract := graph.GraphContract{}
+	nodeExists, err := graphContract.DoesNodeExists(iCtx, iCertificateId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !nodeExists {
+		return &report, nil
+	}
+
+	var certificate Certificate
+	err = graphContract.GetNode(iCtx, iCertificateId, &certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	if certificate.Type != eCertificate {
+		return &report, nil
+	}
+
+	report.CertificateType = certificate.CertificateType
+	report.SubjectId = certificate.SubjectId
+	report.ExpiryTime = certificate.ExpiryTime
+	report.IssuerId = certificate.IssuerId
+
+	issuer, err := c.GetCertificateAuthority(iCtx, certificate.IssuerId)
+	if err != nil {
+		return nil, err
+	}
+	report.IssuerPublicKey = issuer.OwnerPublicKey
+	report.RootAuthorityId = issuer.RootId
+
+	revocation, err := getRevocation(iCtx, certificate.IssuerId, iCertificateId)
+	if err != nil {
+		return nil, err
+	}
+
+	if revocation != nil {
+		report.RevocationReason = revocation.ReasonCode
+		report.RevocationTime = revocation.EffectiveTime
+	}
+
+	checkErr := checkCertificate(iCtx, &certificate, transactionTime)
+	if checkErr == nil {
+		report.Status = eStatusGood
+		return &report, nil
+	}
+	report.Reason = checkErr.Error()
+
+	if !transactionTime.Before(certificate.ExpiryTime) {
+		report.Status = eStatusExpired
+		return &report, nil
+	}
+
+	if revocation != nil && !transactionTime.Before(revocation.EffectiveTime) {
+		report.Status = eStatusRevoked
+		return &report, nil
+	}
+
+	/// an authority above the issuer may have been revoked
+	report.Status = eStatusInvalid
+	visited := map[string]bool{}
+	authority := issuer
+	for authority.ParentId != "" && !visited[authority.Id] {
+		visited[authority.Id] = true
+
+		revoked, err := isRevoked(iCtx, authority.ParentId, authority.Id, transactionTime)
+		if err != nil {
+			return nil, err
+		}
+
+		if revoked {
+			report.Status = eStatusRevoked
+			break
+		}
+
+		authority, err = c.GetCertificateAuthority(iCtx, authority.ParentId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &report, nil
+}
+
+type CertificatePage struct {
+	Certificates        []Certificate `json:"Certificates"`
+	Bookmark            string        `json:"Bookmark"`
+	FetchedRecordsCount int32         `json:"FetchedRecordsCount"`
+}
+
+/// lists the certificates issued by iIssuerId, only the unexpired ones unless iIncludeExpired is set.
+/// Revocations are not taken into account, see CheckCertificateStatus
+func (c *CertificateContract) GetCertificatesByIssuer(
+	iCtx contractapi.TransactionContextInterface,
+	iIssuerId string,
+	iIncludeExpired bool,
+	iPageSize int32,
+	iBookmark string,
+) (*CertificatePage, error) {
+	selector := map[string]interface{}{
+		"Type":     eCertificate,
+		"IssuerId": iIssuerId,
+	}
+
+	if !iIncludeExpired {
+		transactionTime, err := getTransactionTime(iCtx)
+		if err != nil {
+			return nil, err
+		}
+
+		selector["ExpiryTime"] = map[string]interface{}{
+			"$gt": formatQueryTime(transactionTime),
+		}
+	}
+
+	records := [][]byte{}
+	bookmark, fetchedRecordsCount, err := getQueryResultWithPagination(iCtx, selector, "indexCertificateStatus", iPageSize, iBookmark, &records)
+	if err != nil {
+		return nil, err
+	}
+
+	certificates := []Certificate{}
+	for _, record := range records {
+		var certificate Certificate
+		err = json.Unmarshal(record, &certificate)
+		if err != nil {
+			return nil, err
+		}
+
+		certificates = append(certificates, certificate)
+	}
+
+	return &CertificatePage{
+		Certificates:        certificates,
+		Bookmark:            bookmark,
+		FetchedRecordsCount: fetchedRecordsCount,
+	}, nil
+}
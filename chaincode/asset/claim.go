@@ -0,0 +1,221 @@
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/shopspring/decimal"
+)
+
+type ClaimType = string
+
+const (
+	eClaimString  ClaimType = "eClaimString"
+	eClaimNumber  ClaimType = "eClaimNumber"
+	eClaimBoolean ClaimType = "eClaimBoolean"
+	eClaimDate    ClaimType = "eClaimDate" /// RFC 3339
+)
+
+const claimSchemaObjectType = "claimSchema"
+
+type ClaimField struct {
+	Name       string    `json:"Name"`
+	Type       ClaimType `json:"Type"`
+	IsRequired bool      `json:"IsRequired"`
+}
+
+/// Signed by the authority, describes the claims of the certificates of CertificateType it and its
+/// child authorities issue, e.g. the certified area of an "organic" certificate
+type ClaimSchema struct {
+	AuthorityId     string       `json:"AuthorityId"`
+	CertificateType string       `json:"CertificateType"`
+	Fields          []ClaimField `json:"Fields"`
+	Signature       string       `json:"Signature"`
+}
+
+func isClaimType(
+	iType string,
+) bool {
+	switch iType {
+	case eClaimString, eClaimNumber, eClaimBoolean, eClaimDate:
+		return true
+	default:
+		return false
+	}
+}
+
+/// returns the schema registered by iAuthorityId or by its closest ancestor, nil if there is none
+func getClaimSchema(
+	iCtx contractapi.TransactionContextInterface,
+	iAuthorityId string,
+	iCertificateType string,
+) (*ClaimSchema, error) {
+	graphContract := graph.GraphContract{}
+	visited := map[string]bool{}
+	authorityId := iAuthorityId
+	for authorityId != "" {
+		if visited[authorityId] {
+			return nil, fmt.Errorf("certificate authority chain of %s has a cycle", iAuthorityId)
+		}
+		visited[authorityId] = true
+
+		key, err := iCtx.GetStub().CreateCompositeKey(claimSchemaObjectType, []string{authorityId, iCertificateType})
+		if err != nil {
+			return nil, err
+		}
+
+		schemaJson, err := iCtx.GetStub().GetState(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from ledger: %v", err)
+		}
+
+		if schemaJson != nil {
+			var schema ClaimSchema
+			err = json.Unmarshal(schemaJson, &schema)
+			if err != nil {
+				return nil, err
+			}
+
+			return &schema, nil
+		}
+
+		var authority CertificateAuthority
+		err = graphContract.GetNode(iCtx, authorityId, &authority)
+		if err != nil {
+			return nil, err
+		}
+		authorityId = authority.ParentId
+	}
+
+	return nil, nil
+}
+
+/// certificates without a schema for their type cannot carry claims
+func checkClaims(
+	iCtx contractapi.TransactionContextInterface,
+	iAuthorityId string,
+	iCertificateType string,
+	iClaims map[string]string,
+) error {
+	schema, err := getClaimSchema(iCtx, iAuthorityId, iCertificateType)
+	if err != nil {
+		return err
+	}
+
+	if schema == nil {
+		if len(iClaims) > 0 {
+			return fmt.Errorf("no claim schema for certificate type %s", iCertificateType)
+		}
+		return nil
+	}
+
+	fields := map[string]ClaimField{}
+	for _, field := range schema.Fields {
+		fields[field.Name] = field
+
+		if field.IsRequired && iClaims[field.Name] == "" {
+			return fmt.Errorf("missing required claim %s", field.Name)
+		}
+	}
+
+	for name, value := range iClaims {
+		field, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("unknown claim %s", name)
+		}
+
+		switch field.Type {
+		case eClaimNumber:
+			_, err = decimal.NewFromString(value)
+		case eClaimBoolean:
+			_, err = strconv.ParseBool(value)
+		case eClaimDate:
+			_, err = time.Parse(time.RFC3339, value)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid claim %s: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+/// iSignature is the authority's signature of the ClaimSchema, it replaces any previous schema of the type.
+/// Certificates already issued are not checked against the new schema
+func (c *CertificateContract) RegisterClaimSchema(
+	iCtx contractapi.TransactionContextInterface,
+	iAuthorityId string,
+	iCertificateType string,
+	iFields []ClaimField,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	if iCertificateType == "" {
+		return nil, fmt.Errorf("certificate type cannot be empty")
+	}
+
+	names := map[string]bool{}
+	for _, field := range iFields {
+		if field.Name == "" {
+			return nil, fmt.Errorf("claim name cannot be empty")
+		}
+
+		if names[field.Name] {
+			return nil, fmt.Errorf("claim %s is defined more than once", field.Name)
+		}
+		names[field.Name] = true
+
+		if !isClaimType(field.Type) {
+			return nil, fmt.Errorf("unknown claim type %s", field.Type)
+		}
+	}
+
+	authority, err := c.GetCertificateAuthority(iCtx, iAuthorityId)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := ClaimSchema{
+		AuthorityId:     iAuthorityId,
+		CertificateType: iCertificateType,
+		Fields:          iFields,
+	}
+	err = graph.VerifyPayload(authority.OwnerPublicKey, &schema, iSignature)
+	if err != nil {
+		return nil, err
+	}
+	schema.Signature = iSignature
+
+	schemaJson, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(claimSchemaObjectType, []string{iAuthorityId, iCertificateType})
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, iCtx.GetStub().PutState(key, schemaJson))
+}
+
+/// returns the schema applying to the certificates of iCertificateType issued by iAuthorityId
+func (c *CertificateContract) GetClaimSchema(
+	iCtx contractapi.TransactionContextInterface,
+	iAuthorityId string,
+	iCertificateType string,
+) (*ClaimSchema, error) {
+	schema, err := getClaimSchema(iCtx, iAuthorityId, iCertificateType)
+	if err != nil {
+		return nil, err
+	}
+
+	if schema == nil {
+		return nil, fmt.Errorf("no claim schema for certificate type %s", iCertificateType)
+	}
+
+	return schema, nil
+}
@@ -0,0 +1,129 @@
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const coSignatureObjectType = "coSignature"
+
+/// Signed by one of the co-issuers of the certificate
+type CertificateCoSignature struct {
+	CertificateId string `json:"CertificateId"`
+	IssuerId      string `json:"IssuerId"`
+	Signature     string `json:"Signature"`
+}
+
+/// returns nil if iIssuerId has not co-signed iCertificateId
+func getCoSignature(
+	iCtx contractapi.TransactionContextInterface,
+	iCertificateId string,
+	iIssuerId string,
+) (*CertificateCoSignature, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(coSignatureObjectType, []string{iCertificateId, iIssuerId})
+	if err != nil {
+		return nil, err
+	}
+
+	coSignatureJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if coSignatureJson == nil {
+		return nil, nil
+	}
+
+	var coSignature CertificateCoSignature
+	err = json.Unmarshal(coSignatureJson, &coSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	return &coSignature, nil
+}
+
+/// the stored co-signature is verified again so that the check does not only rely on its presence
+func checkCoSignature(
+	iCtx contractapi.TransactionContextInterface,
+	iCertificate *Certificate,
+	iCoIssuerId string,
+) error {
+	coSignature, err := getCoSignature(iCtx, iCertificate.Id, iCoIssuerId)
+	if err != nil {
+		return err
+	}
+
+	if coSignature == nil {
+		return fmt.Errorf("certificate %s is not co-signed by %s", iCertificate.Id, iCoIssuerId)
+	}
+
+	graphContract := graph.GraphContract{}
+	var coIssuer CertificateAuthority
+	err = graphContract.GetNode(iCtx, iCoIssuerId, &coIssuer)
+	if err != nil {
+		return err
+	}
+
+	request := CertificateCoSignature{
+		CertificateId: iCertificate.Id,
+		IssuerId:      iCoIssuerId,
+	}
+	return graph.VerifyPayload(coIssuer.OwnerPublicKey, &request, coSignature.Signature)
+}
+
+/// iSignature is the co-issuer's signature of the CertificateCoSignature
+func (c *CertificateContract) CoSignCertificate(
+	iCtx contractapi.TransactionContextInterface,
+	iCertificateId string,
+	iCoIssuerId string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	certificate, err := c.GetCertificate(iCtx, iCertificateId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !containsString(certificate.CoIssuerIds, iCoIssuerId) {
+		return nil, fmt.Errorf("%s is not a co-issuer of certificate %s", iCoIssuerId, iCertificateId)
+	}
+
+	existing, err := getCoSignature(iCtx, iCertificateId, iCoIssuerId)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		return nil, fmt.Errorf("certificate %s is already co-signed by %s", iCertificateId, iCoIssuerId)
+	}
+
+	coIssuer, err := c.GetCertificateAuthority(iCtx, iCoIssuerId)
+	if err != nil {
+		return nil, err
+	}
+
+	coSignature := CertificateCoSignature{
+		CertificateId: iCertificateId,
+		IssuerId:      iCoIssuerId,
+	}
+	err = graph.VerifyPayload(coIssuer.OwnerPublicKey, &coSignature, iSignature)
+	if err != nil {
+		return nil, err
+	}
+	coSignature.Signature = iSignature
+
+	coSignatureJson, err := json.Marshal(coSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(coSignatureObjectType, []string{iCertificateId, iCoIssuerId})
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, iCtx.GetStub().PutState(key, coSignatureJson))
+}
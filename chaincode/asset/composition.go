@@ -0,0 +1,60 @@
+package asset
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+/// share of a material's composition contributed by one input of a merge or transform
+type compositionPart struct {
+	Composition map[string]string
+	Quantity    decimal.Decimal
+}
+
+/// materials created before compositions were tracked are made of themselves only
+func getComposition(
+	iMaterial *Material,
+) map[string]string {
+	if len(iMaterial.Composition) == 0 {
+		return map[string]string{iMaterial.Name: "100"}
+	}
+
+	return iMaterial.Composition
+}
+
+/// averages the compositions of iParts weighted by their quantities, which must share the same unit
+func mixCompositions(
+	iParts []compositionPart,
+) (map[string]string, error) {
+	total := decimal.NewFromInt(0)
+	for _, part := range iParts {
+		total = total.Add(part.Quantity)
+	}
+
+	if !total.IsPositive() {
+		return nil, fmt.Errorf("cannot mix materials without any quantity")
+	}
+
+	percentages := map[string]decimal.Decimal{}
+	for _, part := range iParts {
+		for component, percentageString := range part.Composition {
+			percentage, err := decimal.NewFromString(percentageString)
+			if err != nil {
+				return nil, err
+			}
+
+			share := percentage.Mul(part.Quantity).DivRound(total, quantityPrecision)
+			percentages[component] = percentages[component].Add(share)
+		}
+	}
+
+	composition := map[string]string{}
+	for component, percentage := range percentages {
+		if !percentage.IsZero() {
+			composition[component] = percentage.String()
+		}
+	}
+
+	return composition, nil
+}
@@ -0,0 +1,282 @@
+package asset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	confidentialObjectType = "confidentialMaterial"
+
+	/// the ConfidentialMaterial json is passed in the transient map so that it never appears in the transaction proposal
+	confidentialTransientKey = "confidential"
+
+	/// collections are defined in collections_config.json for every pair of trading organizations
+	confidentialCollectionPrefix = "confidential"
+)
+
+/// Body of a confidentially transferred material along with the commercial terms of the transfer, only stored
+/// in the collection of the two trading orgs. The public node holds the SHA-256 of the json as passed in the
+/// transient map, so that the buyer can check what it was given. Salt is chosen by the seller so that the hash
+/// cannot be brute forced
+type ConfidentialMaterial struct {
+	Name         string            `json:"Name"`
+	LotNumber    string            `json:"LotNumber"`
+	BatchNumber  string            `json:"BatchNumber"`
+	Composition  map[string]string `json:"Composition"`
+	TemplateId   string            `json:"TemplateId"`
+	Attributes   map[string]string `json:"Attributes"`
+	Gtin         string            `json:"Gtin"`
+	Sscc         string            `json:"Sscc"`
+	SerialNumber string            `json:"SerialNumber"`
+	Amount       string            `json:"Amount"`
+	Currency     string            `json:"Currency"`
+	Terms        string            `json:"Terms"`
+	Salt         string            `json:"Salt"`
+}
+
+/// the name of the collection shared by both organizations, whatever their order
+func getConfidentialCollection(
+	iMspId string,
+	iOtherMspId string,
+) string {
+	mspIds := []string{iMspId, iOtherMspId}
+	sort.Strings(mspIds)
+	return fmt.Sprintf("%s_%s_%s", confidentialCollectionPrefix, mspIds[0], mspIds[1])
+}
+
+func getConfidentialMaterial(
+	iCtx contractapi.TransactionContextInterface,
+	iCollection string,
+	iPrivateDataHash string,
+) (*ConfidentialMaterial, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(confidentialObjectType, []string{iPrivateDataHash})
+	if err != nil {
+		return nil, err
+	}
+
+	confidentialJson, err := iCtx.GetStub().GetPrivateData(iCollection, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if confidentialJson == nil {
+		return nil, fmt.Errorf("no confidential data %s in %s", iPrivateDataHash, iCollection)
+	}
+
+	hash := sha256.Sum256(confidentialJson)
+	if hex.EncodeToString(hash[:]) != iPrivateDataHash {
+		return nil, fmt.Errorf("confidential data does not match its hash")
+	}
+
+	var confidential ConfidentialMaterial
+	err = json.Unmarshal(confidentialJson, &confidential)
+	if err != nil {
+		return nil, err
+	}
+
+	return &confidential, nil
+}
+
+/// the body of iMaterial, read from its collection if it was confidentially transferred
+func getMaterialBody(
+	iCtx contractapi.TransactionContextInterface,
+	iMaterial *Material,
+) (*ConfidentialMaterial, error) {
+	if iMaterial.PrivateDataHash != "" {
+		confidential, err := getConfidentialMaterial(iCtx, iMaterial.PrivateCollection, iMaterial.PrivateDataHash)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ConfidentialMaterial{
+			Name:         confidential.Name,
+			LotNumber:    confidential.LotNumber,
+			BatchNumber:  confidential.BatchNumber,
+			Composition:  confidential.Composition,
+			TemplateId:   confidential.TemplateId,
+			Attributes:   confidential.Attributes,
+			Gtin:         confidential.Gtin,
+			Sscc:         confidential.Sscc,
+			SerialNumber: confidential.SerialNumber,
+		}, nil
+	}
+
+	return &ConfidentialMaterial{
+		Name:         iMaterial.Name,
+		LotNumber:    iMaterial.LotNumber,
+		BatchNumber:  iMaterial.BatchNumber,
+		Composition:  iMaterial.Composition,
+		TemplateId:   iMaterial.TemplateId,
+		Attributes:   iMaterial.Attributes,
+		Gtin:         iMaterial.Gtin,
+		Sscc:         iMaterial.Sscc,
+		SerialNumber: iMaterial.SerialNumber,
+	}, nil
+}
+
+func isSameMaterialBody(
+	iBody *ConfidentialMaterial,
+	iOtherBody *ConfidentialMaterial,
+) bool {
+	return iBody.Name == iOtherBody.Name &&
+		iBody.LotNumber == iOtherBody.LotNumber &&
+		iBody.BatchNumber == iOtherBody.BatchNumber &&
+		areAttributesEqual(iBody.Composition, iOtherBody.Composition) &&
+		iBody.TemplateId == iOtherBody.TemplateId &&
+		areAttributesEqual(iBody.Attributes, iOtherBody.Attributes) &&
+		iBody.Gtin == iOtherBody.Gtin &&
+		iBody.Sscc == iOtherBody.Sscc &&
+		iBody.SerialNumber == iOtherBody.SerialNumber
+}
+
+/// stores the ConfidentialMaterial passed in the transient map into iCollection and clears the body of
+/// iNewMaterial, whose public node then only keeps the fields needed by the mass balance and expiry checks
+func putConfidentialMaterial(
+	iCtx contractapi.TransactionContextInterface,
+	iCollection string,
+	iMaterial *Material,
+	iNewMaterial *Material,
+) error {
+	transient, err := iCtx.GetStub().GetTransient()
+	if err != nil {
+		return err
+	}
+
+	confidentialJson, ok := transient[confidentialTransientKey]
+	if !ok {
+		return fmt.Errorf("confidential material must be passed in the transient map as %s", confidentialTransientKey)
+	}
+
+	var confidential ConfidentialMaterial
+	err = json.Unmarshal(confidentialJson, &confidential)
+	if err != nil {
+		return err
+	}
+
+	if confidential.Amount != "" {
+		amount, err := decimal.NewFromString(confidential.Amount)
+		if err != nil {
+			return err
+		}
+
+		if amount.IsNegative() {
+			return fmt.Errorf("price cannot be negative")
+		}
+	}
+
+	body, err := getMaterialBody(iCtx, iMaterial)
+	if err != nil {
+		return err
+	}
+
+	if !isSameMaterialBody(&confidential, body) {
+		return fmt.Errorf("confidential material does not match material %s", iMaterial.Id)
+	}
+
+	hash := sha256.Sum256(confidentialJson)
+	privateDataHash := hex.EncodeToString(hash[:])
+	key, err := iCtx.GetStub().CreateCompositeKey(confidentialObjectType, []string{privateDataHash})
+	if err != nil {
+		return err
+	}
+
+	err = iCtx.GetStub().PutPrivateData(iCollection, key, confidentialJson)
+	if err != nil {
+		return err
+	}
+
+	iNewMaterial.Name = ""
+	iNewMaterial.LotNumber = ""
+	iNewMaterial.BatchNumber = ""
+	iNewMaterial.Composition = map[string]string{}
+	iNewMaterial.TemplateId = ""
+	iNewMaterial.Attributes = map[string]string{}
+	iNewMaterial.Gtin = ""
+	iNewMaterial.Sscc = ""
+	iNewMaterial.SerialNumber = ""
+	iNewMaterial.PrivateCollection = iCollection
+	iNewMaterial.PrivateDataHash = privateDataHash
+	return nil
+}
+
+/// same as TransferMaterial, but the body of the material and the commercial terms of the transfer are only
+/// written to the collection shared by the submitter's org and iBuyerMspId. The ConfidentialMaterial is passed
+/// in the transient map, iNewNodeSignature is the new owner's signature of the public node, whose body is empty
+/// and whose PrivateDataHash is the SHA-256 of the transient ConfidentialMaterial
+func (c *MaterialContract) TransferMaterialConfidentially(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iNewNodeId string,
+	iNewOwnerPublicKey string,
+	iBuyerMspId string,
+	iSignature string,
+	iNewNodeSignature string,
+	iTransferTime time.Time,
+) (*graph.TransactionReceipt, error) {
+	err := checkFeatureEnabled(iCtx, confidentialTransfersFeature)
+	if err != nil {
+		return nil, err
+	}
+
+	graphContract := graph.GraphContract{}
+
+	var material Material
+	err = graphContract.GetNode(iCtx, iNodeId, &material)
+	if err != nil {
+		return nil, err
+	}
+
+	transferTime, err := getOperationTime(iCtx, iTransferTime)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkNoPendingOffer(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	sellerMspId, err := iCtx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, c.transferMaterial(
+		iCtx,
+		eTransfer,
+		&material,
+		iNewNodeId,
+		iNewOwnerPublicKey,
+		iBuyerMspId,
+		iSignature,
+		iNewNodeSignature,
+		transferTime,
+		getConfidentialCollection(sellerMspId, iBuyerMspId),
+	))
+}
+
+/// only succeeds on the peers of the organizations sharing the collection of the material
+func (c *MaterialContract) GetConfidentialMaterial(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) (*ConfidentialMaterial, error) {
+	material, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	if material.PrivateDataHash == "" {
+		return nil, fmt.Errorf("material %s is not confidential", iNodeId)
+	}
+
+	return getConfidentialMaterial(iCtx, material.PrivateCollection, material.PrivateDataHash)
+}
@@ -0,0 +1,210 @@
+package asset
+
+import (
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	configObjectType  = "config"
+	adminPublicKeyKey = "adminPublicKey"
+
+	/// when set, operations take the transaction's timestamp and ignore client supplied times. Clients then sign
+	/// the nodes they create with IsTransactionTime set and the zero time, see setTimeSource
+	useTransactionTimeKey = "useTransactionTime"
+
+	/// maximum difference in seconds between a client supplied time and the transaction's timestamp
+	clockDriftToleranceKey     = "clockDriftTolerance"
+	defaultClockDriftTolerance = 3600
+)
+
+/// Signed by the current administrator
+type AdministratorChange struct {
+	AdminPublicKey string `json:"AdminPublicKey"`
+	Signature      string `json:"Signature"`
+}
+
+/// Signed by the administrator
+type TimeSourceChange struct {
+	UseTransactionTime bool   `json:"UseTransactionTime"`
+	Signature          string `json:"Signature"`
+}
+
+/// Signed by the administrator
+type ClockDriftToleranceChange struct {
+	ToleranceSeconds int64  `json:"ToleranceSeconds"`
+	Signature        string `json:"Signature"`
+}
+
+func getConfigValue(
+	iCtx contractapi.TransactionContextInterface,
+	iName string,
+) ([]byte, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(configObjectType, []string{iName})
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	return value, nil
+}
+
+func putConfigValue(
+	iCtx contractapi.TransactionContextInterface,
+	iName string,
+	iValue []byte,
+) error {
+	key, err := iCtx.GetStub().CreateCompositeKey(configObjectType, []string{iName})
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().PutState(key, iValue)
+}
+
+func deleteConfigValue(
+	iCtx contractapi.TransactionContextInterface,
+	iName string,
+) error {
+	key, err := iCtx.GetStub().CreateCompositeKey(configObjectType, []string{iName})
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().DelState(key)
+}
+
+func (c *MaterialContract) GetAdministrator(
+	iCtx contractapi.TransactionContextInterface,
+) (string, error) {
+	adminPublicKey, err := getConfigValue(iCtx, adminPublicKeyKey)
+	if err != nil {
+		return "", err
+	}
+
+	return string(adminPublicKey), nil
+}
+
+/// hands over the administration to iAdminPublicKey. The first administrator is configured by InitLedger only
+/// iSignature is the current administrator's signature of the AdministratorChange
+func (c *MaterialContract) SetAdministrator(
+	iCtx contractapi.TransactionContextInterface,
+	iAdminPublicKey string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	if iAdminPublicKey == "" {
+		return nil, fmt.Errorf("admin public key cannot be empty")
+	}
+
+	change := AdministratorChange{
+		AdminPublicKey: iAdminPublicKey,
+	}
+	err := verifyAdministratorSignature(iCtx, &change, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putConfigValue(iCtx, adminPublicKeyKey, []byte(iAdminPublicKey)))
+}
+
+/// iPayload must have its signature field cleared
+func verifyAdministratorSignature(
+	iCtx contractapi.TransactionContextInterface,
+	iPayload interface{},
+	iSignature string,
+) error {
+	adminPublicKey, err := getConfigValue(iCtx, adminPublicKeyKey)
+	if err != nil {
+		return err
+	}
+
+	if adminPublicKey == nil {
+		return fmt.Errorf("administrator is not configured, the ledger must be bootstrapped with InitLedger")
+	}
+
+	return graph.VerifyPayload(string(adminPublicKey), iPayload, iSignature)
+}
+
+func getUseTransactionTime(
+	iCtx contractapi.TransactionContextInterface,
+) (bool, error) {
+	value, err := getConfigValue(iCtx, useTransactionTimeKey)
+	if err != nil {
+		return false, err
+	}
+
+	return string(value) == "true", nil
+}
+
+func (c *MaterialContract) GetUseTransactionTime(
+	iCtx contractapi.TransactionContextInterface,
+) (bool, error) {
+	return getUseTransactionTime(iCtx)
+}
+
+/// iSignature is the administrator's signature of the TimeSourceChange
+func (c *MaterialContract) SetUseTransactionTime(
+	iCtx contractapi.TransactionContextInterface,
+	iUseTransactionTime bool,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	change := TimeSourceChange{
+		UseTransactionTime: iUseTransactionTime,
+	}
+	err := verifyAdministratorSignature(iCtx, &change, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putConfigValue(iCtx, useTransactionTimeKey, []byte(strconv.FormatBool(iUseTransactionTime))))
+}
+
+func getClockDriftTolerance(
+	iCtx contractapi.TransactionContextInterface,
+) (int64, error) {
+	value, err := getConfigValue(iCtx, clockDriftToleranceKey)
+	if err != nil {
+		return 0, err
+	}
+
+	if value == nil {
+		return defaultClockDriftTolerance, nil
+	}
+
+	return strconv.ParseInt(string(value), 10, 64)
+}
+
+/// returns the tolerance in seconds
+func (c *MaterialContract) GetClockDriftTolerance(
+	iCtx contractapi.TransactionContextInterface,
+) (int64, error) {
+	return getClockDriftTolerance(iCtx)
+}
+
+/// iSignature is the administrator's signature of the ClockDriftToleranceChange
+func (c *MaterialContract) SetClockDriftTolerance(
+	iCtx contractapi.TransactionContextInterface,
+	iToleranceSeconds int64,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	if iToleranceSeconds < 0 {
+		return nil, fmt.Errorf("tolerance cannot be negative")
+	}
+
+	change := ClockDriftToleranceChange{
+		ToleranceSeconds: iToleranceSeconds,
+	}
+	err := verifyAdministratorSignature(iCtx, &change, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putConfigValue(iCtx, clockDriftToleranceKey, []byte(strconv.FormatInt(iToleranceSeconds, 10))))
+}
@@ -0,0 +1,299 @@
+package asset
+
+import (
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+type ConfigValueType = string
+
+const (
+	eBoolConfig        ConfigValueType = "eBoolConfig"
+	eNonNegativeConfig ConfigValueType = "eNonNegativeConfig"
+	eStringConfig      ConfigValueType = "eStringConfig"
+	eMspIdListConfig   ConfigValueType = "eMspIdListConfig" /// comma separated, cannot be empty
+)
+
+const (
+	/// feature toggles turn optional features off, e.g. "feature.confidentialTransfers"
+	featureKeyPrefix = "feature."
+
+	/// strict modes turn silent no-ops into errors, e.g. "strict.tokenConsumption". Modes are free form so
+	/// that the contracts sharing the ledger can have their own
+	strictKeyPrefix = "strict."
+
+	/// organizational unit of the channel admins when node OUs are enabled
+	adminOrganizationalUnit = "admin"
+
+	/// MSPs whose admins are channel admins, configured by InitLedger
+	adminMspIdsKey = "adminMspIds"
+)
+
+const (
+	confidentialTransfersFeature = "confidentialTransfers"
+	settlementFeature            = "settlement"
+)
+
+/// features enabled until a channel admin configures otherwise
+var defaultFeatures = map[string]bool{
+	confidentialTransfersFeature: true,
+	settlementFeature:            true,
+}
+
+const (
+	/// a transfer without settlement arguments fails while a settlement chaincode is configured
+	strictSettlementMode = "settlement"
+
+	/// an empty required role is refused instead of lifting the restriction of the operation
+	strictAccessControlMode = "accessControl"
+)
+
+/// settings which can be changed through SetConfig, other config values have dedicated transactions
+var configValueTypes = map[string]ConfigValueType{
+	useTransactionTimeKey:       eBoolConfig,
+	clockDriftToleranceKey:      eNonNegativeConfig,
+	adminMspIdsKey:              eMspIdListConfig,
+	recallAuthorityPublicKeyKey: eStringConfig,
+}
+
+type ConfigEntry struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+func getConfigValueType(
+	iName string,
+) (ConfigValueType, error) {
+	if valueType, ok := configValueTypes[iName]; ok {
+		return valueType, nil
+	}
+
+	if strings.HasPrefix(iName, featureKeyPrefix) {
+		if _, ok := defaultFeatures[strings.TrimPrefix(iName, featureKeyPrefix)]; ok {
+			return eBoolConfig, nil
+		}
+	}
+
+	if strings.HasPrefix(iName, strictKeyPrefix) && len(iName) > len(strictKeyPrefix) {
+		return eBoolConfig, nil
+	}
+
+	if strings.HasPrefix(iName, requiredRoleKeyPrefix) && isOperation(strings.TrimPrefix(iName, requiredRoleKeyPrefix)) {
+		return eStringConfig, nil
+	}
+
+	return "", fmt.Errorf("unknown config %s", iName)
+}
+
+/// returns iValue in the form it is stored
+func normalizeConfigValue(
+	iValueType ConfigValueType,
+	iValue string,
+) (string, error) {
+	switch iValueType {
+	case eBoolConfig:
+		value, err := strconv.ParseBool(iValue)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(value), nil
+	case eNonNegativeConfig:
+		value, err := strconv.ParseInt(iValue, 10, 64)
+		if err != nil {
+			return "", err
+		}
+
+		if value < 0 {
+			return "", fmt.Errorf("value cannot be negative")
+		}
+		return strconv.FormatInt(value, 10), nil
+	case eMspIdListConfig:
+		mspIds := splitMspIds(iValue)
+		if len(mspIds) == 0 {
+			return "", fmt.Errorf("at least one msp id is required")
+		}
+		return strings.Join(mspIds, ","), nil
+	default:
+		return iValue, nil
+	}
+}
+
+/// the msp ids of a comma separated list, blanks are dropped
+func splitMspIds(
+	iMspIds string,
+) []string {
+	mspIds := []string{}
+	for _, mspId := range strings.Split(iMspIds, ",") {
+		mspId = strings.TrimSpace(mspId)
+		if mspId != "" {
+			mspIds = append(mspIds, mspId)
+		}
+	}
+
+	return mspIds
+}
+
+/// channel admins are the identities of the admin organizational unit of one of the admin MSPs, any MSP
+/// can issue certificates with the admin unit so the MSP must be checked too
+func checkChannelAdmin(
+	iCtx contractapi.TransactionContextInterface,
+) error {
+	adminMspIds, err := getConfigValue(iCtx, adminMspIdsKey)
+	if err != nil {
+		return err
+	}
+
+	if adminMspIds == nil {
+		return fmt.Errorf("admin msp ids are not configured, the ledger must be bootstrapped with InitLedger")
+	}
+
+	return checkAdminSubmitter(iCtx, string(adminMspIds))
+}
+
+/// iAdminMspIds are comma separated, InitLedger checks the ones it is configuring before they are stored
+func checkAdminSubmitter(
+	iCtx contractapi.TransactionContextInterface,
+	iAdminMspIds string,
+) error {
+	mspId, err := iCtx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return err
+	}
+
+	isAdminMsp := false
+	for _, adminMspId := range splitMspIds(iAdminMspIds) {
+		if adminMspId == mspId {
+			isAdminMsp = true
+			break
+		}
+	}
+
+	if !isAdminMsp {
+		return fmt.Errorf("submitter is not a channel admin, %s is not an admin msp", mspId)
+	}
+
+	certificate, err := iCtx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return err
+	}
+
+	for _, unit := range certificate.Subject.OrganizationalUnit {
+		if unit == adminOrganizationalUnit {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("submitter is not a channel admin")
+}
+
+/// features are enabled by default, a channel admin can turn them off with SetConfig
+func isFeatureEnabled(
+	iCtx contractapi.TransactionContextInterface,
+	iFeature string,
+) (bool, error) {
+	value, err := getConfigValue(iCtx, featureKeyPrefix+iFeature)
+	if err != nil {
+		return false, err
+	}
+
+	if value == nil {
+		return defaultFeatures[iFeature], nil
+	}
+
+	return string(value) == "true", nil
+}
+
+func checkFeatureEnabled(
+	iCtx contractapi.TransactionContextInterface,
+	iFeature string,
+) error {
+	isEnabled, err := isFeatureEnabled(iCtx, iFeature)
+	if err != nil {
+		return err
+	}
+
+	if !isEnabled {
+		return fmt.Errorf("feature %s is disabled", iFeature)
+	}
+
+	return nil
+}
+
+/// strict modes are disabled until a channel admin enables them with SetConfig. The contracts sharing the
+/// ledger read their strict modes this way
+func IsStrictModeEnabled(
+	iCtx contractapi.TransactionContextInterface,
+	iMode string,
+) (bool, error) {
+	value, err := getConfigValue(iCtx, strictKeyPrefix+iMode)
+	if err != nil {
+		return false, err
+	}
+
+	return string(value) == "true", nil
+}
+
+/// returns the settings which can be changed through SetConfig, unset ones are omitted
+func (c *MaterialContract) GetConfig(
+	iCtx contractapi.TransactionContextInterface,
+) ([]ConfigEntry, error) {
+	iterator, err := iCtx.GetStub().GetStateByPartialCompositeKey(configObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	entries := []ConfigEntry{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, attributes, err := iCtx.GetStub().SplitCompositeKey(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = getConfigValueType(attributes[0])
+		if err != nil {
+			continue
+		}
+
+		entries = append(entries, ConfigEntry{
+			Name:  attributes[0],
+			Value: string(kv.Value),
+		})
+	}
+
+	return entries, nil
+}
+
+/// only channel admins can change settings this way, so that behavior can be tuned without redeploying the
+/// chaincode or holding the administrator key
+func (c *MaterialContract) SetConfig(
+	iCtx contractapi.TransactionContextInterface,
+	iName string,
+	iValue string,
+) (*graph.TransactionReceipt, error) {
+	err := checkChannelAdmin(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	valueType, err := getConfigValueType(iName)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := normalizeConfigValue(valueType, iValue)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %v", iName, err)
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putConfigValue(iCtx, iName, []byte(value)))
+}
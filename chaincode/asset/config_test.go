@@ -0,0 +1,107 @@
+package asset_test
+
+import (
+	"sig_chain/chaincode/asset"
+	"sig_chain/pkg/client"
+	"sig_chain/pkg/testutil"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// runs InitLedger with iConfig signed by iAdmin, as a channel admin of Org1MSP
+func (l *testLedger) bootstrap(
+	iAdmin client.Signer,
+	iConfig asset.BootstrapConfig,
+) {
+	l.t.Helper()
+	iConfig.AdminPublicKey = iAdmin.GetPublicKey()
+	iConfig.AdminMspIds = []string{"Org1MSP"}
+	signature := signPayload(l.t, iAdmin, &iConfig)
+
+	identity := l.identity
+	defer func() {
+		l.identity = identity
+	}()
+
+	var err error
+	l.identity, err = testutil.MakeMockIdentity("admin", "Org1MSP", "admin")
+	if err != nil {
+		l.t.Fatal(err)
+	}
+
+	l.mustSubmit("bootstrap", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.InitLedger(iCtx, iConfig, signature)
+		return err
+	})
+}
+
+func TestCreateMaterialAtTransactionTime(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	l.bootstrap(makeTestSigner(t), asset.BootstrapConfig{UseTransactionTime: true, ClockDriftTolerance: 60})
+
+	/// signed with its own time, which the ledger replaces
+	material := makeTestMaterial("m1", "10", alice)
+	signature := signNode(t, alice, &material)
+	l.mustFail("create signed with a client time", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.CreateMaterial(iCtx, "m1", "flour", "kg", "10", "", "", time.Time{}, "", "", "", "", "", alice.GetPublicKey(), testTime, signature)
+		return err
+	})
+
+	header := material.GetHeader()
+	header.CreatedTime = time.Time{}
+	header.IsTransactionTime = true
+	material.SetHeader(header)
+	signature = signNode(t, alice, &material)
+	l.mustSubmit("create signed without its time", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.CreateMaterial(iCtx, "m1", "flour", "kg", "10", "", "", time.Time{}, "", "", "", "", "", alice.GetPublicKey(), time.Time{}, signature)
+		return err
+	})
+
+	stored := l.getMaterial("m1")
+	if !stored.IsTransactionTime || !stored.CreatedTime.Equal(testTime) {
+		t.Fatalf("m1 is not created at the transaction time: %v", stored.CreatedTime)
+	}
+	l.checkNodeSignature("m1")
+}
+
+func TestBootstrapByChannelAdmin(t *testing.T) {
+	l := makeTestLedger(t)
+	admin := makeTestSigner(t)
+	config := asset.BootstrapConfig{AdminPublicKey: admin.GetPublicKey(), AdminMspIds: []string{"Org1MSP"}}
+	signature := signPayload(t, admin, &config)
+	l.mustFail("bootstrap by a user", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.InitLedger(iCtx, config, signature)
+		return err
+	})
+
+	/// an admin of an MSP which is not being configured is not a channel admin
+	other := asset.BootstrapConfig{AdminPublicKey: admin.GetPublicKey(), AdminMspIds: []string{"Org2MSP"}}
+	signature = signPayload(t, admin, &other)
+	identity := l.identity
+	var err error
+	l.identity, err = testutil.MakeMockIdentity("admin", "Org1MSP", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.mustFail("bootstrap by an admin of another msp", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.InitLedger(iCtx, other, signature)
+		return err
+	})
+	l.identity = identity
+
+	l.bootstrap(admin, asset.BootstrapConfig{})
+	l.mustSubmit("get tolerance", func(iCtx contractapi.TransactionContextInterface) error {
+		tolerance, err := l.contract.GetClockDriftTolerance(iCtx)
+		if err != nil {
+			return err
+		}
+
+		if tolerance <= 0 {
+			t.Fatalf("omitted tolerance is stored as %d", tolerance)
+		}
+		return nil
+	})
+}
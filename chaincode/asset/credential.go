@@ -0,0 +1,285 @@
+package asset
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sig_chain/chaincode/graph"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	credentialObjectType = "credential"
+
+	verifiableCredentialType = "VerifiableCredential"
+)
+
+type credentialHeader struct {
+	Algorithm string `json:"alg"`
+}
+
+/// the claims of a JWT encoded W3C verifiable credential, the vc claim holds the JSON-LD credential
+type credentialClaims struct {
+	Issuer     string `json:"iss"`
+	NotBefore  int64  `json:"nbf"`
+	Expiry     int64  `json:"exp"`
+	Credential struct {
+		Type              []string               `json:"type"`
+		CredentialSubject map[string]interface{} `json:"credentialSubject"`
+	} `json:"vc"`
+}
+
+/// verifies the JWS of iCredential against iPublicKey, only RS256 and ES256 are supported
+func verifyCredential(
+	iCredential string,
+	iPublicKey string,
+) (*credentialClaims, error) {
+	parts := strings.Split(iCredential, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("credential must be a JWT")
+	}
+
+	headerJson, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var header credentialHeader
+	err = json.Unmarshal(headerJson, &header)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+
+	ifc, err := graph.ParsePublicKey(iPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch header.Algorithm {
+	case "RS256":
+		key, ok := ifc.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("RS256 requires an RSA key")
+		}
+
+		err = rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credential signature: %v", err)
+		}
+	case "ES256":
+		key, ok := ifc.(*ecdsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("ES256 requires an ECDSA key")
+		}
+
+		if len(signature) != 64 {
+			return nil, fmt.Errorf("invalid credential signature length")
+		}
+
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(key, hash[:], r, s) {
+			return nil, fmt.Errorf("invalid credential signature")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %s", header.Algorithm)
+	}
+
+	claimsJson, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	var claims credentialClaims
+	err = json.Unmarshal(claimsJson, &claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+/// returns the first type of the credential other than VerifiableCredential, e.g. "OrganicCertificate"
+func getCredentialType(
+	iClaims *credentialClaims,
+) (string, error) {
+	for _, credentialType := range iClaims.Credential.Type {
+		if credentialType != verifiableCredentialType {
+			return credentialType, nil
+		}
+	}
+
+	return "", fmt.Errorf("credential has no type other than %s", verifiableCredentialType)
+}
+
+/// the fields of the credential subject other than its id, values which are not strings are kept as json
+func getCredentialSubjectClaims(
+	iClaims *credentialClaims,
+) (map[string]string, error) {
+	subjectClaims := map[string]string{}
+	for name, value := range iClaims.Credential.CredentialSubject {
+		if name == "id" {
+			continue
+		}
+
+		if stringValue, ok := value.(string); ok {
+			subjectClaims[name] = stringValue
+			continue
+		}
+
+		valueJson, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		subjectClaims[name] = string(valueJson)
+	}
+
+	return subjectClaims, nil
+}
+
+/// imports a JWT encoded W3C verifiable credential signed by the key of iIssuerId as a certificate of iSubjectId.
+/// JSON-LD proofs are not supported since they require RDF canonicalization.
+/// The certificate node is owned by the importer and iSignature is its signature of the node,
+/// its claims are the credential subject and are not checked against a ClaimSchema
+func (c *CertificateContract) ImportVerifiableCredential(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iIssuerId string,
+	iSubjectId string,
+	iCredential string,
+	iOwnerPublicKey string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	issuer, err := c.GetCertificateAuthority(iCtx, iIssuerId)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := verifyCredential(iCredential, issuer.OwnerPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Expiry == 0 {
+		return nil, fmt.Errorf("credential must have an expiry")
+	}
+
+	issueTime := time.Unix(claims.NotBefore, 0).UTC()
+	expiryTime := time.Unix(claims.Expiry, 0).UTC()
+	if !expiryTime.After(issueTime) {
+		return nil, fmt.Errorf("expiry time must be after issue time")
+	}
+
+	certificateType, err := getCredentialType(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	subjectClaims, err := getCredentialSubjectClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialHash := sha256.Sum256([]byte(iCredential))
+	credentialHashHex := hex.EncodeToString(credentialHash[:])
+	importedIds, err := getIndexedIds(iCtx, credentialObjectType, []string{credentialHashHex})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(importedIds) > 0 {
+		return nil, fmt.Errorf("credential is already imported as %s", importedIds[0])
+	}
+
+	materialContract := MaterialContract{}
+	subject, err := materialContract.GetMaterial(iCtx, iSubjectId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkCertificateTypeAllowed(iCtx, subject, certificateType)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	graphContract := graph.GraphContract{}
+	nodeHeader := graph.MakeNodeHeader(
+		iNodeId,
+		eCertificate,
+		false,
+		graph.MakeHashSet(graph.HashId(iIssuerId)),
+		graph.MakeHashSet(),
+		iOwnerPublicKey,
+		transactionTime,
+		iSignature,
+	)
+	certificate := Certificate{
+		NodeHeader:      nodeHeader,
+		CertificateType: certificateType,
+		SubjectId:       iSubjectId,
+		IssueTime:       issueTime,
+		ExpiryTime:      expiryTime,
+		IssuerId:        iIssuerId,
+		Scope:           CertificateScope{MaterialNames: []string{}, Origins: []string{}},
+		CoIssuerIds:     []string{},
+		Claims:          subjectClaims,
+		Credential:      iCredential,
+	}
+
+	err = graphContract.CreateNode(iCtx, &certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putIndex(iCtx, credentialObjectType, []string{credentialHashHex, iNodeId})
+	if err != nil {
+		return nil, err
+	}
+
+	err = putCertification(iCtx, iNodeId, iSubjectId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putAuditEntry(iCtx, iSubjectId, eCertified, fmt.Sprintf("%s %s by %s", certificateType, iNodeId, iIssuerId))
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, appendCertificateLog(iCtx, eLogIssued, iNodeId, iIssuerId, fmt.Sprintf("%s for %s from %s", certificateType, iSubjectId, claims.Issuer)))
+}
+
+/// checks the signature of a JWT encoded verifiable credential against the key of iIssuerId without storing it
+func (c *CertificateContract) VerifyVerifiableCredential(
+	iCtx contractapi.TransactionContextInterface,
+	iIssuerId string,
+	iCredential string,
+) (bool, error) {
+	issuer, err := c.GetCertificateAuthority(iCtx, iIssuerId)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = verifyCredential(iCredential, issuer.OwnerPublicKey)
+	return err == nil, nil
+}
@@ -0,0 +1,227 @@
+package asset
+
+import (
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/shopspring/decimal"
+)
+
+type CustodyEventType = string
+
+const (
+	eShipped   CustodyEventType = "eShipped"
+	eReceived  CustodyEventType = "eReceived"
+	eStored    CustodyEventType = "eStored"
+	eInTransit CustodyEventType = "eInTransit"
+)
+
+const custodyObjectType = "custody"
+
+/// Node owned by the custodian which handled the material, e.g. a 3PL, linked to the material
+/// through its previous node hashes the same way as quality records.
+/// The location is either a registered GLN, coordinates, or both
+type CustodyEvent struct {
+	graph.NodeHeader
+	MaterialId  string           `json:"MaterialId"`
+	EventType   CustodyEventType `json:"EventType"`
+	LocationGln string           `json:"LocationGln"`
+	Latitude    string           `json:"Latitude"`
+	Longitude   string           `json:"Longitude"`
+	EventTime   time.Time        `json:"EventTime"`
+}
+
+func (e *CustodyEvent) GetHeader() graph.NodeHeader {
+	return e.NodeHeader
+}
+func (e *CustodyEvent) SetHeader(iHeader graph.NodeHeader) {
+	e.NodeHeader = iHeader
+}
+
+func isCustodyEventType(
+	iEventType string,
+) bool {
+	switch iEventType {
+	case eShipped, eReceived, eStored, eInTransit:
+		return true
+	default:
+		return false
+	}
+}
+
+func validateCoordinate(
+	iCoordinate string,
+	iLimit int64,
+) error {
+	coordinate, err := decimal.NewFromString(iCoordinate)
+	if err != nil {
+		return err
+	}
+
+	if coordinate.Abs().GreaterThan(decimal.NewFromInt(iLimit)) {
+		return fmt.Errorf("coordinate %s is out of range", iCoordinate)
+	}
+
+	return nil
+}
+
+func validateCustodyLocation(
+	iLocationGln string,
+	iLatitude string,
+	iLongitude string,
+) error {
+	if iLocationGln == "" && iLatitude == "" && iLongitude == "" {
+		return fmt.Errorf("location cannot be empty")
+	}
+
+	if iLocationGln != "" {
+		err := validateGln(iLocationGln)
+		if err != nil {
+			return err
+		}
+	}
+
+	if iLatitude != "" || iLongitude != "" {
+		err := validateCoordinate(iLatitude, 90)
+		if err != nil {
+			return err
+		}
+
+		err = validateCoordinate(iLongitude, 180)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getCustodyEvents(
+	iCtx contractapi.TransactionContextInterface,
+	iMaterialId string,
+) ([]CustodyEvent, error) {
+	eventIds, err := getIndexedIds(iCtx, custodyObjectType, []string{iMaterialId})
+	if err != nil {
+		return nil, err
+	}
+
+	graphContract := graph.GraphContract{}
+	events := []CustodyEvent{}
+	for _, eventId := range eventIds {
+		var event CustodyEvent
+		err = graphContract.GetNode(iCtx, eventId, &event)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+/// events may be recorded after the fact by scanners that were offline, but not ahead of time
+/// iSignature is the custodian's signature of the custody node
+func (c *MaterialContract) RecordCustodyEvent(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iMaterialId string,
+	iEventType string,
+	iLocationGln string,
+	iLatitude string,
+	iLongitude string,
+	iEventTime time.Time,
+	iCustodianPublicKey string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	if !isCustodyEventType(iEventType) {
+		return nil, fmt.Errorf("unknown event type %s", iEventType)
+	}
+
+	err := validateCustodyLocation(iLocationGln, iLatitude, iLongitude)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkNotInFuture(iCtx, iEventTime)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.GetMaterial(iCtx, iMaterialId)
+	if err != nil {
+		return nil, err
+	}
+
+	graphContract := graph.GraphContract{}
+	nodeHeader := graph.MakeNodeHeader(
+		iNodeId,
+		eCustody,
+		true,
+		graph.MakeHashSet(graph.HashId(iMaterialId)),
+		graph.MakeHashSet(),
+		iCustodianPublicKey,
+		transactionTime,
+		iSignature,
+	)
+	event := CustodyEvent{
+		NodeHeader:  nodeHeader,
+		MaterialId:  iMaterialId,
+		EventType:   iEventType,
+		LocationGln: iLocationGln,
+		Latitude:    iLatitude,
+		Longitude:   iLongitude,
+		EventTime:   iEventTime,
+	}
+
+	err = graphContract.CreateNode(iCtx, &event)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putIndex(iCtx, custodyObjectType, []string{iMaterialId, iNodeId})
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putAuditEntry(iCtx, iMaterialId, eCustodyRecorded, fmt.Sprintf("%s %s", iEventType, iNodeId)))
+}
+
+/// returns the custody events of iNodeId and of all of its ancestors, oldest first
+func (c *MaterialContract) GetMaterialMovements(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) ([]CustodyEvent, error) {
+	_, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	lineageIds, err := getLineageIds(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []CustodyEvent{}
+	for _, nodeId := range lineageIds {
+		nodeEvents, err := getCustodyEvents(iCtx, nodeId)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, nodeEvents...)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].EventTime.Before(events[j].EventTime)
+	})
+
+	return events, nil
+}
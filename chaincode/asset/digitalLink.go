@@ -0,0 +1,118 @@
+package asset
+
+import (
+	"fmt"
+	"net/url"
+	"sig_chain/chaincode/graph"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	digitalLinkDomainKey     = "digitalLinkDomain"
+	defaultDigitalLinkDomain = "https://id.gs1.org"
+)
+
+/// Signed by the administrator, Domain is the resolver printed QR codes point to
+type DigitalLinkDomainChange struct {
+	Domain    string `json:"Domain"`
+	Signature string `json:"Signature"`
+}
+
+/// Uri is the GS1 Digital Link to encode in the QR code, NodeId is carried as an extra query parameter
+/// so that the resolver can return the provenance of the exact node
+type DigitalLink struct {
+	Uri    string `json:"Uri"`
+	NodeId string `json:"NodeId"`
+}
+
+func getDigitalLinkDomain(
+	iCtx contractapi.TransactionContextInterface,
+) (string, error) {
+	value, err := getConfigValue(iCtx, digitalLinkDomainKey)
+	if err != nil {
+		return "", err
+	}
+
+	if value == nil {
+		return defaultDigitalLinkDomain, nil
+	}
+
+	return string(value), nil
+}
+
+/// iSignature is the administrator's signature of the DigitalLinkDomainChange
+func (c *MaterialContract) SetDigitalLinkDomain(
+	iCtx contractapi.TransactionContextInterface,
+	iDomain string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	domain, err := url.Parse(iDomain)
+	if err != nil {
+		return nil, err
+	}
+
+	if domain.Scheme != "https" || domain.Host == "" {
+		return nil, fmt.Errorf("domain must be an https url")
+	}
+
+	change := DigitalLinkDomainChange{
+		Domain: iDomain,
+	}
+	err = verifyAdministratorSignature(iCtx, &change, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putConfigValue(iCtx, digitalLinkDomainKey, []byte(strings.TrimRight(iDomain, "/"))))
+}
+
+/// returns the GS1 Digital Link of the material, which must have either a GTIN or an SSCC
+func (c *MaterialContract) GetDigitalLink(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) (*DigitalLink, error) {
+	material, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	domain, err := getDigitalLinkDomain(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	/// application identifiers: 01 GTIN, 10 batch or lot, 21 serial number, 00 SSCC, 17 expiry date
+	path := ""
+	if material.Gtin != "" {
+		path = "/01/" + strings.Repeat("0", 14-len(material.Gtin)) + material.Gtin
+
+		lot := material.LotNumber
+		if lot == "" {
+			lot = material.BatchNumber
+		}
+		if lot != "" {
+			path += "/10/" + url.PathEscape(lot)
+		}
+
+		if material.SerialNumber != "" {
+			path += "/21/" + url.PathEscape(material.SerialNumber)
+		}
+	} else if material.Sscc != "" {
+		path = "/00/" + material.Sscc
+	} else {
+		return nil, fmt.Errorf("material has neither a GTIN nor an SSCC")
+	}
+
+	query := url.Values{}
+	if !material.ExpiryDate.IsZero() {
+		query.Set("17", material.ExpiryDate.Format("060102"))
+	}
+	query.Set("nodeId", iNodeId)
+
+	return &DigitalLink{
+		Uri:    domain + path + "?" + query.Encode(),
+		NodeId: iNodeId,
+	}, nil
+}
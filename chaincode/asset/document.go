@@ -0,0 +1,156 @@
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+type DocumentType = string
+
+const (
+	eLabReport             DocumentType = "eLabReport"
+	eCertificateOfAnalysis DocumentType = "eCertificateOfAnalysis"
+	eInvoice               DocumentType = "eInvoice"
+	eBillOfLading          DocumentType = "eBillOfLading"
+	eOtherDocument         DocumentType = "eOtherDocument"
+)
+
+const documentObjectType = "document"
+
+/// Signed by the owner of the material. DocHash is either the hash of the document or its IPFS CID,
+/// the document itself is never stored on the ledger
+type DocumentAttachment struct {
+	NodeId    string       `json:"NodeId"`
+	DocHash   string       `json:"DocHash"`
+	DocType   DocumentType `json:"DocType"`
+	Uri       string       `json:"Uri"`
+	Signature string       `json:"Signature"`
+}
+
+type MaterialDocument struct {
+	DocumentAttachment
+	AttachedTime time.Time `json:"AttachedTime"`
+	TxId         string    `json:"TxId"`
+}
+
+func isDocumentType(
+	iDocType string,
+) bool {
+	switch iDocType {
+	case eLabReport, eCertificateOfAnalysis, eInvoice, eBillOfLading, eOtherDocument:
+		return true
+	default:
+		return false
+	}
+}
+
+/// iSignature is the owner's signature of the DocumentAttachment
+func (c *MaterialContract) AttachDocument(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iDocHash string,
+	iDocType string,
+	iUri string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	if iDocHash == "" {
+		return nil, fmt.Errorf("document hash cannot be empty")
+	}
+
+	if !isDocumentType(iDocType) {
+		return nil, fmt.Errorf("unknown document type %s", iDocType)
+	}
+
+	material, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment := DocumentAttachment{
+		NodeId:  iNodeId,
+		DocHash: iDocHash,
+		DocType: iDocType,
+		Uri:     iUri,
+	}
+	err = graph.VerifyPayload(material.OwnerPublicKey, &attachment, iSignature)
+	if err != nil {
+		return nil, err
+	}
+	attachment.Signature = iSignature
+
+	key, err := iCtx.GetStub().CreateCompositeKey(documentObjectType, []string{iNodeId, iDocHash})
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if existing != nil {
+		return nil, fmt.Errorf("document %s is already attached", iDocHash)
+	}
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	document := MaterialDocument{
+		DocumentAttachment: attachment,
+		AttachedTime:       transactionTime,
+		TxId:               iCtx.GetStub().GetTxID(),
+	}
+	documentJson, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	err = iCtx.GetStub().PutState(key, documentJson)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putAuditEntry(iCtx, iNodeId, eDocumentAttached, fmt.Sprintf("%s %s", iDocType, iDocHash)))
+}
+
+func (c *MaterialContract) GetMaterialDocuments(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) ([]MaterialDocument, error) {
+	return getDocuments(iCtx, iNodeId)
+}
+
+func getDocuments(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) ([]MaterialDocument, error) {
+	iterator, err := iCtx.GetStub().GetStateByPartialCompositeKey(documentObjectType, []string{iNodeId})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	documents := []MaterialDocument{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var document MaterialDocument
+		err = json.Unmarshal(kv.Value, &document)
+		if err != nil {
+			return nil, err
+		}
+
+		documents = append(documents, document)
+	}
+
+	return documents, nil
+}
@@ -0,0 +1,48 @@
+package asset
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func checkNotExpired(
+	iCtx contractapi.TransactionContextInterface,
+	iMaterial *Material,
+) error {
+	if iMaterial.ExpiryDate.IsZero() {
+		return nil
+	}
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return err
+	}
+
+	if !iMaterial.ExpiryDate.After(transactionTime) {
+		return fmt.Errorf("material %s expired on %s", iMaterial.Id, iMaterial.ExpiryDate.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+/// returns the materials currently owned by iOwnerPublicKey that expire before iDate, expired ones included
+func (c *MaterialContract) GetMaterialsExpiringBefore(
+	iCtx contractapi.TransactionContextInterface,
+	iOwnerPublicKey string,
+	iDate time.Time,
+	iPageSize int32,
+	iBookmark string,
+) (*MaterialPage, error) {
+	selector := map[string]interface{}{
+		"OwnerPublicKey": iOwnerPublicKey,
+		"IsFinalized":    false,
+		"ExpiryDate": map[string]interface{}{
+			"$gt": formatQueryTime(time.Time{}),
+			"$lt": formatQueryTimeCeiling(iDate),
+		},
+	}
+
+	return queryMaterials(iCtx, selector, "indexExpiry", iPageSize, iBookmark)
+}
@@ -0,0 +1,69 @@
+package asset_test
+
+import (
+	"sig_chain/chaincode/asset"
+	"sig_chain/pkg/client"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func (l *testLedger) createExpiringMaterial(
+	iNodeId string,
+	iOwner client.Signer,
+	iExpiryDate time.Time,
+) error {
+	material := makeTestMaterial(iNodeId, "10", iOwner)
+	material.ExpiryDate = iExpiryDate
+	signature := signNode(l.t, iOwner, &material)
+	return l.submit(func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.CreateMaterial(iCtx, iNodeId, "flour", "kg", "10", "", "", iExpiryDate, "", "", "", "", "", iOwner.GetPublicKey(), testTime, signature)
+		return err
+	})
+}
+
+func (l *testLedger) getMaterialsExpiringBefore(
+	iOwner client.Signer,
+	iDate time.Time,
+) []asset.Material {
+	l.t.Helper()
+	var page *asset.MaterialPage
+	l.mustSubmit("get materials expiring before "+iDate.String(), func(iCtx contractapi.TransactionContextInterface) error {
+		var err error
+		page, err = l.contract.GetMaterialsExpiringBefore(iCtx, iOwner.GetPublicKey(), iDate, 10, "")
+		return err
+	})
+
+	return page.Materials
+}
+
+/// expiry dates are compared as strings by CouchDB, the dates of other zones must not be compared by their digits
+func TestExpiryInOtherZones(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	expiryDate := testTime.Add(48 * time.Hour)
+	east := time.FixedZone("UTC+2", 2*60*60)
+	west := time.FixedZone("UTC-5", -5*60*60)
+
+	err := l.createExpiringMaterial("m1", alice, expiryDate.In(east))
+	if err == nil {
+		t.Fatal("material expiring in another zone than UTC is created")
+	}
+
+	err = l.createExpiringMaterial("m1", alice, expiryDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	/// 01:00 in UTC+2 is before midnight in UTC, 20:00 in UTC-5 is after it
+	if len(l.getMaterialsExpiringBefore(alice, expiryDate.Add(-time.Hour).In(east))) != 0 {
+		t.Fatal("m1 expires an hour before its expiry date")
+	}
+	if len(l.getMaterialsExpiringBefore(alice, expiryDate.Add(time.Hour).In(west))) != 1 {
+		t.Fatal("m1 does not expire an hour after its expiry date")
+	}
+	if len(l.getMaterialsExpiringBefore(alice, expiryDate.Add(500*time.Millisecond))) != 1 {
+		t.Fatal("m1 does not expire half a second after its expiry date")
+	}
+}
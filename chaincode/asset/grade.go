@@ -0,0 +1,175 @@
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const gradeObjectType = "grade"
+
+/// Signed by the owner of the material. FromGrade must be the current grade of the material
+/// so that an older downgrade cannot be replayed
+type DowngradeRequest struct {
+	NodeId    string `json:"NodeId"`
+	FromGrade string `json:"FromGrade"`
+	ToGrade   string `json:"ToGrade"`
+	Signature string `json:"Signature"`
+}
+
+type GradeChange struct {
+	DowngradeRequest
+	ChangedTime time.Time `json:"ChangedTime"`
+	TxId        string    `json:"TxId"`
+}
+
+/// returns the position of iGrade in the allowed grades of the template, best grade first
+func getGradeRank(
+	iProduct *ProductDefinition,
+	iGrade string,
+) (int, error) {
+	for i, grade := range iProduct.AllowedGrades {
+		if grade == iGrade {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("grade %s is not allowed for product %s", iGrade, iProduct.TemplateId)
+}
+
+/// the grade of the material once its downgrade, if any, is applied
+func getEffectiveGrade(
+	iCtx contractapi.TransactionContextInterface,
+	iMaterial *Material,
+) (string, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(gradeObjectType, []string{iMaterial.Id})
+	if err != nil {
+		return "", err
+	}
+
+	changeJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if changeJson == nil {
+		return iMaterial.Grade, nil
+	}
+
+	var change GradeChange
+	err = json.Unmarshal(changeJson, &change)
+	if err != nil {
+		return "", err
+	}
+
+	return change.ToGrade, nil
+}
+
+/// iSignature is the owner's signature of the DowngradeRequest, only materials following a product
+/// template can be downgraded since the template defines the order of the grades
+func (c *MaterialContract) DowngradeMaterial(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iGrade string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	material, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	if material.IsFinalized {
+		return nil, fmt.Errorf("node is already finalized")
+	}
+
+	err = checkNoPendingOffer(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	if material.TemplateId == "" {
+		return nil, fmt.Errorf("material does not follow a product template")
+	}
+
+	product, err := getProductDefinition(iCtx, material.TemplateId)
+	if err != nil {
+		return nil, err
+	}
+
+	if product == nil {
+		return nil, fmt.Errorf("product template %s does not exist", material.TemplateId)
+	}
+
+	grade, err := getEffectiveGrade(iCtx, material)
+	if err != nil {
+		return nil, err
+	}
+
+	fromRank, err := getGradeRank(product, grade)
+	if err != nil {
+		return nil, err
+	}
+
+	toRank, err := getGradeRank(product, iGrade)
+	if err != nil {
+		return nil, err
+	}
+
+	if toRank <= fromRank {
+		return nil, fmt.Errorf("grade %s is not lower than %s", iGrade, grade)
+	}
+
+	request := DowngradeRequest{
+		NodeId:    iNodeId,
+		FromGrade: grade,
+		ToGrade:   iGrade,
+	}
+	err = graph.VerifyPayload(material.OwnerPublicKey, &request, iSignature)
+	if err != nil {
+		return nil, err
+	}
+	request.Signature = iSignature
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	change := GradeChange{
+		DowngradeRequest: request,
+		ChangedTime:      transactionTime,
+		TxId:             iCtx.GetStub().GetTxID(),
+	}
+	changeJson, err := json.Marshal(change)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(gradeObjectType, []string{iNodeId})
+	if err != nil {
+		return nil, err
+	}
+
+	err = iCtx.GetStub().PutState(key, changeJson)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putAuditEntry(iCtx, iNodeId, eDowngraded, fmt.Sprintf("%s to %s", grade, iGrade)))
+}
+
+/// returns the grade of the material once its downgrade, if any, is applied
+func (c *MaterialContract) GetMaterialGrade(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) (string, error) {
+	material, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return "", err
+	}
+
+	return getEffectiveGrade(iCtx, material)
+}
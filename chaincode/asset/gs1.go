@@ -0,0 +1,204 @@
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	glnObjectType      = "gln"
+	ownerGlnObjectType = "ownerGln"
+)
+
+/// Signed by the owner, binds a GS1 global location number to its public key
+type GlnRegistration struct {
+	Gln            string `json:"Gln"`
+	OwnerPublicKey string `json:"OwnerPublicKey"`
+	Signature      string `json:"Signature"`
+}
+
+/// checks the length and the GS1 mod 10 check digit of iId
+func validateGs1Id(
+	iKind string,
+	iId string,
+	iLengths ...int,
+) error {
+	isLengthValid := false
+	for _, length := range iLengths {
+		if len(iId) == length {
+			isLengthValid = true
+		}
+	}
+
+	if !isLengthValid {
+		return fmt.Errorf("%s %s has an invalid length", iKind, iId)
+	}
+
+	/// weights alternate 3 and 1 starting from the digit left of the check digit
+	sum := 0
+	for i := len(iId) - 2; i >= 0; i-- {
+		if iId[i] < '0' || iId[i] > '9' {
+			return fmt.Errorf("%s %s must only contain digits", iKind, iId)
+		}
+
+		digit := int(iId[i] - '0')
+		if (len(iId)-2-i)%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+
+	checkDigit := iId[len(iId)-1]
+	if checkDigit < '0' || checkDigit > '9' || int(checkDigit-'0') != (10-sum%10)%10 {
+		return fmt.Errorf("%s %s has an invalid check digit", iKind, iId)
+	}
+
+	return nil
+}
+
+func validateGtin(
+	iGtin string,
+) error {
+	return validateGs1Id("GTIN", iGtin, 8, 12, 13, 14)
+}
+
+func validateSscc(
+	iSscc string,
+) error {
+	return validateGs1Id("SSCC", iSscc, 18)
+}
+
+func validateGln(
+	iGln string,
+) error {
+	return validateGs1Id("GLN", iGln, 13)
+}
+
+func (c *MaterialContract) GetMaterialsByGtin(
+	iCtx contractapi.TransactionContextInterface,
+	iGtin string,
+	iPageSize int32,
+	iBookmark string,
+) (*MaterialPage, error) {
+	selector := map[string]interface{}{
+		"Gtin": iGtin,
+	}
+
+	return queryMaterials(iCtx, selector, "indexGtin", iPageSize, iBookmark)
+}
+
+func (c *MaterialContract) GetMaterialsBySscc(
+	iCtx contractapi.TransactionContextInterface,
+	iSscc string,
+	iPageSize int32,
+	iBookmark string,
+) (*MaterialPage, error) {
+	selector := map[string]interface{}{
+		"Sscc": iSscc,
+	}
+
+	return queryMaterials(iCtx, selector, "indexSscc", iPageSize, iBookmark)
+}
+
+/// returns nil if iGln is not registered
+func getGlnRegistration(
+	iCtx contractapi.TransactionContextInterface,
+	iGln string,
+) (*GlnRegistration, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(glnObjectType, []string{iGln})
+	if err != nil {
+		return nil, err
+	}
+
+	registrationJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if registrationJson == nil {
+		return nil, nil
+	}
+
+	var registration GlnRegistration
+	err = json.Unmarshal(registrationJson, &registration)
+	if err != nil {
+		return nil, err
+	}
+
+	return &registration, nil
+}
+
+/// iSignature is the owner's signature of the GlnRegistration, an owner may register several locations
+func (c *MaterialContract) RegisterGln(
+	iCtx contractapi.TransactionContextInterface,
+	iGln string,
+	iOwnerPublicKey string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	err := validateGln(iGln)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := getGlnRegistration(iCtx, iGln)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		return nil, fmt.Errorf("GLN %s is already registered", iGln)
+	}
+
+	registration := GlnRegistration{
+		Gln:            iGln,
+		OwnerPublicKey: iOwnerPublicKey,
+	}
+	err = graph.VerifyPayload(iOwnerPublicKey, &registration, iSignature)
+	if err != nil {
+		return nil, err
+	}
+	registration.Signature = iSignature
+
+	registrationJson, err := json.Marshal(registration)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(glnObjectType, []string{iGln})
+	if err != nil {
+		return nil, err
+	}
+
+	err = iCtx.GetStub().PutState(key, registrationJson)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putIndex(iCtx, ownerGlnObjectType, []string{ownerFingerprint(iOwnerPublicKey), iGln}))
+}
+
+func (c *MaterialContract) GetOwnerByGln(
+	iCtx contractapi.TransactionContextInterface,
+	iGln string,
+) (*GlnRegistration, error) {
+	registration, err := getGlnRegistration(iCtx, iGln)
+	if err != nil {
+		return nil, err
+	}
+
+	if registration == nil {
+		return nil, fmt.Errorf("GLN %s is not registered", iGln)
+	}
+
+	return registration, nil
+}
+
+func (c *MaterialContract) GetOwnerGlns(
+	iCtx contractapi.TransactionContextInterface,
+	iOwnerPublicKey string,
+) ([]string, error) {
+	return getIndexedIds(iCtx, ownerGlnObjectType, []string{ownerFingerprint(iOwnerPublicKey)})
+}
@@ -0,0 +1,66 @@
+package asset
+
+import (
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// every contract shares the hooks of the graph contract, which validate the arguments, log the
+/// transactions and list the available functions when an unknown one is called.
+/// They also share the transaction context, which records the keys written for the transaction receipts and
+/// the submitter of the transactions which write
+
+func (c *MaterialContract) GetBeforeTransaction() interface{} {
+	return graph.BeforeTransaction
+}
+
+func (c *MaterialContract) GetAfterTransaction() interface{} {
+	return graph.AfterTransaction
+}
+
+func (c *MaterialContract) GetUnknownTransaction() interface{} {
+	return func(iCtx contractapi.TransactionContextInterface) error {
+		return graph.UnknownTransaction(iCtx, c)
+	}
+}
+
+func (c *ProductContract) GetBeforeTransaction() interface{} {
+	return graph.BeforeTransaction
+}
+
+func (c *ProductContract) GetAfterTransaction() interface{} {
+	return graph.AfterTransaction
+}
+
+func (c *ProductContract) GetUnknownTransaction() interface{} {
+	return func(iCtx contractapi.TransactionContextInterface) error {
+		return graph.UnknownTransaction(iCtx, c)
+	}
+}
+
+func (c *CertificateContract) GetBeforeTransaction() interface{} {
+	return graph.BeforeTransaction
+}
+
+func (c *CertificateContract) GetAfterTransaction() interface{} {
+	return graph.AfterTransaction
+}
+
+func (c *CertificateContract) GetUnknownTransaction() interface{} {
+	return func(iCtx contractapi.TransactionContextInterface) error {
+		return graph.UnknownTransaction(iCtx, c)
+	}
+}
+
+func (c *MaterialContract) GetTransactionContextHandler() contractapi.SettableTransactionContextInterface {
+	return &graph.TransactionContext{}
+}
+
+func (c *ProductContract) GetTransactionContextHandler() contractapi.SettableTransactionContextInterface {
+	return &graph.TransactionContext{}
+}
+
+func (c *CertificateContract) GetTransactionContextHandler() contractapi.SettableTransactionContextInterface {
+	return &graph.TransactionContext{}
+}
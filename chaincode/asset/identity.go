@@ -0,0 +1,61 @@
+package asset
+
+import (
+	"encoding/pem"
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// Signed by the administrator
+type TrustedRootsChange struct {
+	graph.TrustedRoots
+	Signature string `json:"Signature"`
+}
+
+/// owners and issuers may then be identified by certificates of iMspId instead of bare keys,
+/// iSignature is the administrator's signature of the TrustedRootsChange
+func (c *MaterialContract) SetTrustedRoots(
+	iCtx contractapi.TransactionContextInterface,
+	iMspId string,
+	iRoots []string,
+	iIntermediates []string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	change := TrustedRootsChange{
+		TrustedRoots: graph.TrustedRoots{
+			MspId:         iMspId,
+			Roots:         iRoots,
+			Intermediates: iIntermediates,
+		},
+	}
+	err := verifyAdministratorSignature(iCtx, &change, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, graph.PutTrustedRoots(iCtx, &change.TrustedRoots))
+}
+
+func (c *MaterialContract) GetTrustedRoots(
+	iCtx contractapi.TransactionContextInterface,
+) ([]graph.TrustedRoots, error) {
+	return graph.GetTrustedRoots(iCtx)
+}
+
+/// returns the PEM encoded enrollment certificate of the submitter, to be used as its OwnerPublicKey
+/// so that the same identity signs both the transactions and the node payloads
+func (c *MaterialContract) GetSubmitterIdentity(
+	iCtx contractapi.TransactionContextInterface,
+) (string, error) {
+	certificate, err := iCtx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return "", err
+	}
+
+	certificatePem := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: certificate.Raw,
+	})
+	return string(certificatePem), nil
+}
@@ -0,0 +1,110 @@
+package asset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// public keys are too long to be used in composite keys
+func ownerFingerprint(
+	iPublicKey string,
+) string {
+	hash := sha256.Sum256([]byte(iPublicKey))
+	return hex.EncodeToString(hash[:])
+}
+
+func putIndex(
+	iCtx contractapi.TransactionContextInterface,
+	iObjectType string,
+	iAttributes []string,
+) error {
+	key, err := iCtx.GetStub().CreateCompositeKey(iObjectType, iAttributes)
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().PutState(key, []byte{0x00})
+}
+
+/// returns the last attribute of every key of iObjectType starting with iAttributes
+func getIndexedIds(
+	iCtx contractapi.TransactionContextInterface,
+	iObjectType string,
+	iAttributes []string,
+) ([]string, error) {
+	iterator, err := iCtx.GetStub().GetStateByPartialCompositeKey(iObjectType, iAttributes)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	ids := []string{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, attributes, err := iCtx.GetStub().SplitCompositeKey(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, attributes[len(attributes)-1])
+	}
+
+	return ids, nil
+}
+
+/// must be called whenever a material node is created. Listings use rich queries instead,
+/// the serial number index is kept so that duplicates are rejected without CouchDB
+func putMaterialIndexes(
+	iCtx contractapi.TransactionContextInterface,
+	iMaterial *Material,
+) error {
+	/// items keep the same serial number across transfers
+	if iMaterial.SerialNumber != "" {
+		err := putIndex(iCtx, serialObjectType, []string{iMaterial.Gtin, iMaterial.SerialNumber, iMaterial.Id})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *MaterialContract) GetMaterialsByLot(
+	iCtx contractapi.TransactionContextInterface,
+	iLotNumber string,
+	iPageSize int32,
+	iBookmark string,
+) (*MaterialPage, error) {
+	selector := map[string]interface{}{
+		"LotNumber": iLotNumber,
+	}
+
+	return queryMaterials(iCtx, selector, "indexLot", iPageSize, iBookmark)
+}
+
+type MaterialPage struct {
+	Materials           []Material `json:"Materials"`
+	Bookmark            string     `json:"Bookmark"`
+	FetchedRecordsCount int32      `json:"FetchedRecordsCount"`
+}
+
+/// lists the materials currently owned by iOwnerPublicKey, an empty iBookmark starts from the first page
+func (c *MaterialContract) GetMaterialsByOwner(
+	iCtx contractapi.TransactionContextInterface,
+	iOwnerPublicKey string,
+	iPageSize int32,
+	iBookmark string,
+) (*MaterialPage, error) {
+	selector := map[string]interface{}{
+		"OwnerPublicKey": iOwnerPublicKey,
+		"IsFinalized":    false,
+	}
+
+	return queryMaterials(iCtx, selector, "indexOwner", iPageSize, iBookmark)
+}
@@ -0,0 +1,89 @@
+package asset
+
+import (
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// returns iNodeId followed by all of its ancestors, closest first
+func getLineageIds(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) ([]string, error) {
+	graphContract := graph.GraphContract{}
+	lineage := []string{}
+	visited := map[string]bool{iNodeId: true}
+	queue := []string{iNodeId}
+	for len(queue) > 0 {
+		nodeId := queue[0]
+		queue = queue[1:]
+		lineage = append(lineage, nodeId)
+
+		previousIds, err := graphContract.GetPreviousNodeIds(iCtx, nodeId)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, previousId := range previousIds {
+			if !visited[previousId] {
+				visited[previousId] = true
+				queue = append(queue, previousId)
+			}
+		}
+	}
+
+	return lineage, nil
+}
+
+type OwnershipRecord struct {
+	NodeId         string    `json:"NodeId"`
+	OwnerPublicKey string    `json:"OwnerPublicKey"`
+	TransferTime   time.Time `json:"TransferTime"` /// creation time of the node, i.e. when the owner received it
+}
+
+/// returns the owners of iNodeId going back through transfers and returns only, oldest first.
+/// The chain stops at the node created by a split, merge or transform
+func (c *MaterialContract) GetOwnershipChain(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) ([]OwnershipRecord, error) {
+	graphContract := graph.GraphContract{}
+	chain := []OwnershipRecord{}
+	nodeId := iNodeId
+	for {
+		material, err := c.GetMaterial(iCtx, nodeId)
+		if err != nil {
+			return nil, err
+		}
+
+		chain = append([]OwnershipRecord{{
+			NodeId:         nodeId,
+			OwnerPublicKey: material.OwnerPublicKey,
+			TransferTime:   material.CreatedTime,
+		}}, chain...)
+
+		previousIds, err := graphContract.GetPreviousNodeIds(iCtx, nodeId)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(previousIds) != 1 {
+			break
+		}
+
+		derivation, err := getDerivation(iCtx, previousIds[0])
+		if err != nil {
+			return nil, err
+		}
+
+		if derivation == nil || (derivation.Kind != eTransfer && derivation.Kind != eReturn) {
+			break
+		}
+
+		nodeId = previousIds[0]
+	}
+
+	return chain, nil
+}
@@ -0,0 +1,311 @@
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/shopspring/decimal"
+)
+
+type DerivationKind = string
+
+const (
+	eTransfer  DerivationKind = "eTransfer"
+	eSplit     DerivationKind = "eSplit"
+	eMerge     DerivationKind = "eMerge"
+	eTransform DerivationKind = "eTransform"
+	eReturn    DerivationKind = "eReturn"
+	eSerialize DerivationKind = "eSerialize"
+)
+
+const derivationObjectType = "derivation"
+
+/// Records how input materials were turned into output materials, stored once per input
+type Derivation struct {
+	Kind      DerivationKind `json:"Kind"`
+	InputIds  []string       `json:"InputIds"`
+	OutputIds []string       `json:"OutputIds"`
+	Waste     string         `json:"Waste"`
+	WasteUnit string         `json:"WasteUnit"`
+}
+
+type MassBalanceDiscrepancy struct {
+	InputIds       []string `json:"InputIds"`
+	OutputIds      []string `json:"OutputIds"`
+	InputQuantity  string   `json:"InputQuantity"`
+	OutputQuantity string   `json:"OutputQuantity"`
+	Waste          string   `json:"Waste"`
+	Unit           string   `json:"Unit"`
+	Reason         string   `json:"Reason"`
+
+	PositiveAdjustment string `json:"PositiveAdjustment,omitempty" metadata:",optional"` /// included in InputQuantity
+}
+
+func putDerivation(
+	iCtx contractapi.TransactionContextInterface,
+	iKind DerivationKind,
+	iInputIds []string,
+	iOutputIds []string,
+	iWaste string,
+	iWasteUnit string,
+) error {
+	derivation := Derivation{
+		Kind:      iKind,
+		InputIds:  iInputIds,
+		OutputIds: iOutputIds,
+		Waste:     iWaste,
+		WasteUnit: iWasteUnit,
+	}
+
+	derivationJson, err := json.Marshal(derivation)
+	if err != nil {
+		return err
+	}
+
+	for _, inputId := range iInputIds {
+		key, err := iCtx.GetStub().CreateCompositeKey(derivationObjectType, []string{inputId})
+		if err != nil {
+			return err
+		}
+
+		err = iCtx.GetStub().PutState(key, derivationJson)
+		if err != nil {
+			return err
+		}
+
+		err = putAuditEntry(iCtx, inputId, eDerived, fmt.Sprintf("%s into %s", iKind, strings.Join(iOutputIds, ",")))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, outputId := range iOutputIds {
+		err = putAuditEntry(iCtx, outputId, eDerived, fmt.Sprintf("%s from %s", iKind, strings.Join(iInputIds, ",")))
+		if err != nil {
+			return err
+		}
+	}
+
+	return inheritRecalls(iCtx, iInputIds, iOutputIds)
+}
+
+/// returns nil if iInputId has not been derived into anything
+func getDerivation(
+	iCtx contractapi.TransactionContextInterface,
+	iInputId string,
+) (*Derivation, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(derivationObjectType, []string{iInputId})
+	if err != nil {
+		return nil, err
+	}
+
+	derivationJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if derivationJson == nil {
+		return nil, nil
+	}
+
+	var derivation Derivation
+	err = json.Unmarshal(derivationJson, &derivation)
+	if err != nil {
+		return nil, err
+	}
+
+	return &derivation, nil
+}
+
+/// inputs are finalized so their adjusted quantity is the one they were derived with,
+/// outputs may have been adjusted since so their original quantity is used instead
+func (c *MaterialContract) sumQuantities(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeIds []string,
+	iUnit string,
+	iIsAdjusted bool,
+) (decimal.Decimal, error) {
+	total := decimal.NewFromInt(0)
+	for _, nodeId := range iNodeIds {
+		material, err := c.GetMaterial(iCtx, nodeId)
+		if err != nil {
+			return decimal.Decimal{}, err
+		}
+
+		quantity, err := decimal.NewFromString(material.Quantity)
+		if iIsAdjusted {
+			quantity, err = getEffectiveQuantity(iCtx, material)
+		}
+		if err != nil {
+			return decimal.Decimal{}, err
+		}
+
+		converted, err := convertQuantity(iCtx, quantity, material.Unit, iUnit)
+		if err != nil {
+			return decimal.Decimal{}, err
+		}
+		total = total.Add(converted)
+	}
+
+	return total, nil
+}
+
+/// converted to iUnit
+func (c *MaterialContract) sumPositiveAdjustments(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeIds []string,
+	iUnit string,
+) (decimal.Decimal, error) {
+	total := decimal.NewFromInt(0)
+	for _, nodeId := range iNodeIds {
+		material, err := c.GetMaterial(iCtx, nodeId)
+		if err != nil {
+			return decimal.Decimal{}, err
+		}
+
+		adjustment, err := getPositiveAdjustment(iCtx, nodeId)
+		if err != nil {
+			return decimal.Decimal{}, err
+		}
+
+		if adjustment.IsZero() {
+			continue
+		}
+
+		converted, err := convertQuantity(iCtx, adjustment, material.Unit, iUnit)
+		if err != nil {
+			return decimal.Decimal{}, err
+		}
+		total = total.Add(converted)
+	}
+
+	return total, nil
+}
+
+/// returns a discrepancy if the inputs of iDerivation do not reconcile with its outputs and waste, nil otherwise.
+/// Inputs whose quantity was corrected upwards are reported even when they reconcile, since the added quantity
+/// could hide outputs which do not come from the inputs
+func (c *MaterialContract) checkDerivation(
+	iCtx contractapi.TransactionContextInterface,
+	iDerivation *Derivation,
+) (*MassBalanceDiscrepancy, error) {
+	discrepancy := MassBalanceDiscrepancy{
+		InputIds:  iDerivation.InputIds,
+		OutputIds: iDerivation.OutputIds,
+		Waste:     iDerivation.Waste,
+		Unit:      iDerivation.WasteUnit,
+	}
+
+	inputQuantity, err := c.sumQuantities(iCtx, iDerivation.InputIds, iDerivation.WasteUnit, true)
+	if err != nil {
+		discrepancy.Reason = err.Error()
+		return &discrepancy, nil
+	}
+	discrepancy.InputQuantity = inputQuantity.String()
+
+	outputQuantity, err := c.sumQuantities(iCtx, iDerivation.OutputIds, iDerivation.WasteUnit, false)
+	if err != nil {
+		discrepancy.Reason = err.Error()
+		return &discrepancy, nil
+	}
+	discrepancy.OutputQuantity = outputQuantity.String()
+
+	positiveAdjustment, err := c.sumPositiveAdjustments(iCtx, iDerivation.InputIds, iDerivation.WasteUnit)
+	if err != nil {
+		discrepancy.Reason = err.Error()
+		return &discrepancy, nil
+	}
+
+	if positiveAdjustment.IsPositive() {
+		discrepancy.PositiveAdjustment = positiveAdjustment.String()
+	}
+
+	waste, err := decimal.NewFromString(iDerivation.Waste)
+	if err != nil {
+		return nil, err
+	}
+
+	loss := inputQuantity.Sub(outputQuantity)
+	if loss.IsNegative() {
+		discrepancy.Reason = "outputs exceed inputs"
+		return &discrepancy, nil
+	}
+
+	/// conversions are rounded so allow for the rounding error of every converted quantity
+	tolerance := decimal.New(int64(len(iDerivation.InputIds)+len(iDerivation.OutputIds)), -quantityPrecision)
+	if loss.Sub(waste).GreaterThan(tolerance) {
+		discrepancy.Reason = "loss exceeds declared waste"
+		return &discrepancy, nil
+	}
+
+	if positiveAdjustment.IsPositive() {
+		discrepancy.Reason = "inputs were adjusted upwards"
+		return &discrepancy, nil
+	}
+
+	return nil, nil
+}
+
+/// walks every descendant of iNodeId and returns the derivations whose quantities do not reconcile
+func (c *MaterialContract) VerifyMassBalance(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) ([]MassBalanceDiscrepancy, error) {
+	_, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	graphContract := graph.GraphContract{}
+	discrepancies := []MassBalanceDiscrepancy{}
+	visited := map[string]bool{iNodeId: true}
+	queue := []string{iNodeId}
+	for len(queue) > 0 {
+		nodeId := queue[0]
+		queue = queue[1:]
+
+		derivation, err := getDerivation(iCtx, nodeId)
+		if err != nil {
+			return nil, err
+		}
+
+		nextIds := []string{}
+		if derivation == nil {
+			nextIds, err = graphContract.GetNextNodeIds(iCtx, nodeId)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(nextIds) > 0 {
+				discrepancies = append(discrepancies, MassBalanceDiscrepancy{
+					InputIds:  []string{nodeId},
+					OutputIds: nextIds,
+					Reason:    "untracked derivation",
+				})
+			}
+		} else if !visited[derivation.OutputIds[0]] {
+			nextIds = derivation.OutputIds
+
+			discrepancy, err := c.checkDerivation(iCtx, derivation)
+			if err != nil {
+				return nil, err
+			}
+
+			if discrepancy != nil {
+				discrepancies = append(discrepancies, *discrepancy)
+			}
+		}
+
+		for _, nextId := range nextIds {
+			if !visited[nextId] {
+				visited[nextId] = true
+				queue = append(queue, nextId)
+			}
+		}
+	}
+
+	return discrepancies, nil
+}
@@ -0,0 +1,92 @@
+package asset_test
+
+import (
+	"sig_chain/chaincode/asset"
+	"sig_chain/pkg/client"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func (l *testLedger) verifyMassBalance(
+	iNodeId string,
+) []asset.MassBalanceDiscrepancy {
+	l.t.Helper()
+	var discrepancies []asset.MassBalanceDiscrepancy
+	l.mustSubmit("verify mass balance of "+iNodeId, func(iCtx contractapi.TransactionContextInterface) error {
+		var err error
+		discrepancies, err = l.contract.VerifyMassBalance(iCtx, iNodeId)
+		return err
+	})
+
+	return discrepancies
+}
+
+func TestAdjustmentCannotBeReplayed(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	l.createMaterial("m1", "10", alice)
+
+	signature := signAdjustment(t, alice, "m1", 0, "-1", "eSampling")
+	l.mustSubmit("adjust", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AdjustMaterialQuantity(iCtx, "m1", "-1", "eSampling", signature)
+		return err
+	})
+
+	l.mustFail("replay adjustment", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AdjustMaterialQuantity(iCtx, "m1", "-1", "eSampling", signature)
+		return err
+	})
+
+	l.mustFail("increase without a correction", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AdjustMaterialQuantity(iCtx, "m1", "1", "eDamage", signAdjustment(t, alice, "m1", 1, "1", "eDamage"))
+		return err
+	})
+
+	var quantity string
+	l.mustSubmit("get quantity", func(iCtx contractapi.TransactionContextInterface) error {
+		var err error
+		quantity, err = l.contract.GetMaterialQuantity(iCtx, "m1")
+		return err
+	})
+	if quantity != "9" {
+		t.Fatalf("adjusted quantity is %s", quantity)
+	}
+}
+
+func TestMassBalanceOfAdjustedSplit(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	l.createMaterial("m1", "10", alice)
+	l.createMaterial("m4", "10", alice)
+
+	l.mustSubmit("shrink", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AdjustMaterialQuantity(iCtx, "m1", "-2", "eShrinkage", signAdjustment(t, alice, "m1", 0, "-2", "eShrinkage"))
+		return err
+	})
+
+	err := l.splitMaterial("m1", alice, []string{"3", "4"}, "1", []string{"m2", "m3"}, []client.Signer{alice, alice})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	discrepancies := l.verifyMassBalance("m1")
+	if len(discrepancies) != 0 {
+		t.Fatalf("split with declared waste has discrepancies: %+v", discrepancies)
+	}
+
+	l.mustSubmit("correct upwards", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AdjustMaterialQuantity(iCtx, "m4", "2", "eCorrection", signAdjustment(t, alice, "m4", 0, "2", "eCorrection"))
+		return err
+	})
+
+	err = l.splitMaterial("m4", alice, []string{"12"}, "0", []string{"m5"}, []client.Signer{alice})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	discrepancies = l.verifyMassBalance("m4")
+	if len(discrepancies) != 1 || discrepancies[0].PositiveAdjustment != "2" {
+		t.Fatalf("upward correction is not reported: %+v", discrepancies)
+	}
+}
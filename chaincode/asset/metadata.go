@@ -0,0 +1,80 @@
+package asset
+
+/// Functions listed by GetEvaluateTransactions are tagged as "evaluate" in the contract metadata,
+/// so that client generators query them instead of submitting a transaction
+
+func (c *MaterialContract) GetEvaluateTransactions() []string {
+	return []string{
+		"ComputeSplitQuantities",
+		"ConvertQuantity",
+		"ExportState",
+		"GetAdministrator",
+		"GetAttestations",
+		"GetClockDriftTolerance",
+		"GetConfidentialMaterial",
+		"GetConfig",
+		"GetDevice",
+		"GetDigitalLink",
+		"GetFullProvenance",
+		"GetMaterial",
+		"GetMaterialAdjustments",
+		"GetMaterialCertificates",
+		"GetMaterialDocuments",
+		"GetMaterialGrade",
+		"GetMaterialHistory",
+		"GetMaterialMovements",
+		"GetMaterialQualityRecords",
+		"GetMaterialQuantity",
+		"GetMaterialRecalls",
+		"GetMaterialReservations",
+		"GetMaterialReturn",
+		"GetMaterialsByGtin",
+		"GetMaterialsByLot",
+		"GetMaterialsByOwner",
+		"GetMaterialsBySerialNumber",
+		"GetMaterialsBySscc",
+		"GetMaterialsCreatedBetween",
+		"GetMaterialsExpiringBefore",
+		"GetOwnerByGln",
+		"GetOwnerGlns",
+		"GetOwnershipChain",
+		"GetPersonalData",
+		"GetRequiredCertifications",
+		"GetRequiredRole",
+		"GetSensorExcursions",
+		"GetSettlementChaincode",
+		"GetSubmitterIdentity",
+		"GetTransferOffer",
+		"GetTransferPrice",
+		"GetTransferRejections",
+		"GetTrustedRoots",
+		"GetUnit",
+		"GetUseTransactionTime",
+		"IsMaterialRecalled",
+		"SearchMaterials",
+		"VerifyMassBalance",
+		"VerifyTransferPrice",
+	}
+}
+
+func (c *ProductContract) GetEvaluateTransactions() []string {
+	return []string{
+		"GetProductDefinition",
+	}
+}
+
+func (c *CertificateContract) GetEvaluateTransactions() []string {
+	return []string{
+		"CheckCertificateStatus",
+		"GetCertificate",
+		"GetCertificateAuthority",
+		"GetCertificateLog",
+		"GetCertificateRenewals",
+		"GetCertificatesByIssuer",
+		"GetClaimSchema",
+		"GetRevocations",
+		"IsRevoked",
+		"VerifyCertificate",
+		"VerifyVerifiableCredential",
+	}
+}
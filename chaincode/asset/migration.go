@@ -0,0 +1,35 @@
+package asset
+
+import (
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// Signed by the administrator
+type MigrationRequest struct {
+	StartKey  string `json:"StartKey"`
+	Count     int    `json:"Count"`
+	Signature string `json:"Signature"`
+}
+
+/// meant to be run in batches after a chaincode upgrade, starting with an empty iStartKey and then with the
+/// NextKey of the previous batch until it is empty. Nodes which are not migrated are upgraded when read.
+/// iSignature is the administrator's signature of the MigrationRequest
+func (c *MaterialContract) Migrate(
+	iCtx contractapi.TransactionContextInterface,
+	iStartKey string,
+	iCount int,
+	iSignature string,
+) (*graph.MigrationResult, error) {
+	request := MigrationRequest{
+		StartKey: iStartKey,
+		Count:    iCount,
+	}
+	err := verifyAdministratorSignature(iCtx, &request, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MigrateNodes(iCtx, iStartKey, iCount)
+}
@@ -0,0 +1,184 @@
+package asset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	personalDataObjectType = "personalData"
+
+	/// the PersonalData json is passed in the transient map so that it never appears in the transaction proposal
+	personalDataTransientKey = "personalData"
+
+	/// defined in collections_config.json. Its blockToLive bounds how long peers keep personal data, including
+	/// the writes preceding a purge which remain in their private data store
+	personalDataCollection = "personalData"
+)
+
+/// Personal data of the producer of an origin material, e.g. a farmer's name and contact. It is only stored
+/// in the personal data collection so that it can be erased without rewriting the ledger
+type PersonalData struct {
+	Name    string `json:"Name"`
+	Contact string `json:"Contact"`
+	Address string `json:"Address"`
+}
+
+/// Signed by the owner of the material. PersonalDataHash is the SHA-256 of the PersonalData json as passed in
+/// the transient map
+type PersonalDataAttachment struct {
+	NodeId           string `json:"NodeId"`
+	PersonalDataHash string `json:"PersonalDataHash"`
+	Signature        string `json:"Signature"`
+}
+
+/// Signed by the administrator, to honor an erasure request
+type PersonalDataPurge struct {
+	NodeId    string `json:"NodeId"`
+	Signature string `json:"Signature"`
+}
+
+/// only materials which do not derive from other nodes have a producer
+func (c *MaterialContract) getOriginMaterial(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) (*Material, error) {
+	material, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(material.PreviousNodeHashedIds) > 0 {
+		return nil, fmt.Errorf("material %s is not an origin material", iNodeId)
+	}
+
+	return material, nil
+}
+
+/// the personal data is passed in the transient map as personalData, iSignature is the owner's signature
+/// of the PersonalDataAttachment. Existing personal data is replaced
+func (c *MaterialContract) SetPersonalData(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	material, err := c.getOriginMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	transient, err := iCtx.GetStub().GetTransient()
+	if err != nil {
+		return nil, err
+	}
+
+	personalDataJson, ok := transient[personalDataTransientKey]
+	if !ok {
+		return nil, fmt.Errorf("personal data must be passed in the transient map as %s", personalDataTransientKey)
+	}
+
+	var personalData PersonalData
+	err = json.Unmarshal(personalDataJson, &personalData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid personal data: %v", err)
+	}
+
+	if personalData.Name == "" {
+		return nil, fmt.Errorf("personal data name cannot be empty")
+	}
+
+	hash := sha256.Sum256(personalDataJson)
+	attachment := PersonalDataAttachment{
+		NodeId:           iNodeId,
+		PersonalDataHash: hex.EncodeToString(hash[:]),
+	}
+	err = graph.VerifyPayload(material.OwnerPublicKey, &attachment, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(personalDataObjectType, []string{iNodeId})
+	if err != nil {
+		return nil, err
+	}
+
+	err = iCtx.GetStub().PutPrivateData(personalDataCollection, key, personalDataJson)
+	if err != nil {
+		return nil, err
+	}
+
+	/// the detail is public, so it must not contain any personal data
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putAuditEntry(iCtx, iNodeId, ePersonalDataSet, ""))
+}
+
+/// only members of the personal data collection can read it
+func (c *MaterialContract) GetPersonalData(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) (*PersonalData, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(personalDataObjectType, []string{iNodeId})
+	if err != nil {
+		return nil, err
+	}
+
+	personalDataJson, err := iCtx.GetStub().GetPrivateData(personalDataCollection, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if personalDataJson == nil {
+		return nil, fmt.Errorf("no personal data for %s", iNodeId)
+	}
+
+	var personalData PersonalData
+	err = json.Unmarshal(personalDataJson, &personalData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &personalData, nil
+}
+
+/// deletes the personal data of iNodeId from the collection, only its hash remains on the ledger. Peers keep
+/// the previous writes in their private data store until they expire according to the blockToLive of the
+/// collection. iSignature is the administrator's signature of the PersonalDataPurge
+func (c *MaterialContract) PurgePersonalData(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	purge := PersonalDataPurge{
+		NodeId: iNodeId,
+	}
+	err := verifyAdministratorSignature(iCtx, &purge, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(personalDataObjectType, []string{iNodeId})
+	if err != nil {
+		return nil, err
+	}
+
+	/// the hash can be read by peers which are not members of the collection
+	hash, err := iCtx.GetStub().GetPrivateDataHash(personalDataCollection, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if hash == nil {
+		return nil, fmt.Errorf("no personal data for %s", iNodeId)
+	}
+
+	err = iCtx.GetStub().DelPrivateData(personalDataCollection, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putAuditEntry(iCtx, iNodeId, ePersonalDataPurged, ""))
+}
@@ -0,0 +1,226 @@
+package asset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	priceObjectType     = "price"
+	priceHashObjectType = "priceHash"
+
+	/// prices are passed in the transient map so that they never appear in the transaction proposal
+	priceTransientKey = "price"
+
+	/// in bytes, so that the hash of a price cannot be brute-forced from its few possible amounts and currencies
+	minPriceSaltSize = 16
+)
+
+/// Commercial terms of a transfer, only stored in the seller org's implicit private data collection.
+/// The public state only holds its hash so that the buyer can check the terms it was given.
+/// Salt is a random hex string chosen by the seller, which gives it to the buyer along with the terms
+type TransferPrice struct {
+	NodeId    string `json:"NodeId"`
+	NewNodeId string `json:"NewNodeId"`
+	Amount    string `json:"Amount"`
+	Currency  string `json:"Currency"`
+	Salt      string `json:"Salt"`
+}
+
+func getImplicitCollection(
+	iCtx contractapi.TransactionContextInterface,
+) (string, error) {
+	mspId, err := iCtx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", err
+	}
+
+	return "_implicit_org_" + mspId, nil
+}
+
+/// the salt is generated by the seller since the endorsing peers would each generate a different one
+func checkPriceSalt(
+	iSalt string,
+) error {
+	salt, err := hex.DecodeString(iSalt)
+	if err != nil {
+		return fmt.Errorf("salt must be hex encoded: %v", err)
+	}
+
+	if len(salt) < minPriceSaltSize {
+		return fmt.Errorf("salt must be at least %d random bytes", minPriceSaltSize)
+	}
+
+	return nil
+}
+
+/// the hash covers the salt, which is part of iPrice
+func hashTransferPrice(
+	iPrice *TransferPrice,
+) (string, error) {
+	priceJson, err := json.Marshal(iPrice)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(priceJson)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+/// stores the price passed in the transient map, if any, for the transfer of iNodeId to iNewNodeId
+func putTransferPrice(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iNewNodeId string,
+) error {
+	transient, err := iCtx.GetStub().GetTransient()
+	if err != nil {
+		return err
+	}
+
+	priceJson, ok := transient[priceTransientKey]
+	if !ok {
+		return nil
+	}
+
+	var price TransferPrice
+	err = json.Unmarshal(priceJson, &price)
+	if err != nil {
+		return fmt.Errorf("price must be a json object with Amount, Currency and Salt: %v", err)
+	}
+
+	err = checkPriceSalt(price.Salt)
+	if err != nil {
+		return err
+	}
+
+	amount, err := decimal.NewFromString(price.Amount)
+	if err != nil {
+		return err
+	}
+
+	if amount.IsNegative() {
+		return fmt.Errorf("price cannot be negative")
+	}
+
+	price = TransferPrice{
+		NodeId:    iNodeId,
+		NewNodeId: iNewNodeId,
+		Amount:    amount.String(),
+		Currency:  price.Currency,
+		Salt:      price.Salt,
+	}
+	priceJson, err = json.Marshal(price)
+	if err != nil {
+		return err
+	}
+
+	collection, err := getImplicitCollection(iCtx)
+	if err != nil {
+		return err
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(priceObjectType, []string{iNodeId, iNewNodeId})
+	if err != nil {
+		return err
+	}
+
+	err = iCtx.GetStub().PutPrivateData(collection, key, priceJson)
+	if err != nil {
+		return err
+	}
+
+	priceHash, err := hashTransferPrice(&price)
+	if err != nil {
+		return err
+	}
+
+	hashKey, err := iCtx.GetStub().CreateCompositeKey(priceHashObjectType, []string{iNodeId, iNewNodeId})
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().PutState(hashKey, []byte(priceHash))
+}
+
+/// only succeeds on the peers of the seller's org, the price includes the salt to give to the buyer
+func (c *MaterialContract) GetTransferPrice(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iNewNodeId string,
+) (*TransferPrice, error) {
+	collection, err := getImplicitCollection(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(priceObjectType, []string{iNodeId, iNewNodeId})
+	if err != nil {
+		return nil, err
+	}
+
+	priceJson, err := iCtx.GetStub().GetPrivateData(collection, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if priceJson == nil {
+		return nil, fmt.Errorf("no price for the transfer of %s to %s", iNodeId, iNewNodeId)
+	}
+
+	var price TransferPrice
+	err = json.Unmarshal(priceJson, &price)
+	if err != nil {
+		return nil, err
+	}
+
+	return &price, nil
+}
+
+/// checks iAmount and iCurrency against the hash of the price stored by the seller, iSalt is the salt the seller
+/// gave with the terms
+func (c *MaterialContract) VerifyTransferPrice(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iNewNodeId string,
+	iAmount string,
+	iCurrency string,
+	iSalt string,
+) (bool, error) {
+	hashKey, err := iCtx.GetStub().CreateCompositeKey(priceHashObjectType, []string{iNodeId, iNewNodeId})
+	if err != nil {
+		return false, err
+	}
+
+	storedHash, err := iCtx.GetStub().GetState(hashKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if storedHash == nil {
+		return false, fmt.Errorf("no price for the transfer of %s to %s", iNodeId, iNewNodeId)
+	}
+
+	amount, err := decimal.NewFromString(iAmount)
+	if err != nil {
+		return false, err
+	}
+
+	priceHash, err := hashTransferPrice(&TransferPrice{
+		NodeId:    iNodeId,
+		NewNodeId: iNewNodeId,
+		Amount:    amount.String(),
+		Currency:  iCurrency,
+		Salt:      iSalt,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return priceHash == string(storedHash), nil
+}
@@ -0,0 +1,83 @@
+package asset_test
+
+import (
+	"encoding/json"
+	"sig_chain/chaincode/asset"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func (l *testLedger) verifyTransferPrice(
+	iAmount string,
+	iCurrency string,
+	iSalt string,
+) bool {
+	l.t.Helper()
+	isValid := false
+	l.mustSubmit("verify price", func(iCtx contractapi.TransactionContextInterface) error {
+		var err error
+		isValid, err = l.contract.VerifyTransferPrice(iCtx, "m1", "m2", iAmount, iCurrency, iSalt)
+		return err
+	})
+
+	return isValid
+}
+
+/// the public hash of a price can only be checked with the salt the seller gives to the buyer
+func TestSaltedTransferPrice(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	bob := makeTestSigner(t)
+	l.createMaterial("m1", "10", alice)
+
+	signature := signTransfer(t, alice, *l.getMaterial("m1"), "m2", bob.GetPublicKey())
+	newMaterial := makeTransferredMaterial(*l.getMaterial("m1"), "m2", bob.GetPublicKey())
+	newNodeSignature := signNode(t, bob, &newMaterial)
+	transfer := func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.TransferMaterial(iCtx, "m1", "m2", bob.GetPublicKey(), "Org1MSP", signature, newNodeSignature, testTime)
+		return err
+	}
+
+	salt := strings.Repeat("5a", 16)
+	for _, priceSalt := range []string{"", "5a5a", "not hex"} {
+		priceJson, err := json.Marshal(asset.TransferPrice{Amount: "12.50", Currency: "EUR", Salt: priceSalt})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		l.transient = map[string][]byte{"price": priceJson}
+		l.mustFail("transfer with the salt "+priceSalt, transfer)
+	}
+
+	priceJson, err := json.Marshal(asset.TransferPrice{Amount: "12.50", Currency: "EUR", Salt: salt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.transient = map[string][]byte{"price": priceJson}
+	l.mustSubmit("transfer", transfer)
+	l.transient = nil
+
+	l.mustSubmit("get price", func(iCtx contractapi.TransactionContextInterface) error {
+		price, err := l.contract.GetTransferPrice(iCtx, "m1", "m2")
+		if err != nil {
+			return err
+		}
+
+		if price.Salt != salt || price.Amount != "12.5" {
+			t.Fatalf("stored price is %+v", price)
+		}
+		return nil
+	})
+
+	if !l.verifyTransferPrice("12.5", "EUR", salt) {
+		t.Fatal("price does not verify with its salt")
+	}
+	if l.verifyTransferPrice("12.5", "EUR", "") {
+		t.Fatal("price verifies without its salt")
+	}
+	if l.verifyTransferPrice("13", "EUR", salt) {
+		t.Fatal("another amount verifies")
+	}
+}
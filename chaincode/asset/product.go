@@ -0,0 +1,173 @@
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const productObjectType = "product"
+
+/// Template shared by every lot of the same SKU, registered by the administrator.
+/// Materials referencing the template must have its name and unit and carry every required attribute.
+/// AllowedGrades are ordered from best to worst, materials must be graded if it is not empty.
+/// Materials can only be transferred if they hold a valid certificate of every RequiredCertifications type
+type ProductDefinition struct {
+	TemplateId             string   `json:"TemplateId"`
+	Name                   string   `json:"Name"`
+	Unit                   string   `json:"Unit"`
+	AllowedCertifications  []string `json:"AllowedCertifications"`
+	RequiredCertifications []string `json:"RequiredCertifications"`
+	RequiredAttributes     []string `json:"RequiredAttributes"`
+	AllowedGrades          []string `json:"AllowedGrades"`
+	Signature              string   `json:"Signature"`
+}
+
+type ProductContract struct {
+	contractapi.Contract
+}
+
+/// returns nil if iTemplateId is not registered
+func getProductDefinition(
+	iCtx contractapi.TransactionContextInterface,
+	iTemplateId string,
+) (*ProductDefinition, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(productObjectType, []string{iTemplateId})
+	if err != nil {
+		return nil, err
+	}
+
+	productJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if productJson == nil {
+		return nil, nil
+	}
+
+	var product ProductDefinition
+	err = json.Unmarshal(productJson, &product)
+	if err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+/// checks that iMaterial matches the template it references, if any
+func checkProductDefinition(
+	iCtx contractapi.TransactionContextInterface,
+	iMaterial *Material,
+) error {
+	if iMaterial.TemplateId == "" {
+		return nil
+	}
+
+	product, err := getProductDefinition(iCtx, iMaterial.TemplateId)
+	if err != nil {
+		return err
+	}
+
+	if product == nil {
+		return fmt.Errorf("product template %s does not exist", iMaterial.TemplateId)
+	}
+
+	if iMaterial.Name != product.Name {
+		return fmt.Errorf("material name must be %s", product.Name)
+	}
+
+	if iMaterial.Unit != product.Unit {
+		return fmt.Errorf("material unit must be %s", product.Unit)
+	}
+
+	for _, attribute := range product.RequiredAttributes {
+		if iMaterial.Attributes[attribute] == "" {
+			return fmt.Errorf("missing required attribute %s", attribute)
+		}
+	}
+
+	if len(product.AllowedGrades) > 0 {
+		_, err = getGradeRank(product, iMaterial.Grade)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/// iSignature is the administrator's signature of the product definition
+func (c *ProductContract) CreateProductDefinition(
+	iCtx contractapi.TransactionContextInterface,
+	iTemplateId string,
+	iName string,
+	iUnit string,
+	iAllowedCertifications []string,
+	iRequiredCertifications []string,
+	iRequiredAttributes []string,
+	iAllowedGrades []string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	if iTemplateId == "" {
+		return nil, fmt.Errorf("template id cannot be empty")
+	}
+
+	if iName == "" || iUnit == "" {
+		return nil, fmt.Errorf("name and unit cannot be empty")
+	}
+
+	existing, err := getProductDefinition(iCtx, iTemplateId)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		return nil, fmt.Errorf("product template %s already exists", iTemplateId)
+	}
+
+	product := ProductDefinition{
+		TemplateId:             iTemplateId,
+		Name:                   iName,
+		Unit:                   iUnit,
+		AllowedCertifications:  iAllowedCertifications,
+		RequiredCertifications: iRequiredCertifications,
+		RequiredAttributes:     iRequiredAttributes,
+		AllowedGrades:          iAllowedGrades,
+	}
+	err = verifyAdministratorSignature(iCtx, &product, iSignature)
+	if err != nil {
+		return nil, err
+	}
+	product.Signature = iSignature
+
+	productJson, err := json.Marshal(product)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(productObjectType, []string{iTemplateId})
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, iCtx.GetStub().PutState(key, productJson))
+}
+
+func (c *ProductContract) GetProductDefinition(
+	iCtx contractapi.TransactionContextInterface,
+	iTemplateId string,
+) (*ProductDefinition, error) {
+	product, err := getProductDefinition(iCtx, iTemplateId)
+	if err != nil {
+		return nil, err
+	}
+
+	if product == nil {
+		return nil, fmt.Errorf("product template %s does not exist", iTemplateId)
+	}
+
+	return product, nil
+}
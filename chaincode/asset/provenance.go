@@ -0,0 +1,133 @@
+package asset
+
+import (
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// A material along with everything attached to it and the provenance of the materials it was made from
+type ProvenanceNode struct {
+	Material                      Material            `json:"Material"`
+	Certificates                  []Certificate       `json:"Certificates"`
+	CertificateStatuses           []CertificateStatus `json:"CertificateStatuses"`           /// at the time of the query, in the same order as Certificates
+	CertificateStatusesAtCreation []CertificateStatus `json:"CertificateStatusesAtCreation"` /// at the creation of the material, tells apart materials certified before a revocation
+	QualityRecords                []QualityRecord     `json:"QualityRecords"`
+	CustodyEvents                 []CustodyEvent      `json:"CustodyEvents"`
+	SensorReadings                []SensorReading     `json:"SensorReadings"`
+	Documents                     []MaterialDocument  `json:"Documents"`
+	Attestations                  []Attestation       `json:"Attestations"` /// of the material and of the facilities it went through
+	Inputs                        []ProvenanceNode    `json:"Inputs"`
+}
+
+/// iVisited holds the nodes already built so that shared ancestors are only read once
+func (c *MaterialContract) getProvenanceNode(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iVisited map[string]*ProvenanceNode,
+) (*ProvenanceNode, error) {
+	if node, ok := iVisited[iNodeId]; ok {
+		return node, nil
+	}
+
+	material, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	certificates, err := getCertificates(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	certificateStatuses := []CertificateStatus{}
+	certificateStatusesAtCreation := []CertificateStatus{}
+	for i := range certificates {
+		certificateStatuses = append(certificateStatuses, getCertificateStatus(iCtx, &certificates[i], transactionTime))
+		certificateStatusesAtCreation = append(certificateStatusesAtCreation, getCertificateStatus(iCtx, &certificates[i], material.CreatedTime))
+	}
+
+	qualityRecords, err := getQualityRecords(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	custodyEvents, err := getCustodyEvents(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	sensorReadings, err := getSensorReadings(iCtx, iNodeId, "")
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := getDocuments(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	attestations, err := getAttestations(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	visitedGlns := map[string]bool{}
+	for _, custodyEvent := range custodyEvents {
+		if custodyEvent.LocationGln == "" || visitedGlns[custodyEvent.LocationGln] {
+			continue
+		}
+		visitedGlns[custodyEvent.LocationGln] = true
+
+		facilityAttestations, err := getAttestations(iCtx, custodyEvent.LocationGln)
+		if err != nil {
+			return nil, err
+		}
+		attestations = append(attestations, facilityAttestations...)
+	}
+
+	node := &ProvenanceNode{
+		Material:                      *material,
+		Certificates:                  certificates,
+		CertificateStatuses:           certificateStatuses,
+		CertificateStatusesAtCreation: certificateStatusesAtCreation,
+		QualityRecords:                qualityRecords,
+		CustodyEvents:                 custodyEvents,
+		SensorReadings:                sensorReadings,
+		Documents:                     documents,
+		Attestations:                  attestations,
+		Inputs:                        []ProvenanceNode{},
+	}
+	iVisited[iNodeId] = node
+
+	graphContract := graph.GraphContract{}
+	previousIds, err := graphContract.GetPreviousNodeIds(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, previousId := range previousIds {
+		input, err := c.getProvenanceNode(iCtx, previousId, iVisited)
+		if err != nil {
+			return nil, err
+		}
+
+		node.Inputs = append(node.Inputs, *input)
+	}
+
+	return node, nil
+}
+
+/// returns the whole tree of materials iNodeId comes from, with the data attached to each of them,
+/// so that a provenance page can be rendered in one call
+func (c *MaterialContract) GetFullProvenance(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) (*ProvenanceNode, error) {
+	return c.getProvenanceNode(iCtx, iNodeId, map[string]*ProvenanceNode{})
+}
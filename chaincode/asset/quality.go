@@ -0,0 +1,145 @@
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const qualityRecordObjectType = "qualityRecord"
+
+/// eData node owned by the lab which produced the readings.
+/// The record points back to the material through its previous node hashes, the material itself is
+/// left untouched since it is signed by its owner and not by the lab
+type QualityRecord struct {
+	graph.NodeHeader
+	MaterialId string            `json:"MaterialId"`
+	Metrics    map[string]string `json:"Metrics"` /// e.g. "moisture" -> "12.5%"
+}
+
+func (d *QualityRecord) GetHeader() graph.NodeHeader {
+	return d.NodeHeader
+}
+func (d *QualityRecord) SetHeader(iHeader graph.NodeHeader) {
+	d.NodeHeader = iHeader
+}
+
+func getQualityRecords(
+	iCtx contractapi.TransactionContextInterface,
+	iMaterialId string,
+) ([]QualityRecord, error) {
+	recordIds, err := getIndexedIds(iCtx, qualityRecordObjectType, []string{iMaterialId})
+	if err != nil {
+		return nil, err
+	}
+
+	graphContract := graph.GraphContract{}
+	records := []QualityRecord{}
+	for _, recordId := range recordIds {
+		var record QualityRecord
+		err = graphContract.GetNode(iCtx, recordId, &record)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+/// iMetrics is a json object of readings, iSignature is the lab's signature of the record node
+func (c *MaterialContract) CreateQualityRecord(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iMaterialId string,
+	iMetrics string,
+	iLabPublicKey string,
+	iCreatedTime time.Time,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	var metrics map[string]string
+	err := json.Unmarshal([]byte(iMetrics), &metrics)
+	if err != nil {
+		return nil, fmt.Errorf("metrics must be a json object of strings: %v", err)
+	}
+
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("metrics cannot be empty")
+	}
+
+	createdTime, err := getOperationTime(iCtx, iCreatedTime)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.GetMaterial(iCtx, iMaterialId)
+	if err != nil {
+		return nil, err
+	}
+
+	graphContract := graph.GraphContract{}
+	nodeHeader := graph.MakeNodeHeader(
+		iNodeId,
+		eData,
+		true,
+		graph.MakeHashSet(graph.HashId(iMaterialId)),
+		graph.MakeHashSet(),
+		iLabPublicKey,
+		createdTime,
+		iSignature,
+	)
+
+	err = setTimeSource(iCtx, &nodeHeader)
+	if err != nil {
+		return nil, err
+	}
+	record := QualityRecord{
+		NodeHeader: nodeHeader,
+		MaterialId: iMaterialId,
+		Metrics:    metrics,
+	}
+
+	err = graphContract.CreateNode(iCtx, &record)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putIndex(iCtx, qualityRecordObjectType, []string{iMaterialId, iNodeId})
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putAuditEntry(iCtx, iMaterialId, eQualityRecorded, fmt.Sprintf("%s by %s", iNodeId, ownerFingerprint(iLabPublicKey))))
+}
+
+/// returns the quality records of iNodeId and of all of its ancestors,
+/// so that readings taken before a transfer, split or merge travel with the lot
+func (c *MaterialContract) GetMaterialQualityRecords(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) ([]QualityRecord, error) {
+	_, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	lineageIds, err := getLineageIds(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []QualityRecord{}
+	for _, nodeId := range lineageIds {
+		nodeRecords, err := getQualityRecords(iCtx, nodeId)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, nodeRecords...)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,250 @@
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	recallObjectType = "recall"
+
+	/// key of the authority which signs the recalls of materials it does not own, e.g. a food safety agency.
+	/// Set by a channel admin through SetConfig
+	recallAuthorityPublicKeyKey = "recallAuthorityPublicKey"
+)
+
+/// Signed by the recall authority, or by the owner of the recalled node
+type RecallRequest struct {
+	NodeId    string `json:"NodeId"`
+	Reason    string `json:"Reason"`
+	Signature string `json:"Signature"`
+}
+
+/// Stored for the recalled node and every one of its descendants. The recall is signed by SignerPublicKey and
+/// submitted by a Fabric identity with the recall role, RecallerId is its id as returned by cid
+type Recall struct {
+	RecalledNodeId  string    `json:"RecalledNodeId"`
+	Reason          string    `json:"Reason"`
+	RecallTime      time.Time `json:"RecallTime"`
+	TxId            string    `json:"TxId"`
+	SignerPublicKey string    `json:"SignerPublicKey"`
+	Signature       string    `json:"Signature"`
+	RecallerMspId   string    `json:"RecallerMspId"`
+	RecallerId      string    `json:"RecallerId"`
+}
+
+func putRecall(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iRecall *Recall,
+) error {
+	key, err := iCtx.GetStub().CreateCompositeKey(recallObjectType, []string{iNodeId, iRecall.RecalledNodeId})
+	if err != nil {
+		return err
+	}
+
+	recallJson, err := json.Marshal(iRecall)
+	if err != nil {
+		return err
+	}
+
+	err = iCtx.GetStub().PutState(key, recallJson)
+	if err != nil {
+		return err
+	}
+
+	return putAuditEntry(iCtx, iNodeId, eRecalled, fmt.Sprintf("%s %s", iRecall.RecalledNodeId, iRecall.Reason))
+}
+
+func getRecalls(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) ([]Recall, error) {
+	iterator, err := iCtx.GetStub().GetStateByPartialCompositeKey(recallObjectType, []string{iNodeId})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	recalls := []Recall{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var recall Recall
+		err = json.Unmarshal(kv.Value, &recall)
+		if err != nil {
+			return nil, err
+		}
+
+		recalls = append(recalls, recall)
+	}
+
+	return recalls, nil
+}
+
+/// outputs of a derivation inherit the recalls of its inputs
+func inheritRecalls(
+	iCtx contractapi.TransactionContextInterface,
+	iInputIds []string,
+	iOutputIds []string,
+) error {
+	for _, inputId := range iInputIds {
+		recalls, err := getRecalls(iCtx, inputId)
+		if err != nil {
+			return err
+		}
+
+		for _, recall := range recalls {
+			for _, outputId := range iOutputIds {
+				err = putRecall(iCtx, outputId, &recall)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+/// returns the key the RecallRequest of iNodeOwnerPublicKey's node is signed with, the recall authority
+/// recalls any node while owners only recall their own ones
+func verifyRecallSignature(
+	iCtx contractapi.TransactionContextInterface,
+	iRequest *RecallRequest,
+	iNodeOwnerPublicKey string,
+	iSignature string,
+) (string, error) {
+	authorityPublicKey, err := getConfigValue(iCtx, recallAuthorityPublicKeyKey)
+	if err != nil {
+		return "", err
+	}
+
+	if authorityPublicKey != nil && graph.VerifyPayload(string(authorityPublicKey), iRequest, iSignature) == nil {
+		return string(authorityPublicKey), nil
+	}
+
+	err = graph.VerifyPayload(iNodeOwnerPublicKey, iRequest, iSignature)
+	if err != nil {
+		return "", fmt.Errorf("recall is signed by neither the recall authority nor the owner of node %s", iRequest.NodeId)
+	}
+
+	return iNodeOwnerPublicKey, nil
+}
+
+/// iSignature is the signature of the RecallRequest by the recall authority or by the owner of the node. The
+/// transaction must be submitted by an identity with the role required for eRecallOperation, e.g. a regulator
+func (c *MaterialContract) RecallMaterial(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iReason string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	if iReason == "" {
+		return nil, fmt.Errorf("recall reason cannot be empty")
+	}
+
+	err := CheckSubmitterRole(iCtx, eRecallOperation)
+	if err != nil {
+		return nil, err
+	}
+
+	material, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	request := RecallRequest{
+		NodeId: iNodeId,
+		Reason: iReason,
+	}
+	signerPublicKey, err := verifyRecallSignature(iCtx, &request, material.OwnerPublicKey, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	recallerMspId, err := iCtx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, err
+	}
+
+	recallerId, err := iCtx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, err
+	}
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	recall := Recall{
+		RecalledNodeId:  iNodeId,
+		Reason:          iReason,
+		RecallTime:      transactionTime,
+		TxId:            iCtx.GetStub().GetTxID(),
+		SignerPublicKey: signerPublicKey,
+		Signature:       iSignature,
+		RecallerMspId:   recallerMspId,
+		RecallerId:      recallerId,
+	}
+
+	graphContract := graph.GraphContract{}
+	visited := map[string]bool{iNodeId: true}
+	queue := []string{iNodeId}
+	for len(queue) > 0 {
+		nodeId := queue[0]
+		queue = queue[1:]
+
+		err = putRecall(iCtx, nodeId, &recall)
+		if err != nil {
+			return nil, err
+		}
+
+		nextIds, err := graphContract.GetNextNodeIds(iCtx, nodeId)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, nextId := range nextIds {
+			if !visited[nextId] {
+				visited[nextId] = true
+				queue = append(queue, nextId)
+			}
+		}
+	}
+
+	return graph.MakeTransactionReceipt(iCtx)
+}
+
+/// returns the recalls affecting iNodeId, either directly or through one of its ancestors
+func (c *MaterialContract) GetMaterialRecalls(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) ([]Recall, error) {
+	_, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	return getRecalls(iCtx, iNodeId)
+}
+
+func (c *MaterialContract) IsMaterialRecalled(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) (bool, error) {
+	recalls, err := c.GetMaterialRecalls(iCtx, iNodeId)
+	if err != nil {
+		return false, err
+	}
+
+	return len(recalls) > 0, nil
+}
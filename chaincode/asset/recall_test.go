@@ -0,0 +1,88 @@
+package asset_test
+
+import (
+	"sig_chain/chaincode/asset"
+	"sig_chain/pkg/client"
+	"sig_chain/pkg/testutil"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func (l *testLedger) recallMaterial(
+	iNodeId string,
+	iSigner client.Signer,
+) error {
+	request := asset.RecallRequest{NodeId: iNodeId, Reason: "contaminated"}
+	signature := signPayload(l.t, iSigner, &request)
+	return l.submit(func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.RecallMaterial(iCtx, iNodeId, "contaminated", signature)
+		return err
+	})
+}
+
+func (l *testLedger) getRecallSigner(
+	iNodeId string,
+) string {
+	l.t.Helper()
+	var recalls []asset.Recall
+	l.mustSubmit("get recalls of "+iNodeId, func(iCtx contractapi.TransactionContextInterface) error {
+		var err error
+		recalls, err = l.contract.GetMaterialRecalls(iCtx, iNodeId)
+		return err
+	})
+
+	if len(recalls) != 1 {
+		l.t.Fatalf("%s has %d recalls", iNodeId, len(recalls))
+	}
+
+	return recalls[0].SignerPublicKey
+}
+
+func TestSignedRecall(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	authority := makeTestSigner(t)
+	l.bootstrap(makeTestSigner(t), asset.BootstrapConfig{})
+	l.createMaterial("m1", "10", alice)
+	l.createMaterial("m2", "10", alice)
+
+	err := l.recallMaterial("m1", alice)
+	if err == nil {
+		t.Fatal("recall submitted without the recall role succeeded")
+	}
+
+	/// a channel admin which is also a regulator, so that it configures the recall authority and submits recalls
+	l.identity, err = testutil.MakeMockIdentity("regulator", "Org1MSP", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.identity.Attributes["role"] = "regulator"
+
+	err = l.recallMaterial("m1", authority)
+	if err == nil {
+		t.Fatal("recall signed by an unknown key succeeded")
+	}
+
+	l.mustSubmit("set recall authority", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.SetConfig(iCtx, "recallAuthorityPublicKey", authority.GetPublicKey())
+		return err
+	})
+
+	err = l.recallMaterial("m1", authority)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.getRecallSigner("m1") != authority.GetPublicKey() {
+		t.Fatal("recall of m1 is not recorded as signed by the recall authority")
+	}
+
+	/// owners recall their own materials
+	err = l.recallMaterial("m2", alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.getRecallSigner("m2") != alice.GetPublicKey() {
+		t.Fatal("recall of m2 is not recorded as signed by its owner")
+	}
+}
@@ -0,0 +1,151 @@
+package asset
+
+import (
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// the renewal is issued by the same authority, for the same subject and type, and starts no later than
+/// the expiry of the renewed certificate so that the certification is continuous. Its scope and claims are kept
+/// and must still match the current ClaimSchema of the type. Co-issuers must co-sign the renewal again.
+/// iSignature is the issuer's signature of the new certificate node,
+/// iPreviousSignature is the issuer's signature of the renewed certificate once finalized and pointing to iNewNodeId
+func (c *CertificateContract) RenewCertificate(
+	iCtx contractapi.TransactionContextInterface,
+	iCertificateId string,
+	iNewNodeId string,
+	iIssueTime time.Time,
+	iExpiryTime time.Time,
+	iSignature string,
+	iPreviousSignature string,
+) (*graph.TransactionReceipt, error) {
+	previous, err := c.GetCertificate(iCtx, iCertificateId)
+	if err != nil {
+		return nil, err
+	}
+
+	if previous.IsFinalized {
+		return nil, fmt.Errorf("certificate %s is already renewed", iCertificateId)
+	}
+
+	if previous.Credential != "" {
+		return nil, fmt.Errorf("imported credentials are renewed by importing a new credential")
+	}
+
+	err = checkQueryTime("expiry time", iExpiryTime)
+	if err != nil {
+		return nil, err
+	}
+
+	if !iExpiryTime.After(iIssueTime) {
+		return nil, fmt.Errorf("expiry time must be after issue time")
+	}
+
+	if iIssueTime.After(previous.ExpiryTime) {
+		return nil, fmt.Errorf("renewal must start before the expiry of certificate %s", iCertificateId)
+	}
+
+	if !iExpiryTime.After(previous.ExpiryTime) {
+		return nil, fmt.Errorf("renewal must expire after certificate %s", iCertificateId)
+	}
+
+	revocation, err := getRevocation(iCtx, previous.IssuerId, iCertificateId)
+	if err != nil {
+		return nil, err
+	}
+
+	if revocation != nil {
+		return nil, fmt.Errorf("revoked certificates cannot be renewed")
+	}
+
+	issuer, err := c.GetCertificateAuthority(iCtx, previous.IssuerId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkClaims(iCtx, previous.IssuerId, previous.CertificateType, previous.Claims)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeHeader := graph.MakeNodeHeader(
+		iNewNodeId,
+		eCertificate,
+		false,
+		graph.MakeHashSet(graph.HashId(previous.IssuerId)),
+		graph.MakeHashSet(),
+		issuer.OwnerPublicKey,
+		transactionTime,
+		iSignature,
+	)
+	certificate := Certificate{
+		NodeHeader:            nodeHeader,
+		CertificateType:       previous.CertificateType,
+		SubjectId:             previous.SubjectId,
+		IssueTime:             iIssueTime,
+		ExpiryTime:            iExpiryTime,
+		IssuerId:              previous.IssuerId,
+		PreviousCertificateId: iCertificateId,
+		Scope:                 previous.Scope,
+		Claims:                previous.Claims,
+		CoIssuerIds:           previous.CoIssuerIds,
+	}
+
+	graphContract := graph.GraphContract{}
+	err = graphContract.CreateDerivedNodes(
+		iCtx,
+		[]string{iCertificateId},
+		[]graph.NodeI{&Certificate{}},
+		[]string{iPreviousSignature},
+		[]graph.NodeI{&certificate},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putCertification(iCtx, iNewNodeId, previous.SubjectId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putAuditEntry(iCtx, previous.SubjectId, eCertificateRenewed, fmt.Sprintf("%s renewed by %s", iCertificateId, iNewNodeId))
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, appendCertificateLog(iCtx, eLogRenewed, iNewNodeId, previous.IssuerId, iCertificateId))
+}
+
+/// returns iCertificateId followed by every certificate it renews, directly or not, most recent first
+func (c *CertificateContract) GetCertificateRenewals(
+	iCtx contractapi.TransactionContextInterface,
+	iCertificateId string,
+) ([]Certificate, error) {
+	certificates := []Certificate{}
+	visited := map[string]bool{}
+	certificateId := iCertificateId
+	for certificateId != "" {
+		if visited[certificateId] {
+			return nil, fmt.Errorf("renewals of %s have a cycle", iCertificateId)
+		}
+		visited[certificateId] = true
+
+		certificate, err := c.GetCertificate(iCtx, certificateId)
+		if err != nil {
+			return nil, err
+		}
+
+		certificates = append(certificates, *certificate)
+		certificateId = certificate.PreviousCertificateId
+	}
+
+	return certificates, nil
+}
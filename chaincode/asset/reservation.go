@@ -0,0 +1,359 @@
+package asset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	reservationObjectType         = "reservation"
+	releasedReservationObjectType = "releasedReservation"
+)
+
+/// Signed by the owner of the material
+type ReservationRequest struct {
+	NodeId               string    `json:"NodeId"`
+	Quantity             string    `json:"Quantity"`
+	BeneficiaryPublicKey string    `json:"BeneficiaryPublicKey"`
+	ExpiryTime           time.Time `json:"ExpiryTime"`
+	Signature            string    `json:"Signature"`
+}
+
+/// Id is the sha256 of the signed request, so that a request cannot be replayed. It is derived from the request
+/// rather than the signature since ecdsa signatures are malleable
+type Reservation struct {
+	ReservationRequest
+	Id string `json:"Id"`
+}
+
+/// Signed by either the owner of the material or the beneficiary of the reservation
+type ReleaseRequest struct {
+	NodeId        string `json:"NodeId"`
+	ReservationId string `json:"ReservationId"`
+	Signature     string `json:"Signature"`
+}
+
+/// quantity of a material handed over to an owner by an operation
+type allocation struct {
+	OwnerPublicKey string
+	Quantity       decimal.Decimal
+}
+
+func getReservations(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) ([]Reservation, error) {
+	iterator, err := iCtx.GetStub().GetStateByPartialCompositeKey(reservationObjectType, []string{iNodeId})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	reservations := []Reservation{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var reservation Reservation
+		err = json.Unmarshal(kv.Value, &reservation)
+		if err != nil {
+			return nil, err
+		}
+
+		reservations = append(reservations, reservation)
+	}
+
+	return reservations, nil
+}
+
+func getActiveReservations(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) ([]Reservation, error) {
+	reservations, err := getReservations(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	active := []Reservation{}
+	for _, reservation := range reservations {
+		if reservation.ExpiryTime.After(transactionTime) {
+			active = append(active, reservation)
+		}
+	}
+
+	return active, nil
+}
+
+func getReservedQuantity(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) (decimal.Decimal, error) {
+	reservations, err := getActiveReservations(iCtx, iNodeId)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	reserved := decimal.NewFromInt(0)
+	for _, reservation := range reservations {
+		quantity, err := decimal.NewFromString(reservation.Quantity)
+		if err != nil {
+			return decimal.Decimal{}, err
+		}
+		reserved = reserved.Add(quantity)
+	}
+
+	return reserved, nil
+}
+
+/// every beneficiary of an active reservation must be allocated at least its reserved quantity
+func checkReservations(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iAllocations []allocation,
+) error {
+	reservations, err := getActiveReservations(iCtx, iNodeId)
+	if err != nil {
+		return err
+	}
+
+	reserved := map[string]decimal.Decimal{}
+	for _, reservation := range reservations {
+		quantity, err := decimal.NewFromString(reservation.Quantity)
+		if err != nil {
+			return err
+		}
+		reserved[reservation.BeneficiaryPublicKey] = reserved[reservation.BeneficiaryPublicKey].Add(quantity)
+	}
+
+	for _, allocation := range iAllocations {
+		if quantity, ok := reserved[allocation.OwnerPublicKey]; ok {
+			reserved[allocation.OwnerPublicKey] = quantity.Sub(allocation.Quantity)
+		}
+	}
+
+	for _, quantity := range reserved {
+		if quantity.IsPositive() {
+			return fmt.Errorf("material %s is reserved", iNodeId)
+		}
+	}
+
+	return nil
+}
+
+/// iRequest must have its signature cleared
+func getReservationId(
+	iRequest *ReservationRequest,
+) (string, error) {
+	requestJson, err := json.Marshal(iRequest)
+	if err != nil {
+		return "", err
+	}
+
+	requestHash := sha256.Sum256(requestJson)
+	return hex.EncodeToString(requestHash[:]), nil
+}
+
+/// iSignature is the owner's signature of the ReservationRequest
+/// a released reservation cannot be made again with the same request, the owner signs a new expiry instead.
+/// The id of the reservation is in the written keys of the receipt
+func (c *MaterialContract) ReserveMaterial(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iQuantity string,
+	iBeneficiaryPublicKey string,
+	iExpiryTime time.Time,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	quantity, err := decimal.NewFromString(iQuantity)
+	if err != nil {
+		return nil, err
+	}
+
+	if !quantity.IsPositive() {
+		return nil, fmt.Errorf("reserved quantity must be positive")
+	}
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !iExpiryTime.After(transactionTime) {
+		return nil, fmt.Errorf("reservation expiry must be in the future")
+	}
+
+	material, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	if material.IsFinalized {
+		return nil, fmt.Errorf("node is already finalized")
+	}
+
+	err = checkNoPendingOffer(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	request := ReservationRequest{
+		NodeId:               iNodeId,
+		Quantity:             quantity.String(),
+		BeneficiaryPublicKey: iBeneficiaryPublicKey,
+		ExpiryTime:           iExpiryTime,
+	}
+	err = graph.VerifyPayload(material.OwnerPublicKey, &request, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	reservationId, err := getReservationId(&request)
+	if err != nil {
+		return nil, err
+	}
+	request.Signature = iSignature
+
+	releasedKey, err := iCtx.GetStub().CreateCompositeKey(releasedReservationObjectType, []string{iNodeId, reservationId})
+	if err != nil {
+		return nil, err
+	}
+
+	released, err := iCtx.GetStub().GetState(releasedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if released != nil {
+		return nil, fmt.Errorf("reservation %s was released", reservationId)
+	}
+
+	available, err := getEffectiveQuantity(iCtx, material)
+	if err != nil {
+		return nil, err
+	}
+
+	reserved, err := getReservedQuantity(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	if reserved.Add(quantity).GreaterThan(available) {
+		return nil, fmt.Errorf("insufficient quantity available")
+	}
+
+	reservation := Reservation{
+		ReservationRequest: request,
+		Id:                 reservationId,
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(reservationObjectType, []string{iNodeId, reservation.Id})
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if existing != nil {
+		return nil, fmt.Errorf("reservation already exists")
+	}
+
+	reservationJson, err := json.Marshal(reservation)
+	if err != nil {
+		return nil, err
+	}
+
+	err = iCtx.GetStub().PutState(key, reservationJson)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putAuditEntry(iCtx, iNodeId, eReserved, fmt.Sprintf("%s %s for %s", reservation.Id, reservation.Quantity, ownerFingerprint(iBeneficiaryPublicKey))))
+}
+
+/// iSignature is the signature of the ReleaseRequest by either the owner or the beneficiary
+func (c *MaterialContract) ReleaseReservation(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iReservationId string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	material, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(reservationObjectType, []string{iNodeId, iReservationId})
+	if err != nil {
+		return nil, err
+	}
+
+	reservationJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if reservationJson == nil {
+		return nil, fmt.Errorf("reservation %s does not exist", iReservationId)
+	}
+
+	var reservation Reservation
+	err = json.Unmarshal(reservationJson, &reservation)
+	if err != nil {
+		return nil, err
+	}
+
+	request := ReleaseRequest{
+		NodeId:        iNodeId,
+		ReservationId: iReservationId,
+	}
+	err = graph.VerifyPayload(material.OwnerPublicKey, &request, iSignature)
+	if err != nil {
+		err = graph.VerifyPayload(reservation.BeneficiaryPublicKey, &request, iSignature)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = iCtx.GetStub().DelState(key)
+	if err != nil {
+		return nil, err
+	}
+
+	/// the signed ReservationRequest is public, resubmitting it must not reserve the quantity again
+	err = putIndex(iCtx, releasedReservationObjectType, []string{iNodeId, iReservationId})
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putAuditEntry(iCtx, iNodeId, eReservationReleased, iReservationId))
+}
+
+func (c *MaterialContract) GetMaterialReservations(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) ([]Reservation, error) {
+	_, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	return getActiveReservations(iCtx, iNodeId)
+}
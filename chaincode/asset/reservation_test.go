@@ -0,0 +1,107 @@
+package asset_test
+
+import (
+	"sig_chain/chaincode/asset"
+	"sig_chain/pkg/client"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func (l *testLedger) getReservations(
+	iNodeId string,
+) []asset.Reservation {
+	l.t.Helper()
+	var reservations []asset.Reservation
+	l.mustSubmit("get reservations of "+iNodeId, func(iCtx contractapi.TransactionContextInterface) error {
+		var err error
+		reservations, err = l.contract.GetMaterialReservations(iCtx, iNodeId)
+		return err
+	})
+
+	return reservations
+}
+
+func signAdjustment(
+	t *testing.T,
+	iOwner client.Signer,
+	iNodeId string,
+	iSequence int,
+	iDelta string,
+	iReasonCode string,
+) string {
+	return signPayload(t, iOwner, &asset.AdjustmentRequest{
+		NodeId:     iNodeId,
+		Sequence:   iSequence,
+		Delta:      iDelta,
+		ReasonCode: iReasonCode,
+	})
+}
+
+func TestReservationAndRelease(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	bob := makeTestSigner(t)
+	eve := makeTestSigner(t)
+	l.createMaterial("m1", "10", alice)
+
+	expiryTime := testTime.Add(24 * time.Hour)
+	request := asset.ReservationRequest{
+		NodeId:               "m1",
+		Quantity:             "6",
+		BeneficiaryPublicKey: bob.GetPublicKey(),
+		ExpiryTime:           expiryTime,
+	}
+	signature := signPayload(t, alice, &request)
+
+	l.mustFail("reserve signed by the beneficiary", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.ReserveMaterial(iCtx, "m1", "6", bob.GetPublicKey(), expiryTime, signPayload(t, bob, &request))
+		return err
+	})
+
+	l.mustSubmit("reserve", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.ReserveMaterial(iCtx, "m1", "6", bob.GetPublicKey(), expiryTime, signature)
+		return err
+	})
+
+	l.mustFail("replay reservation", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.ReserveMaterial(iCtx, "m1", "6", bob.GetPublicKey(), expiryTime, signature)
+		return err
+	})
+
+	l.mustFail("adjust below the reserved quantity", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AdjustMaterialQuantity(iCtx, "m1", "-5", "eShrinkage", signAdjustment(t, alice, "m1", 0, "-5", "eShrinkage"))
+		return err
+	})
+
+	reservations := l.getReservations("m1")
+	if len(reservations) != 1 {
+		t.Fatalf("expected 1 reservation, got %d", len(reservations))
+	}
+	release := asset.ReleaseRequest{NodeId: "m1", ReservationId: reservations[0].Id}
+
+	l.mustFail("release signed by a stranger", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.ReleaseReservation(iCtx, "m1", release.ReservationId, signPayload(t, eve, &release))
+		return err
+	})
+
+	l.mustSubmit("release by the beneficiary", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.ReleaseReservation(iCtx, "m1", release.ReservationId, signPayload(t, bob, &release))
+		return err
+	})
+
+	if len(l.getReservations("m1")) != 0 {
+		t.Fatal("released reservation is still active")
+	}
+
+	l.mustFail("replay released reservation", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.ReserveMaterial(iCtx, "m1", "6", bob.GetPublicKey(), expiryTime, signature)
+		return err
+	})
+
+	l.mustSubmit("adjust once released", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AdjustMaterialQuantity(iCtx, "m1", "-5", "eShrinkage", signAdjustment(t, alice, "m1", 0, "-5", "eShrinkage"))
+		return err
+	})
+}
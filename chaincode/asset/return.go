@@ -0,0 +1,297 @@
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+type ReturnReason = string
+
+const (
+	eDamaged            ReturnReason = "eDamaged"
+	eWrongSpecification ReturnReason = "eWrongSpecification"
+	eQuantityMismatch   ReturnReason = "eQuantityMismatch"
+	eOtherReason        ReturnReason = "eOtherReason"
+)
+
+const (
+	rejectionObjectType = "rejection"
+	returnObjectType    = "return"
+)
+
+/// Signed by the receiver of the offer
+type RejectTransferRequest struct {
+	NodeId     string       `json:"NodeId"`
+	NewNodeId  string       `json:"NewNodeId"`
+	ReasonCode ReturnReason `json:"ReasonCode"`
+	Signature  string       `json:"Signature"`
+}
+
+type TransferRejection struct {
+	RejectTransferRequest
+	RejectedTime time.Time `json:"RejectedTime"`
+	TxId         string    `json:"TxId"`
+}
+
+/// Signed by the owner returning the material
+type ReturnRequest struct {
+	NodeId       string       `json:"NodeId"`
+	ReturnNodeId string       `json:"ReturnNodeId"`
+	ReasonCode   ReturnReason `json:"ReasonCode"`
+	Signature    string       `json:"Signature"`
+}
+
+type MaterialReturn struct {
+	ReturnRequest
+	ReturnTime time.Time `json:"ReturnTime"`
+	TxId       string    `json:"TxId"`
+}
+
+func isReturnReason(
+	iReasonCode string,
+) bool {
+	switch iReasonCode {
+	case eDamaged, eWrongSpecification, eQuantityMismatch, eOtherReason:
+		return true
+	default:
+		return false
+	}
+}
+
+/// iSignature is the receiver's signature of the RejectTransferRequest
+/// the sender has to offer again with a new node id, the rejected one can no longer be used
+func (c *MaterialContract) RejectTransfer(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iReasonCode string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	if !isReturnReason(iReasonCode) {
+		return nil, fmt.Errorf("unknown reason code %s", iReasonCode)
+	}
+
+	offer, err := c.GetTransferOffer(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	request := RejectTransferRequest{
+		NodeId:     iNodeId,
+		NewNodeId:  offer.NewNodeId,
+		ReasonCode: iReasonCode,
+	}
+	err = graph.VerifyPayload(offer.NewOwnerPublicKey, &request, iSignature)
+	if err != nil {
+		return nil, err
+	}
+	request.Signature = iSignature
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	rejection := TransferRejection{
+		RejectTransferRequest: request,
+		RejectedTime:          transactionTime,
+		TxId:                  iCtx.GetStub().GetTxID(),
+	}
+	rejectionJson, err := json.Marshal(rejection)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(rejectionObjectType, []string{iNodeId, offer.NewNodeId})
+	if err != nil {
+		return nil, err
+	}
+
+	err = iCtx.GetStub().PutState(key, rejectionJson)
+	if err != nil {
+		return nil, err
+	}
+
+	/// the sender's signature stays public in the rejected offer, transferMaterial refuses it for this new node id
+	/// whether it is passed to TransferMaterial, ReturnMaterial or Execute
+	err = putIndex(iCtx, cancelledOfferObjectType, []string{iNodeId, offer.NewNodeId})
+	if err != nil {
+		return nil, err
+	}
+
+	err = putAuditEntry(iCtx, iNodeId, eTransferRejected, fmt.Sprintf("%s %s", offer.NewNodeId, iReasonCode))
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, deleteTransferOffer(iCtx, iNodeId))
+}
+
+func (c *MaterialContract) GetTransferRejections(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) ([]TransferRejection, error) {
+	iterator, err := iCtx.GetStub().GetStateByPartialCompositeKey(rejectionObjectType, []string{iNodeId})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	rejections := []TransferRejection{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var rejection TransferRejection
+		err = json.Unmarshal(kv.Value, &rejection)
+		if err != nil {
+			return nil, err
+		}
+
+		rejections = append(rejections, rejection)
+	}
+
+	return rejections, nil
+}
+
+/// returns the material to the owner it was transferred from
+/// iSignature is the current owner's signature of the finalized node, as in TransferMaterial
+/// iReturnSignature is the current owner's signature of the ReturnRequest
+/// iReturnNodeSignature is the previous owner's signature of the returned node
+/// iPreviousOwnerMspId is required when the previous owner is a bare key, as in TransferMaterial
+func (c *MaterialContract) ReturnMaterial(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iReturnNodeId string,
+	iPreviousOwnerMspId string,
+	iReasonCode string,
+	iSignature string,
+	iReturnSignature string,
+	iReturnNodeSignature string,
+	iReturnTime time.Time,
+) (*graph.TransactionReceipt, error) {
+	if !isReturnReason(iReasonCode) {
+		return nil, fmt.Errorf("unknown reason code %s", iReasonCode)
+	}
+
+	returnTime, err := getOperationTime(iCtx, iReturnTime)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkNoPendingOffer(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	material, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	graphContract := graph.GraphContract{}
+	previousIds, err := graphContract.GetPreviousNodeIds(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(previousIds) != 1 {
+		return nil, fmt.Errorf("material %s was not transferred", iNodeId)
+	}
+
+	derivation, err := getDerivation(iCtx, previousIds[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if derivation == nil || derivation.Kind != eTransfer {
+		return nil, fmt.Errorf("material %s was not transferred", iNodeId)
+	}
+
+	previousMaterial, err := c.GetMaterial(iCtx, previousIds[0])
+	if err != nil {
+		return nil, err
+	}
+
+	request := ReturnRequest{
+		NodeId:       iNodeId,
+		ReturnNodeId: iReturnNodeId,
+		ReasonCode:   iReasonCode,
+	}
+	err = graph.VerifyPayload(material.OwnerPublicKey, &request, iReturnSignature)
+	if err != nil {
+		return nil, err
+	}
+	request.Signature = iReturnSignature
+
+	err = c.transferMaterial(
+		iCtx,
+		eReturn,
+		material,
+		iReturnNodeId,
+		previousMaterial.OwnerPublicKey,
+		iPreviousOwnerMspId,
+		iSignature,
+		iReturnNodeSignature,
+		returnTime,
+		"",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	materialReturn := MaterialReturn{
+		ReturnRequest: request,
+		ReturnTime:    transactionTime,
+		TxId:          iCtx.GetStub().GetTxID(),
+	}
+	returnJson, err := json.Marshal(materialReturn)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(returnObjectType, []string{iReturnNodeId})
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, iCtx.GetStub().PutState(key, returnJson))
+}
+
+/// iReturnNodeId is the id of the node created by ReturnMaterial
+func (c *MaterialContract) GetMaterialReturn(
+	iCtx contractapi.TransactionContextInterface,
+	iReturnNodeId string,
+) (*MaterialReturn, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(returnObjectType, []string{iReturnNodeId})
+	if err != nil {
+		return nil, err
+	}
+
+	returnJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if returnJson == nil {
+		return nil, fmt.Errorf("node %s is not a returned material", iReturnNodeId)
+	}
+
+	var materialReturn MaterialReturn
+	err = json.Unmarshal(returnJson, &materialReturn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &materialReturn, nil
+}
@@ -0,0 +1,282 @@
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+type RevocationReason = string
+
+const (
+	eKeyCompromise     RevocationReason = "eKeyCompromise"
+	eNonCompliance     RevocationReason = "eNonCompliance"
+	eSuperseded        RevocationReason = "eSuperseded"
+	eCessation         RevocationReason = "eCessation"
+	eIssuedInError     RevocationReason = "eIssuedInError"
+	eUnspecifiedReason RevocationReason = "eUnspecifiedReason"
+)
+
+const revocationObjectType = "revocation"
+
+/// Signed by the authority, RevokedId is either a certificate it issued or one of its child authorities.
+/// EffectiveTime may be in the past, e.g. when a non-compliance is found after the fact,
+/// materials certified before it stay certified
+type RevocationRequest struct {
+	AuthorityId   string           `json:"AuthorityId"`
+	RevokedId     string           `json:"RevokedId"`
+	ReasonCode    RevocationReason `json:"ReasonCode"`
+	EffectiveTime time.Time        `json:"EffectiveTime"`
+	Signature     string           `json:"Signature"`
+}
+
+type Revocation struct {
+	RevocationRequest
+	RevocationTime time.Time `json:"RevocationTime"`
+	TxId           string    `json:"TxId"`
+}
+
+func isRevocationReason(
+	iReasonCode string,
+) bool {
+	switch iReasonCode {
+	case eKeyCompromise, eNonCompliance, eSuperseded, eCessation, eIssuedInError, eUnspecifiedReason:
+		return true
+	default:
+		return false
+	}
+}
+
+/// returns nil if iRevokedId is not revoked by iAuthorityId
+func getRevocation(
+	iCtx contractapi.TransactionContextInterface,
+	iAuthorityId string,
+	iRevokedId string,
+) (*Revocation, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(revocationObjectType, []string{iAuthorityId, iRevokedId})
+	if err != nil {
+		return nil, err
+	}
+
+	revocationJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if revocationJson == nil {
+		return nil, nil
+	}
+
+	var revocation Revocation
+	err = json.Unmarshal(revocationJson, &revocation)
+	if err != nil {
+		return nil, err
+	}
+
+	return &revocation, nil
+}
+
+/// returns true if iRevokedId is revoked by iAuthorityId and the revocation is effective at iTime
+func isRevoked(
+	iCtx contractapi.TransactionContextInterface,
+	iAuthorityId string,
+	iRevokedId string,
+	iTime time.Time,
+) (bool, error) {
+	revocation, err := getRevocation(iCtx, iAuthorityId, iRevokedId)
+	if err != nil {
+		return false, err
+	}
+
+	return revocation != nil && !iTime.Before(revocation.EffectiveTime), nil
+}
+
+/// iAuthority must be the issuer of iRevokedId, iSignature is its signature of the RevocationRequest.
+/// A zero iEffectiveTime makes the revocation effective at the transaction time
+func putRevocation(
+	iCtx contractapi.TransactionContextInterface,
+	iAuthority *CertificateAuthority,
+	iRevokedId string,
+	iReasonCode string,
+	iEffectiveTime time.Time,
+	iSignature string,
+) error {
+	if !isRevocationReason(iReasonCode) {
+		return fmt.Errorf("unknown reason code %s", iReasonCode)
+	}
+
+	existing, err := getRevocation(iCtx, iAuthority.Id, iRevokedId)
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		return fmt.Errorf("%s is already revoked", iRevokedId)
+	}
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return err
+	}
+
+	effectiveTime := iEffectiveTime
+	if effectiveTime.IsZero() {
+		effectiveTime = transactionTime
+	}
+
+	request := RevocationRequest{
+		AuthorityId:   iAuthority.Id,
+		RevokedId:     iRevokedId,
+		ReasonCode:    iReasonCode,
+		EffectiveTime: iEffectiveTime,
+	}
+	err = graph.VerifyPayload(iAuthority.OwnerPublicKey, &request, iSignature)
+	if err != nil {
+		return err
+	}
+	request.Signature = iSignature
+	request.EffectiveTime = effectiveTime
+
+	revocation := Revocation{
+		RevocationRequest: request,
+		RevocationTime:    transactionTime,
+		TxId:              iCtx.GetStub().GetTxID(),
+	}
+	revocationJson, err := json.Marshal(revocation)
+	if err != nil {
+		return err
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(revocationObjectType, []string{iAuthority.Id, iRevokedId})
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().PutState(key, revocationJson)
+}
+
+/// iSignature is the issuer's signature of the RevocationRequest, see putRevocation for iEffectiveTime
+func (c *CertificateContract) RevokeCertificate(
+	iCtx contractapi.TransactionContextInterface,
+	iCertificateId string,
+	iReasonCode string,
+	iEffectiveTime time.Time,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	certificate, err := c.GetCertificate(iCtx, iCertificateId)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer, err := c.GetCertificateAuthority(iCtx, certificate.IssuerId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putRevocation(iCtx, issuer, iCertificateId, iReasonCode, iEffectiveTime, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putAuditEntry(iCtx, certificate.SubjectId, eCertificateRevoked, fmt.Sprintf("%s %s", iCertificateId, iReasonCode))
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, appendCertificateLog(iCtx, eLogRevoked, iCertificateId, certificate.IssuerId, iReasonCode))
+}
+
+/// iSignature is the parent's signature of the RevocationRequest,
+/// every certificate issued under the revoked authority becomes invalid
+func (c *CertificateContract) RevokeCertificateAuthority(
+	iCtx contractapi.TransactionContextInterface,
+	iAuthorityId string,
+	iReasonCode string,
+	iEffectiveTime time.Time,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	authority, err := c.GetCertificateAuthority(iCtx, iAuthorityId)
+	if err != nil {
+		return nil, err
+	}
+
+	if authority.ParentId == "" {
+		return nil, fmt.Errorf("root certificate authorities cannot be revoked")
+	}
+
+	parent, err := c.GetCertificateAuthority(iCtx, authority.ParentId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putRevocation(iCtx, parent, iAuthorityId, iReasonCode, iEffectiveTime, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, appendCertificateLog(iCtx, eLogAuthorityRevoked, iAuthorityId, authority.ParentId, iReasonCode))
+}
+
+/// returns the revocations made by iAuthorityId at or after iSince
+func (c *CertificateContract) GetRevocations(
+	iCtx contractapi.TransactionContextInterface,
+	iAuthorityId string,
+	iSince time.Time,
+) ([]Revocation, error) {
+	iterator, err := iCtx.GetStub().GetStateByPartialCompositeKey(revocationObjectType, []string{iAuthorityId})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	revocations := []Revocation{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var revocation Revocation
+		err = json.Unmarshal(kv.Value, &revocation)
+		if err != nil {
+			return nil, err
+		}
+
+		if !revocation.RevocationTime.Before(iSince) {
+			revocations = append(revocations, revocation)
+		}
+	}
+
+	return revocations, nil
+}
+
+/// iNodeId is either a certificate, checked against its issuer, or an authority, checked against its parent.
+/// Revocations which are not effective yet are ignored
+func (c *CertificateContract) IsRevoked(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) (bool, error) {
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return false, err
+	}
+
+	authority, err := c.GetCertificateAuthority(iCtx, iNodeId)
+	if err == nil {
+		if authority.ParentId == "" {
+			return false, nil
+		}
+
+		return isRevoked(iCtx, authority.ParentId, iNodeId, transactionTime)
+	}
+
+	certificate, err := c.GetCertificate(iCtx, iNodeId)
+	if err != nil {
+		return false, err
+	}
+
+	return isRevoked(iCtx, certificate.IssuerId, iNodeId, transactionTime)
+}
@@ -0,0 +1,144 @@
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	certifiedObjectType = "certified"
+
+	/// material attribute holding the country or region the material comes from
+	originAttribute = "origin"
+)
+
+/// Restrictions on the materials a certificate can certify, empty fields are not restricted.
+/// MaxQuantity caps the total quantity of every material certified, expressed in Unit
+type CertificateScope struct {
+	MaterialNames []string `json:"MaterialNames"`
+	Origins       []string `json:"Origins"`
+	MaxQuantity   string   `json:"MaxQuantity"`
+	Unit          string   `json:"Unit"`
+}
+
+func parseCertificateScope(
+	iScope string,
+) (CertificateScope, error) {
+	scope := CertificateScope{
+		MaterialNames: []string{},
+		Origins:       []string{},
+	}
+	if iScope == "" {
+		return scope, nil
+	}
+
+	err := json.Unmarshal([]byte(iScope), &scope)
+	if err != nil {
+		return CertificateScope{}, err
+	}
+
+	if scope.MaxQuantity != "" {
+		maxQuantity, err := decimal.NewFromString(scope.MaxQuantity)
+		if err != nil {
+			return CertificateScope{}, err
+		}
+
+		if !maxQuantity.IsPositive() {
+			return CertificateScope{}, fmt.Errorf("max quantity must be positive")
+		}
+
+		if scope.Unit == "" {
+			return CertificateScope{}, fmt.Errorf("unit of the max quantity cannot be empty")
+		}
+	}
+
+	return scope, nil
+}
+
+func containsString(
+	iValues []string,
+	iValue string,
+) bool {
+	for _, value := range iValues {
+		if value == iValue {
+			return true
+		}
+	}
+
+	return false
+}
+
+/// iMaterial must fall in the scope of iCertificate, and the materials already certified by it
+/// along with iMaterial must not exceed its max quantity
+func checkCertificateRestrictions(
+	iCtx contractapi.TransactionContextInterface,
+	iCertificate *Certificate,
+	iMaterial *Material,
+) error {
+	scope := iCertificate.Scope
+	if len(scope.MaterialNames) > 0 && !containsString(scope.MaterialNames, iMaterial.Name) {
+		return fmt.Errorf("certificate %s does not cover %s", iCertificate.Id, iMaterial.Name)
+	}
+
+	if len(scope.Origins) > 0 && !containsString(scope.Origins, iMaterial.Attributes[originAttribute]) {
+		return fmt.Errorf("certificate %s does not cover origin %s", iCertificate.Id, iMaterial.Attributes[originAttribute])
+	}
+
+	if scope.MaxQuantity == "" {
+		return nil
+	}
+
+	maxQuantity, err := decimal.NewFromString(scope.MaxQuantity)
+	if err != nil {
+		return err
+	}
+
+	materialIds, err := getIndexedIds(iCtx, certifiedObjectType, []string{iCertificate.Id})
+	if err != nil {
+		return err
+	}
+	materialIds = append(materialIds, iMaterial.Id)
+
+	materialContract := MaterialContract{}
+	total := decimal.NewFromInt(0)
+	for _, materialId := range materialIds {
+		material, err := materialContract.GetMaterial(iCtx, materialId)
+		if err != nil {
+			return err
+		}
+
+		quantity, err := getEffectiveQuantity(iCtx, material)
+		if err != nil {
+			return err
+		}
+
+		quantity, err = convertQuantity(iCtx, quantity, material.Unit, scope.Unit)
+		if err != nil {
+			return err
+		}
+		total = total.Add(quantity)
+	}
+
+	if total.GreaterThan(maxQuantity) {
+		return fmt.Errorf("certificate %s cannot certify more than %s %s", iCertificate.Id, scope.MaxQuantity, scope.Unit)
+	}
+
+	return nil
+}
+
+/// indexes the certificate under the material and the material under the certificate
+func putCertification(
+	iCtx contractapi.TransactionContextInterface,
+	iCertificateId string,
+	iMaterialId string,
+) error {
+	err := putIndex(iCtx, certifiesObjectType, []string{iMaterialId, iCertificateId})
+	if err != nil {
+		return err
+	}
+
+	return putIndex(iCtx, certifiedObjectType, []string{iCertificateId, iMaterialId})
+}
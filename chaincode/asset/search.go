@@ -0,0 +1,170 @@
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// Rich queries are only available when the peers use CouchDB as state database, the indexes they rely on
+/// are defined in META-INF/statedb/couchdb/indexes
+
+/// times compared by rich queries are stored and queried in UTC at a whole second, so that their RFC 3339
+/// strings all have the same width and compare chronologically. Fractions of a second are trimmed of their
+/// trailing zeros when marshalled, and strings of different zone offsets do not compare
+const queryTimeLayout = "2006-01-02T15:04:05Z"
+
+/// bound of $gt and $lte conditions, iTime is truncated to the second
+func formatQueryTime(
+	iTime time.Time,
+) string {
+	return iTime.UTC().Format(queryTimeLayout)
+}
+
+/// bound of $gte and $lt conditions, iTime is rounded up to the second since the stored time of the same second
+/// is before it
+func formatQueryTimeCeiling(
+	iTime time.Time,
+) string {
+	ceiling := iTime.UTC().Truncate(time.Second)
+	if ceiling.Before(iTime) {
+		ceiling = ceiling.Add(time.Second)
+	}
+
+	return ceiling.Format(queryTimeLayout)
+}
+
+/// the times compared by queries are signed by their owners, so they are refused rather than normalized when they
+/// are not in the form they are stored in
+func checkQueryTime(
+	iName string,
+	iTime time.Time,
+) error {
+	_, offset := iTime.Zone()
+	if offset != 0 || iTime.Nanosecond() != 0 {
+		return fmt.Errorf("%s must be in UTC and at a whole second, not %s", iName, iTime.Format(time.RFC3339Nano))
+	}
+
+	return nil
+}
+
+/// runs a CouchDB query, iIndex is the name of the index to use and can be empty to let CouchDB pick one.
+/// The index definition must be named iIndex + ".json" and its design document iIndex + "Doc"
+func getQueryResultWithPagination(
+	iCtx contractapi.TransactionContextInterface,
+	iSelector map[string]interface{},
+	iIndex string,
+	iPageSize int32,
+	iBookmark string,
+	oRecords *[][]byte,
+) (string, int32, error) {
+	if iPageSize <= 0 {
+		return "", 0, fmt.Errorf("page size must be positive")
+	}
+
+	query := map[string]interface{}{
+		"selector": iSelector,
+	}
+	if iIndex != "" {
+		query["use_index"] = []string{"_design/" + iIndex + "Doc", iIndex}
+	}
+
+	queryJson, err := json.Marshal(query)
+	if err != nil {
+		return "", 0, err
+	}
+
+	iterator, metadata, err := iCtx.GetStub().GetQueryResultWithPagination(string(queryJson), iPageSize, iBookmark)
+	if err != nil {
+		return "", 0, err
+	}
+	defer iterator.Close()
+
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return "", 0, err
+		}
+
+		*oRecords = append(*oRecords, kv.Value)
+	}
+
+	return metadata.Bookmark, metadata.FetchedRecordsCount, nil
+}
+
+/// iSelector must not constrain the node type, it is restricted to material nodes
+func queryMaterials(
+	iCtx contractapi.TransactionContextInterface,
+	iSelector map[string]interface{},
+	iIndex string,
+	iPageSize int32,
+	iBookmark string,
+) (*MaterialPage, error) {
+	iSelector["Type"] = eMaterial
+
+	records := [][]byte{}
+	bookmark, fetchedRecordsCount, err := getQueryResultWithPagination(iCtx, iSelector, iIndex, iPageSize, iBookmark, &records)
+	if err != nil {
+		return nil, err
+	}
+
+	materials := []Material{}
+	for _, record := range records {
+		var material Material
+		err = json.Unmarshal(record, &material)
+		if err != nil {
+			return nil, err
+		}
+
+		materials = append(materials, material)
+	}
+
+	return &MaterialPage{
+		Materials:           materials,
+		Bookmark:            bookmark,
+		FetchedRecordsCount: fetchedRecordsCount,
+	}, nil
+}
+
+/// iSelector is a CouchDB selector object such as {"Name": "Arabica beans", "IsFinalized": false},
+/// it is restricted to material nodes
+func (c *MaterialContract) SearchMaterials(
+	iCtx contractapi.TransactionContextInterface,
+	iSelector string,
+	iPageSize int32,
+	iBookmark string,
+) (*MaterialPage, error) {
+	var selector map[string]interface{}
+	err := json.Unmarshal([]byte(iSelector), &selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %v", err)
+	}
+
+	return queryMaterials(
+		iCtx,
+		map[string]interface{}{"$and": []interface{}{selector}},
+		"",
+		iPageSize,
+		iBookmark,
+	)
+}
+
+/// lists the materials created in [iFrom, iTo), an empty iBookmark starts from the first page
+func (c *MaterialContract) GetMaterialsCreatedBetween(
+	iCtx contractapi.TransactionContextInterface,
+	iFrom time.Time,
+	iTo time.Time,
+	iPageSize int32,
+	iBookmark string,
+) (*MaterialPage, error) {
+	selector := map[string]interface{}{
+		"CreatedTime": map[string]interface{}{
+			"$gte": formatQueryTimeCeiling(iFrom),
+			"$lt":  formatQueryTimeCeiling(iTo),
+		},
+	}
+
+	return queryMaterials(iCtx, selector, "indexCreatedTime", iPageSize, iBookmark)
+}
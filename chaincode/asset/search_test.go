@@ -0,0 +1,125 @@
+package asset_test
+
+import (
+	"encoding/json"
+	"sig_chain/chaincode/asset"
+	"sig_chain/chaincode/graph"
+	"sig_chain/pkg/client"
+	"sig_chain/pkg/testutil"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// the contracts as the chaincode registers them, so that the transactions run the hooks
+func makeTestContract(
+	t *testing.T,
+) *testutil.MockContract {
+	identity, err := testutil.MakeMockIdentity("user", "Org1MSP")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chaincode, err := contractapi.NewChaincode(&asset.MaterialContract{}, &graph.GraphContract{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return testutil.MakeMockContract(testutil.MakeMockLedger(), chaincode, identity)
+}
+
+/// Fabric refuses writes after paginated queries, so only the transactions which write record their submitter
+func TestSubmittedPaginatedQuery(t *testing.T) {
+	contract := makeTestContract(t)
+	owner := client.MakeClient(contract, makeTestSigner(t))
+
+	receipts := []*graph.TransactionReceipt{}
+	for _, nodeId := range []string{"m1", "m2", "m3"} {
+		receipt, err := owner.CreateMaterial(asset.MaterialSpec{
+			NodeId:      nodeId,
+			Name:        "flour",
+			Unit:        "kg",
+			Quantity:    "10",
+			CreatedTime: time.Now(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		receipts = append(receipts, receipt)
+	}
+
+	pageJson, err := contract.SubmitTransaction("SearchMaterials", `{"Name": "flour"}`, "2", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var page asset.MaterialPage
+	err = json.Unmarshal(pageJson, &page)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Materials) != 2 || page.Bookmark == "" {
+		t.Fatalf("expected a first page of 2 materials, got %d", len(page.Materials))
+	}
+
+	_, err = contract.SubmitTransaction("SearchMaterials", `{"Name": "flour"}`, "2", page.Bookmark)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, receipt := range receipts {
+		_, err = contract.EvaluateTransaction("GraphContract:GetTransactionSubmitter", receipt.TxId)
+		if err != nil {
+			t.Fatalf("submitter of %s: %v", receipt.TxId, err)
+		}
+	}
+}
+
+func (l *testLedger) getMaterialsCreatedBetween(
+	iFrom time.Time,
+	iTo time.Time,
+) []asset.Material {
+	l.t.Helper()
+	var page *asset.MaterialPage
+	l.mustSubmit("get materials created between", func(iCtx contractapi.TransactionContextInterface) error {
+		var err error
+		page, err = l.contract.GetMaterialsCreatedBetween(iCtx, iFrom, iTo, 10, "")
+		return err
+	})
+
+	return page.Materials
+}
+
+/// created times are compared as strings by CouchDB, so they are stored in UTC at a whole second
+func TestQueryCreatedTimes(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	for _, createdTime := range []time.Time{testTime.In(zone), testTime.Add(500 * time.Millisecond)} {
+		material := makeTestMaterial("m1", "10", alice)
+		header := material.GetHeader()
+		header.CreatedTime = createdTime
+		material.SetHeader(header)
+		signature := signNode(t, alice, &material)
+		l.mustFail("create at "+createdTime.String(), func(iCtx contractapi.TransactionContextInterface) error {
+			_, err := l.contract.CreateMaterial(iCtx, "m1", "flour", "kg", "10", "", "", time.Time{}, "", "", "", "", "", alice.GetPublicKey(), createdTime, signature)
+			return err
+		})
+	}
+
+	l.createMaterial("m1", "10", alice)
+
+	/// bounds within a second and in other zones
+	if len(l.getMaterialsCreatedBetween(testTime.Add(-time.Second).In(zone), testTime.Add(500*time.Millisecond).In(zone))) != 1 {
+		t.Fatal("m1 is not created before half a second after its created time")
+	}
+	if len(l.getMaterialsCreatedBetween(testTime.Add(500*time.Millisecond), testTime.Add(time.Hour))) != 0 {
+		t.Fatal("m1 is created after half a second after its created time")
+	}
+	if len(l.getMaterialsCreatedBetween(testTime.Add(-time.Hour), testTime)) != 0 {
+		t.Fatal("m1 is created before its created time")
+	}
+}
@@ -0,0 +1,316 @@
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	deviceObjectType        = "device"
+	sensorReadingObjectType = "sensorReading"
+)
+
+/// Signed by the owner of the device, readings can only be recorded by registered devices
+type DeviceRegistration struct {
+	DeviceId        string `json:"DeviceId"`
+	DevicePublicKey string `json:"DevicePublicKey"`
+	OwnerPublicKey  string `json:"OwnerPublicKey"`
+	Signature       string `json:"Signature"`
+}
+
+/// Node owned by the device which took the reading, linked to the material through its previous node hashes
+type SensorReading struct {
+	graph.NodeHeader
+	MaterialId  string    `json:"MaterialId"`
+	DeviceId    string    `json:"DeviceId"`
+	Metric      string    `json:"Metric"` /// e.g. "temperature"
+	Value       string    `json:"Value"`
+	ReadingTime time.Time `json:"ReadingTime"`
+}
+
+func (r *SensorReading) GetHeader() graph.NodeHeader {
+	return r.NodeHeader
+}
+func (r *SensorReading) SetHeader(iHeader graph.NodeHeader) {
+	r.NodeHeader = iHeader
+}
+
+/// Signature is the device's signature of the reading node
+type SensorReadingSpec struct {
+	NodeId      string    `json:"NodeId"`
+	DeviceId    string    `json:"DeviceId"`
+	Metric      string    `json:"Metric"`
+	Value       string    `json:"Value"`
+	ReadingTime time.Time `json:"ReadingTime"`
+	Signature   string    `json:"Signature"`
+}
+
+/// returns nil if iDeviceId is not registered
+func getDeviceRegistration(
+	iCtx contractapi.TransactionContextInterface,
+	iDeviceId string,
+) (*DeviceRegistration, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(deviceObjectType, []string{iDeviceId})
+	if err != nil {
+		return nil, err
+	}
+
+	registrationJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if registrationJson == nil {
+		return nil, nil
+	}
+
+	var registration DeviceRegistration
+	err = json.Unmarshal(registrationJson, &registration)
+	if err != nil {
+		return nil, err
+	}
+
+	return &registration, nil
+}
+
+/// an empty iMetric returns the readings of every metric
+func getSensorReadings(
+	iCtx contractapi.TransactionContextInterface,
+	iMaterialId string,
+	iMetric string,
+) ([]SensorReading, error) {
+	attributes := []string{iMaterialId}
+	if iMetric != "" {
+		attributes = append(attributes, iMetric)
+	}
+
+	readingIds, err := getIndexedIds(iCtx, sensorReadingObjectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	graphContract := graph.GraphContract{}
+	readings := []SensorReading{}
+	for _, readingId := range readingIds {
+		var reading SensorReading
+		err = graphContract.GetNode(iCtx, readingId, &reading)
+		if err != nil {
+			return nil, err
+		}
+
+		readings = append(readings, reading)
+	}
+
+	return readings, nil
+}
+
+/// iSignature is the owner's signature of the DeviceRegistration
+func (c *MaterialContract) RegisterDevice(
+	iCtx contractapi.TransactionContextInterface,
+	iDeviceId string,
+	iDevicePublicKey string,
+	iOwnerPublicKey string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	if iDeviceId == "" {
+		return nil, fmt.Errorf("device id cannot be empty")
+	}
+
+	existing, err := getDeviceRegistration(iCtx, iDeviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		return nil, fmt.Errorf("device %s is already registered", iDeviceId)
+	}
+
+	registration := DeviceRegistration{
+		DeviceId:        iDeviceId,
+		DevicePublicKey: iDevicePublicKey,
+		OwnerPublicKey:  iOwnerPublicKey,
+	}
+	err = graph.VerifyPayload(iOwnerPublicKey, &registration, iSignature)
+	if err != nil {
+		return nil, err
+	}
+	registration.Signature = iSignature
+
+	registrationJson, err := json.Marshal(registration)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(deviceObjectType, []string{iDeviceId})
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, iCtx.GetStub().PutState(key, registrationJson))
+}
+
+func (c *MaterialContract) GetDevice(
+	iCtx contractapi.TransactionContextInterface,
+	iDeviceId string,
+) (*DeviceRegistration, error) {
+	registration, err := getDeviceRegistration(iCtx, iDeviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if registration == nil {
+		return nil, fmt.Errorf("device %s is not registered", iDeviceId)
+	}
+
+	return registration, nil
+}
+
+/// links every reading of iReadings to iMaterialId in a single transaction,
+/// gateways usually buffer readings while a shipment is in transit
+func (c *MaterialContract) RecordSensorReadings(
+	iCtx contractapi.TransactionContextInterface,
+	iMaterialId string,
+	iReadings []SensorReadingSpec,
+) (*graph.TransactionReceipt, error) {
+	if len(iReadings) == 0 {
+		return nil, fmt.Errorf("readings cannot be empty")
+	}
+
+	_, err := c.GetMaterial(iCtx, iMaterialId)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionTime, err := getTransactionTime(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	graphContract := graph.GraphContract{}
+	devices := map[string]*DeviceRegistration{}
+	nodeIds := map[string]bool{}
+	for _, spec := range iReadings {
+		if nodeIds[spec.NodeId] {
+			return nil, fmt.Errorf("node id %s is used more than once", spec.NodeId)
+		}
+		nodeIds[spec.NodeId] = true
+
+		_, err = decimal.NewFromString(spec.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		err = checkNotInFuture(iCtx, spec.ReadingTime)
+		if err != nil {
+			return nil, err
+		}
+
+		device, ok := devices[spec.DeviceId]
+		if !ok {
+			device, err = c.GetDevice(iCtx, spec.DeviceId)
+			if err != nil {
+				return nil, err
+			}
+			devices[spec.DeviceId] = device
+		}
+
+		nodeHeader := graph.MakeNodeHeader(
+			spec.NodeId,
+			eSensorReading,
+			true,
+			graph.MakeHashSet(graph.HashId(iMaterialId)),
+			graph.MakeHashSet(),
+			device.DevicePublicKey,
+			transactionTime,
+			spec.Signature,
+		)
+		reading := SensorReading{
+			NodeHeader:  nodeHeader,
+			MaterialId:  iMaterialId,
+			DeviceId:    spec.DeviceId,
+			Metric:      spec.Metric,
+			Value:       spec.Value,
+			ReadingTime: spec.ReadingTime,
+		}
+
+		err = graphContract.CreateNode(iCtx, &reading)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record reading %s: %v", spec.NodeId, err)
+		}
+
+		err = putIndex(iCtx, sensorReadingObjectType, []string{iMaterialId, spec.Metric, spec.NodeId})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putAuditEntry(iCtx, iMaterialId, eSensorRecorded, fmt.Sprintf("%d readings", len(iReadings))))
+}
+
+/// returns the readings of iMetric taken on iNodeId or any of its ancestors which fall outside of
+/// [iMinValue, iMaxValue], oldest first. An empty bound is not checked
+func (c *MaterialContract) GetSensorExcursions(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iMetric string,
+	iMinValue string,
+	iMaxValue string,
+) ([]SensorReading, error) {
+	var minValue, maxValue *decimal.Decimal
+	if iMinValue != "" {
+		value, err := decimal.NewFromString(iMinValue)
+		if err != nil {
+			return nil, err
+		}
+		minValue = &value
+	}
+
+	if iMaxValue != "" {
+		value, err := decimal.NewFromString(iMaxValue)
+		if err != nil {
+			return nil, err
+		}
+		maxValue = &value
+	}
+
+	_, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	lineageIds, err := getLineageIds(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	excursions := []SensorReading{}
+	for _, nodeId := range lineageIds {
+		readings, err := getSensorReadings(iCtx, nodeId, iMetric)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, reading := range readings {
+			value, err := decimal.NewFromString(reading.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			if (minValue != nil && value.LessThan(*minValue)) || (maxValue != nil && value.GreaterThan(*maxValue)) {
+				excursions = append(excursions, reading)
+			}
+		}
+	}
+
+	sort.SliceStable(excursions, func(i, j int) bool {
+		return excursions[i].ReadingTime.Before(excursions[j].ReadingTime)
+	})
+
+	return excursions, nil
+}
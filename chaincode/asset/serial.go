@@ -0,0 +1,181 @@
+package asset
+
+import (
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/shopspring/decimal"
+)
+
+const serialObjectType = "serial"
+
+/// splits a lot into one item per serial number, each item keeps the lot's fields and can be
+/// transferred on its own while still tracing back to the lot
+/// iSignature is the signature for the finalized lot node
+/// iNewNodeSignatures are the owner's signatures for the new item nodes
+func (c *MaterialContract) SerializeMaterial(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iSerialNumbers []string,
+	iNewNodeIds []string,
+	iCreatedTime time.Time,
+	iSignature string,
+	iNewNodeSignatures []string,
+) (*graph.TransactionReceipt, error) {
+	if len(iSerialNumbers) == 0 {
+		return nil, fmt.Errorf("serial numbers cannot be empty")
+	}
+
+	if len(iSerialNumbers) != len(iNewNodeIds) {
+		return nil, fmt.Errorf("mismatch new node ids and serial numbers")
+	}
+
+	if len(iSerialNumbers) != len(iNewNodeSignatures) {
+		return nil, fmt.Errorf("mismatch signatures and serial numbers")
+	}
+
+	createdTime, err := getOperationTime(iCtx, iCreatedTime)
+	if err != nil {
+		return nil, err
+	}
+
+	lot, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	if lot.SerialNumber != "" {
+		return nil, fmt.Errorf("material is already a serialized item")
+	}
+
+	err = checkNoPendingOffer(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkNotExpired(iCtx, lot)
+	if err != nil {
+		return nil, err
+	}
+
+	quantity, err := getEffectiveQuantity(iCtx, lot)
+	if err != nil {
+		return nil, err
+	}
+
+	if !quantity.Equal(decimal.NewFromInt(int64(len(iSerialNumbers)))) {
+		return nil, fmt.Errorf("quantity must match the number of serial numbers")
+	}
+
+	grade, err := getEffectiveGrade(iCtx, lot)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkReservations(iCtx, iNodeId, []allocation{{lot.OwnerPublicKey, quantity}})
+	if err != nil {
+		return nil, err
+	}
+
+	serialNumbers := map[string]bool{}
+	children := []graph.NodeI{}
+	for i, serialNumber := range iSerialNumbers {
+		if serialNumber == "" {
+			return nil, fmt.Errorf("serial numbers cannot be empty")
+		}
+
+		if serialNumbers[serialNumber] {
+			return nil, fmt.Errorf("serial number %s is used more than once", serialNumber)
+		}
+		serialNumbers[serialNumber] = true
+
+		/// serial numbers are unique per trade item
+		existingIds, err := getIndexedIds(iCtx, serialObjectType, []string{lot.Gtin, serialNumber})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(existingIds) > 0 {
+			return nil, fmt.Errorf("serial number %s is already used", serialNumber)
+		}
+
+		nodeHeader := graph.MakeNodeHeader(
+			iNewNodeIds[i],
+			eMaterial,
+			false,
+			graph.MakeHashSet(),
+			graph.MakeHashSet(),
+			lot.OwnerPublicKey,
+			createdTime,
+			iNewNodeSignatures[i],
+		)
+
+		err = setTimeSource(iCtx, &nodeHeader)
+		if err != nil {
+			return nil, err
+		}
+		item := MakeMaterial(
+			lot.Name,
+			lot.Unit,
+			"1",
+			lot.LotNumber,
+			lot.BatchNumber,
+			lot.ExpiryDate,
+			getComposition(lot),
+			lot.TemplateId,
+			lot.Attributes,
+			lot.Gtin,
+			"",
+			grade,
+			serialNumber,
+			nodeHeader,
+		)
+		children = append(children, &item)
+	}
+
+	graphContract := graph.GraphContract{}
+	err = graphContract.CreateDerivedNodes(
+		iCtx,
+		[]string{iNodeId},
+		[]graph.NodeI{&Material{}},
+		[]string{iSignature},
+		children,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, child := range children {
+		err = putMaterialIndexes(iCtx, child.(*Material))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putDerivation(
+		iCtx,
+		eSerialize,
+		[]string{iNodeId},
+		iNewNodeIds,
+		"0",
+		lot.Unit,
+	))
+}
+
+/// returns every node of the item, the latest one is the only one which is not finalized
+func (c *MaterialContract) GetMaterialsBySerialNumber(
+	iCtx contractapi.TransactionContextInterface,
+	iGtin string,
+	iSerialNumber string,
+	iPageSize int32,
+	iBookmark string,
+) (*MaterialPage, error) {
+	selector := map[string]interface{}{
+		"Gtin":         iGtin,
+		"SerialNumber": iSerialNumber,
+	}
+
+	return queryMaterials(iCtx, selector, "indexSerialNumber", iPageSize, iBookmark)
+}
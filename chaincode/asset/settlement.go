@@ -0,0 +1,162 @@
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	settlementChaincodeKey = "settlementChaincode"
+
+	/// the settlement arguments are passed in the transient map, as a json array of strings
+	settlementTransientKey = "settlement"
+)
+
+/// Signed by the administrator. Channel can be empty to call a chaincode of the same channel, Fabric only
+/// commits the writes of chaincodes of the same channel so settlement on another channel is only checked
+type SettlementChaincode struct {
+	ChaincodeName string `json:"ChaincodeName"`
+	Channel       string `json:"Channel"`
+	Function      string `json:"Function"` /// called with the transferred node id, the new node id and the transient arguments
+	Signature     string `json:"Signature"`
+}
+
+/// returns nil if no settlement chaincode is configured
+func getSettlementChaincode(
+	iCtx contractapi.TransactionContextInterface,
+) (*SettlementChaincode, error) {
+	settlementJson, err := getConfigValue(iCtx, settlementChaincodeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if settlementJson == nil {
+		return nil, nil
+	}
+
+	var settlement SettlementChaincode
+	err = json.Unmarshal(settlementJson, &settlement)
+	if err != nil {
+		return nil, err
+	}
+
+	return &settlement, nil
+}
+
+/// invokes the settlement chaincode if the transfer carries settlement arguments, the transfer fails with it
+/// so that custody and payment are committed together. In strict settlement mode, a transfer without
+/// arguments fails while a settlement chaincode is configured
+func settleTransfer(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iNewNodeId string,
+) error {
+	transient, err := iCtx.GetStub().GetTransient()
+	if err != nil {
+		return err
+	}
+
+	settlement, err := getSettlementChaincode(iCtx)
+	if err != nil {
+		return err
+	}
+
+	argumentsJson, ok := transient[settlementTransientKey]
+	if !ok {
+		if settlement == nil {
+			return nil
+		}
+
+		isStrict, err := IsStrictModeEnabled(iCtx, strictSettlementMode)
+		if err != nil {
+			return err
+		}
+
+		if isStrict {
+			return fmt.Errorf("transfer must carry settlement arguments in the transient map as %s", settlementTransientKey)
+		}
+		return nil
+	}
+
+	err = checkFeatureEnabled(iCtx, settlementFeature)
+	if err != nil {
+		return err
+	}
+
+	if settlement == nil {
+		return fmt.Errorf("no settlement chaincode is configured")
+	}
+
+	var arguments []string
+	err = json.Unmarshal(argumentsJson, &arguments)
+	if err != nil {
+		return fmt.Errorf("settlement arguments must be a json array of strings: %v", err)
+	}
+
+	args := [][]byte{[]byte(settlement.Function), []byte(iNodeId), []byte(iNewNodeId)}
+	for _, argument := range arguments {
+		args = append(args, []byte(argument))
+	}
+
+	response := iCtx.GetStub().InvokeChaincode(settlement.ChaincodeName, args, settlement.Channel)
+	if response.Status != shim.OK {
+		return fmt.Errorf("settlement by %s failed: %s", settlement.ChaincodeName, response.Message)
+	}
+
+	return nil
+}
+
+/// an empty iChaincodeName disables settlement, iSignature is the administrator's signature of the SettlementChaincode
+func (c *MaterialContract) SetSettlementChaincode(
+	iCtx contractapi.TransactionContextInterface,
+	iChaincodeName string,
+	iChannel string,
+	iFunction string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	settlement := SettlementChaincode{
+		ChaincodeName: iChaincodeName,
+		Channel:       iChannel,
+		Function:      iFunction,
+	}
+	err := verifyAdministratorSignature(iCtx, &settlement, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	if iChaincodeName == "" {
+		return graph.MakeTransactionReceiptIfSucceeded(iCtx, deleteConfigValue(iCtx, settlementChaincodeKey))
+	}
+
+	if iFunction == "" {
+		return nil, fmt.Errorf("settlement function cannot be empty")
+	}
+
+	settlement.Signature = iSignature
+	settlementJson, err := json.Marshal(settlement)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putConfigValue(iCtx, settlementChaincodeKey, settlementJson))
+}
+
+/// returns an empty SettlementChaincode if none is configured
+func (c *MaterialContract) GetSettlementChaincode(
+	iCtx contractapi.TransactionContextInterface,
+) (*SettlementChaincode, error) {
+	settlement, err := getSettlementChaincode(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	if settlement == nil {
+		return &SettlementChaincode{}, nil
+	}
+
+	return settlement, nil
+}
@@ -0,0 +1,232 @@
+package asset_test
+
+import (
+	"fmt"
+	"sig_chain/chaincode/asset"
+	"sig_chain/chaincode/graph"
+	"sig_chain/pkg/client"
+	"sig_chain/pkg/keys"
+	"sig_chain/pkg/testutil"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// every transaction of the tests runs at this time, so that node times are within the clock drift tolerance
+var testTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+/// runs the transactions of a test on its own ledger, a transaction is committed before the next one runs.
+/// transient is passed to the transactions if it is set
+type testLedger struct {
+	t         *testing.T
+	ledger    *testutil.MockLedger
+	identity  *testutil.MockIdentity
+	transient map[string][]byte
+	txCount   int
+	contract  asset.MaterialContract
+}
+
+func makeTestLedger(
+	t *testing.T,
+) *testLedger {
+	identity, err := testutil.MakeMockIdentity("user", "Org1MSP")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testLedger{
+		t:        t,
+		ledger:   testutil.MakeMockLedger(),
+		identity: identity,
+	}
+}
+
+/// returns the error of iTransaction, its writes are only committed if it succeeds
+func (l *testLedger) submit(
+	iTransaction func(iCtx contractapi.TransactionContextInterface) error,
+) error {
+	l.txCount++
+	stub := l.ledger.MakeStub(fmt.Sprintf("tx%d", l.txCount), testTime)
+	if l.transient != nil {
+		stub.SetTransient(l.transient)
+	}
+	ctx, err := testutil.MakeTransactionContext(stub, l.identity)
+	if err != nil {
+		l.t.Fatal(err)
+	}
+
+	err = iTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	return stub.Commit()
+}
+
+/// fails the test if iTransaction fails
+func (l *testLedger) mustSubmit(
+	iName string,
+	iTransaction func(iCtx contractapi.TransactionContextInterface) error,
+) {
+	l.t.Helper()
+	err := l.submit(iTransaction)
+	if err != nil {
+		l.t.Fatalf("%s: %v", iName, err)
+	}
+}
+
+/// fails the test if iTransaction succeeds
+func (l *testLedger) mustFail(
+	iName string,
+	iTransaction func(iCtx contractapi.TransactionContextInterface) error,
+) {
+	l.t.Helper()
+	err := l.submit(iTransaction)
+	if err == nil {
+		l.t.Fatalf("%s: expected an error", iName)
+	}
+}
+
+func (l *testLedger) getMaterial(
+	iNodeId string,
+) *asset.Material {
+	l.t.Helper()
+	var material *asset.Material
+	l.mustSubmit("get "+iNodeId, func(iCtx contractapi.TransactionContextInterface) error {
+		var err error
+		material, err = l.contract.GetMaterial(iCtx, iNodeId)
+		return err
+	})
+
+	return material
+}
+
+func makeTestSigner(
+	t *testing.T,
+) client.Signer {
+	privateKey, err := keys.GenerateKey("ecdsa")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey, err := keys.EncodePublicKey(privateKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := client.MakeKeySignerFromKey(privateKey, publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signer
+}
+
+func signNode(
+	t *testing.T,
+	iSigner client.Signer,
+	iNode graph.NodeI,
+) string {
+	signature, err := client.SignNode(iSigner, iNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signature
+}
+
+func signPayload(
+	t *testing.T,
+	iSigner client.Signer,
+	iPayload interface{},
+) string {
+	signature, err := client.SignPayload(iSigner, iPayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signature
+}
+
+/// material of iOwner in kg, as CreateMaterial builds it
+func makeTestMaterial(
+	iNodeId string,
+	iQuantity string,
+	iOwner client.Signer,
+	iPreviousNodeIds ...string,
+) asset.Material {
+	previousNodeHashedIds := graph.MakeHashSet()
+	for _, previousNodeId := range iPreviousNodeIds {
+		previousNodeHashedIds = previousNodeHashedIds.Add(graph.HashId(previousNodeId))
+	}
+
+	return asset.MakeMaterial(
+		"flour",
+		"kg",
+		iQuantity,
+		"",
+		"",
+		time.Time{},
+		map[string]string{"flour": "100"},
+		"",
+		map[string]string{},
+		"",
+		"",
+		"",
+		"",
+		graph.MakeNodeHeader(
+			iNodeId,
+			"eMaterial",
+			false,
+			previousNodeHashedIds,
+			graph.MakeHashSet(),
+			iOwner.GetPublicKey(),
+			testTime,
+			"",
+		),
+	)
+}
+
+func (l *testLedger) createMaterial(
+	iNodeId string,
+	iQuantity string,
+	iOwner client.Signer,
+) {
+	l.t.Helper()
+	material := makeTestMaterial(iNodeId, iQuantity, iOwner)
+	signature := signNode(l.t, iOwner, &material)
+	l.mustSubmit("create "+iNodeId, func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.CreateMaterial(iCtx, iNodeId, "flour", "kg", iQuantity, "", "", time.Time{}, "", "", "", "", "", iOwner.GetPublicKey(), testTime, signature)
+		return err
+	})
+}
+
+/// the signature of iMaterial once finalized and pointing to iNextNodeIds
+func signFinalization(
+	t *testing.T,
+	iOwner client.Signer,
+	iMaterial asset.Material,
+	iNextNodeIds ...string,
+) string {
+	header := iMaterial.GetHeader()
+	header.IsFinalized = true
+	for _, nextNodeId := range iNextNodeIds {
+		header.NextNodeHashedIds = header.NextNodeHashedIds.Add(graph.HashId(nextNodeId))
+	}
+	iMaterial.SetHeader(header)
+
+	return signNode(t, iOwner, &iMaterial)
+}
+
+/// the stored signature of iNodeId must still verify once read back from the ledger
+func (l *testLedger) checkNodeSignature(
+	iNodeId string,
+) {
+	l.t.Helper()
+	material := l.getMaterial(iNodeId)
+	err := graph.VerifyNodeSignature(material)
+	if err != nil {
+		l.t.Fatalf("signature of %s: %v", iNodeId, err)
+	}
+}
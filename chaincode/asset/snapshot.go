@@ -0,0 +1,88 @@
+package asset
+
+import (
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// every object type of the contracts, a record of a type missing here would not be exported. Private data
+/// collections are not exported, their hashes are
+var exportedObjectTypes = append(graph.GetObjectTypes(),
+	adjustmentObjectType,
+	attestationObjectType,
+	auditObjectType,
+	cancelledOfferObjectType,
+	certificateLogObjectType,
+	certifiedObjectType,
+	certifiesObjectType,
+	claimSchemaObjectType,
+	coSignatureObjectType,
+	confidentialObjectType,
+	configObjectType,
+	credentialObjectType,
+	custodyObjectType,
+	derivationObjectType,
+	deviceObjectType,
+	documentObjectType,
+	glnObjectType,
+	gradeObjectType,
+	offerObjectType,
+	ownerGlnObjectType,
+	personalDataObjectType,
+	priceHashObjectType,
+	priceObjectType,
+	productObjectType,
+	qualityRecordObjectType,
+	recallObjectType,
+	rejectionObjectType,
+	releasedReservationObjectType,
+	requiredCertificationsObjectType,
+	reservationObjectType,
+	returnObjectType,
+	revocationObjectType,
+	sensorReadingObjectType,
+	serialObjectType,
+	unitObjectType,
+)
+
+/// Signed by the administrator, PageHash is the Hash of the exported page
+type ImportRequest struct {
+	PageHash  string `json:"PageHash"`
+	Signature string `json:"Signature"`
+}
+
+/// meant to be called with an empty iBookmark and then with the Bookmark of the previous page until it is empty.
+/// Only channel admins can export the whole world state
+func (c *MaterialContract) ExportState(
+	iCtx contractapi.TransactionContextInterface,
+	iBookmark string,
+	iPageSize int,
+) (*graph.StatePage, error) {
+	err := checkChannelAdmin(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.ExportState(iCtx, exportedObjectTypes, iBookmark, int32(iPageSize))
+}
+
+/// imports a page exported by ExportState, typically into a new channel whose administrator is the one of the
+/// exported channel: the config is imported too and existing records cannot be changed.
+/// iSignature is the administrator's signature of the ImportRequest
+func (c *MaterialContract) ImportState(
+	iCtx contractapi.TransactionContextInterface,
+	iEntries []graph.StateEntry,
+	iPageHash string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	request := ImportRequest{
+		PageHash: iPageHash,
+	}
+	err := verifyAdministratorSignature(iCtx, &request, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, graph.ImportState(iCtx, iEntries, iPageHash))
+}
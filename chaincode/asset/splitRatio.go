@@ -0,0 +1,151 @@
+package asset
+
+import (
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/shopspring/decimal"
+)
+
+type RemainderPolicy = string
+
+/// where the quantity left over by rounding every child down goes
+const (
+	eRemainderToFirst   RemainderPolicy = "eRemainderToFirst"
+	eRemainderToLast    RemainderPolicy = "eRemainderToLast"
+	eRemainderToLargest RemainderPolicy = "eRemainderToLargest"
+	eRemainderToWaste   RemainderPolicy = "eRemainderToWaste"
+)
+
+/// Quantities are in the unit of the split material
+type SplitQuantities struct {
+	Quantities []string `json:"Quantities"`
+	Waste      string   `json:"Waste"`
+}
+
+/// iRatios are percentages which must add up to 100, every child is rounded down to iPrecision
+/// decimal places and the remainder is handled according to iRemainderPolicy
+func computeSplitQuantities(
+	iQuantity decimal.Decimal,
+	iRatios []string,
+	iRemainderPolicy string,
+	iPrecision int32,
+) (*SplitQuantities, error) {
+	if len(iRatios) == 0 {
+		return nil, fmt.Errorf("ratios cannot be empty")
+	}
+
+	if iPrecision < 0 || iPrecision > quantityPrecision {
+		return nil, fmt.Errorf("precision must be between 0 and %d", quantityPrecision)
+	}
+
+	hundred := decimal.NewFromInt(100)
+	totalRatio := decimal.NewFromInt(0)
+	quantities := []decimal.Decimal{}
+	remainder := iQuantity
+	largest := 0
+	for i, ratioString := range iRatios {
+		ratio, err := decimal.NewFromString(ratioString)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ratio.IsPositive() {
+			return nil, fmt.Errorf("ratios must be positive")
+		}
+		totalRatio = totalRatio.Add(ratio)
+
+		quantity := iQuantity.Mul(ratio).Div(hundred).Truncate(iPrecision)
+		quantities = append(quantities, quantity)
+		remainder = remainder.Sub(quantity)
+
+		if quantity.GreaterThan(quantities[largest]) {
+			largest = i
+		}
+	}
+
+	if !totalRatio.Equal(hundred) {
+		return nil, fmt.Errorf("ratios must add up to 100")
+	}
+
+	waste := decimal.NewFromInt(0)
+	switch iRemainderPolicy {
+	case eRemainderToFirst:
+		quantities[0] = quantities[0].Add(remainder)
+	case eRemainderToLast:
+		quantities[len(quantities)-1] = quantities[len(quantities)-1].Add(remainder)
+	case eRemainderToLargest:
+		quantities[largest] = quantities[largest].Add(remainder)
+	case eRemainderToWaste:
+		waste = remainder
+	default:
+		return nil, fmt.Errorf("unknown remainder policy %s", iRemainderPolicy)
+	}
+
+	ret := SplitQuantities{
+		Quantities: []string{},
+		Waste:      waste.String(),
+	}
+	for _, quantity := range quantities {
+		if !quantity.IsPositive() {
+			return nil, fmt.Errorf("ratios are too small for the quantity of the material")
+		}
+		ret.Quantities = append(ret.Quantities, quantity.String())
+	}
+
+	return &ret, nil
+}
+
+/// returns the quantities SplitMaterialByRatio will create, so that the new nodes can be signed beforehand
+func (c *MaterialContract) ComputeSplitQuantities(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iRatios []string,
+	iRemainderPolicy string,
+	iPrecision int32,
+) (*SplitQuantities, error) {
+	material, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	quantity, err := getEffectiveQuantity(iCtx, material)
+	if err != nil {
+		return nil, err
+	}
+
+	return computeSplitQuantities(quantity, iRatios, iRemainderPolicy, iPrecision)
+}
+
+/// same as SplitMaterial with child quantities computed from iRatios, see ComputeSplitQuantities
+func (c *MaterialContract) SplitMaterialByRatio(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iRatios []string,
+	iRemainderPolicy string,
+	iPrecision int32,
+	iNewNodeIds []string,
+	iNewNodeOwnerPublicKeys []string,
+	iCreatedTime time.Time,
+	iSignature string,
+	iNewNodeSignatures []string,
+) (*graph.TransactionReceipt, error) {
+	split, err := c.ComputeSplitQuantities(iCtx, iNodeId, iRatios, iRemainderPolicy, iPrecision)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.SplitMaterial(
+		iCtx,
+		iNodeId,
+		split.Quantities,
+		split.Waste,
+		iNewNodeIds,
+		iNewNodeOwnerPublicKeys,
+		iCreatedTime,
+		iSignature,
+		iNewNodeSignatures,
+	)
+}
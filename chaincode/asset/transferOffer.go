@@ -0,0 +1,307 @@
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	offerObjectType          = "offer"
+	cancelledOfferObjectType = "cancelledOffer"
+)
+
+/// A transfer waiting for the receiver's signature of the new node.
+/// Signature is the sender's signature of the finalized node, which carries NewOwnerPublicKey as in TransferMaterial
+type TransferOffer struct {
+	NodeId            string    `json:"NodeId"`
+	NewNodeId         string    `json:"NewNodeId"`
+	NewOwnerPublicKey string    `json:"NewOwnerPublicKey"`
+	NewOwnerMspId     string    `json:"NewOwnerMspId,omitempty" metadata:",optional"`
+	TransferTime      time.Time `json:"TransferTime"`
+	Signature         string    `json:"Signature"`
+	TxId              string    `json:"TxId"`
+}
+
+/// Signed by the sender
+type CancelTransferRequest struct {
+	NodeId    string `json:"NodeId"`
+	NewNodeId string `json:"NewNodeId"`
+	Signature string `json:"Signature"`
+}
+
+/// returns nil if there is no pending offer for iNodeId
+func getTransferOffer(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) (*TransferOffer, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(offerObjectType, []string{iNodeId})
+	if err != nil {
+		return nil, err
+	}
+
+	offerJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if offerJson == nil {
+		return nil, nil
+	}
+
+	var offer TransferOffer
+	err = json.Unmarshal(offerJson, &offer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &offer, nil
+}
+
+func deleteTransferOffer(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) error {
+	key, err := iCtx.GetStub().CreateCompositeKey(offerObjectType, []string{iNodeId})
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().DelState(key)
+}
+
+/// the sender's signature of a cancelled or rejected offer is public and must not be usable again, whichever transaction it is
+/// passed to
+func checkOfferNotWithdrawn(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iNewNodeId string,
+) error {
+	key, err := iCtx.GetStub().CreateCompositeKey(cancelledOfferObjectType, []string{iNodeId, iNewNodeId})
+	if err != nil {
+		return err
+	}
+
+	cancelled, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if cancelled == nil {
+		return nil
+	}
+
+	/// RejectTransfer also writes the cancelled index, tell the sender which of the two happened
+	rejectionKey, err := iCtx.GetStub().CreateCompositeKey(rejectionObjectType, []string{iNodeId, iNewNodeId})
+	if err != nil {
+		return err
+	}
+
+	rejection, err := iCtx.GetStub().GetState(rejectionKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if rejection != nil {
+		return fmt.Errorf("offer of %s to %s was rejected, a new node id must be used", iNodeId, iNewNodeId)
+	}
+
+	return fmt.Errorf("offer of %s to %s was cancelled, a new node id must be used", iNodeId, iNewNodeId)
+}
+
+/// materials with a pending offer cannot be modified until it is accepted or cancelled
+func checkNoPendingOffer(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) error {
+	offer, err := getTransferOffer(iCtx, iNodeId)
+	if err != nil {
+		return err
+	}
+
+	if offer != nil {
+		return fmt.Errorf("material %s has a pending transfer offer", iNodeId)
+	}
+
+	return nil
+}
+
+/// iSignature is the sender's signature of the finalized node pointing to iNewNodeId and carrying iNewOwnerPublicKey
+/// iNewOwnerMspId is required when iNewOwnerPublicKey is a bare key, as in TransferMaterial.
+/// an optional price can be passed in the transient map, see TransferPrice
+func (c *MaterialContract) OfferTransfer(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iNewNodeId string,
+	iNewOwnerPublicKey string,
+	iNewOwnerMspId string,
+	iSignature string,
+	iTransferTime time.Time,
+) (*graph.TransactionReceipt, error) {
+	material, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	if material.IsFinalized {
+		return nil, fmt.Errorf("node is already finalized")
+	}
+
+	transferTime, err := getOperationTime(iCtx, iTransferTime)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkNoPendingOffer(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkNotExpired(iCtx, material)
+	if err != nil {
+		return nil, err
+	}
+
+	graphContract := graph.GraphContract{}
+	nodeExists, err := graphContract.DoesNodeExists(iCtx, iNewNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	if nodeExists {
+		return nil, fmt.Errorf("node with id %s already exists", iNewNodeId)
+	}
+
+	err = checkOfferNotWithdrawn(iCtx, iNodeId, iNewNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = graphContract.VerifyTransferFinalization(iCtx, material, iNewNodeId, iNewOwnerPublicKey, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	offer := TransferOffer{
+		NodeId:            iNodeId,
+		NewNodeId:         iNewNodeId,
+		NewOwnerPublicKey: iNewOwnerPublicKey,
+		NewOwnerMspId:     iNewOwnerMspId,
+		TransferTime:      transferTime,
+		Signature:         iSignature,
+		TxId:              iCtx.GetStub().GetTxID(),
+	}
+	offerJson, err := json.Marshal(offer)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(offerObjectType, []string{iNodeId})
+	if err != nil {
+		return nil, err
+	}
+
+	err = iCtx.GetStub().PutState(key, offerJson)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putTransferPrice(iCtx, iNodeId, iNewNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putAuditEntry(iCtx, iNodeId, eTransferOffered, fmt.Sprintf("%s to %s", iNewNodeId, ownerFingerprint(iNewOwnerPublicKey))))
+}
+
+func (c *MaterialContract) GetTransferOffer(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) (*TransferOffer, error) {
+	offer, err := getTransferOffer(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	if offer == nil {
+		return nil, fmt.Errorf("no pending offer for node %s", iNodeId)
+	}
+
+	return offer, nil
+}
+
+/// iNewNodeSignature is the receiver's signature of the new node
+func (c *MaterialContract) AcceptTransfer(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iNewNodeSignature string,
+) (*graph.TransactionReceipt, error) {
+	offer, err := c.GetTransferOffer(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	material, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = deleteTransferOffer(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, c.transferMaterial(
+		iCtx,
+		eTransfer,
+		material,
+		offer.NewNodeId,
+		offer.NewOwnerPublicKey,
+		offer.NewOwnerMspId,
+		offer.Signature,
+		iNewNodeSignature,
+		offer.TransferTime,
+		"",
+	))
+}
+
+/// iSignature is the sender's signature of the CancelTransferRequest
+func (c *MaterialContract) CancelTransfer(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	offer, err := c.GetTransferOffer(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	material, err := c.GetMaterial(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	request := CancelTransferRequest{
+		NodeId:    iNodeId,
+		NewNodeId: offer.NewNodeId,
+	}
+	err = graph.VerifyPayload(material.OwnerPublicKey, &request, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putIndex(iCtx, cancelledOfferObjectType, []string{iNodeId, offer.NewNodeId})
+	if err != nil {
+		return nil, err
+	}
+
+	err = putAuditEntry(iCtx, iNodeId, eTransferCancelled, offer.NewNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, deleteTransferOffer(iCtx, iNodeId))
+}
@@ -0,0 +1,162 @@
+package asset_test
+
+import (
+	"sig_chain/chaincode/asset"
+	"sig_chain/chaincode/graph"
+	"sig_chain/pkg/client"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// the sender's signature of iMaterial once finalized by its transfer to iNewOwnerPublicKey as iNewNodeId
+func signTransfer(
+	t *testing.T,
+	iSender client.Signer,
+	iMaterial asset.Material,
+	iNewNodeId string,
+	iNewOwnerPublicKey string,
+) string {
+	header := iMaterial.GetHeader()
+	header.NewOwnerPublicKey = iNewOwnerPublicKey
+	iMaterial.SetHeader(header)
+
+	return signFinalization(t, iSender, iMaterial, iNewNodeId)
+}
+
+/// the node iMaterial is transferred to, which the receiver signs
+func makeTransferredMaterial(
+	iMaterial asset.Material,
+	iNewNodeId string,
+	iNewOwnerPublicKey string,
+) asset.Material {
+	iMaterial.SetHeader(graph.MakeNodeHeader(
+		iNewNodeId,
+		iMaterial.Type,
+		false,
+		graph.MakeHashSet(graph.HashId(iMaterial.Id)),
+		graph.MakeHashSet(),
+		iNewOwnerPublicKey,
+		testTime,
+		"",
+	))
+
+	return iMaterial
+}
+
+func TestOfferAndAcceptTransfer(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	bob := makeTestSigner(t)
+	l.createMaterial("m1", "10", alice)
+
+	signature := signTransfer(t, alice, *l.getMaterial("m1"), "m2", bob.GetPublicKey())
+
+	l.mustSubmit("offer", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.OfferTransfer(iCtx, "m1", "m2", bob.GetPublicKey(), "Org1MSP", signature, testTime)
+		return err
+	})
+
+	l.mustFail("offer twice", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.OfferTransfer(iCtx, "m1", "m2", bob.GetPublicKey(), "Org1MSP", signature, testTime)
+		return err
+	})
+
+	newMaterial := makeTransferredMaterial(*l.getMaterial("m1"), "m2", bob.GetPublicKey())
+
+	l.mustFail("accept signed by the sender", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AcceptTransfer(iCtx, "m1", signNode(t, alice, &newMaterial))
+		return err
+	})
+
+	l.mustSubmit("accept", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AcceptTransfer(iCtx, "m1", signNode(t, bob, &newMaterial))
+		return err
+	})
+
+	if l.getMaterial("m2").OwnerPublicKey != bob.GetPublicKey() {
+		t.Fatal("m2 is not owned by the receiver")
+	}
+	l.checkNodeSignature("m1")
+	l.checkNodeSignature("m2")
+}
+
+func TestCancelledOfferCannotBeReplayed(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	bob := makeTestSigner(t)
+	l.createMaterial("m1", "10", alice)
+
+	signature := signTransfer(t, alice, *l.getMaterial("m1"), "m2", bob.GetPublicKey())
+
+	l.mustSubmit("offer", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.OfferTransfer(iCtx, "m1", "m2", bob.GetPublicKey(), "Org1MSP", signature, testTime)
+		return err
+	})
+
+	cancellation := asset.CancelTransferRequest{NodeId: "m1", NewNodeId: "m2"}
+	l.mustFail("cancel signed by the receiver", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.CancelTransfer(iCtx, "m1", signPayload(t, bob, &cancellation))
+		return err
+	})
+
+	l.mustSubmit("cancel", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.CancelTransfer(iCtx, "m1", signPayload(t, alice, &cancellation))
+		return err
+	})
+
+	l.mustFail("accept cancelled offer", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AcceptTransfer(iCtx, "m1", "")
+		return err
+	})
+
+	l.mustFail("replay offer", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.OfferTransfer(iCtx, "m1", "m2", bob.GetPublicKey(), "Org1MSP", signature, testTime)
+		return err
+	})
+
+	newMaterial := makeTransferredMaterial(*l.getMaterial("m1"), "m2", bob.GetPublicKey())
+	l.mustFail("replay offer signature in a direct transfer", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.TransferMaterial(iCtx, "m1", "m2", bob.GetPublicKey(), "Org1MSP", signature, signNode(t, bob, &newMaterial), testTime)
+		return err
+	})
+
+	if l.getMaterial("m1").IsFinalized {
+		t.Fatal("m1 is finalized by a cancelled offer")
+	}
+}
+
+/// a bare key does not name its organization, so the peers which endorse updates of the new node must be given
+func TestTransferToBareKeyRequiresMsp(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	bob := makeTestSigner(t)
+	l.createMaterial("m1", "10", alice)
+
+	signature := signTransfer(t, alice, *l.getMaterial("m1"), "m2", bob.GetPublicKey())
+	newMaterial := makeTransferredMaterial(*l.getMaterial("m1"), "m2", bob.GetPublicKey())
+	newNodeSignature := signNode(t, bob, &newMaterial)
+
+	l.mustFail("transfer without the msp of the new owner", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.TransferMaterial(iCtx, "m1", "m2", bob.GetPublicKey(), "", signature, newNodeSignature, testTime)
+		return err
+	})
+
+	l.mustSubmit("transfer", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.TransferMaterial(iCtx, "m1", "m2", bob.GetPublicKey(), "Org2MSP", signature, newNodeSignature, testTime)
+		return err
+	})
+
+	l.mustSubmit("get endorsement policy of m2", func(iCtx contractapi.TransactionContextInterface) error {
+		policy, err := iCtx.GetStub().GetStateValidationParameter("m2")
+		if err != nil {
+			return err
+		}
+
+		if !strings.Contains(string(policy), "Org2MSP") {
+			t.Fatal("m2 is not endorsed by the organization of its owner")
+		}
+		return nil
+	})
+}
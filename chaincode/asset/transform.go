@@ -0,0 +1,201 @@
+package asset
+
+import (
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/shopspring/decimal"
+)
+
+/// One of the products of a transformation, Signature is the owner's signature of the output node
+type TransformOutput struct {
+	NodeId         string    `json:"NodeId"`
+	Name           string    `json:"Name"`
+	Unit           string    `json:"Unit"`
+	Quantity       string    `json:"Quantity"`
+	LotNumber      string    `json:"LotNumber"`
+	BatchNumber    string    `json:"BatchNumber"`
+	ExpiryDate     time.Time `json:"ExpiryDate"`
+	OwnerPublicKey string    `json:"OwnerPublicKey"`
+	Signature      string    `json:"Signature"`
+}
+
+/// turns iNodeIds into different materials, e.g. soybeans into oil and meal
+/// iSignatures are the signatures for the finalized input nodes
+/// every input and output must be convertible to iWasteUnit, in which inputs must add up to outputs and iWaste
+func (c *MaterialContract) TransformMaterials(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeIds []string,
+	iSignatures []string,
+	iOutputs []TransformOutput,
+	iWaste string,
+	iWasteUnit string,
+	iCreatedTime time.Time,
+) (*graph.TransactionReceipt, error) {
+	if len(iNodeIds) == 0 {
+		return nil, fmt.Errorf("input node ids cannot be empty")
+	}
+
+	if len(iNodeIds) != len(iSignatures) {
+		return nil, fmt.Errorf("mismatch node ids and signatures")
+	}
+
+	if len(iOutputs) == 0 {
+		return nil, fmt.Errorf("outputs cannot be empty")
+	}
+
+	createdTime, err := getOperationTime(iCtx, iCreatedTime)
+	if err != nil {
+		return nil, err
+	}
+
+	waste, err := decimal.NewFromString(iWaste)
+	if err != nil {
+		return nil, err
+	}
+
+	if waste.IsNegative() {
+		return nil, fmt.Errorf("waste cannot be negative")
+	}
+
+	inputQuantity := decimal.NewFromInt(0)
+	parts := []compositionPart{}
+	parents := []graph.NodeI{}
+	for _, nodeId := range iNodeIds {
+		material, err := c.GetMaterial(iCtx, nodeId)
+		if err != nil {
+			return nil, err
+		}
+
+		err = checkNotExpired(iCtx, material)
+		if err != nil {
+			return nil, err
+		}
+
+		err = checkNoPendingOffer(iCtx, nodeId)
+		if err != nil {
+			return nil, err
+		}
+
+		materialQuantity, err := getEffectiveQuantity(iCtx, material)
+		if err != nil {
+			return nil, err
+		}
+
+		/// the inputs are consumed so nothing is left for their beneficiaries
+		err = checkReservations(iCtx, nodeId, []allocation{})
+		if err != nil {
+			return nil, err
+		}
+
+		convertedQuantity, err := convertQuantity(iCtx, materialQuantity, material.Unit, iWasteUnit)
+		if err != nil {
+			return nil, err
+		}
+		inputQuantity = inputQuantity.Add(convertedQuantity)
+		parts = append(parts, compositionPart{getComposition(material), convertedQuantity})
+
+		parents = append(parents, &Material{})
+	}
+
+	/// co-products are all made of the same inputs
+	composition, err := mixCompositions(parts)
+	if err != nil {
+		return nil, err
+	}
+
+	outputQuantity := decimal.NewFromInt(0)
+	outputIds := []string{}
+	children := []graph.NodeI{}
+	for _, output := range iOutputs {
+		quantity, err := decimal.NewFromString(output.Quantity)
+		if err != nil {
+			return nil, err
+		}
+
+		if !quantity.IsPositive() {
+			return nil, fmt.Errorf("output quantities must be positive")
+		}
+
+		err = checkQueryTime("expiry date", output.ExpiryDate)
+		if err != nil {
+			return nil, err
+		}
+
+		convertedQuantity, err := convertQuantity(iCtx, quantity, output.Unit, iWasteUnit)
+		if err != nil {
+			return nil, err
+		}
+		outputQuantity = outputQuantity.Add(convertedQuantity)
+
+		nodeHeader := graph.MakeNodeHeader(
+			output.NodeId,
+			eMaterial,
+			false,
+			graph.MakeHashSet(),
+			graph.MakeHashSet(),
+			output.OwnerPublicKey,
+			createdTime,
+			output.Signature,
+		)
+
+		err = setTimeSource(iCtx, &nodeHeader)
+		if err != nil {
+			return nil, err
+		}
+		material := MakeMaterial(
+			output.Name,
+			output.Unit,
+			quantity.String(),
+			output.LotNumber,
+			output.BatchNumber,
+			output.ExpiryDate,
+			composition,
+			"",
+			map[string]string{},
+			"",
+			"",
+			"",
+			"",
+			nodeHeader,
+		)
+		children = append(children, &material)
+		outputIds = append(outputIds, output.NodeId)
+	}
+
+	/// conversions are rounded so allow for the rounding error of every converted quantity
+	tolerance := decimal.New(int64(len(iNodeIds)+len(iOutputs)), -quantityPrecision)
+	if inputQuantity.Sub(outputQuantity.Add(waste)).Abs().GreaterThan(tolerance) {
+		return nil, fmt.Errorf("incorrect quantities")
+	}
+
+	graphContract := graph.GraphContract{}
+	err = graphContract.CreateDerivedNodes(
+		iCtx,
+		iNodeIds,
+		parents,
+		iSignatures,
+		children,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, child := range children {
+		err = putMaterialIndexes(iCtx, child.(*Material))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putDerivation(
+		iCtx,
+		eTransform,
+		iNodeIds,
+		outputIds,
+		waste.String(),
+		iWasteUnit,
+	))
+}
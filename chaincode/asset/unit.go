@@ -0,0 +1,179 @@
+package asset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	unitObjectType = "unit"
+
+	/// number of decimal places kept after a conversion between two different units
+	quantityPrecision int32 = 8
+)
+
+/// One Name is worth Factor BaseUnit, e.g. Name "kg", BaseUnit "g", Factor "1000".
+/// Units sharing the same base unit can be converted to each other.
+/// Units that are not registered can only be converted to themselves.
+type UnitDefinition struct {
+	Name      string `json:"Name"`
+	BaseUnit  string `json:"BaseUnit"`
+	Factor    string `json:"Factor"`
+	Signature string `json:"Signature"`
+}
+
+func getUnitDefinition(
+	iCtx contractapi.TransactionContextInterface,
+	iName string,
+) (*UnitDefinition, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(unitObjectType, []string{iName})
+	if err != nil {
+		return nil, err
+	}
+
+	unitJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if unitJson == nil {
+		return &UnitDefinition{
+			Name:     iName,
+			BaseUnit: iName,
+			Factor:   "1",
+		}, nil
+	}
+
+	var unit UnitDefinition
+	err = json.Unmarshal(unitJson, &unit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &unit, nil
+}
+
+/// iSignature is the administrator's signature of the unit definition
+func (c *MaterialContract) RegisterUnit(
+	iCtx contractapi.TransactionContextInterface,
+	iName string,
+	iBaseUnit string,
+	iFactor string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	if iName == "" || iBaseUnit == "" {
+		return nil, fmt.Errorf("unit names cannot be empty")
+	}
+
+	factor, err := decimal.NewFromString(iFactor)
+	if err != nil {
+		return nil, err
+	}
+
+	if !factor.IsPositive() {
+		return nil, fmt.Errorf("factor must be positive")
+	}
+
+	if iName == iBaseUnit && !factor.Equal(decimal.NewFromInt(1)) {
+		return nil, fmt.Errorf("base unit must have a factor of 1")
+	}
+
+	baseUnit, err := getUnitDefinition(iCtx, iBaseUnit)
+	if err != nil {
+		return nil, err
+	}
+
+	if baseUnit.BaseUnit != iBaseUnit {
+		return nil, fmt.Errorf("%s is not a base unit", iBaseUnit)
+	}
+
+	unit := UnitDefinition{
+		Name:     iName,
+		BaseUnit: iBaseUnit,
+		Factor:   iFactor,
+	}
+	err = verifyAdministratorSignature(iCtx, &unit, iSignature)
+	if err != nil {
+		return nil, err
+	}
+	unit.Signature = iSignature
+
+	unitJson, err := json.Marshal(unit)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(unitObjectType, []string{iName})
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, iCtx.GetStub().PutState(key, unitJson))
+}
+
+func (c *MaterialContract) GetUnit(
+	iCtx contractapi.TransactionContextInterface,
+	iName string,
+) (*UnitDefinition, error) {
+	return getUnitDefinition(iCtx, iName)
+}
+
+func convertQuantity(
+	iCtx contractapi.TransactionContextInterface,
+	iQuantity decimal.Decimal,
+	iFromUnit string,
+	iToUnit string,
+) (decimal.Decimal, error) {
+	if iFromUnit == iToUnit {
+		return iQuantity, nil
+	}
+
+	fromUnit, err := getUnitDefinition(iCtx, iFromUnit)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	toUnit, err := getUnitDefinition(iCtx, iToUnit)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	if fromUnit.BaseUnit != toUnit.BaseUnit {
+		return decimal.Decimal{}, fmt.Errorf("cannot convert %s to %s", iFromUnit, iToUnit)
+	}
+
+	fromFactor, err := decimal.NewFromString(fromUnit.Factor)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	toFactor, err := decimal.NewFromString(toUnit.Factor)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	return iQuantity.Mul(fromFactor).DivRound(toFactor, quantityPrecision), nil
+}
+
+func (c *MaterialContract) ConvertQuantity(
+	iCtx contractapi.TransactionContextInterface,
+	iQuantity string,
+	iFromUnit string,
+	iToUnit string,
+) (string, error) {
+	quantity, err := decimal.NewFromString(iQuantity)
+	if err != nil {
+		return "", err
+	}
+
+	converted, err := convertQuantity(iCtx, quantity, iFromUnit, iToUnit)
+	if err != nil {
+		return "", err
+	}
+
+	return converted.String(), nil
+}
@@ -0,0 +1,76 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/msp"
+)
+
+/// signature policy requiring the endorsement of a peer of iMspId, as built by the statebased package
+func makePeerEndorsementPolicy(
+	iMspId string,
+) ([]byte, error) {
+	principal, err := proto.Marshal(&msp.MSPRole{
+		MspIdentifier: iMspId,
+		Role:          msp.MSPRole_PEER,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	policy := common.SignaturePolicyEnvelope{
+		Version: 0,
+		Rule: &common.SignaturePolicy{
+			Type: &common.SignaturePolicy_NOutOf_{
+				NOutOf: &common.SignaturePolicy_NOutOf{
+					N: 1,
+					Rules: []*common.SignaturePolicy{
+						{Type: &common.SignaturePolicy_SignedBy{SignedBy: 0}},
+					},
+				},
+			},
+		},
+		Identities: []*msp.MSPPrincipal{
+			{
+				PrincipalClassification: msp.MSPPrincipal_ROLE,
+				Principal:               principal,
+			},
+		},
+	}
+
+	return proto.Marshal(&policy)
+}
+
+/// restricts the endorsement of later updates of iNodeId to the peers of its owner's organization.
+/// iOwnerMspId is required when iOwnerPublicKey is a bare key, which does not name its organization. It can be
+/// empty for a certificate, otherwise it must be the MSP of the certificate
+func setOwnerEndorsementPolicy(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iOwnerPublicKey string,
+	iOwnerMspId string,
+) error {
+	mspId, err := GetIdentityMspId(iCtx, iOwnerPublicKey)
+	if err != nil {
+		return err
+	}
+
+	if mspId == "" {
+		if iOwnerMspId == "" {
+			return fmt.Errorf("msp id of the owner of %s is required, its public key does not name its organization", iNodeId)
+		}
+		mspId = iOwnerMspId
+	} else if iOwnerMspId != "" && iOwnerMspId != mspId {
+		return fmt.Errorf("owner of %s is a member of %s, not %s", iNodeId, mspId, iOwnerMspId)
+	}
+
+	policy, err := makePeerEndorsementPolicy(mspId)
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().SetStateValidationParameter(iNodeId, policy)
+}
@@ -0,0 +1,132 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const eGenericNode = "eGenericNode"
+
+func IsGenericNodeType(
+	iType string,
+) bool {
+	return iType == eGenericNode
+}
+
+/// Concrete node used by the invokable graph functions, since contractapi cannot build NodeI parameters.
+/// Nodes of other types are owned by the contracts defining them and cannot be modified through these functions
+type GenericNode struct {
+	NodeHeader
+	Data map[string]string `json:"Data"`
+}
+
+func (n *GenericNode) GetHeader() NodeHeader {
+	return n.NodeHeader
+}
+func (n *GenericNode) SetHeader(iHeader NodeHeader) {
+	n.NodeHeader = iHeader
+}
+
+func (c *GraphContract) getGenericNode(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) (*GenericNode, error) {
+	var node GenericNode
+	err := c.GetNode(iCtx, iNodeId, &node)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.Type != eGenericNode {
+		return nil, fmt.Errorf("node %s is a %s and must be modified through its own contract", iNodeId, node.Type)
+	}
+
+	return &node, nil
+}
+
+/// iNodeJson is the json of a GenericNode, its Type must be eGenericNode and its Signature the owner's
+/// signature of the node
+func (c *GraphContract) CreateGenericNode(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeJson string,
+) (*TransactionReceipt, error) {
+	var node GenericNode
+	err := json.Unmarshal([]byte(iNodeJson), &node)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.Type != eGenericNode {
+		return nil, fmt.Errorf("node type must be %s", eGenericNode)
+	}
+
+	if node.IsFinalized || len(node.NextNodeHashedIds) > 0 {
+		return nil, fmt.Errorf("new nodes cannot be finalized")
+	}
+
+	if node.PreviousNodeHashedIds == nil {
+		node.PreviousNodeHashedIds = MakeHashSet()
+	}
+	if node.NextNodeHashedIds == nil {
+		node.NextNodeHashedIds = MakeHashSet()
+	}
+	if node.Data == nil {
+		node.Data = map[string]string{}
+	}
+
+	return MakeTransactionReceiptIfSucceeded(iCtx, c.CreateNode(iCtx, &node))
+}
+
+/// iSignature is the owner's signature of the finalized node
+func (c *GraphContract) FinalizeGenericNode(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iSignature string,
+) (*TransactionReceipt, error) {
+	_, err := c.getGenericNode(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	return MakeTransactionReceiptIfSucceeded(iCtx, c.FinalizeNode(iCtx, iNodeId, iSignature, &GenericNode{}))
+}
+
+/// iSignature and iNextNodeSignature are the owners' signatures of both nodes once linked
+func (c *GraphContract) CreateGenericEdge(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iSignature string,
+	iNextNodeId string,
+	iNextNodeSignature string,
+) (*TransactionReceipt, error) {
+	_, err := c.getGenericNode(iCtx, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.getGenericNode(iCtx, iNextNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	return MakeTransactionReceiptIfSucceeded(iCtx, c.CreateEdge(iCtx, iNodeId, &GenericNode{}, iSignature, iNextNodeId, &GenericNode{}, iNextNodeSignature))
+}
+
+/// returns the stored json of any node, whatever its type
+func (c *GraphContract) GetNodeJson(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) (string, error) {
+	nodeJson, err := iCtx.GetStub().GetState(iNodeId)
+	if err != nil {
+		return "", fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if nodeJson == nil {
+		return "", fmt.Errorf("Node with id %s does not exist", iNodeId)
+	}
+
+	return string(nodeJson), nil
+}
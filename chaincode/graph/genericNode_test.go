@@ -0,0 +1,101 @@
+package graph_test
+
+import (
+	"encoding/json"
+	"sig_chain/chaincode/graph"
+	"sig_chain/pkg/client"
+	"sig_chain/pkg/testutil"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// the owner's signature of iNode in its signed form
+func signGenericNode(
+	t *testing.T,
+	iOwner client.Signer,
+	iNode graph.GenericNode,
+) string {
+	iNode.SetHeader(graph.GetSignedHeader(iNode.GetHeader()))
+	return sign(t, iOwner, &iNode)
+}
+
+func getGenericNode(
+	t *testing.T,
+	iLedger *testutil.MockLedger,
+	iNodeId string,
+) graph.GenericNode {
+	var node graph.GenericNode
+	submit(t, iLedger, "get "+iNodeId, func(iCtx contractapi.TransactionContextInterface) error {
+		return (&graph.GraphContract{}).GetNode(iCtx, iNodeId, &node)
+	})
+
+	return node
+}
+
+/// the stored nodes carry the signatures of their last update, not the ones they were created with
+func checkStoredSignature(
+	t *testing.T,
+	iLedger *testutil.MockLedger,
+	iNodeId string,
+) {
+	t.Helper()
+	node := getGenericNode(t, iLedger, iNodeId)
+	submit(t, iLedger, "verify "+iNodeId, func(iCtx contractapi.TransactionContextInterface) error {
+		return (&graph.GraphContract{}).Verify(iCtx, node.Signature, &node)
+	})
+}
+
+func TestEdgeAndFinalizationKeepTheirSignatures(t *testing.T) {
+	owner := makeTestSigner(t)
+	ledger := testutil.MakeMockLedger()
+	contract := graph.GraphContract{}
+
+	for _, nodeId := range []string{"a", "b"} {
+		node := graph.GenericNode{
+			NodeHeader: graph.MakeNodeHeader(
+				nodeId,
+				"eGenericNode",
+				false,
+				graph.MakeHashSet(),
+				graph.MakeHashSet(),
+				owner.GetPublicKey(),
+				time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				"",
+			),
+			Data: map[string]string{},
+		}
+		node.Signature = signGenericNode(t, owner, node)
+		nodeJson, err := json.Marshal(&node)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		submit(t, ledger, "create "+nodeId, func(iCtx contractapi.TransactionContextInterface) error {
+			_, err := contract.CreateGenericNode(iCtx, string(nodeJson))
+			return err
+		})
+	}
+
+	a := getGenericNode(t, ledger, "a")
+	a.NextNodeHashedIds = a.NextNodeHashedIds.Add(graph.HashId("b"))
+	b := getGenericNode(t, ledger, "b")
+	b.PreviousNodeHashedIds = b.PreviousNodeHashedIds.Add(graph.HashId("a"))
+	submit(t, ledger, "edge", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := contract.CreateGenericEdge(iCtx, "a", signGenericNode(t, owner, a), "b", signGenericNode(t, owner, b))
+		return err
+	})
+
+	checkStoredSignature(t, ledger, "a")
+	checkStoredSignature(t, ledger, "b")
+
+	b = getGenericNode(t, ledger, "b")
+	b.IsFinalized = true
+	submit(t, ledger, "finalize", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := contract.FinalizeGenericNode(iCtx, "b", signGenericNode(t, owner, b))
+		return err
+	})
+
+	checkStoredSignature(t, ledger, "b")
+}
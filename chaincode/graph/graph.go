@@ -2,9 +2,13 @@ package graph
 
 import (
 	"crypto"
+	"crypto/ecdsa"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/sha512"
 	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
@@ -13,20 +17,74 @@ import (
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
+const (
+	edgeObjectType        = "edge"
+	reverseEdgeObjectType = "reverseEdge"
+)
+
 // SmartContract provides functions for managing an Asset
+/// Functions taking nodes are not exposed when registered in the chaincode since contractapi cannot
+/// build NodeI parameters, clients use the GenericNode functions instead
 type GraphContract struct {
+	contractapi.Contract
+}
+
+func (c *GraphContract) GetIgnoredFunctions() []string {
+	return []string{
+		"Verify",
+		"VerifyFinalization",
+		"VerifyTransferFinalization",
+		"GetNode",
+		"FinalizeNode",
+		"CreateEdge",
+		"CreateChildrenNodesAndFinalize",
+		"CreateDerivedNodes",
+		"CreateNode",
+		"TransferNodeOwnership",
+	}
+}
+
+func (c *GraphContract) GetEvaluateTransactions() []string {
+	return []string{
+		"DoesNodeExists",
+		"AreIdsAvailable",
+		"GetNextNodeIds",
+		"GetPreviousNodeIds",
+		"GetNodeJson",
+		"GetTransactionSubmitter",
+	}
 }
 
 /// Increment version after every update so that the next time an update is needed,
 /// a different signature is needed
 type NodeHeader struct {
-	Id                    string          `json:"Id"`
-	IsFinalized           bool            `json:"IsFinalized"`
-	PreviousNodeHashedIds map[string]bool `json:"PreviousNodeHashedIds"` /// used as a set
-	NextNodeHashedIds     map[string]bool `json:"NextNodeHashedIds"`     /// used as a set
-	OwnerPublicKey        string          `json:"OwnerPublicKey"`
-	CreatedTime           time.Time       `json:"CreatedTime"`
-	Signature             string          `json:"Signature"`
+	Id                    string    `json:"Id"`
+	Type                  string    `json:"Type"` /// lets rich queries tell node types apart
+	IsFinalized           bool      `json:"IsFinalized"`
+	PreviousNodeHashedIds HashSet   `json:"PreviousNodeHashedIds"`
+	NextNodeHashedIds     HashSet   `json:"NextNodeHashedIds"`
+	OwnerPublicKey        string    `json:"OwnerPublicKey"`
+	CreatedTime           time.Time `json:"CreatedTime"`
+	Signature             string    `json:"Signature"`
+	SchemaVersion         int       `json:"SchemaVersion,omitempty"`                          /// stamped when the node is written, not covered by the signature
+	NewOwnerPublicKey     string    `json:"NewOwnerPublicKey,omitempty" metadata:",optional"` /// set on the node finalized by a transfer, so that the signature of the previous owner binds the new owner
+	SignedJson            string    `json:"SignedJson,omitempty" metadata:",optional"`        /// json signed by the owner of a node migrated from hashed id sets stored as maps, see VerifyNodeSignature
+	IsTransactionTime     bool      `json:"IsTransactionTime,omitempty" metadata:",optional"` /// CreatedTime is the timestamp of the transaction which wrote the node, see GetSignedHeader
+}
+
+/// the header the owner of a node signs, i.e. without its signature nor its schema version. The created time of
+/// a node created at the time of its transaction is not known when the node is signed, it is signed as the zero
+/// time
+func GetSignedHeader(
+	iHeader NodeHeader,
+) NodeHeader {
+	iHeader.Signature = ""
+	iHeader.SchemaVersion = 0
+	if iHeader.IsTransactionTime {
+		iHeader.CreatedTime = time.Time{}
+	}
+
+	return iHeader
 }
 
 type NodeI interface {
@@ -36,15 +94,17 @@ type NodeI interface {
 
 func MakeNodeHeader(
 	iId string,
+	iType string,
 	iIsFinalized bool,
-	iPreviousNodeHashedIds map[string]bool,
-	iNextNodeHashedIds map[string]bool,
+	iPreviousNodeHashedIds HashSet,
+	iNextNodeHashedIds HashSet,
 	iOwnerPublicKey string,
 	iCreatedTime time.Time,
 	iSignature string,
 ) NodeHeader {
 	return NodeHeader{
 		Id:                    iId,
+		Type:                  iType,
 		IsFinalized:           iIsFinalized,
 		NextNodeHashedIds:     iNextNodeHashedIds,
 		PreviousNodeHashedIds: iPreviousNodeHashedIds,
@@ -54,11 +114,105 @@ func MakeNodeHeader(
 	}
 }
 
-func parsePublicKey(
+/// iPublicKey is either a PKCS1 RSA key, a PKIX key or an X.509 certificate such as a Fabric enrollment certificate
+func ParsePublicKey(
 	iPublicKey string,
 ) (interface{}, error) {
 	block, _ := pem.Decode([]byte(iPublicKey))
-	return x509.ParsePKCS1PublicKey(block.Bytes)
+	if block == nil {
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	switch block.Type {
+	case "CERTIFICATE":
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return certificate.PublicKey, nil
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+}
+
+/// hex encoded, raw hashes are not valid UTF-8 and would be altered by json
+func HashId(
+	iId string,
+) string {
+	hash := sha512.Sum512([]byte(iId))
+	return hex.EncodeToString(hash[:])
+}
+
+/// signatures are base64 encoded wherever they are passed or stored, raw signatures are not valid UTF-8 and
+/// would be altered by json
+func EncodeSignature(
+	iSignature []byte,
+) string {
+	return base64.StdEncoding.EncodeToString(iSignature)
+}
+
+/// strict so that a signature has a single encoding
+func DecodeSignature(
+	iSignature string,
+) ([]byte, error) {
+	signature, err := base64.StdEncoding.Strict().DecodeString(iSignature)
+	if err != nil {
+		return nil, fmt.Errorf("signature must be base64 encoded: %v", err)
+	}
+
+	return signature, nil
+}
+
+/// iSignature is base64 encoded, see EncodeSignature
+func VerifySignature(
+	iPublicKey string,
+	iMessage []byte,
+	iSignature string,
+) error {
+	signature, err := DecodeSignature(iSignature)
+	if err != nil {
+		return err
+	}
+
+	ifc, err := ParsePublicKey(iPublicKey)
+	if err != nil {
+		return err
+	}
+
+	switch key := ifc.(type) {
+	case *rsa.PublicKey:
+		hash := sha512.Sum512(iMessage)
+		err = rsa.VerifyPKCS1v15(key, crypto.SHA512, hash[:], signature)
+		if err != nil {
+			return fmt.Errorf("verify err: %s", err.Error())
+		}
+	case *ecdsa.PublicKey:
+		/// Fabric identities sign with SHA-256
+		hash := sha256.Sum256(iMessage)
+		if !ecdsa.VerifyASN1(key, hash[:], signature) {
+			return fmt.Errorf("verify err: invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported key format")
+	}
+
+	return nil
+}
+
+/// iPayload is marshalled as is, the signature field of iPayload must be cleared by the caller
+func VerifyPayload(
+	iPublicKey string,
+	iPayload interface{},
+	iSignature string,
+) error {
+	payloadJson, err := json.Marshal(iPayload)
+	if err != nil {
+		return err
+	}
+
+	return VerifySignature(iPublicKey, payloadJson, iSignature)
 }
 
 func (c *GraphContract) Verify(
@@ -66,9 +220,8 @@ func (c *GraphContract) Verify(
 	iSignature string,
 	iNode NodeI,
 ) error {
-	noSignatureHeader := iNode.GetHeader()
+	noSignatureHeader := GetSignedHeader(iNode.GetHeader())
 	originalHeader := iNode.GetHeader()
-	noSignatureHeader.Signature = ""
 
 	defer func() {
 		iNode.SetHeader(originalHeader)
@@ -76,27 +229,60 @@ func (c *GraphContract) Verify(
 	iNode.SetHeader(noSignatureHeader)
 
 	json, err := json.Marshal(iNode)
-	fmt.Println("json: ", string(json))
 	if err != nil {
 		return err
 	}
 
-	hash := sha512.Sum512(json)
-	ifc, err := parsePublicKey(iNode.GetHeader().OwnerPublicKey)
+	err = VerifyIdentity(iCtx, iNode.GetHeader().OwnerPublicKey)
 	if err != nil {
 		return err
 	}
-	key, ok := ifc.(*rsa.PublicKey)
-	if !ok {
-		return fmt.Errorf("unsupported key format")
-	}
 
-	err = rsa.VerifyPKCS1v15(key, crypto.SHA512, hash[:], []byte(iSignature))
-	if err != nil {
-		return fmt.Errorf("verify err: %s", err.Error())
+	return VerifySignature(iNode.GetHeader().OwnerPublicKey, json, iSignature)
+}
+
+/// verifies iSignature against iNode once finalized and pointing to iNextNodeIds, iNode is left unchanged
+func (c *GraphContract) VerifyFinalization(
+	iCtx contractapi.TransactionContextInterface,
+	iNode NodeI,
+	iNextNodeIds []string,
+	iSignature string,
+) error {
+	originalHeader := iNode.GetHeader()
+	defer func() {
+		iNode.SetHeader(originalHeader)
+	}()
+
+	finalizedHeader := iNode.GetHeader()
+	finalizedHeader.IsFinalized = true
+	finalizedHeader.NextNodeHashedIds = MakeHashSet(originalHeader.NextNodeHashedIds...)
+	for _, nextNodeId := range iNextNodeIds {
+		finalizedHeader.NextNodeHashedIds = finalizedHeader.NextNodeHashedIds.Add(HashId(nextNodeId))
 	}
+	iNode.SetHeader(finalizedHeader)
 
-	return err
+	return c.Verify(iCtx, iSignature, iNode)
+}
+
+/// verifies iSignature against iNode once finalized by the transfer of iNewNodeId to iNewOwnerPublicKey, iNode is
+/// left unchanged
+func (c *GraphContract) VerifyTransferFinalization(
+	iCtx contractapi.TransactionContextInterface,
+	iNode NodeI,
+	iNewNodeId string,
+	iNewOwnerPublicKey string,
+	iSignature string,
+) error {
+	originalHeader := iNode.GetHeader()
+	defer func() {
+		iNode.SetHeader(originalHeader)
+	}()
+
+	transferredHeader := iNode.GetHeader()
+	transferredHeader.NewOwnerPublicKey = iNewOwnerPublicKey
+	iNode.SetHeader(transferredHeader)
+
+	return c.VerifyFinalization(iCtx, iNode, []string{iNewNodeId}, iSignature)
 }
 
 func (c *GraphContract) GetNode(
@@ -114,6 +300,12 @@ func (c *GraphContract) GetNode(
 		return fmt.Errorf("Token with id %s does not exist", iNodeId)
 	}
 
+	/// nodes of older versions are upgraded when read, and stored in the current version when next written
+	nodeJson, _, err = migrateNodeJson(iCtx, iNodeId, nodeJson)
+	if err != nil {
+		return err
+	}
+
 	err = json.Unmarshal(nodeJson, oNode)
 	if err != nil {
 		return err
@@ -145,7 +337,10 @@ func (c *GraphContract) FinalizeNode(
 		return err
 	}
 
-	thisNodeJson, err := json.Marshal(iNode)
+	newHeader.Signature = iSignature
+	iNode.SetHeader(newHeader)
+
+	thisNodeJson, err := marshalNode(iNode)
 	if err != nil {
 		return err
 	}
@@ -181,9 +376,13 @@ func (c *GraphContract) CreateEdge(
 		return fmt.Errorf("next node is already finalized")
 	}
 
-	hasher := sha512.New()
-	iNode.GetHeader().NextNodeHashedIds[string(hasher.Sum([]byte(nextNodeId)))] = true
-	iNextNode.GetHeader().PreviousNodeHashedIds[string(hasher.Sum([]byte(id)))] = true
+	header := iNode.GetHeader()
+	header.NextNodeHashedIds = header.NextNodeHashedIds.Add(HashId(nextNodeId))
+	iNode.SetHeader(header)
+
+	nextHeader := iNextNode.GetHeader()
+	nextHeader.PreviousNodeHashedIds = nextHeader.PreviousNodeHashedIds.Add(HashId(id))
+	iNextNode.SetHeader(nextHeader)
 
 	err = c.Verify(iCtx, iNewSignature, iNode)
 	if err != nil {
@@ -195,12 +394,17 @@ func (c *GraphContract) CreateEdge(
 		return err
 	}
 
-	thisNodeJson, err := json.Marshal(iNode)
+	header.Signature = iNewSignature
+	iNode.SetHeader(header)
+	nextHeader.Signature = iNextNodeNewSignature
+	iNextNode.SetHeader(nextHeader)
+
+	thisNodeJson, err := marshalNode(iNode)
 	if err != nil {
 		return err
 	}
 
-	nextNodeJson, err := json.Marshal(iNextNode)
+	nextNodeJson, err := marshalNode(iNextNode)
 	if err != nil {
 		return err
 	}
@@ -215,7 +419,7 @@ func (c *GraphContract) CreateEdge(
 		return err
 	}
 
-	return nil
+	return putEdge(iCtx, id, nextNodeId)
 }
 
 /// new nodes reference to updated node
@@ -241,18 +445,17 @@ func (c *GraphContract) CreateChildrenNodesAndFinalize(
 	}
 
 	for _, node := range iChildren {
-		idHash := sha512.Sum512([]byte(node.GetHeader().Id))
-		header.NextNodeHashedIds[string(idHash[:])] = true
+		header.NextNodeHashedIds = header.NextNodeHashedIds.Add(HashId(node.GetHeader().Id))
 	}
 	header.IsFinalized = true
+	iNode.SetHeader(header)
 
 	err = c.Verify(iCtx, iNewSignature, iNode)
 	if err != nil {
 		return err
 	}
 
-	oldNodeHashBytes := sha512.Sum512([]byte(header.Id))
-	oldNodeHash := string(oldNodeHashBytes[:])
+	oldNodeHash := HashId(header.Id)
 	for _, child := range iChildren {
 		nodeExists, err := c.DoesNodeExists(iCtx, child.GetHeader().Id)
 		if err != nil {
@@ -263,14 +466,16 @@ func (c *GraphContract) CreateChildrenNodesAndFinalize(
 			return fmt.Errorf("node already exists")
 		}
 
-		child.GetHeader().PreviousNodeHashedIds[oldNodeHash] = true
+		childHeader := child.GetHeader()
+		childHeader.PreviousNodeHashedIds = childHeader.PreviousNodeHashedIds.Add(oldNodeHash)
+		child.SetHeader(childHeader)
 
 		err = c.Verify(iCtx, child.GetHeader().Signature, child)
 		if err != nil {
 			return err
 		}
 
-		newNodeJson, err := json.Marshal(child)
+		newNodeJson, err := marshalNode(child)
 		if err != nil {
 			return err
 		}
@@ -279,9 +484,14 @@ func (c *GraphContract) CreateChildrenNodesAndFinalize(
 		if err != nil {
 			return err
 		}
+
+		err = putEdge(iCtx, header.Id, child.GetHeader().Id)
+		if err != nil {
+			return err
+		}
 	}
 
-	nodeJson, err := json.Marshal(iNode)
+	nodeJson, err := marshalNode(iNode)
 	err = iCtx.GetStub().PutState(header.Id, nodeJson)
 	if err != nil {
 		return err
@@ -290,6 +500,121 @@ func (c *GraphContract) CreateChildrenNodesAndFinalize(
 	return nil
 }
 
+/// every parent is finalized and references every child, every child references every parent
+/// iParentSignatures are the signatures of the finalized parents
+/// iParents are used as placeholders for json unmarshal / marshal and can be empty
+/// iChildren must carry their own signatures in their headers
+func (c *GraphContract) CreateDerivedNodes(
+	iCtx contractapi.TransactionContextInterface,
+	iParentIds []string,
+	iParents []NodeI,
+	iParentSignatures []string,
+	iChildren []NodeI,
+) error {
+	if len(iParentIds) == 0 {
+		return fmt.Errorf("parent ids cannot be empty")
+	}
+
+	if len(iParentIds) != len(iParents) || len(iParentIds) != len(iParentSignatures) {
+		return fmt.Errorf("mismatch parent ids, parents and signatures")
+	}
+
+	if len(iChildren) == 0 {
+		return fmt.Errorf("children cannot be empty")
+	}
+
+	usedIds := map[string]bool{}
+	for i, parentId := range iParentIds {
+		if usedIds[parentId] {
+			return fmt.Errorf("node %s is used more than once", parentId)
+		}
+		usedIds[parentId] = true
+
+		parent := iParents[i]
+		err := c.GetNode(iCtx, parentId, parent)
+		if err != nil {
+			return err
+		}
+
+		header := parent.GetHeader()
+		if header.IsFinalized {
+			return fmt.Errorf("node %s is already finalized", parentId)
+		}
+
+		for _, child := range iChildren {
+			header.NextNodeHashedIds = header.NextNodeHashedIds.Add(HashId(child.GetHeader().Id))
+		}
+		header.IsFinalized = true
+		parent.SetHeader(header)
+
+		err = c.Verify(iCtx, iParentSignatures[i], parent)
+		if err != nil {
+			return err
+		}
+
+		header.Signature = iParentSignatures[i]
+		parent.SetHeader(header)
+	}
+
+	for _, child := range iChildren {
+		header := child.GetHeader()
+		if usedIds[header.Id] {
+			return fmt.Errorf("node %s is used more than once", header.Id)
+		}
+		usedIds[header.Id] = true
+
+		nodeExists, err := c.DoesNodeExists(iCtx, header.Id)
+		if err != nil {
+			return err
+		}
+
+		if nodeExists {
+			return fmt.Errorf("node with id %s already exists", header.Id)
+		}
+
+		for _, parentId := range iParentIds {
+			header.PreviousNodeHashedIds = header.PreviousNodeHashedIds.Add(HashId(parentId))
+		}
+		child.SetHeader(header)
+
+		err = c.Verify(iCtx, header.Signature, child)
+		if err != nil {
+			return err
+		}
+
+		childJson, err := marshalNode(child)
+		if err != nil {
+			return err
+		}
+
+		err = iCtx.GetStub().PutState(header.Id, childJson)
+		if err != nil {
+			return err
+		}
+
+		for _, parentId := range iParentIds {
+			err = putEdge(iCtx, parentId, header.Id)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, parentId := range iParentIds {
+		parentJson, err := marshalNode(iParents[i])
+		if err != nil {
+			return err
+		}
+
+		err = iCtx.GetStub().PutState(parentId, parentJson)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (c *GraphContract) CreateNode(
 	iCtx contractapi.TransactionContextInterface,
 	iNode NodeI,
@@ -304,12 +629,11 @@ func (c *GraphContract) CreateNode(
 	}
 
 	err = c.Verify(iCtx, iNode.GetHeader().Signature, iNode)
-	fmt.Printf("iNode: %+v\n", iNode)
 	if err != nil {
 		return err
 	}
 
-	nodeJson, err := json.Marshal(iNode)
+	nodeJson, err := marshalNode(iNode)
 	if err != nil {
 		return err
 	}
@@ -347,26 +671,34 @@ func (c *GraphContract) AreIdsAvailable(
 	return ret, nil
 }
 
+/// iNode is used as placeholder for json unmarshal / marshal and can be empty
+/// iNewNode carries the body of the new node, its header is overwritten but for IsTransactionTime
+/// iNewSignature is the signature of the finalized node, which carries iNewOwnerPublicKey so that the signature cannot
+/// be used to transfer the node to anyone else. iNewNodeSignature is the new owner's signature of the new node.
+/// Only peers of iNewOwnerMspId can then endorse updates of the new node, it is required when the new owner is a
+/// bare key and can be empty when it is a certificate, see setOwnerEndorsementPolicy
 func (c *GraphContract) TransferNodeOwnership(
 	iCtx contractapi.TransactionContextInterface,
 	iNodeId string,
 	iNode NodeI,
+	iNewNode NodeI,
 	iNewNodeId string,
 	iTransferTime time.Time,
 	iNewOwnerPublicKey string,
+	iNewOwnerMspId string,
 	iNewSignature string,
 	iNewNodeSignature string,
 ) error {
 	id := iNodeId
-	nodeExists, err := c.DoesNodeExists(iCtx, id)
+	err := c.GetNode(iCtx, id, iNode)
 	if err != nil {
 		return err
 	}
-	if !nodeExists {
-		return fmt.Errorf("node with id %s does not exists", id)
+	if iNode.GetHeader().IsFinalized {
+		return fmt.Errorf("node is already finalized")
 	}
 
-	nodeExists, err = c.DoesNodeExists(iCtx, iNewNodeId)
+	nodeExists, err := c.DoesNodeExists(iCtx, iNewNodeId)
 	if err != nil {
 		return err
 	}
@@ -374,50 +706,125 @@ func (c *GraphContract) TransferNodeOwnership(
 		return fmt.Errorf("node with id %s already exists", iNewNodeId)
 	}
 
-	newNode := iNode
-	newHeader := newNode.GetHeader()
+	newHeader := iNewNode.GetHeader()
 	newHeader.Id = iNewNodeId
+	newHeader.IsFinalized = false
 	newHeader.OwnerPublicKey = iNewOwnerPublicKey
 	newHeader.CreatedTime = iTransferTime
-	newNode.SetHeader(newHeader)
+	newHeader.Signature = iNewNodeSignature
+	newHeader.NextNodeHashedIds = MakeHashSet()
+	newHeader.PreviousNodeHashedIds = MakeHashSet(HashId(id))
+	newHeader.NewOwnerPublicKey = ""
+	iNewNode.SetHeader(newHeader)
+
+	oldHeader := iNode.GetHeader()
+	oldHeader.NextNodeHashedIds = oldHeader.NextNodeHashedIds.Add(HashId(iNewNodeId))
+	oldHeader.IsFinalized = true
+	oldHeader.NewOwnerPublicKey = iNewOwnerPublicKey
+	iNode.SetHeader(oldHeader)
 
-	hasher := sha512.New()
+	err = c.Verify(iCtx, iNewSignature, iNode)
+	if err != nil {
+		return err
+	}
 
-	oldNode := iNode
-	oldNodeHeader := iNode.GetHeader()
-	oldNodeHeader.NextNodeHashedIds[string(hasher.Sum([]byte(iNewNodeId)))] = true
-	oldNodeHeader.IsFinalized = true
-	oldNode.SetHeader(oldNodeHeader)
+	err = c.Verify(iCtx, iNewNodeSignature, iNewNode)
+	if err != nil {
+		return err
+	}
 
-	newNode.GetHeader().PreviousNodeHashedIds[string(hasher.Sum([]byte(id)))] = true
+	oldHeader.Signature = iNewSignature
+	iNode.SetHeader(oldHeader)
 
-	err = c.Verify(iCtx, iNewSignature, oldNode)
+	nodeJson, err := marshalNode(iNode)
+	if err != nil {
+		return err
+	}
+	err = iCtx.GetStub().PutState(id, nodeJson)
 	if err != nil {
 		return err
 	}
 
-	err = c.Verify(iCtx, iNewNodeSignature, newNode)
+	nodeJson, err = marshalNode(iNewNode)
+	if err != nil {
+		return err
+	}
+	err = iCtx.GetStub().PutState(iNewNodeId, nodeJson)
 	if err != nil {
 		return err
 	}
 
-	nodeJson, err := json.Marshal(oldNode)
+	err = setOwnerEndorsementPolicy(iCtx, iNewNodeId, iNewOwnerPublicKey, iNewOwnerMspId)
 	if err != nil {
 		return err
 	}
-	err = iCtx.GetStub().PutState(id, nodeJson)
+
+	return putEdge(iCtx, id, iNewNodeId)
+}
+
+/// edges are indexed both ways so that the graph can be walked from any node
+func putEdge(
+	iCtx contractapi.TransactionContextInterface,
+	iFromId string,
+	iToId string,
+) error {
+	key, err := iCtx.GetStub().CreateCompositeKey(edgeObjectType, []string{iFromId, iToId})
 	if err != nil {
 		return err
 	}
 
-	nodeJson, err = json.Marshal(newNode)
+	err = iCtx.GetStub().PutState(key, []byte{0x00})
 	if err != nil {
 		return err
 	}
-	err = iCtx.GetStub().PutState(iNewNodeId, nodeJson)
+
+	key, err = iCtx.GetStub().CreateCompositeKey(reverseEdgeObjectType, []string{iToId, iFromId})
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return iCtx.GetStub().PutState(key, []byte{0x00})
+}
+
+func getEdges(
+	iCtx contractapi.TransactionContextInterface,
+	iObjectType string,
+	iNodeId string,
+) ([]string, error) {
+	iterator, err := iCtx.GetStub().GetStateByPartialCompositeKey(iObjectType, []string{iNodeId})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	ret := []string{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, attributes, err := iCtx.GetStub().SplitCompositeKey(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, attributes[1])
+	}
+
+	return ret, nil
+}
+
+func (c *GraphContract) GetNextNodeIds(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) ([]string, error) {
+	return getEdges(iCtx, edgeObjectType, iNodeId)
+}
+
+func (c *GraphContract) GetPreviousNodeIds(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+) ([]string, error) {
+	return getEdges(iCtx, reverseEdgeObjectType, iNodeId)
 }
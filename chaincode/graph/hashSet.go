@@ -0,0 +1,74 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+/// Sorted list of unique hashed ids, marshalled as a json array so that every client serializes
+/// it the same way when signing a node
+type HashSet []string
+
+func MakeHashSet(
+	iHashedIds ...string,
+) HashSet {
+	set := HashSet{}
+	for _, hashedId := range iHashedIds {
+		set = set.Add(hashedId)
+	}
+
+	return set
+}
+
+/// returns the set with iHashedId inserted in order, iHashedId is not added twice
+func (s HashSet) Add(
+	iHashedId string,
+) HashSet {
+	index := sort.SearchStrings(s, iHashedId)
+	if index < len(s) && s[index] == iHashedId {
+		return s
+	}
+
+	set := make(HashSet, 0, len(s)+1)
+	set = append(set, s[:index]...)
+	set = append(set, iHashedId)
+	return append(set, s[index:]...)
+}
+
+func (s HashSet) Contains(
+	iHashedId string,
+) bool {
+	index := sort.SearchStrings(s, iHashedId)
+	return index < len(s) && s[index] == iHashedId
+}
+
+/// an empty set is marshalled as [] rather than null
+func (s HashSet) MarshalJSON() ([]byte, error) {
+	if s == nil {
+		return []byte("[]"), nil
+	}
+
+	return json.Marshal([]string(s))
+}
+
+/// sets are sorted and deduplicated whatever json they come from, e.g. a client which did not sort its set or
+/// the state of an older version, so that they are marshalled back the same way. null is the empty set
+func (s *HashSet) UnmarshalJSON(
+	iJson []byte,
+) error {
+	var hashedIds []string
+	err := json.Unmarshal(iJson, &hashedIds)
+	if err != nil {
+		return fmt.Errorf("hash set must be an array of hashed ids: %v", err)
+	}
+
+	for _, hashedId := range hashedIds {
+		if hashedId == "" {
+			return fmt.Errorf("hash set cannot contain an empty hashed id")
+		}
+	}
+
+	*s = MakeHashSet(hashedIds...)
+	return nil
+}
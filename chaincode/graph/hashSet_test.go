@@ -0,0 +1,44 @@
+package graph_test
+
+import (
+	"encoding/json"
+	"sig_chain/chaincode/graph"
+	"testing"
+)
+
+func TestUnmarshalUnsortedHashSet(t *testing.T) {
+	first := graph.HashId("a")
+	second := graph.HashId("b")
+	if first > second {
+		first, second = second, first
+	}
+
+	var set graph.HashSet
+	err := json.Unmarshal([]byte(`["`+second+`","`+first+`","`+second+`"]`), &set)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setJson, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(setJson) != `["`+first+`","`+second+`"]` {
+		t.Fatalf("unsorted set is marshalled as %s", setJson)
+	}
+	if !set.Contains(first) || !set.Contains(second) {
+		t.Fatal("unsorted set does not contain its hashed ids")
+	}
+
+	err = json.Unmarshal([]byte(`null`), &set)
+	if err != nil || len(set) != 0 {
+		t.Fatalf("null is unmarshalled as %v: %v", set, err)
+	}
+
+	for _, invalidJson := range []string{`[""]`, `[1]`, `{"` + first + `":true}`} {
+		if json.Unmarshal([]byte(invalidJson), &set) == nil {
+			t.Fatalf("%s is unmarshalled as a hash set", invalidJson)
+		}
+	}
+}
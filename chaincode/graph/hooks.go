@@ -0,0 +1,89 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// arguments are mostly ids, keys and signatures, larger ones are rejected before reaching the contracts
+const maxArgumentSize = 1 << 20
+
+/// one json line is logged when a transaction starts and another when it succeeds
+type transactionLogEntry struct {
+	Stage       string `json:"Stage"`
+	TxId        string `json:"TxId"`
+	ChannelId   string `json:"ChannelId"`
+	Function    string `json:"Function"`
+	ArgCount    int    `json:"ArgCount,omitempty"`
+	MspId       string `json:"MspId"`
+	Fingerprint string `json:"Fingerprint"`
+}
+
+func logTransaction(
+	iStage string,
+	iSubmitter *TransactionSubmitter,
+	iChannelId string,
+	iArgCount int,
+) {
+	entryJson, err := json.Marshal(transactionLogEntry{
+		Stage:       iStage,
+		TxId:        iSubmitter.TxId,
+		ChannelId:   iChannelId,
+		Function:    iSubmitter.Function,
+		ArgCount:    iArgCount,
+		MspId:       iSubmitter.MspId,
+		Fingerprint: iSubmitter.Fingerprint,
+	})
+	if err != nil {
+		log.Printf("failed to log transaction %s: %v", iSubmitter.TxId, err)
+		return
+	}
+
+	log.Println(string(entryJson))
+}
+
+/// meant to be the before transaction function of every contract
+func BeforeTransaction(
+	iCtx contractapi.TransactionContextInterface,
+) error {
+	submitter, err := MakeTransactionSubmitter(iCtx)
+	if err != nil {
+		return err
+	}
+
+	_, args := iCtx.GetStub().GetFunctionAndParameters()
+	for i, arg := range args {
+		if len(arg) > maxArgumentSize {
+			return fmt.Errorf("argument %d of %s exceeds %d bytes", i, submitter.Function, maxArgumentSize)
+		}
+	}
+
+	logTransaction("begin", submitter, iCtx.GetStub().GetChannelID(), len(args))
+	return nil
+}
+
+/// meant to be the after transaction function of every contract, it is not called when the transaction fails.
+/// It must not write, the transaction may have run paginated queries. The submitter is recorded by the stub of
+/// the TransactionContext
+func AfterTransaction(
+	iCtx contractapi.TransactionContextInterface,
+) error {
+	submitter, err := MakeTransactionSubmitter(iCtx)
+	if err != nil {
+		return err
+	}
+
+	logTransaction("end", submitter, iCtx.GetStub().GetChannelID(), 0)
+	return nil
+}
+
+func (c *GraphContract) GetBeforeTransaction() interface{} {
+	return BeforeTransaction
+}
+
+func (c *GraphContract) GetAfterTransaction() interface{} {
+	return AfterTransaction
+}
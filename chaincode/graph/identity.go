@@ -0,0 +1,166 @@
+package graph
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const trustedRootsObjectType = "trustedRoots"
+
+/// CA certificates of an MSP, owners identified by a certificate must chain up to one of the roots
+type TrustedRoots struct {
+	MspId         string   `json:"MspId"`
+	Roots         []string `json:"Roots"`         /// PEM encoded
+	Intermediates []string `json:"Intermediates"` /// PEM encoded
+}
+
+/// returns nil if iPublicKey is a bare key rather than a certificate
+func parseCertificate(
+	iPublicKey string,
+) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(iPublicKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	if block.Type != "CERTIFICATE" {
+		return nil, nil
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func addCertificates(
+	iPool *x509.CertPool,
+	iCertificates []string,
+) error {
+	for _, certificatePem := range iCertificates {
+		if !iPool.AppendCertsFromPEM([]byte(certificatePem)) {
+			return fmt.Errorf("invalid certificate in trusted roots")
+		}
+	}
+
+	return nil
+}
+
+/// replaces the trusted roots of iRoots.MspId, the caller is responsible for authorizing the change
+func PutTrustedRoots(
+	iCtx contractapi.TransactionContextInterface,
+	iRoots *TrustedRoots,
+) error {
+	if len(iRoots.Roots) == 0 {
+		return fmt.Errorf("roots cannot be empty")
+	}
+
+	err := addCertificates(x509.NewCertPool(), append(append([]string{}, iRoots.Roots...), iRoots.Intermediates...))
+	if err != nil {
+		return err
+	}
+
+	rootsJson, err := json.Marshal(iRoots)
+	if err != nil {
+		return err
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(trustedRootsObjectType, []string{iRoots.MspId})
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().PutState(key, rootsJson)
+}
+
+func GetTrustedRoots(
+	iCtx contractapi.TransactionContextInterface,
+) ([]TrustedRoots, error) {
+	iterator, err := iCtx.GetStub().GetStateByPartialCompositeKey(trustedRootsObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	ret := []TrustedRoots{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var roots TrustedRoots
+		err = json.Unmarshal(kv.Value, &roots)
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, roots)
+	}
+
+	return ret, nil
+}
+
+/// bare keys are accepted as is, certificates must chain up to the trusted roots of an MSP
+/// and be valid at the transaction's timestamp
+func VerifyIdentity(
+	iCtx contractapi.TransactionContextInterface,
+	iPublicKey string,
+) error {
+	_, err := GetIdentityMspId(iCtx, iPublicKey)
+	return err
+}
+
+/// returns the MSP whose trusted roots issued the certificate iPublicKey, or an empty string for bare keys
+func GetIdentityMspId(
+	iCtx contractapi.TransactionContextInterface,
+	iPublicKey string,
+) (string, error) {
+	certificate, err := parseCertificate(iPublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	if certificate == nil {
+		return "", nil
+	}
+
+	timestamp, err := iCtx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", err
+	}
+	transactionTime := time.Unix(timestamp.Seconds, int64(timestamp.Nanos)).UTC()
+
+	trustedRoots, err := GetTrustedRoots(iCtx)
+	if err != nil {
+		return "", err
+	}
+
+	for _, roots := range trustedRoots {
+		rootPool := x509.NewCertPool()
+		intermediatePool := x509.NewCertPool()
+		err = addCertificates(rootPool, roots.Roots)
+		if err != nil {
+			return "", err
+		}
+
+		err = addCertificates(intermediatePool, roots.Intermediates)
+		if err != nil {
+			return "", err
+		}
+
+		_, err = certificate.Verify(x509.VerifyOptions{
+			Roots:         rootPool,
+			Intermediates: intermediatePool,
+			CurrentTime:   transactionTime,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		if err == nil {
+			return roots.MspId, nil
+		}
+	}
+
+	return "", fmt.Errorf("certificate of %s is not issued by a trusted MSP", certificate.Subject.CommonName)
+}
@@ -0,0 +1,188 @@
+package graph
+
+import (
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// Returned by transactions which write to the ledger, so that clients can keep a reference to the
+/// transaction without querying it again
+type TransactionReceipt struct {
+	TxId        string    `json:"TxId"`
+	Timestamp   time.Time `json:"Timestamp"`
+	WrittenKeys []string  `json:"WrittenKeys"` /// world state keys only, the record of the submitter is not listed
+}
+
+/// records the world state keys written by the transaction. Private data keys are not recorded since the
+/// response of a transaction is stored on the ledger.
+/// The submitter of the transaction is recorded before its first write rather than after every transaction,
+/// since Fabric refuses writes in transactions which ran paginated queries
+type recordingStub struct {
+	shim.ChaincodeStubInterface
+	ctx                 contractapi.TransactionContextInterface
+	writtenKeys         map[string]bool
+	isSubmitterRecorded bool
+}
+
+func (s *recordingStub) recordSubmitter() error {
+	if s.isSubmitterRecorded {
+		return nil
+	}
+
+	submitter, err := MakeTransactionSubmitter(s.ctx)
+	if err != nil {
+		return err
+	}
+
+	err = putTransactionSubmitter(s.ChaincodeStubInterface, submitter)
+	if err != nil {
+		return err
+	}
+
+	s.isSubmitterRecorded = true
+	return nil
+}
+
+func (s *recordingStub) PutState(
+	iKey string,
+	iValue []byte,
+) error {
+	err := s.recordSubmitter()
+	if err != nil {
+		return err
+	}
+
+	err = s.ChaincodeStubInterface.PutState(iKey, iValue)
+	if err != nil {
+		return err
+	}
+
+	s.writtenKeys[iKey] = true
+	return nil
+}
+
+func (s *recordingStub) DelState(
+	iKey string,
+) error {
+	err := s.recordSubmitter()
+	if err != nil {
+		return err
+	}
+
+	err = s.ChaincodeStubInterface.DelState(iKey)
+	if err != nil {
+		return err
+	}
+
+	s.writtenKeys[iKey] = true
+	return nil
+}
+
+func (s *recordingStub) SetStateValidationParameter(
+	iKey string,
+	iParameter []byte,
+) error {
+	err := s.recordSubmitter()
+	if err != nil {
+		return err
+	}
+
+	return s.ChaincodeStubInterface.SetStateValidationParameter(iKey, iParameter)
+}
+
+func (s *recordingStub) PutPrivateData(
+	iCollection string,
+	iKey string,
+	iValue []byte,
+) error {
+	err := s.recordSubmitter()
+	if err != nil {
+		return err
+	}
+
+	return s.ChaincodeStubInterface.PutPrivateData(iCollection, iKey, iValue)
+}
+
+func (s *recordingStub) DelPrivateData(
+	iCollection string,
+	iKey string,
+) error {
+	err := s.recordSubmitter()
+	if err != nil {
+		return err
+	}
+
+	return s.ChaincodeStubInterface.DelPrivateData(iCollection, iKey)
+}
+
+func (s *recordingStub) SetPrivateDataValidationParameter(
+	iCollection string,
+	iKey string,
+	iParameter []byte,
+) error {
+	err := s.recordSubmitter()
+	if err != nil {
+		return err
+	}
+
+	return s.ChaincodeStubInterface.SetPrivateDataValidationParameter(iCollection, iKey, iParameter)
+}
+
+/// transaction context of every contract, its stub records the keys written by the transaction and its submitter
+type TransactionContext struct {
+	contractapi.TransactionContext
+}
+
+func (c *TransactionContext) SetStub(
+	iStub shim.ChaincodeStubInterface,
+) {
+	c.TransactionContext.SetStub(&recordingStub{
+		ChaincodeStubInterface: iStub,
+		ctx:                    c,
+		writtenKeys:            map[string]bool{},
+	})
+}
+
+/// returns the receipt of the current transaction, the written keys are only known when iCtx is a TransactionContext
+func MakeTransactionReceipt(
+	iCtx contractapi.TransactionContextInterface,
+) (*TransactionReceipt, error) {
+	timestamp, err := iCtx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+
+	writtenKeys := []string{}
+	if stub, ok := iCtx.GetStub().(*recordingStub); ok {
+		for key := range stub.writtenKeys {
+			writtenKeys = append(writtenKeys, key)
+		}
+	}
+	sort.Strings(writtenKeys)
+
+	return &TransactionReceipt{
+		TxId:        iCtx.GetStub().GetTxID(),
+		Timestamp:   time.Unix(timestamp.Seconds, int64(timestamp.Nanos)).UTC(),
+		WrittenKeys: writtenKeys,
+	}, nil
+}
+
+/// returns the receipt of the current transaction if iErr is nil, so that transactions can end with the
+/// call which performs their last write
+func MakeTransactionReceiptIfSucceeded(
+	iCtx contractapi.TransactionContextInterface,
+	iErr error,
+) (*TransactionReceipt, error) {
+	if iErr != nil {
+		return nil, iErr
+	}
+
+	return MakeTransactionReceipt(iCtx)
+}
+
+func (c *GraphContract) GetTransactionContextHandler() contractapi.SettableTransactionContextInterface {
+	return &TransactionContext{}
+}
@@ -0,0 +1,457 @@
+package graph
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// version of the nodes written by this chaincode, nodes written before versioning have no SchemaVersion
+const CurrentSchemaVersion = 2
+
+/// upgrades a node from the version it is indexed by to the next one. Nodes are handled as generic json objects
+/// so that the nodes of every contract are migrated whatever their type
+var migrations = map[int]func(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iNode map[string]interface{},
+) error{
+	/// version 1 only introduces SchemaVersion
+	0: func(
+		iCtx contractapi.TransactionContextInterface,
+		iNodeId string,
+		iNode map[string]interface{},
+	) error {
+		return nil
+	},
+	/// version 2 stores hashed id sets as sorted arrays of hex hashes
+	1: migrateHashSets,
+}
+
+/// first version storing hashed id sets as arrays, the owners of the nodes of older versions signed them as maps
+const arrayHashSetSchemaVersion = 2
+
+/// hashed ids used to be raw hashes, which json replaces with U+FFFD when they are not valid utf-8
+func getLegacyHash(
+	iHash []byte,
+) (string, error) {
+	hashJson, err := json.Marshal(string(iHash))
+	if err != nil {
+		return "", err
+	}
+
+	var hash string
+	err = json.Unmarshal(hashJson, &hash)
+	if err != nil {
+		return "", err
+	}
+
+	return hash, nil
+}
+
+/// the sha512 of iId, as hashed ids of the first versions were created by CreateNode
+func getLegacyHashedId(
+	iId string,
+) (string, error) {
+	hash := sha512.Sum512([]byte(iId))
+	return getLegacyHash(hash[:])
+}
+
+/// the first versions also hashed ids with hasher.Sum([]byte(iId)), which appends the sha512 of nothing to the
+/// id rather than hashing it. The id of such hashed ids is recovered by removing that suffix
+func getSummedHashedIdId(
+	iHashedId string,
+) (string, bool, error) {
+	suffix, err := getLegacyHash(sha512.New().Sum(nil))
+	if err != nil {
+		return "", false, err
+	}
+
+	if !strings.HasSuffix(iHashedId, suffix) {
+		return "", false, nil
+	}
+
+	return strings.TrimSuffix(iHashedId, suffix), true, nil
+}
+
+/// appends every string found in iValue to oStrings
+func getStrings(
+	iValue interface{},
+	oStrings *[]string,
+) {
+	switch value := iValue.(type) {
+	case string:
+		*oStrings = append(*oStrings, value)
+	case []interface{}:
+		for _, element := range value {
+			getStrings(element, oStrings)
+		}
+	case map[string]interface{}:
+		for _, element := range value {
+			getStrings(element, oStrings)
+		}
+	}
+}
+
+/// summed hashes are reversed, sha512 hashes are recovered by hashing the ids of the neighbours of the node and
+/// every id the node refers to. Hashes which cannot be recovered are kept as they are
+func migrateHashSets(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iNode map[string]interface{},
+) error {
+	candidateIds := []string{}
+	getStrings(iNode, &candidateIds)
+	for _, objectType := range []string{edgeObjectType, reverseEdgeObjectType} {
+		edgeIds, err := getEdges(iCtx, objectType, iNodeId)
+		if err != nil {
+			return err
+		}
+		candidateIds = append(candidateIds, edgeIds...)
+	}
+
+	hashedIds := map[string]string{}
+	for _, id := range candidateIds {
+		legacyHashedId, err := getLegacyHashedId(id)
+		if err != nil {
+			return err
+		}
+		hashedIds[legacyHashedId] = HashId(id)
+	}
+
+	for _, field := range []string{"PreviousNodeHashedIds", "NextNodeHashedIds"} {
+		legacySet, _ := iNode[field].(map[string]interface{})
+		set := MakeHashSet()
+		for legacyHashedId := range legacySet {
+			id, isSummed, err := getSummedHashedIdId(legacyHashedId)
+			if err != nil {
+				return err
+			}
+
+			if isSummed {
+				set = set.Add(HashId(id))
+			} else if hashedId, ok := hashedIds[legacyHashedId]; ok {
+				set = set.Add(hashedId)
+			} else {
+				set = set.Add(legacyHashedId)
+			}
+		}
+		iNode[field] = set
+	}
+
+	return nil
+}
+
+/// the json the owner of iNodeJson signed, i.e. iNodeJson without its signature nor its schema version.
+/// iNodeJson must be as the chaincode wrote it, since the fields of a node signed by its owner are in the order
+/// of the struct declarations and json objects lose it once unmarshalled. Empty if the node has no signature
+func getSignedJson(
+	iNodeJson []byte,
+) (string, error) {
+	signatureKey := []byte(`"Signature":`)
+	start := bytes.Index(iNodeJson, signatureKey)
+	if start < 0 {
+		return "", nil
+	}
+	start += len(signatureKey)
+
+	decoder := json.NewDecoder(bytes.NewReader(iNodeJson[start:]))
+	var signature string
+	err := decoder.Decode(&signature)
+	if err != nil {
+		return "", err
+	}
+	end := start + int(decoder.InputOffset())
+
+	/// SchemaVersion directly follows Signature and is omitted from the signed json
+	rest := iNodeJson[end:]
+	schemaVersionKey := []byte(`,"SchemaVersion":`)
+	if bytes.HasPrefix(rest, schemaVersionKey) {
+		rest = bytes.TrimLeft(rest[len(schemaVersionKey):], "0123456789")
+	}
+
+	signedJson := append([]byte{}, iNodeJson[:start]...)
+	signedJson = append(signedJson, `""`...)
+	return string(append(signedJson, rest...)), nil
+}
+
+/// returns whether iSet is what migrateHashSets made of iLegacySet
+func isMigratedHashSet(
+	iLegacySet map[string]bool,
+	iSet HashSet,
+) (bool, error) {
+	if len(iLegacySet) != len(iSet) {
+		return false, nil
+	}
+
+	legacyHashedIds := map[string]bool{}
+	for _, hashedId := range iSet {
+		/// hashes which could not be recovered are kept as they are
+		legacyHashedIds[hashedId] = true
+
+		hash, err := hex.DecodeString(hashedId)
+		if err != nil || len(hash) != sha512.Size {
+			continue
+		}
+
+		legacyHashedId, err := getLegacyHash(hash)
+		if err != nil {
+			return false, err
+		}
+		legacyHashedIds[legacyHashedId] = true
+	}
+
+	for legacyHashedId := range iLegacySet {
+		id, isSummed, err := getSummedHashedIdId(legacyHashedId)
+		if err != nil {
+			return false, err
+		}
+
+		if isSummed && iSet.Contains(HashId(id)) {
+			continue
+		}
+
+		if !legacyHashedIds[legacyHashedId] {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+/// checks that the SignedJson of iNode is iNode in the form its owner signed it, which only differs by its
+/// hashed id sets. iNode must be a pointer so that the signed node can be unmarshalled in a node of its type
+func checkSignedJson(
+	iNode NodeI,
+) error {
+	nodeType := reflect.TypeOf(iNode)
+	if nodeType.Kind() != reflect.Ptr {
+		return fmt.Errorf("node must be a pointer")
+	}
+
+	header := iNode.GetHeader()
+	var legacySets struct {
+		PreviousNodeHashedIds map[string]bool `json:"PreviousNodeHashedIds"`
+		NextNodeHashedIds     map[string]bool `json:"NextNodeHashedIds"`
+	}
+	err := json.Unmarshal([]byte(header.SignedJson), &legacySets)
+	if err != nil {
+		return err
+	}
+
+	for _, sets := range []struct {
+		legacySet map[string]bool
+		set       HashSet
+	}{
+		{legacySets.PreviousNodeHashedIds, header.PreviousNodeHashedIds},
+		{legacySets.NextNodeHashedIds, header.NextNodeHashedIds},
+	} {
+		isMigrated, err := isMigratedHashSet(sets.legacySet, sets.set)
+		if err != nil {
+			return err
+		}
+
+		if !isMigrated {
+			return fmt.Errorf("hashed ids of node %s differ from the signed ones", header.Id)
+		}
+	}
+
+	var signedFields map[string]json.RawMessage
+	err = json.Unmarshal([]byte(header.SignedJson), &signedFields)
+	if err != nil {
+		return err
+	}
+	delete(signedFields, "PreviousNodeHashedIds")
+	delete(signedFields, "NextNodeHashedIds")
+
+	signedFieldsJson, err := json.Marshal(signedFields)
+	if err != nil {
+		return err
+	}
+
+	signedNode := reflect.New(nodeType.Elem()).Interface().(NodeI)
+	err = json.Unmarshal(signedFieldsJson, signedNode)
+	if err != nil {
+		return err
+	}
+
+	/// the fields which are not covered by the signed json are compared as they are now
+	signedHeader := signedNode.GetHeader()
+	signedHeader.PreviousNodeHashedIds = header.PreviousNodeHashedIds
+	signedHeader.NextNodeHashedIds = header.NextNodeHashedIds
+	signedHeader.Signature = header.Signature
+	signedHeader.SchemaVersion = header.SchemaVersion
+	signedHeader.SignedJson = header.SignedJson
+	signedNode.SetHeader(signedHeader)
+
+	nodeJson, err := json.Marshal(iNode)
+	if err != nil {
+		return err
+	}
+
+	signedNodeJson, err := json.Marshal(signedNode)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(nodeJson, signedNodeJson) {
+		return fmt.Errorf("node %s differs from the signed one", header.Id)
+	}
+
+	return nil
+}
+
+/// verifies the signature stored in iNode. Nodes migrated from versions which stored hashed id sets as maps
+/// are also verified against the json their owner signed, as long as they were not signed again since
+func VerifyNodeSignature(
+	iNode NodeI,
+) error {
+	originalHeader := iNode.GetHeader()
+	defer func() {
+		iNode.SetHeader(originalHeader)
+	}()
+
+	iNode.SetHeader(GetSignedHeader(originalHeader))
+
+	payload, err := json.Marshal(iNode)
+	if err != nil {
+		return err
+	}
+	iNode.SetHeader(originalHeader)
+
+	err = VerifySignature(originalHeader.OwnerPublicKey, payload, originalHeader.Signature)
+	if err == nil || originalHeader.SignedJson == "" {
+		return err
+	}
+
+	err = VerifySignature(originalHeader.OwnerPublicKey, []byte(originalHeader.SignedJson), originalHeader.Signature)
+	if err != nil {
+		return err
+	}
+
+	return checkSignedJson(iNode)
+}
+
+type MigrationResult struct {
+	MigratedCount int    `json:"MigratedCount"`
+	NextKey       string `json:"NextKey"` /// empty once every node is migrated
+}
+
+/// stamps the current schema version on iNode before marshalling it, every node must be written through it
+func marshalNode(
+	iNode NodeI,
+) ([]byte, error) {
+	header := iNode.GetHeader()
+	header.SchemaVersion = CurrentSchemaVersion
+	iNode.SetHeader(header)
+
+	return json.Marshal(iNode)
+}
+
+/// returns iNodeJson upgraded to the current version, and whether it was upgraded
+func migrateNodeJson(
+	iCtx contractapi.TransactionContextInterface,
+	iNodeId string,
+	iNodeJson []byte,
+) ([]byte, bool, error) {
+	var node map[string]interface{}
+	err := json.Unmarshal(iNodeJson, &node)
+	if err != nil {
+		return nil, false, err
+	}
+
+	version := 0
+	if value, ok := node["SchemaVersion"].(float64); ok {
+		version = int(value)
+	}
+
+	if version > CurrentSchemaVersion {
+		return nil, false, fmt.Errorf("node has schema version %d, this chaincode only supports up to %d", version, CurrentSchemaVersion)
+	}
+
+	if version == CurrentSchemaVersion {
+		return iNodeJson, false, nil
+	}
+
+	/// the raw json of the node is needed, the migrations only have its fields
+	if version < arrayHashSetSchemaVersion {
+		signedJson, err := getSignedJson(iNodeJson)
+		if err != nil {
+			return nil, false, err
+		}
+		if signedJson != "" {
+			node["SignedJson"] = signedJson
+		}
+	}
+
+	for ; version < CurrentSchemaVersion; version++ {
+		err = migrations[version](iCtx, iNodeId, node)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to migrate node from schema version %d: %v", version, err)
+		}
+	}
+	node["SchemaVersion"] = CurrentSchemaVersion
+
+	nodeJson, err := json.Marshal(node)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return nodeJson, true, nil
+}
+
+/// stores in the current version the nodes of older versions among the iCount nodes starting at iStartKey.
+/// Pagination is not available to transactions which write, so the next key is returned instead of a bookmark.
+/// The caller is responsible for authorizing the migration
+func MigrateNodes(
+	iCtx contractapi.TransactionContextInterface,
+	iStartKey string,
+	iCount int,
+) (*MigrationResult, error) {
+	if iCount <= 0 {
+		return nil, fmt.Errorf("count must be positive")
+	}
+
+	/// nodes are the only simple keys, other records use composite keys which range queries skip
+	iterator, err := iCtx.GetStub().GetStateByRange(iStartKey, "")
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	result := MigrationResult{}
+	for visited := 0; iterator.HasNext(); visited++ {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		if visited == iCount {
+			result.NextKey = kv.Key
+			break
+		}
+
+		nodeJson, isMigrated, err := migrateNodeJson(iCtx, kv.Key, kv.Value)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %v", kv.Key, err)
+		}
+
+		if !isMigrated {
+			continue
+		}
+
+		err = iCtx.GetStub().PutState(kv.Key, nodeJson)
+		if err != nil {
+			return nil, err
+		}
+		result.MigratedCount++
+	}
+
+	return &result, nil
+}
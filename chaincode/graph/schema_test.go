@@ -0,0 +1,191 @@
+package graph_test
+
+import (
+	"crypto/sha512"
+	"encoding/json"
+	"sig_chain/chaincode/graph"
+	"sig_chain/pkg/client"
+	"sig_chain/pkg/keys"
+	"sig_chain/pkg/testutil"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// a node as signed and stored before hash sets were arrays, with the hashes as map keys
+type legacyNode struct {
+	Id                    string          `json:"Id"`
+	IsFinalized           bool            `json:"IsFinalized"`
+	PreviousNodeHashedIds map[string]bool `json:"PreviousNodeHashedIds"`
+	NextNodeHashedIds     map[string]bool `json:"NextNodeHashedIds"`
+	OwnerPublicKey        string          `json:"OwnerPublicKey"`
+	CreatedTime           time.Time       `json:"CreatedTime"`
+	Signature             string          `json:"Signature"`
+	Name                  string          `json:"Name"`
+	Note                  string          `json:"Note"`
+}
+
+type testNode struct {
+	graph.NodeHeader
+	Name string `json:"Name"`
+	Note string `json:"Note"`
+}
+
+func (n *testNode) GetHeader() graph.NodeHeader {
+	return n.NodeHeader
+}
+
+func (n *testNode) SetHeader(iHeader graph.NodeHeader) {
+	n.NodeHeader = iHeader
+}
+
+func makeTestSigner(
+	t *testing.T,
+) client.Signer {
+	privateKey, err := keys.GenerateKey("ecdsa")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey, err := keys.EncodePublicKey(privateKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := client.MakeKeySignerFromKey(privateKey, publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signer
+}
+
+func sign(
+	t *testing.T,
+	iSigner client.Signer,
+	iPayload interface{},
+) string {
+	payload, err := json.Marshal(iPayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature, err := iSigner.Sign(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return graph.EncodeSignature(signature)
+}
+
+/// commits the writes of iTransaction if it succeeds
+func submit(
+	t *testing.T,
+	iLedger *testutil.MockLedger,
+	iTxId string,
+	iTransaction func(iCtx contractapi.TransactionContextInterface) error,
+) {
+	t.Helper()
+	identity, err := testutil.MakeMockIdentity("admin", "Org1MSP")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stub := iLedger.MakeStub(iTxId, time.Now())
+	ctx, err := testutil.MakeTransactionContext(stub, identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = iTransaction(ctx)
+	if err != nil {
+		t.Fatalf("%s: %v", iTxId, err)
+	}
+
+	err = stub.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigratedNodeKeepsItsSignature(t *testing.T) {
+	owner := makeTestSigner(t)
+	ledger := testutil.MakeMockLedger()
+
+	/// the previous hash is in the format of the first versions, the id followed by the hash of nothing
+	hasher := sha512.New()
+	grandchildHash := sha512.Sum512([]byte("grandchild"))
+	unknownHash := sha512.Sum512([]byte("unknown"))
+	legacy := legacyNode{
+		Id:                    "child",
+		PreviousNodeHashedIds: map[string]bool{string(hasher.Sum([]byte("parent"))): true},
+		NextNodeHashedIds:     map[string]bool{string(grandchildHash[:]): true, string(unknownHash[:]): true},
+		OwnerPublicKey:        owner.GetPublicKey(),
+		CreatedTime:           time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		Name:                  "grandchild",
+		Note:                  "<&>",
+	}
+	legacy.Signature = sign(t, owner, &legacy)
+	legacyJson, err := json.Marshal(&legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	submit(t, ledger, "seed", func(iCtx contractapi.TransactionContextInterface) error {
+		return iCtx.GetStub().PutState("child", legacyJson)
+	})
+
+	submit(t, ledger, "migrate", func(iCtx contractapi.TransactionContextInterface) error {
+		result, err := graph.MigrateNodes(iCtx, "", 10)
+		if err != nil {
+			return err
+		}
+
+		if result.MigratedCount != 1 {
+			t.Fatalf("migrated %d nodes", result.MigratedCount)
+		}
+		return nil
+	})
+
+	var node testNode
+	submit(t, ledger, "get", func(iCtx contractapi.TransactionContextInterface) error {
+		return (&graph.GraphContract{}).GetNode(iCtx, "child", &node)
+	})
+
+	if !node.PreviousNodeHashedIds.Contains(graph.HashId("parent")) {
+		t.Fatal("previous hash is not migrated")
+	}
+	if !node.NextNodeHashedIds.Contains(graph.HashId("grandchild")) || len(node.NextNodeHashedIds) != 2 {
+		t.Fatal("next hashes are not migrated")
+	}
+
+	err = graph.VerifyNodeSignature(&node)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := node
+	tampered.Name = "other"
+	if graph.VerifyNodeSignature(&tampered) == nil {
+		t.Fatal("node with a changed field verifies")
+	}
+
+	tampered = node
+	tampered.NextNodeHashedIds = graph.MakeHashSet(graph.HashId("grandchild"), graph.HashId("other"))
+	if graph.VerifyNodeSignature(&tampered) == nil {
+		t.Fatal("node with changed hashes verifies")
+	}
+
+	/// once signed again, the node verifies in its current form
+	resigned := node
+	resigned.IsFinalized = true
+	resigned.Signature = ""
+	resigned.SchemaVersion = 0
+	resigned.Signature = sign(t, owner, &resigned)
+	resigned.SchemaVersion = graph.CurrentSchemaVersion
+	err = graph.VerifyNodeSignature(&resigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
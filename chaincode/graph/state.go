@@ -0,0 +1,191 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+/// the object types of the graph itself, the contracts export their own object types after them
+var graphObjectTypes = []string{
+	edgeObjectType,
+	reverseEdgeObjectType,
+	submitterObjectType,
+	trustedRootsObjectType,
+}
+
+/// One record of the world state, nodes have simple keys and other records composite keys
+type StateEntry struct {
+	Key   string `json:"Key"`   /// composite keys keep their U+0000 separators
+	Value string `json:"Value"` /// base64, since indexes are not text
+	Hash  string `json:"Hash"`  /// hex SHA-256 of the value
+}
+
+/// Bookmark is empty once every record is exported
+type StatePage struct {
+	Entries  []StateEntry `json:"Entries"`
+	Hash     string       `json:"Hash"` /// see GetStatePageHash
+	Bookmark string       `json:"Bookmark"`
+}
+
+func GetObjectTypes() []string {
+	return append([]string{}, graphObjectTypes...)
+}
+
+func makeStateEntry(
+	iKey string,
+	iValue []byte,
+) StateEntry {
+	hash := sha256.Sum256(iValue)
+	return StateEntry{
+		Key:   iKey,
+		Value: base64.StdEncoding.EncodeToString(iValue),
+		Hash:  hex.EncodeToString(hash[:]),
+	}
+}
+
+/// hex SHA-256 of the keys and value hashes of iEntries in order. Keys are followed by 0xff, which UTF-8
+/// never uses, so that no two pages have the same hashed bytes
+func GetStatePageHash(
+	iEntries []StateEntry,
+) string {
+	hasher := sha256.New()
+	for _, entry := range iEntries {
+		hasher.Write([]byte(entry.Key))
+		hasher.Write([]byte{0xff})
+		hasher.Write([]byte(entry.Hash))
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+/// bookmarks are "<phase>:<bookmark of the ledger>", phase 0 exports the nodes and phase i the records of the
+/// object type i-1
+func parseStateBookmark(
+	iBookmark string,
+) (int, string, error) {
+	if iBookmark == "" {
+		return 0, "", nil
+	}
+
+	separatorIndex := strings.Index(iBookmark, ":")
+	if separatorIndex == -1 {
+		return 0, "", fmt.Errorf("invalid bookmark")
+	}
+
+	phase, err := strconv.Atoi(iBookmark[:separatorIndex])
+	if err != nil || phase < 0 {
+		return 0, "", fmt.Errorf("invalid bookmark")
+	}
+
+	return phase, iBookmark[separatorIndex+1:], nil
+}
+
+/// returns at most iPageSize records of the world state, starting at iBookmark, which is empty for the first page.
+/// iObjectTypes are the composite key object types to export after the nodes, they must be the same for every
+/// page. Private data is not exported. Paginated queries are only allowed in evaluated transactions
+func ExportState(
+	iCtx contractapi.TransactionContextInterface,
+	iObjectTypes []string,
+	iBookmark string,
+	iPageSize int32,
+) (*StatePage, error) {
+	if iPageSize <= 0 {
+		return nil, fmt.Errorf("page size must be positive")
+	}
+
+	phase, bookmark, err := parseStateBookmark(iBookmark)
+	if err != nil {
+		return nil, err
+	}
+
+	page := StatePage{Entries: []StateEntry{}}
+	for phase <= len(iObjectTypes) && int32(len(page.Entries)) < iPageSize {
+		remaining := iPageSize - int32(len(page.Entries))
+
+		var iterator shim.StateQueryIteratorInterface
+		var metadata *peer.QueryResponseMetadata
+		if phase == 0 {
+			/// nodes are the only simple keys, range queries skip composite keys
+			iterator, metadata, err = iCtx.GetStub().GetStateByRangeWithPagination("", "", remaining, bookmark)
+		} else {
+			iterator, metadata, err = iCtx.GetStub().GetStateByPartialCompositeKeyWithPagination(iObjectTypes[phase-1], []string{}, remaining, bookmark)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for iterator.HasNext() {
+			kv, err := iterator.Next()
+			if err != nil {
+				iterator.Close()
+				return nil, err
+			}
+			page.Entries = append(page.Entries, makeStateEntry(kv.Key, kv.Value))
+		}
+		iterator.Close()
+
+		if metadata.FetchedRecordsCount < remaining || metadata.Bookmark == "" {
+			phase++
+			bookmark = ""
+		} else {
+			bookmark = metadata.Bookmark
+		}
+	}
+
+	if phase <= len(iObjectTypes) {
+		page.Bookmark = fmt.Sprintf("%d:%s", phase, bookmark)
+	}
+	page.Hash = GetStatePageHash(page.Entries)
+	return &page, nil
+}
+
+/// writes iEntries as exported by ExportState, after checking them against iPageHash. Records which already
+/// exist must have the same value, so that a page can be imported again after a failure. Nodes are written as
+/// they were exported, the caller is responsible for authorizing the import
+func ImportState(
+	iCtx contractapi.TransactionContextInterface,
+	iEntries []StateEntry,
+	iPageHash string,
+) error {
+	if GetStatePageHash(iEntries) != iPageHash {
+		return fmt.Errorf("entries do not match the page hash")
+	}
+
+	for _, entry := range iEntries {
+		value, err := base64.StdEncoding.DecodeString(entry.Value)
+		if err != nil {
+			return fmt.Errorf("value of %q is not base64: %v", entry.Key, err)
+		}
+
+		if makeStateEntry(entry.Key, value).Hash != entry.Hash {
+			return fmt.Errorf("value of %q does not match its hash", entry.Key)
+		}
+
+		existingValue, err := iCtx.GetStub().GetState(entry.Key)
+		if err != nil {
+			return err
+		}
+
+		if existingValue != nil {
+			if string(existingValue) != string(value) {
+				return fmt.Errorf("%q already exists with a different value", entry.Key)
+			}
+			continue
+		}
+
+		err = iCtx.GetStub().PutState(entry.Key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const submitterObjectType = "submitter"
+
+/// Fabric identity which submitted a transaction, as opposed to the keys signing its payloads.
+/// Fingerprint is the SHA-256 of the DER encoded enrollment certificate
+type TransactionSubmitter struct {
+	TxId        string `json:"TxId"`
+	Function    string `json:"Function"`
+	MspId       string `json:"MspId"`
+	Id          string `json:"Id"` /// subject and issuer of the enrollment certificate, as returned by cid
+	Fingerprint string `json:"Fingerprint"`
+}
+
+func MakeTransactionSubmitter(
+	iCtx contractapi.TransactionContextInterface,
+) (*TransactionSubmitter, error) {
+	identity := iCtx.GetClientIdentity()
+	mspId, err := identity.GetMSPID()
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := identity.GetID()
+	if err != nil {
+		return nil, err
+	}
+
+	certificate, err := identity.GetX509Certificate()
+	if err != nil {
+		return nil, err
+	}
+
+	if certificate == nil {
+		return nil, fmt.Errorf("client identity has no certificate")
+	}
+
+	fingerprint := sha256.Sum256(certificate.Raw)
+	function, _ := iCtx.GetStub().GetFunctionAndParameters()
+	return &TransactionSubmitter{
+		TxId:        iCtx.GetStub().GetTxID(),
+		Function:    function,
+		MspId:       mspId,
+		Id:          id,
+		Fingerprint: hex.EncodeToString(fingerprint[:]),
+	}, nil
+}
+
+/// called before the first write of every transaction, so that every write can be correlated with the identity
+/// which submitted it. Transactions which do not write have no recorded submitter
+func putTransactionSubmitter(
+	iStub shim.ChaincodeStubInterface,
+	iSubmitter *TransactionSubmitter,
+) error {
+	submitterJson, err := json.Marshal(iSubmitter)
+	if err != nil {
+		return err
+	}
+
+	key, err := iStub.CreateCompositeKey(submitterObjectType, []string{iSubmitter.TxId})
+	if err != nil {
+		return err
+	}
+
+	return iStub.PutState(key, submitterJson)
+}
+
+func (c *GraphContract) GetTransactionSubmitter(
+	iCtx contractapi.TransactionContextInterface,
+	iTxId string,
+) (*TransactionSubmitter, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(submitterObjectType, []string{iTxId})
+	if err != nil {
+		return nil, err
+	}
+
+	submitterJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ledger: %v", err)
+	}
+
+	if submitterJson == nil {
+		return nil, fmt.Errorf("no submitter recorded for transaction %s", iTxId)
+	}
+
+	var submitter TransactionSubmitter
+	err = json.Unmarshal(submitterJson, &submitter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &submitter, nil
+}
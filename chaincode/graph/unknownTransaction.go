@@ -0,0 +1,132 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// functions within this edit distance of the requested one are suggested
+const maxSuggestionDistance = 3
+
+/// Returned as the error message when a client calls a function which does not exist
+type UnknownTransactionError struct {
+	Error              string   `json:"Error"`
+	Contract           string   `json:"Contract"`
+	Function           string   `json:"Function"`
+	Suggestions        []string `json:"Suggestions"`
+	AvailableFunctions []string `json:"AvailableFunctions"`
+}
+
+/// the exported methods of iContract which contractapi exposes as transactions
+func getTransactionNames(
+	iContract contractapi.ContractInterface,
+) []string {
+	excluded := map[string]bool{
+		"GetEvaluateTransactions": true,
+		"GetIgnoredFunctions":     true,
+	}
+
+	contractInterface := reflect.TypeOf((*contractapi.ContractInterface)(nil)).Elem()
+	for i := 0; i < contractInterface.NumMethod(); i++ {
+		excluded[contractInterface.Method(i).Name] = true
+	}
+
+	if ignoring, ok := iContract.(contractapi.IgnoreContractInterface); ok {
+		for _, name := range ignoring.GetIgnoredFunctions() {
+			excluded[name] = true
+		}
+	}
+
+	names := []string{}
+	contractType := reflect.TypeOf(iContract)
+	for i := 0; i < contractType.NumMethod(); i++ {
+		name := contractType.Method(i).Name
+		if !excluded[name] {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func getEditDistance(
+	iString string,
+	iOtherString string,
+) int {
+	previous := make([]int, len(iOtherString)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(iString); i++ {
+		current := make([]int, len(iOtherString)+1)
+		current[0] = i
+		for j := 1; j <= len(iOtherString); j++ {
+			substitution := previous[j-1]
+			if iString[i-1] != iOtherString[j-1] {
+				substitution++
+			}
+
+			current[j] = substitution
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous = current
+	}
+
+	return previous[len(iOtherString)]
+}
+
+/// meant to be the unknown transaction function of every contract, contractapi only calls it for unknown
+/// functions of existing contracts
+func UnknownTransaction(
+	iCtx contractapi.TransactionContextInterface,
+	iContract contractapi.ContractInterface,
+) error {
+	function, _ := iCtx.GetStub().GetFunctionAndParameters()
+	if index := strings.LastIndex(function, ":"); index != -1 {
+		function = function[index+1:]
+	}
+
+	availableFunctions := getTransactionNames(iContract)
+	suggestions := []string{}
+	for _, name := range availableFunctions {
+		if getEditDistance(strings.ToLower(function), strings.ToLower(name)) <= maxSuggestionDistance {
+			suggestions = append(suggestions, name)
+		}
+	}
+
+	contractName := iContract.GetName()
+	if contractName == "" {
+		contractName = reflect.TypeOf(iContract).Elem().Name()
+	}
+
+	errorJson, err := json.Marshal(UnknownTransactionError{
+		Error:              "unknown function",
+		Contract:           contractName,
+		Function:           function,
+		Suggestions:        suggestions,
+		AvailableFunctions: availableFunctions,
+	})
+	if err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%s", errorJson)
+}
+
+func (c *GraphContract) GetUnknownTransaction() interface{} {
+	return func(iCtx contractapi.TransactionContextInterface) error {
+		return UnknownTransaction(iCtx, c)
+	}
+}
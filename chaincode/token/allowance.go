@@ -0,0 +1,298 @@
+package token
+
+import (
+	"encoding/json"
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/shopspring/decimal"
+)
+
+/// Owners grant a spender, e.g. an exchange or an escrow service, the right to move their tokens or part of
+/// their balance, as ERC-721 and ERC-20 approvals do
+
+const allowanceObjectType = "allowance"
+
+/// Signed by the owner of the token, an empty SpenderPublicKey revokes the approval.
+/// Sequence is the one of the token before the approval
+type TokenApproval struct {
+	TokenId          string `json:"TokenId"`
+	SpenderPublicKey string `json:"SpenderPublicKey"`
+	Sequence         int    `json:"Sequence"`
+	Signature        string `json:"Signature"`
+}
+
+/// Signed by the owner, the allowance becomes Amount and a zero Amount revokes it.
+/// Sequence is the one of the owner's balance before the approval
+type AllowanceApproval struct {
+	Class            string `json:"Class"`
+	OwnerPublicKey   string `json:"OwnerPublicKey"`
+	SpenderPublicKey string `json:"SpenderPublicKey"`
+	Amount           string `json:"Amount"`
+	Sequence         int    `json:"Sequence"`
+	Signature        string `json:"Signature"`
+}
+
+/// Amount is what the spender can still transfer
+type Allowance struct {
+	Class            string `json:"Class"`
+	OwnerPublicKey   string `json:"OwnerPublicKey"`
+	SpenderPublicKey string `json:"SpenderPublicKey"`
+	Amount           string `json:"Amount"`
+	Sequence         int    `json:"Sequence"` /// number of transfers by the spender
+}
+
+/// Signed by the spender, Sequence is the one of the allowance before the transfer
+type SpenderTransfer struct {
+	Class            string `json:"Class"`
+	OwnerPublicKey   string `json:"OwnerPublicKey"`
+	SpenderPublicKey string `json:"SpenderPublicKey"`
+	ToPublicKey      string `json:"ToPublicKey"`
+	Amount           string `json:"Amount"`
+	Sequence         int    `json:"Sequence"`
+	Signature        string `json:"Signature"`
+}
+
+/// returns an empty allowance if none was granted
+func getAllowance(
+	iCtx contractapi.TransactionContextInterface,
+	iClass string,
+	iOwnerPublicKey string,
+	iSpenderPublicKey string,
+) (*Allowance, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(allowanceObjectType, []string{iClass, ownerFingerprint(iOwnerPublicKey), ownerFingerprint(iSpenderPublicKey)})
+	if err != nil {
+		return nil, err
+	}
+
+	allowanceJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, makeInternalError("failed to read from ledger: %v", err)
+	}
+
+	if allowanceJson == nil {
+		return &Allowance{
+			Class:            iClass,
+			OwnerPublicKey:   iOwnerPublicKey,
+			SpenderPublicKey: iSpenderPublicKey,
+			Amount:           decimal.NewFromInt(0).String(),
+		}, nil
+	}
+
+	var allowance Allowance
+	err = json.Unmarshal(allowanceJson, &allowance)
+	if err != nil {
+		return nil, err
+	}
+
+	return &allowance, nil
+}
+
+func putAllowance(
+	iCtx contractapi.TransactionContextInterface,
+	iAllowance *Allowance,
+) error {
+	key, err := iCtx.GetStub().CreateCompositeKey(allowanceObjectType, []string{iAllowance.Class, ownerFingerprint(iAllowance.OwnerPublicKey), ownerFingerprint(iAllowance.SpenderPublicKey)})
+	if err != nil {
+		return err
+	}
+
+	allowanceJson, err := json.Marshal(iAllowance)
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().PutState(key, allowanceJson)
+}
+
+/// iSignature is the owner's signature of the TokenApproval
+func (c *TokenContract) ApproveToken(
+	iCtx contractapi.TransactionContextInterface,
+	iTokenId string,
+	iSpenderPublicKey string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	token, err := getToken(iCtx, iTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.ConsumingTokenId != "" {
+		return nil, makeAlreadyConsumedError(token)
+	}
+
+	approval := TokenApproval{
+		TokenId:          iTokenId,
+		SpenderPublicKey: iSpenderPublicKey,
+		Sequence:         token.Sequence,
+	}
+	err = verifyPayload(token.OwnerPublicKey, &approval, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	token.SpenderPublicKey = iSpenderPublicKey
+	token.Sequence++
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putToken(iCtx, token))
+}
+
+/// iSignature is the spender's signature of the TokenTransfer, the approval ends with the transfer
+func (c *TokenContract) TransferTokenFrom(
+	iCtx contractapi.TransactionContextInterface,
+	iTokenId string,
+	iNewOwnerPublicKey string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	token, err := getToken(iCtx, iTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.SpenderPublicKey == "" {
+		return nil, makePreconditionFailedError("token %s has no approved spender", iTokenId)
+	}
+
+	transfer := TokenTransfer{
+		TokenId:           iTokenId,
+		NewOwnerPublicKey: iNewOwnerPublicKey,
+		Sequence:          token.Sequence,
+	}
+	err = verifyPayload(token.SpenderPublicKey, &transfer, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, changeOwner(iCtx, token, iNewOwnerPublicKey))
+}
+
+/// iSignature is the owner's signature of the AllowanceApproval, which replaces the previous allowance
+func (c *TokenContract) Approve(
+	iCtx contractapi.TransactionContextInterface,
+	iClass string,
+	iOwnerPublicKey string,
+	iSpenderPublicKey string,
+	iAmount string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	amount, err := decimal.NewFromString(iAmount)
+	if err != nil {
+		return nil, makeInvalidArgumentError("invalid amount %s: %v", iAmount, err)
+	}
+
+	if amount.IsNegative() {
+		return nil, makeInvalidArgumentError("amount cannot be negative")
+	}
+
+	if iSpenderPublicKey == "" || iSpenderPublicKey == iOwnerPublicKey {
+		return nil, makeInvalidArgumentError("spender must be another key than the owner")
+	}
+
+	_, err = getTokenClass(iCtx, iClass)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := getBalance(iCtx, iClass, iOwnerPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	allowance, err := getAllowance(iCtx, iClass, iOwnerPublicKey, iSpenderPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	approval := AllowanceApproval{
+		Class:            iClass,
+		OwnerPublicKey:   iOwnerPublicKey,
+		SpenderPublicKey: iSpenderPublicKey,
+		Amount:           amount.String(),
+		Sequence:         balance.Sequence,
+	}
+	err = verifyPayload(iOwnerPublicKey, &approval, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	/// the balance sequence changes so that the approval cannot be replayed once the allowance is spent
+	balance.Sequence++
+	err = putBalance(iCtx, balance)
+	if err != nil {
+		return nil, err
+	}
+
+	allowance.Amount = amount.String()
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putAllowance(iCtx, allowance))
+}
+
+/// iSignature is the spender's signature of the SpenderTransfer
+func (c *TokenContract) TransferFrom(
+	iCtx contractapi.TransactionContextInterface,
+	iClass string,
+	iOwnerPublicKey string,
+	iSpenderPublicKey string,
+	iToPublicKey string,
+	iAmount string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	amount, err := parseAmount(iAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	allowance, err := getAllowance(iCtx, iClass, iOwnerPublicKey, iSpenderPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer := SpenderTransfer{
+		Class:            iClass,
+		OwnerPublicKey:   iOwnerPublicKey,
+		SpenderPublicKey: iSpenderPublicKey,
+		ToPublicKey:      iToPublicKey,
+		Amount:           amount.String(),
+		Sequence:         allowance.Sequence,
+	}
+	err = verifyPayload(iSpenderPublicKey, &transfer, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, err := decimal.NewFromString(allowance.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	if amount.GreaterThan(allowed) {
+		return nil, makePreconditionFailedError("amount exceeds the allowance of the spender")
+	}
+
+	allowance.Amount = allowed.Sub(amount).String()
+	allowance.Sequence++
+	err = putAllowance(iCtx, allowance)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := getBalance(iCtx, iClass, iOwnerPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, transferAmount(iCtx, iClass, from, iToPublicKey, amount))
+}
+
+/// returns the amount iSpenderPublicKey can still transfer from the balance of iOwnerPublicKey
+func (c *TokenContract) GetAllowance(
+	iCtx contractapi.TransactionContextInterface,
+	iClass string,
+	iOwnerPublicKey string,
+	iSpenderPublicKey string,
+) (*Allowance, error) {
+	_, err := getTokenClass(iCtx, iClass)
+	if err != nil {
+		return nil, err
+	}
+
+	return getAllowance(iCtx, iClass, iOwnerPublicKey, iSpenderPublicKey)
+}
@@ -0,0 +1,124 @@
+package token
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// [msp id, submitter id, client request id] -> clientRequest, client request ids are scoped by submitter
+const clientRequestObjectType = "clientRequest"
+
+/// RequestHash tells a retry from another request reusing the id. Receipt does not list the key of the
+/// clientRequest itself, which is written after the receipt is made
+type clientRequest struct {
+	RequestHash string                   `json:"RequestHash"`
+	Receipt     graph.TransactionReceipt `json:"Receipt"`
+}
+
+func getClientRequestKey(
+	iCtx contractapi.TransactionContextInterface,
+	iClientRequestId string,
+) (string, error) {
+	mspId, err := iCtx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", err
+	}
+
+	submitterId, err := iCtx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", err
+	}
+
+	return iCtx.GetStub().CreateCompositeKey(clientRequestObjectType, []string{mspId, submitterId, iClientRequestId})
+}
+
+func hashRequest(
+	iRequest interface{},
+) (string, error) {
+	requestJson, err := json.Marshal(iRequest)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(requestJson)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+/// returns the receipt of the transaction which first ran iRequest with iClientRequestId, nil if there was none
+func getClientRequestReceipt(
+	iCtx contractapi.TransactionContextInterface,
+	iClientRequestId string,
+	iRequest interface{},
+) (*graph.TransactionReceipt, error) {
+	key, err := getClientRequestKey(iCtx, iClientRequestId)
+	if err != nil {
+		return nil, err
+	}
+
+	requestJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, makeInternalError("failed to read from ledger: %v", err)
+	}
+
+	if requestJson == nil {
+		return nil, nil
+	}
+
+	var request clientRequest
+	err = json.Unmarshal(requestJson, &request)
+	if err != nil {
+		return nil, err
+	}
+
+	requestHash, err := hashRequest(iRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.RequestHash != requestHash {
+		return nil, makeAlreadyExistsError("client request id %s was used for another request", iClientRequestId)
+	}
+
+	return &request.Receipt, nil
+}
+
+/// to be called after the last write of the transaction, returns its receipt
+func putClientRequest(
+	iCtx contractapi.TransactionContextInterface,
+	iClientRequestId string,
+	iRequest interface{},
+) (*graph.TransactionReceipt, error) {
+	receipt, err := graph.MakeTransactionReceipt(iCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	requestHash, err := hashRequest(iRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	requestJson, err := json.Marshal(clientRequest{
+		RequestHash: requestHash,
+		Receipt:     *receipt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := getClientRequestKey(iCtx, iClientRequestId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = iCtx.GetStub().PutState(key, requestJson)
+	if err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
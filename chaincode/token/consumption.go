@@ -0,0 +1,467 @@
+package token
+
+import (
+	"encoding/json"
+	"sig_chain/chaincode/asset"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	consumptionRequestObjectType  = "consumptionRequest"
+	consumptionApprovalObjectType = "consumptionApproval"
+)
+
+/// strict mode of the config of the ledger, see asset.IsStrictModeEnabled
+const strictConsumptionMode = "tokenConsumption"
+
+/// Id is the id of the transaction which opened the request. The request is deleted once the consumption
+/// completes
+type ConsumptionRequest struct {
+	Id               string    `json:"Id"`
+	ConsumedTokenIds []string  `json:"ConsumedTokenIds"`
+	ConsumingTokenId string    `json:"ConsumingTokenId"`
+	RequestedTime    time.Time `json:"RequestedTime"`
+}
+
+type ConsumptionStatus = string
+
+const (
+	eConsumptionRequested ConsumptionStatus = "eConsumptionRequested" /// the owners approve it with ApproveConsumption
+	eNothingRequested     ConsumptionStatus = "eNothingRequested"     /// a token lacks its approval url, nothing is written
+)
+
+/// RequestId is the id of the opened request, empty if nothing is requested
+type ConsumptionReceipt struct {
+	graph.TransactionReceipt
+	Status    ConsumptionStatus `json:"Status"`
+	RequestId string            `json:"RequestId,omitempty" metadata:",optional"`
+}
+
+/// Signed by the owner of a consumed token to open the consumption, it counts as the approval of this owner
+type TokenConsumption struct {
+	ConsumedTokenIds []string `json:"ConsumedTokenIds"`
+	ConsumingTokenId string   `json:"ConsumingTokenId"`
+	Signature        string   `json:"Signature"`
+}
+
+/// Signed by the owner of any of the tokens
+type ConsumptionApproval struct {
+	RequestId        string   `json:"RequestId"`
+	ConsumedTokenIds []string `json:"ConsumedTokenIds"`
+	ConsumingTokenId string   `json:"ConsumingTokenId"`
+	Signature        string   `json:"Signature"`
+}
+
+/// approvals are stored by owner, those of a previous owner do not count anymore
+type approvalRecord struct {
+	ApproverPublicKey string `json:"ApproverPublicKey"`
+}
+
+func getConsumptionRequest(
+	iCtx contractapi.TransactionContextInterface,
+	iRequestId string,
+) (*ConsumptionRequest, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(consumptionRequestObjectType, []string{iRequestId})
+	if err != nil {
+		return nil, err
+	}
+
+	requestJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, makeInternalError("failed to read from ledger: %v", err)
+	}
+
+	if requestJson == nil {
+		return nil, makeNotFoundError("consumption request %s does not exist", iRequestId)
+	}
+
+	var request ConsumptionRequest
+	err = json.Unmarshal(requestJson, &request)
+	if err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+/// returns nil if iOwnerPublicKey has not approved iRequestId yet
+func getApproval(
+	iCtx contractapi.TransactionContextInterface,
+	iRequestId string,
+	iOwnerPublicKey string,
+) (*approvalRecord, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(consumptionApprovalObjectType, []string{iRequestId, ownerFingerprint(iOwnerPublicKey)})
+	if err != nil {
+		return nil, err
+	}
+
+	approvalJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, makeInternalError("failed to read from ledger: %v", err)
+	}
+
+	if approvalJson == nil {
+		return nil, nil
+	}
+
+	var approval approvalRecord
+	err = json.Unmarshal(approvalJson, &approval)
+	if err != nil {
+		return nil, err
+	}
+
+	return &approval, nil
+}
+
+func putApproval(
+	iCtx contractapi.TransactionContextInterface,
+	iRequestId string,
+	iApproval *approvalRecord,
+) error {
+	key, err := iCtx.GetStub().CreateCompositeKey(consumptionApprovalObjectType, []string{iRequestId, ownerFingerprint(iApproval.ApproverPublicKey)})
+	if err != nil {
+		return err
+	}
+
+	approvalJson, err := json.Marshal(iApproval)
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().PutState(key, approvalJson)
+}
+
+func deleteConsumptionRequest(
+	iCtx contractapi.TransactionContextInterface,
+	iRequestId string,
+) error {
+	iterator, err := iCtx.GetStub().GetStateByPartialCompositeKey(consumptionApprovalObjectType, []string{iRequestId})
+	if err != nil {
+		return err
+	}
+	defer iterator.Close()
+
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return err
+		}
+
+		err = iCtx.GetStub().DelState(kv.Key)
+		if err != nil {
+			return err
+		}
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(consumptionRequestObjectType, []string{iRequestId})
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().DelState(key)
+}
+
+/// both tokens must still be available, a token can only be consumed once and a consumed token cannot
+/// consume other tokens. Frozen tokens can neither be consumed nor consume, and the policy of the class of
+/// the consumed token must allow it
+func checkConsumable(
+	iCtx contractapi.TransactionContextInterface,
+	iConsumed *Token,
+	iConsuming *Token,
+) error {
+	if iConsumed.Id == iConsuming.Id {
+		return makeInvalidArgumentError("a token cannot consume itself")
+	}
+
+	if iConsumed.ConsumingTokenId != "" {
+		return makeAlreadyConsumedError(iConsumed)
+	}
+
+	if iConsuming.ConsumingTokenId != "" {
+		return makeAlreadyConsumedError(iConsuming)
+	}
+
+	err := checkNotFrozen(iConsumed)
+	if err != nil {
+		return err
+	}
+
+	err = checkNotFrozen(iConsuming)
+	if err != nil {
+		return err
+	}
+
+	return checkConsumptionPolicy(iCtx, iConsumed, iConsuming)
+}
+
+/// links every token of ioConsumed to iConsuming, approvals must have been checked by the caller. iConsuming
+/// itself is not written, so that consumptions into the same token do not conflict
+func consumeTokens(
+	iCtx contractapi.TransactionContextInterface,
+	ioConsumed []*Token,
+	iConsuming *Token,
+) error {
+	event := TokenEvent{
+		TokenIds:          []string{},
+		OwnerFingerprints: []string{ownerFingerprint(iConsuming.OwnerPublicKey)},
+		ConsumingTokenId:  iConsuming.Id,
+	}
+	for _, consumed := range ioConsumed {
+		err := checkConsumable(iCtx, consumed, iConsuming)
+		if err != nil {
+			return err
+		}
+
+		consumed.ConsumingTokenId = iConsuming.Id
+		err = putToken(iCtx, consumed)
+		if err != nil {
+			return err
+		}
+
+		err = putConsumedIndex(iCtx, iConsuming.Id, consumed.Id)
+		if err != nil {
+			return err
+		}
+
+		event.TokenIds = append(event.TokenIds, consumed.Id)
+		event.OwnerFingerprints = append(event.OwnerFingerprints, ownerFingerprint(consumed.OwnerPublicKey))
+	}
+
+	return setTokenEvent(iCtx, tokenConsumedEventName, &event)
+}
+
+/// iSignature is the signature of the TokenConsumption by the owner of a consumed token.
+/// Returns nil if a token lacks the url its owner is notified at, nothing is requested then. In strict
+/// consumption mode, this fails instead
+func openConsumptionRequest(
+	iCtx contractapi.TransactionContextInterface,
+	iConsumedTokenIds []string,
+	iConsumingTokenId string,
+	iSignature string,
+) (*ConsumptionRequest, error) {
+	if len(iConsumedTokenIds) == 0 {
+		return nil, makeInvalidArgumentError("no token to consume")
+	}
+
+	consuming, err := getToken(iCtx, iConsumingTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	hasUrls := consuming.RequestToAcceptUrl != ""
+	consumedOwnerPublicKeys := []string{}
+	isListed := map[string]bool{}
+	for _, consumedTokenId := range iConsumedTokenIds {
+		if isListed[consumedTokenId] {
+			return nil, makeInvalidArgumentError("token %s is listed more than once", consumedTokenId)
+		}
+		isListed[consumedTokenId] = true
+
+		consumed, err := getToken(iCtx, consumedTokenId)
+		if err != nil {
+			return nil, err
+		}
+
+		err = checkConsumable(iCtx, consumed, consuming)
+		if err != nil {
+			return nil, err
+		}
+
+		hasUrls = hasUrls && consumed.RequestToSendUrl != ""
+		consumedOwnerPublicKeys = append(consumedOwnerPublicKeys, consumed.OwnerPublicKey)
+	}
+
+	consumption := TokenConsumption{
+		ConsumedTokenIds: iConsumedTokenIds,
+		ConsumingTokenId: iConsumingTokenId,
+	}
+	openerPublicKey := ""
+	for _, ownerPublicKey := range consumedOwnerPublicKeys {
+		if verifyPayload(ownerPublicKey, &consumption, iSignature) == nil {
+			openerPublicKey = ownerPublicKey
+			break
+		}
+	}
+
+	if openerPublicKey == "" {
+		return nil, makeUnauthorizedError("signature is not the one of the owner of a consumed token")
+	}
+
+	if !hasUrls {
+		isStrict, err := asset.IsStrictModeEnabled(iCtx, strictConsumptionMode)
+		if err != nil {
+			return nil, makeInternalError("failed to read the config: %v", err)
+		}
+
+		if isStrict {
+			return nil, makePreconditionFailedError("the owners of the tokens cannot be notified, a token lacks its approval url")
+		}
+
+		return nil, nil
+	}
+
+	timestamp, err := iCtx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+
+	request := ConsumptionRequest{
+		Id:               iCtx.GetStub().GetTxID(),
+		ConsumedTokenIds: iConsumedTokenIds,
+		ConsumingTokenId: iConsumingTokenId,
+		RequestedTime:    time.Unix(timestamp.Seconds, int64(timestamp.Nanos)).UTC(),
+	}
+	requestJson, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(consumptionRequestObjectType, []string{request.Id})
+	if err != nil {
+		return nil, err
+	}
+
+	err = iCtx.GetStub().PutState(key, requestJson)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putApproval(iCtx, request.Id, &approvalRecord{ApproverPublicKey: openerPublicKey})
+	if err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+/// iRequest is the opened request, nil if nothing is requested
+func makeConsumptionReceiptIfSucceeded(
+	iCtx contractapi.TransactionContextInterface,
+	iRequest *ConsumptionRequest,
+	iErr error,
+) (*ConsumptionReceipt, error) {
+	receipt, err := graph.MakeTransactionReceiptIfSucceeded(iCtx, iErr)
+	if err != nil {
+		return nil, err
+	}
+
+	if iRequest == nil {
+		return &ConsumptionReceipt{TransactionReceipt: *receipt, Status: eNothingRequested}, nil
+	}
+
+	return &ConsumptionReceipt{TransactionReceipt: *receipt, Status: eConsumptionRequested, RequestId: iRequest.Id}, nil
+}
+
+/// opens a consumption request which completes once the owners of both tokens approve it with
+/// ApproveConsumption, their wallets are notified at the RequestToSendUrl of the consumed token and the
+/// RequestToAcceptUrl of the consuming token. Nothing is requested if either url is empty, unless the
+/// "strict.tokenConsumption" config is set in which case it fails, the Status of the receipt tells both cases
+/// apart. iSignature is the signature of the TokenConsumption by the owner of the consumed token, which
+/// approves the request for this owner. The id of the request is the id of the transaction
+func (c *TokenContract) ConsumeToken(
+	iCtx contractapi.TransactionContextInterface,
+	iConsumedTokenId string,
+	iConsumingTokenId string,
+	iSignature string,
+) (*ConsumptionReceipt, error) {
+	request, err := openConsumptionRequest(iCtx, []string{iConsumedTokenId}, iConsumingTokenId, iSignature)
+	return makeConsumptionReceiptIfSucceeded(iCtx, request, err)
+}
+
+/// as ConsumeToken for many tokens consumed by one, e.g. deposit tokens aggregated into one token. The
+/// consumption completes once every owner of the tokens approves it, so that it is applied in a single
+/// transaction. iSignature is the signature of the TokenConsumption by the owner of any consumed token
+func (c *TokenContract) ConsumeTokens(
+	iCtx contractapi.TransactionContextInterface,
+	iConsumedTokenIds []string,
+	iConsumingTokenId string,
+	iSignature string,
+) (*ConsumptionReceipt, error) {
+	request, err := openConsumptionRequest(iCtx, iConsumedTokenIds, iConsumingTokenId, iSignature)
+	return makeConsumptionReceiptIfSucceeded(iCtx, request, err)
+}
+
+/// iSignature is the signature of the ConsumptionApproval by the owner of any of the tokens, it approves for
+/// every token of this owner. The consumption completes with the last approval
+func (c *TokenContract) ApproveConsumption(
+	iCtx contractapi.TransactionContextInterface,
+	iRequestId string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	request, err := getConsumptionRequest(iCtx, iRequestId)
+	if err != nil {
+		return nil, err
+	}
+
+	consuming, err := getToken(iCtx, request.ConsumingTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	consumedTokens := []*Token{}
+	ownerPublicKeys := []string{consuming.OwnerPublicKey}
+	isOwner := map[string]bool{consuming.OwnerPublicKey: true}
+	for _, consumedTokenId := range request.ConsumedTokenIds {
+		consumed, err := getToken(iCtx, consumedTokenId)
+		if err != nil {
+			return nil, err
+		}
+		consumedTokens = append(consumedTokens, consumed)
+
+		if !isOwner[consumed.OwnerPublicKey] {
+			isOwner[consumed.OwnerPublicKey] = true
+			ownerPublicKeys = append(ownerPublicKeys, consumed.OwnerPublicKey)
+		}
+	}
+
+	approval := ConsumptionApproval{
+		RequestId:        request.Id,
+		ConsumedTokenIds: request.ConsumedTokenIds,
+		ConsumingTokenId: request.ConsumingTokenId,
+	}
+
+	approverPublicKey := ""
+	approvalCount := 0
+	for _, ownerPublicKey := range ownerPublicKeys {
+		if approverPublicKey == "" && verifyPayload(ownerPublicKey, &approval, iSignature) == nil {
+			approverPublicKey = ownerPublicKey
+			approvalCount++
+			continue
+		}
+
+		/// writes of this transaction cannot be read back, only earlier approvals are in the ledger
+		existing, err := getApproval(iCtx, iRequestId, ownerPublicKey)
+		if err != nil {
+			return nil, err
+		}
+
+		if existing != nil {
+			approvalCount++
+		}
+	}
+
+	if approverPublicKey == "" {
+		return nil, makeUnauthorizedError("signature is not the one of a token owner")
+	}
+
+	if approvalCount < len(ownerPublicKeys) {
+		return graph.MakeTransactionReceiptIfSucceeded(iCtx, putApproval(iCtx, iRequestId, &approvalRecord{ApproverPublicKey: approverPublicKey}))
+	}
+
+	err = consumeTokens(iCtx, consumedTokens, consuming)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, deleteConsumptionRequest(iCtx, iRequestId))
+}
+
+func (c *TokenContract) GetConsumptionRequest(
+	iCtx contractapi.TransactionContextInterface,
+	iRequestId string,
+) (*ConsumptionRequest, error) {
+	return getConsumptionRequest(iCtx, iRequestId)
+}
@@ -0,0 +1,113 @@
+package token_test
+
+import (
+	"sig_chain/chaincode/token"
+	"sig_chain/pkg/client"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func signConsumption(
+	t *testing.T,
+	iOwner client.Signer,
+	iConsumedTokenId string,
+	iConsumingTokenId string,
+) string {
+	return signPayload(t, iOwner, &token.TokenConsumption{
+		ConsumedTokenIds: []string{iConsumedTokenId},
+		ConsumingTokenId: iConsumingTokenId,
+	})
+}
+
+func (l *testLedger) consumeToken(
+	iConsumedTokenId string,
+	iConsumingTokenId string,
+	iSignature string,
+) (*token.ConsumptionReceipt, error) {
+	var receipt *token.ConsumptionReceipt
+	err := l.submit(func(iCtx contractapi.TransactionContextInterface) error {
+		var err error
+		receipt, err = l.contract.ConsumeToken(iCtx, iConsumedTokenId, iConsumingTokenId, iSignature)
+		return err
+	})
+
+	return receipt, err
+}
+
+/// without approval urls the owners cannot be notified, the receipt tells that nothing happened
+func TestConsumptionWithoutUrls(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	bob := makeTestSigner(t)
+	l.createToken("t1", alice, "")
+	l.createToken("t2", bob, "")
+
+	receipt, err := l.consumeToken("t1", "t2", signConsumption(t, alice, "t1", "t2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if receipt.Status != "eNothingRequested" || receipt.RequestId != "" || len(receipt.WrittenKeys) != 0 {
+		t.Fatalf("consumption without urls is not reported as a no-op: %+v", receipt)
+	}
+	if l.getToken("t1").ConsumingTokenId != "" {
+		t.Fatal("t1 is consumed without approvals")
+	}
+
+	l.setConfig("strict.tokenConsumption", "true")
+	_, err = l.consumeToken("t1", "t2", signConsumption(t, alice, "t1", "t2"))
+	if err == nil {
+		t.Fatal("consumption without urls succeeded in strict mode")
+	}
+}
+
+func TestConsumptionApprovals(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	bob := makeTestSigner(t)
+	l.createToken("t1", alice, "https://alice.example")
+	l.createToken("t2", bob, "https://bob.example")
+
+	_, err := l.consumeToken("t1", "t2", signConsumption(t, bob, "t1", "t2"))
+	if err == nil {
+		t.Fatal("consumption opened by the owner of the consuming token")
+	}
+
+	receipt, err := l.consumeToken("t1", "t2", signConsumption(t, alice, "t1", "t2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if receipt.Status != "eConsumptionRequested" || receipt.RequestId != receipt.TxId {
+		t.Fatalf("consumption is not requested: %+v", receipt)
+	}
+
+	approval := token.ConsumptionApproval{
+		RequestId:        receipt.RequestId,
+		ConsumedTokenIds: []string{"t1"},
+		ConsumingTokenId: "t2",
+	}
+	l.mustFail("approve by a stranger", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.ApproveConsumption(iCtx, receipt.RequestId, signPayload(t, makeTestSigner(t), &approval))
+		return err
+	})
+
+	if l.getToken("t1").ConsumingTokenId != "" {
+		t.Fatal("t1 is consumed before the owner of t2 approves")
+	}
+
+	l.mustSubmit("approve by the owner of t2", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.ApproveConsumption(iCtx, receipt.RequestId, signPayload(t, bob, &approval))
+		return err
+	})
+
+	if l.getToken("t1").ConsumingTokenId != "t2" {
+		t.Fatal("t1 is not consumed once approved by both owners")
+	}
+
+	l.mustFail("request is deleted once completed", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.GetConsumptionRequest(iCtx, receipt.RequestId)
+		return err
+	})
+}
@@ -0,0 +1,191 @@
+package token
+
+import (
+	"encoding/json"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// token id -> TokenTombstone, so that the id of a deleted token cannot be reused
+const tokenTombstoneObjectType = "tokenTombstone"
+
+/// Signed by the owner, Sequence is the one of the token before the deletion
+type TokenDeletion struct {
+	TokenId   string `json:"TokenId"`
+	Sequence  int    `json:"Sequence"`
+	Signature string `json:"Signature"`
+}
+
+type TokenTombstone struct {
+	Token       Token     `json:"Token"` /// as it was when deleted
+	TxId        string    `json:"TxId"`
+	DeletedTime time.Time `json:"DeletedTime"`
+}
+
+func getTombstoneKey(
+	iCtx contractapi.TransactionContextInterface,
+	iTokenId string,
+) (string, error) {
+	return iCtx.GetStub().CreateCompositeKey(tokenTombstoneObjectType, []string{iTokenId})
+}
+
+func getTombstone(
+	iCtx contractapi.TransactionContextInterface,
+	iTokenId string,
+) (*TokenTombstone, error) {
+	key, err := getTombstoneKey(iCtx, iTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	tombstoneJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, makeInternalError("failed to read from ledger: %v", err)
+	}
+
+	if tombstoneJson == nil {
+		return nil, makeNotFoundError("token %s was not deleted", iTokenId)
+	}
+
+	var tombstone TokenTombstone
+	err = json.Unmarshal(tombstoneJson, &tombstone)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tombstone, nil
+}
+
+/// returns whether the token consumed other tokens, which would be left pointing to a deleted token
+func hasConsumedTokens(
+	iCtx contractapi.TransactionContextInterface,
+	iTokenId string,
+) (bool, error) {
+	iterator, err := iCtx.GetStub().GetStateByPartialCompositeKey(consumedTokenObjectType, []string{iTokenId})
+	if err != nil {
+		return false, err
+	}
+	defer iterator.Close()
+
+	return iterator.HasNext(), nil
+}
+
+/// removes a token created by mistake, which must neither be consumed nor have consumed other tokens. A
+/// tombstone keeps the deleted token, so that its id cannot be reused.
+/// iSignature is the owner's signature of the TokenDeletion
+func (c *TokenContract) DeleteToken(
+	iCtx contractapi.TransactionContextInterface,
+	iTokenId string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	token, err := getToken(iCtx, iTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	deletion := TokenDeletion{
+		TokenId:  iTokenId,
+		Sequence: token.Sequence,
+	}
+	err = verifyPayload(token.OwnerPublicKey, &deletion, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.ConsumingTokenId != "" {
+		return nil, makeAlreadyConsumedError(token)
+	}
+
+	err = checkNotFrozen(token)
+	if err != nil {
+		return nil, err
+	}
+
+	hasConsumed, err := hasConsumedTokens(iCtx, iTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	if hasConsumed {
+		return nil, makePreconditionFailedError("token %s consumed other tokens", iTokenId)
+	}
+
+	if token.MaterialNodeId != "" {
+		key, err := iCtx.GetStub().CreateCompositeKey(tokenMaterialObjectType, []string{token.MaterialNodeId})
+		if err != nil {
+			return nil, err
+		}
+
+		err = iCtx.GetStub().DelState(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	/// the token does not count towards the supply of its class anymore
+	if token.Class != "" {
+		class, err := getTokenClass(iCtx, token.Class)
+		if err != nil {
+			return nil, err
+		}
+
+		class.TokenCount--
+		err = putTokenClass(iCtx, class)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = deleteOwnerIndex(iCtx, token.OwnerPublicKey, iTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenKey, err := getTokenKey(iCtx, iTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = iCtx.GetStub().DelState(tokenKey)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp, err := iCtx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+
+	tombstoneJson, err := json.Marshal(TokenTombstone{
+		Token:       *token,
+		TxId:        iCtx.GetStub().GetTxID(),
+		DeletedTime: time.Unix(timestamp.Seconds, int64(timestamp.Nanos)).UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tombstoneKey, err := getTombstoneKey(iCtx, iTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = iCtx.GetStub().PutState(tombstoneKey, tombstoneJson)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, setTokenEvent(iCtx, tokenBurnedEventName, &TokenEvent{
+		TokenIds:          []string{iTokenId},
+		OwnerFingerprints: []string{ownerFingerprint(token.OwnerPublicKey)},
+	}))
+}
+
+func (c *TokenContract) GetTokenTombstone(
+	iCtx contractapi.TransactionContextInterface,
+	iTokenId string,
+) (*TokenTombstone, error) {
+	return getTombstone(iCtx, iTokenId)
+}
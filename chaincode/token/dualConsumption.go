@@ -0,0 +1,81 @@
+package token
+
+import (
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// nonces of the dual signed consumptions, so that the signatures cannot be replayed
+const consumptionNonceObjectType = "consumptionNonce"
+
+/// Signed by the owners of both tokens. Nonce is chosen by the signers and can be used once
+type DualConsumption struct {
+	ConsumedTokenId  string `json:"ConsumedTokenId"`
+	ConsumingTokenId string `json:"ConsumingTokenId"`
+	Nonce            string `json:"Nonce"`
+	Signature        string `json:"Signature"`
+}
+
+/// as ConsumeToken, but the owners of both tokens sign upfront and the consumption completes in this
+/// transaction, e.g. when linking the tokens has financial consequences. iConsumedOwnerSignature and
+/// iConsumingOwnerSignature are the signatures of the DualConsumption by the owners of the consumed and
+/// consuming tokens
+func (c *TokenContract) ConsumeTokenWithSignatures(
+	iCtx contractapi.TransactionContextInterface,
+	iConsumedTokenId string,
+	iConsumingTokenId string,
+	iNonce string,
+	iConsumedOwnerSignature string,
+	iConsumingOwnerSignature string,
+) (*graph.TransactionReceipt, error) {
+	if iNonce == "" {
+		return nil, makeInvalidArgumentError("nonce cannot be empty")
+	}
+
+	consumed, err := getToken(iCtx, iConsumedTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	consuming, err := getToken(iCtx, iConsumingTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	consumption := DualConsumption{
+		ConsumedTokenId:  iConsumedTokenId,
+		ConsumingTokenId: iConsumingTokenId,
+		Nonce:            iNonce,
+	}
+	err = verifyPayload(consumed.OwnerPublicKey, &consumption, iConsumedOwnerSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	err = verifyPayload(consuming.OwnerPublicKey, &consumption, iConsumingOwnerSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceKey, err := iCtx.GetStub().CreateCompositeKey(consumptionNonceObjectType, []string{iNonce})
+	if err != nil {
+		return nil, err
+	}
+
+	usedNonce, err := iCtx.GetStub().GetState(nonceKey)
+	if err != nil {
+		return nil, makeInternalError("failed to read from ledger: %v", err)
+	}
+
+	if usedNonce != nil {
+		return nil, makeAlreadyExistsError("nonce %s was already used", iNonce)
+	}
+
+	err = iCtx.GetStub().PutState(nonceKey, []byte(iCtx.GetStub().GetTxID()))
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, consumeTokens(iCtx, []*Token{consumed}, consuming))
+}
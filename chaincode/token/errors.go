@@ -0,0 +1,139 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/graph"
+)
+
+/// machine readable code of the errors of the token contract, so that clients do not parse messages
+type ErrorCode = string
+
+const (
+	eNotFound           ErrorCode = "eNotFound"
+	eAlreadyExists      ErrorCode = "eAlreadyExists"
+	eAlreadyConsumed    ErrorCode = "eAlreadyConsumed"
+	eUnauthorized       ErrorCode = "eUnauthorized"
+	eInvalidArgument    ErrorCode = "eInvalidArgument"
+	ePreconditionFailed ErrorCode = "ePreconditionFailed"
+	eInternal           ErrorCode = "eInternal"
+)
+
+/// errors of the token contract embed BaseError, so that callers can tell them apart from ledger errors.
+/// The message of the peer response is the json of the error
+type BaseError struct {
+	Code    ErrorCode `json:"Code"`
+	Message string    `json:"Message"`
+}
+
+func (e *BaseError) Error() string {
+	errorJson, err := json.Marshal(e)
+	if err != nil {
+		return e.Message
+	}
+
+	return string(errorJson)
+}
+
+type NotFoundError struct {
+	BaseError
+}
+
+type AlreadyExistsError struct {
+	BaseError
+}
+
+/// the token is consumed by another token and cannot change anymore
+type AlreadyConsumedError struct {
+	BaseError
+}
+
+/// the signature is missing or is not the one of the expected key
+type UnauthorizedError struct {
+	BaseError
+}
+
+type InvalidArgumentError struct {
+	BaseError
+}
+
+/// the arguments are valid but the state of the ledger does not allow the operation, e.g. an insufficient
+/// balance
+type PreconditionFailedError struct {
+	BaseError
+}
+
+/// the ledger could not be read or written
+type InternalError struct {
+	BaseError
+}
+
+func makeBaseError(
+	iCode ErrorCode,
+	iFormat string,
+	iArgs []interface{},
+) BaseError {
+	return BaseError{Code: iCode, Message: fmt.Sprintf(iFormat, iArgs...)}
+}
+
+func makeNotFoundError(
+	iFormat string,
+	iArgs ...interface{},
+) error {
+	return &NotFoundError{makeBaseError(eNotFound, iFormat, iArgs)}
+}
+
+func makeAlreadyExistsError(
+	iFormat string,
+	iArgs ...interface{},
+) error {
+	return &AlreadyExistsError{makeBaseError(eAlreadyExists, iFormat, iArgs)}
+}
+
+func makeAlreadyConsumedError(
+	iToken *Token,
+) error {
+	return &AlreadyConsumedError{makeBaseError(eAlreadyConsumed, "token %s is already consumed by %s", []interface{}{iToken.Id, iToken.ConsumingTokenId})}
+}
+
+func makeUnauthorizedError(
+	iFormat string,
+	iArgs ...interface{},
+) error {
+	return &UnauthorizedError{makeBaseError(eUnauthorized, iFormat, iArgs)}
+}
+
+func makeInvalidArgumentError(
+	iFormat string,
+	iArgs ...interface{},
+) error {
+	return &InvalidArgumentError{makeBaseError(eInvalidArgument, iFormat, iArgs)}
+}
+
+func makePreconditionFailedError(
+	iFormat string,
+	iArgs ...interface{},
+) error {
+	return &PreconditionFailedError{makeBaseError(ePreconditionFailed, iFormat, iArgs)}
+}
+
+func makeInternalError(
+	iFormat string,
+	iArgs ...interface{},
+) error {
+	return &InternalError{makeBaseError(eInternal, iFormat, iArgs)}
+}
+
+/// as graph.VerifyPayload, a signature which does not verify is reported as unauthorized
+func verifyPayload(
+	iPublicKey string,
+	iPayload interface{},
+	iSignature string,
+) error {
+	err := graph.VerifyPayload(iPublicKey, iPayload, iSignature)
+	if err != nil {
+		return makeUnauthorizedError("%v", err)
+	}
+
+	return nil
+}
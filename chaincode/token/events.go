@@ -0,0 +1,38 @@
+package token
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// names of the chaincode events, so that downstream systems can react without polling the ledger
+const (
+	tokenCreatedEventName     = "TokenCreated"
+	tokenConsumedEventName    = "TokenConsumed"
+	tokenTransferredEventName = "TokenTransferred"
+	tokenBurnedEventName      = "TokenBurned"
+)
+
+/// Fabric keeps a single event per transaction, so one event lists every token of the transaction.
+/// Owners are identified by the sha256 of their public key
+type TokenEvent struct {
+	TokenIds          []string `json:"TokenIds"`
+	OwnerFingerprints []string `json:"OwnerFingerprints"`
+	ConsumingTokenId  string   `json:"ConsumingTokenId,omitempty"`
+	Class             string   `json:"Class,omitempty"`  /// set with Amount for fungible amounts
+	Amount            string   `json:"Amount,omitempty"` /// TokenIds is empty then
+}
+
+func setTokenEvent(
+	iCtx contractapi.TransactionContextInterface,
+	iName string,
+	iEvent *TokenEvent,
+) error {
+	eventJson, err := json.Marshal(iEvent)
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().SetEvent(iName, eventJson)
+}
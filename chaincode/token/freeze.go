@@ -0,0 +1,119 @@
+package token
+
+import (
+	"sig_chain/chaincode/asset"
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// Signed by the administrator of the ledger, Sequence is the one of the token before the change
+type TokenFreeze struct {
+	TokenId   string `json:"TokenId"`
+	IsFrozen  bool   `json:"IsFrozen"`
+	Sequence  int    `json:"Sequence"`
+	Signature string `json:"Signature"`
+}
+
+/// operations whose submitters must have the role configured in the material contract, see
+/// asset.CheckSubmitterRole
+const (
+	eMintOperation   = "eMintOperation"
+	eFreezeOperation = "eFreezeOperation"
+)
+
+func checkSubmitterRole(
+	iCtx contractapi.TransactionContextInterface,
+	iOperation string,
+) error {
+	err := asset.CheckSubmitterRole(iCtx, iOperation)
+	if err != nil {
+		return makeUnauthorizedError("%v", err)
+	}
+
+	return nil
+}
+
+/// the token contract is administered by the administrator of the material contract
+func verifyAdministratorSignature(
+	iCtx contractapi.TransactionContextInterface,
+	iPayload interface{},
+	iSignature string,
+) error {
+	adminPublicKey, err := (&asset.MaterialContract{}).GetAdministrator(iCtx)
+	if err != nil {
+		return makeInternalError("failed to read the administrator: %v", err)
+	}
+
+	if adminPublicKey == "" {
+		return makePreconditionFailedError("administrator is not configured, the ledger must be bootstrapped with InitLedger")
+	}
+
+	return verifyPayload(adminPublicKey, iPayload, iSignature)
+}
+
+func checkNotFrozen(
+	iToken *Token,
+) error {
+	if iToken.IsFrozen {
+		return makePreconditionFailedError("token %s is frozen", iToken.Id)
+	}
+
+	return nil
+}
+
+func setFrozen(
+	iCtx contractapi.TransactionContextInterface,
+	iTokenId string,
+	iIsFrozen bool,
+	iSignature string,
+) error {
+	err := checkSubmitterRole(iCtx, eFreezeOperation)
+	if err != nil {
+		return err
+	}
+
+	token, err := getToken(iCtx, iTokenId)
+	if err != nil {
+		return err
+	}
+
+	freeze := TokenFreeze{
+		TokenId:  iTokenId,
+		IsFrozen: iIsFrozen,
+		Sequence: token.Sequence,
+	}
+	err = verifyAdministratorSignature(iCtx, &freeze, iSignature)
+	if err != nil {
+		return err
+	}
+
+	if token.IsFrozen == iIsFrozen {
+		return makePreconditionFailedError("token %s is already in this state", iTokenId)
+	}
+
+	token.IsFrozen = iIsFrozen
+	token.Sequence++
+	return putToken(iCtx, token)
+}
+
+/// the token cannot be transferred nor consumed until UnfreezeToken, e.g. while a fraud is investigated.
+/// iSignature is the administrator's signature of the TokenFreeze with IsFrozen set, the submitter must have the
+/// role required for eFreezeOperation
+func (c *TokenContract) FreezeToken(
+	iCtx contractapi.TransactionContextInterface,
+	iTokenId string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, setFrozen(iCtx, iTokenId, true, iSignature))
+}
+
+/// iSignature is the administrator's signature of the TokenFreeze with IsFrozen unset, the submitter must have the
+/// role required for eFreezeOperation
+func (c *TokenContract) UnfreezeToken(
+	iCtx contractapi.TransactionContextInterface,
+	iTokenId string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, setFrozen(iCtx, iTokenId, false, iSignature))
+}
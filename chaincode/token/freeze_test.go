@@ -0,0 +1,50 @@
+package token_test
+
+import (
+	"sig_chain/chaincode/token"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func TestFreezeToken(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	bob := makeTestSigner(t)
+	l.createToken("t1", alice, "")
+
+	freeze := token.TokenFreeze{TokenId: "t1", IsFrozen: true}
+	l.mustFail("freeze signed by the owner", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.FreezeToken(iCtx, "t1", signPayload(t, alice, &freeze))
+		return err
+	})
+
+	l.mustFail("freeze submitted without the regulator role", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.FreezeToken(iCtx, "t1", signPayload(t, l.admin, &freeze))
+		return err
+	})
+
+	l.setRole("regulator")
+	l.mustSubmit("freeze", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.FreezeToken(iCtx, "t1", signPayload(t, l.admin, &freeze))
+		return err
+	})
+
+	transfer := token.TokenTransfer{TokenId: "t1", NewOwnerPublicKey: bob.GetPublicKey(), Sequence: 1}
+	l.mustFail("transfer frozen token", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.TransferToken(iCtx, "t1", bob.GetPublicKey(), signPayload(t, alice, &transfer))
+		return err
+	})
+
+	unfreeze := token.TokenFreeze{TokenId: "t1", IsFrozen: false, Sequence: 1}
+	l.mustSubmit("unfreeze", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.UnfreezeToken(iCtx, "t1", signPayload(t, l.admin, &unfreeze))
+		return err
+	})
+
+	transfer.Sequence = 2
+	l.mustSubmit("transfer unfrozen token", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.TransferToken(iCtx, "t1", bob.GetPublicKey(), signPayload(t, alice, &transfer))
+		return err
+	})
+}
@@ -0,0 +1,622 @@
+package token
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/shopspring/decimal"
+)
+
+/// Amounts are decimal strings, signed payloads carry them in the form decimal.Decimal.String() prints them
+
+const (
+	tokenClassObjectType = "tokenClass"
+	balanceObjectType    = "balance"
+)
+
+/// Amounts of a class are minted by its issuer, e.g. kg of certified material credits. The issuer also
+/// approves the creation of the tokens of the class, whose metadata must follow MetadataFields.
+/// Each token of the class counts as one unit of its supply
+type TokenClass struct {
+	Name            string           `json:"Name"`
+	IssuerPublicKey string           `json:"IssuerPublicKey"`
+	MetadataFields  []MetadataField  `json:"MetadataFields"`
+	Policy          TokenClassPolicy `json:"Policy"`
+	MaxSupply       string           `json:"MaxSupply"` /// empty if the supply is unlimited
+	Supply          string           `json:"Supply"`    /// amount minted and not burned yet
+	BurnedAmount    string           `json:"BurnedAmount"`
+	TokenCount      int              `json:"TokenCount"` /// number of tokens created in the class
+	Sequence        int              `json:"Sequence"`   /// number of mints, so that a signed mint cannot be replayed
+}
+
+/// Signed by the issuer of the class
+type TokenClassRegistration struct {
+	Name            string           `json:"Name"`
+	IssuerPublicKey string           `json:"IssuerPublicKey"`
+	MetadataFields  []MetadataField  `json:"MetadataFields"`
+	Policy          TokenClassPolicy `json:"Policy"`
+	MaxSupply       string           `json:"MaxSupply"`
+	Signature       string           `json:"Signature"`
+}
+
+type TokenClassStats struct {
+	Class           string `json:"Class"`
+	MaxSupply       string `json:"MaxSupply"` /// empty if the supply is unlimited
+	Supply          string `json:"Supply"`
+	BurnedAmount    string `json:"BurnedAmount"`
+	TokenCount      int    `json:"TokenCount"`
+	RemainingSupply string `json:"RemainingSupply"` /// empty if the supply is unlimited
+}
+
+type Balance struct {
+	Class          string `json:"Class"`
+	OwnerPublicKey string `json:"OwnerPublicKey"`
+	Amount         string `json:"Amount"`
+	Sequence       int    `json:"Sequence"`                /// number of transfers from this balance
+	SchemaVersion  int    `json:"SchemaVersion,omitempty"` /// stamped when the balance is written, see CurrentSchemaVersion
+}
+
+/// Signed by the issuer of the class, Sequence is the one of the class before the mint
+type Mint struct {
+	Class          string `json:"Class"`
+	OwnerPublicKey string `json:"OwnerPublicKey"`
+	Amount         string `json:"Amount"`
+	Sequence       int    `json:"Sequence"`
+	Signature      string `json:"Signature"`
+}
+
+/// Signed by the owner, Sequence is the one of the balance of the owner before the burn
+type Burn struct {
+	Class          string `json:"Class"`
+	OwnerPublicKey string `json:"OwnerPublicKey"`
+	Amount         string `json:"Amount"`
+	Sequence       int    `json:"Sequence"`
+	Signature      string `json:"Signature"`
+}
+
+/// Signed by the sender, Sequence is the one of the balance of the sender before the transfer
+type AmountTransfer struct {
+	Class         string `json:"Class"`
+	FromPublicKey string `json:"FromPublicKey"`
+	ToPublicKey   string `json:"ToPublicKey"`
+	Amount        string `json:"Amount"`
+	Sequence      int    `json:"Sequence"`
+	Signature     string `json:"Signature"`
+}
+
+func ownerFingerprint(
+	iPublicKey string,
+) string {
+	hash := sha256.Sum256([]byte(iPublicKey))
+	return hex.EncodeToString(hash[:])
+}
+
+func parseAmount(
+	iAmount string,
+) (decimal.Decimal, error) {
+	amount, err := decimal.NewFromString(iAmount)
+	if err != nil {
+		return decimal.Decimal{}, makeInvalidArgumentError("invalid amount %s: %v", iAmount, err)
+	}
+
+	if !amount.IsPositive() {
+		return decimal.Decimal{}, makeInvalidArgumentError("amount must be positive")
+	}
+
+	return amount, nil
+}
+
+func getTokenClass(
+	iCtx contractapi.TransactionContextInterface,
+	iName string,
+) (*TokenClass, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(tokenClassObjectType, []string{iName})
+	if err != nil {
+		return nil, err
+	}
+
+	classJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, makeInternalError("failed to read from ledger: %v", err)
+	}
+
+	if classJson == nil {
+		return nil, makeNotFoundError("token class %s does not exist", iName)
+	}
+
+	var class TokenClass
+	err = json.Unmarshal(classJson, &class)
+	if err != nil {
+		return nil, err
+	}
+
+	return &class, nil
+}
+
+func putTokenClass(
+	iCtx contractapi.TransactionContextInterface,
+	iClass *TokenClass,
+) error {
+	key, err := iCtx.GetStub().CreateCompositeKey(tokenClassObjectType, []string{iClass.Name})
+	if err != nil {
+		return err
+	}
+
+	classJson, err := json.Marshal(iClass)
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().PutState(key, classJson)
+}
+
+/// returns an empty balance if iOwnerPublicKey never held any amount of iClass
+func getBalance(
+	iCtx contractapi.TransactionContextInterface,
+	iClass string,
+	iOwnerPublicKey string,
+) (*Balance, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(balanceObjectType, []string{iClass, ownerFingerprint(iOwnerPublicKey)})
+	if err != nil {
+		return nil, err
+	}
+
+	balanceJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, makeInternalError("failed to read from ledger: %v", err)
+	}
+
+	if balanceJson == nil {
+		return &Balance{
+			Class:          iClass,
+			OwnerPublicKey: iOwnerPublicKey,
+			Amount:         decimal.NewFromInt(0).String(),
+		}, nil
+	}
+
+	var balance Balance
+	err = unmarshalRecord(balanceJson, &balance)
+	if err != nil {
+		return nil, err
+	}
+
+	return &balance, nil
+}
+
+func putBalance(
+	iCtx contractapi.TransactionContextInterface,
+	iBalance *Balance,
+) error {
+	key, err := iCtx.GetStub().CreateCompositeKey(balanceObjectType, []string{iBalance.Class, ownerFingerprint(iBalance.OwnerPublicKey)})
+	if err != nil {
+		return err
+	}
+
+	iBalance.SchemaVersion = CurrentSchemaVersion
+	balanceJson, err := json.Marshal(iBalance)
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().PutState(key, balanceJson)
+}
+
+/// adds iDelta, which can be negative, to the balance of iOwnerPublicKey
+func addToBalance(
+	iCtx contractapi.TransactionContextInterface,
+	ioBalance *Balance,
+	iDelta decimal.Decimal,
+) error {
+	amount, err := decimal.NewFromString(ioBalance.Amount)
+	if err != nil {
+		return err
+	}
+
+	amount = amount.Add(iDelta)
+	if amount.IsNegative() {
+		return makePreconditionFailedError("insufficient balance")
+	}
+
+	ioBalance.Amount = amount.String()
+	return putBalance(iCtx, ioBalance)
+}
+
+/// returns what can still be minted or created in the class, the remaining supply is meaningless if the
+/// supply is unlimited
+func getRemainingSupply(
+	iClass *TokenClass,
+) (decimal.Decimal, bool, error) {
+	if iClass.MaxSupply == "" {
+		return decimal.Decimal{}, false, nil
+	}
+
+	maxSupply, err := decimal.NewFromString(iClass.MaxSupply)
+	if err != nil {
+		return decimal.Decimal{}, false, err
+	}
+
+	supply, err := decimal.NewFromString(iClass.Supply)
+	if err != nil {
+		return decimal.Decimal{}, false, err
+	}
+
+	return maxSupply.Sub(supply).Sub(decimal.NewFromInt(int64(iClass.TokenCount))), true, nil
+}
+
+/// iAmount is the amount minted, or one for a token created in the class
+func checkSupplyCap(
+	iClass *TokenClass,
+	iAmount decimal.Decimal,
+) error {
+	remaining, isCapped, err := getRemainingSupply(iClass)
+	if err != nil {
+		return err
+	}
+
+	if isCapped && iAmount.GreaterThan(remaining) {
+		return makePreconditionFailedError("class %s would exceed its maximum supply of %s", iClass.Name, iClass.MaxSupply)
+	}
+
+	return nil
+}
+
+/// moves iAmount between two balances, signatures must have been checked by the caller
+func transferAmount(
+	iCtx contractapi.TransactionContextInterface,
+	iClass string,
+	ioFrom *Balance,
+	iToPublicKey string,
+	iAmount decimal.Decimal,
+) error {
+	if iToPublicKey == "" {
+		return makeInvalidArgumentError("recipient public key cannot be empty")
+	}
+
+	if iToPublicKey == ioFrom.OwnerPublicKey {
+		return makeInvalidArgumentError("sender and recipient must be different")
+	}
+
+	class, err := getTokenClass(iCtx, iClass)
+	if err != nil {
+		return err
+	}
+
+	if !class.Policy.IsTransferable {
+		return makePreconditionFailedError("amounts of class %s are not transferable", iClass)
+	}
+
+	err = addToBalance(iCtx, ioFrom, iAmount.Neg())
+	if err != nil {
+		return err
+	}
+
+	to, err := getBalance(iCtx, iClass, iToPublicKey)
+	if err != nil {
+		return err
+	}
+
+	err = addToBalance(iCtx, to, iAmount)
+	if err != nil {
+		return err
+	}
+
+	return setTokenEvent(iCtx, tokenTransferredEventName, &TokenEvent{
+		TokenIds:          []string{},
+		OwnerFingerprints: []string{ownerFingerprint(ioFrom.OwnerPublicKey), ownerFingerprint(iToPublicKey)},
+		Class:             iClass,
+		Amount:            iAmount.String(),
+	})
+}
+
+/// iMetadataFields is the schema of the metadata of the tokens of the class and iPolicy how they may be used.
+/// iMaxSupply caps the amount minted and the number of tokens created in the class, it is empty if the supply
+/// is unlimited. iSignature is the issuer's signature of the TokenClassRegistration, class names are first come
+/// first served
+func (c *TokenContract) RegisterTokenClass(
+	iCtx contractapi.TransactionContextInterface,
+	iName string,
+	iIssuerPublicKey string,
+	iMetadataFields []MetadataField,
+	iPolicy TokenClassPolicy,
+	iMaxSupply string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	if iName == "" {
+		return nil, makeInvalidArgumentError("class name cannot be empty")
+	}
+
+	if iMaxSupply != "" {
+		maxSupply, err := parseAmount(iMaxSupply)
+		if err != nil {
+			return nil, err
+		}
+		iMaxSupply = maxSupply.String()
+	}
+
+	err := checkMetadataFields(iMetadataFields)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = getTokenClass(iCtx, iName)
+	if err == nil {
+		return nil, makeAlreadyExistsError("token class %s already exists", iName)
+	}
+	if _, ok := err.(*NotFoundError); !ok {
+		return nil, err
+	}
+
+	registration := TokenClassRegistration{
+		Name:            iName,
+		IssuerPublicKey: iIssuerPublicKey,
+		MetadataFields:  iMetadataFields,
+		Policy:          iPolicy,
+		MaxSupply:       iMaxSupply,
+	}
+	err = verifyPayload(iIssuerPublicKey, &registration, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	if iPolicy.ConsumingClasses == nil {
+		iPolicy.ConsumingClasses = []string{}
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putTokenClass(iCtx, &TokenClass{
+		Name:            iName,
+		IssuerPublicKey: iIssuerPublicKey,
+		MetadataFields:  iMetadataFields,
+		Policy:          iPolicy,
+		MaxSupply:       iMaxSupply,
+		Supply:          decimal.NewFromInt(0).String(),
+		BurnedAmount:    decimal.NewFromInt(0).String(),
+	}))
+}
+
+func (c *TokenContract) GetTokenClass(
+	iCtx contractapi.TransactionContextInterface,
+	iName string,
+) (*TokenClass, error) {
+	return getTokenClass(iCtx, iName)
+}
+
+/// iSignature is the issuer's signature of the Mint, the submitter must have the role required for eMintOperation
+func (c *TokenContract) Mint(
+	iCtx contractapi.TransactionContextInterface,
+	iClass string,
+	iOwnerPublicKey string,
+	iAmount string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	err := checkSubmitterRole(iCtx, eMintOperation)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := parseAmount(iAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	if iOwnerPublicKey == "" {
+		return nil, makeInvalidArgumentError("owner public key cannot be empty")
+	}
+
+	class, err := getTokenClass(iCtx, iClass)
+	if err != nil {
+		return nil, err
+	}
+
+	mint := Mint{
+		Class:          iClass,
+		OwnerPublicKey: iOwnerPublicKey,
+		Amount:         amount.String(),
+		Sequence:       class.Sequence,
+	}
+	err = verifyPayload(class.IssuerPublicKey, &mint, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkSupplyCap(class, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	supply, err := decimal.NewFromString(class.Supply)
+	if err != nil {
+		return nil, err
+	}
+
+	class.Supply = supply.Add(amount).String()
+	class.Sequence++
+	err = putTokenClass(iCtx, class)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := getBalance(iCtx, iClass, iOwnerPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	err = addToBalance(iCtx, balance, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, setTokenEvent(iCtx, tokenCreatedEventName, &TokenEvent{
+		TokenIds:          []string{},
+		OwnerFingerprints: []string{ownerFingerprint(iOwnerPublicKey)},
+		Class:             iClass,
+		Amount:            amount.String(),
+	}))
+}
+
+/// destroys part of the balance of the owner, e.g. credits retired once used.
+/// iSignature is the owner's signature of the Burn
+func (c *TokenContract) Burn(
+	iCtx contractapi.TransactionContextInterface,
+	iClass string,
+	iOwnerPublicKey string,
+	iAmount string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	amount, err := parseAmount(iAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	class, err := getTokenClass(iCtx, iClass)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := getBalance(iCtx, iClass, iOwnerPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	burn := Burn{
+		Class:          iClass,
+		OwnerPublicKey: iOwnerPublicKey,
+		Amount:         amount.String(),
+		Sequence:       balance.Sequence,
+	}
+	err = verifyPayload(iOwnerPublicKey, &burn, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	balance.Sequence++
+	err = addToBalance(iCtx, balance, amount.Neg())
+	if err != nil {
+		return nil, err
+	}
+
+	supply, err := decimal.NewFromString(class.Supply)
+	if err != nil {
+		return nil, err
+	}
+
+	burnedAmount, err := decimal.NewFromString(class.BurnedAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	class.Supply = supply.Sub(amount).String()
+	class.BurnedAmount = burnedAmount.Add(amount).String()
+	err = putTokenClass(iCtx, class)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, setTokenEvent(iCtx, tokenBurnedEventName, &TokenEvent{
+		TokenIds:          []string{},
+		OwnerFingerprints: []string{ownerFingerprint(iOwnerPublicKey)},
+		Class:             iClass,
+		Amount:            amount.String(),
+	}))
+}
+
+/// iSignature is the sender's signature of the AmountTransfer
+func (c *TokenContract) Transfer(
+	iCtx contractapi.TransactionContextInterface,
+	iClass string,
+	iFromPublicKey string,
+	iToPublicKey string,
+	iAmount string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	amount, err := parseAmount(iAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = getTokenClass(iCtx, iClass)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := getBalance(iCtx, iClass, iFromPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer := AmountTransfer{
+		Class:         iClass,
+		FromPublicKey: iFromPublicKey,
+		ToPublicKey:   iToPublicKey,
+		Amount:        amount.String(),
+		Sequence:      from.Sequence,
+	}
+	err = verifyPayload(iFromPublicKey, &transfer, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	from.Sequence++
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, transferAmount(iCtx, iClass, from, iToPublicKey, amount))
+}
+
+func (c *TokenContract) BalanceOf(
+	iCtx contractapi.TransactionContextInterface,
+	iClass string,
+	iOwnerPublicKey string,
+) (string, error) {
+	_, err := getTokenClass(iCtx, iClass)
+	if err != nil {
+		return "", err
+	}
+
+	balance, err := getBalance(iCtx, iClass, iOwnerPublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	return balance.Amount, nil
+}
+
+func (c *TokenContract) TotalSupply(
+	iCtx contractapi.TransactionContextInterface,
+	iClass string,
+) (string, error) {
+	class, err := getTokenClass(iCtx, iClass)
+	if err != nil {
+		return "", err
+	}
+
+	return class.Supply, nil
+}
+
+func (c *TokenContract) GetTokenClassStats(
+	iCtx contractapi.TransactionContextInterface,
+	iClass string,
+) (*TokenClassStats, error) {
+	class, err := getTokenClass(iCtx, iClass)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := TokenClassStats{
+		Class:        class.Name,
+		MaxSupply:    class.MaxSupply,
+		Supply:       class.Supply,
+		BurnedAmount: class.BurnedAmount,
+		TokenCount:   class.TokenCount,
+	}
+
+	remaining, isCapped, err := getRemainingSupply(class)
+	if err != nil {
+		return nil, err
+	}
+
+	if isCapped {
+		stats.RemainingSupply = remaining.String()
+	}
+
+	return &stats, nil
+}
@@ -0,0 +1,150 @@
+package token_test
+
+import (
+	"sig_chain/chaincode/token"
+	"sig_chain/pkg/client"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// signed by the issuer with the current sequence of the class
+func (l *testLedger) signMint(
+	iClass string,
+	iOwner client.Signer,
+	iAmount string,
+) string {
+	l.t.Helper()
+	var class *token.TokenClass
+	l.mustSubmit("get class "+iClass, func(iCtx contractapi.TransactionContextInterface) error {
+		var err error
+		class, err = l.contract.GetTokenClass(iCtx, iClass)
+		return err
+	})
+
+	return signPayload(l.t, l.issuer, &token.Mint{
+		Class:          iClass,
+		OwnerPublicKey: iOwner.GetPublicKey(),
+		Amount:         iAmount,
+		Sequence:       class.Sequence,
+	})
+}
+
+func (l *testLedger) mint(
+	iClass string,
+	iOwner client.Signer,
+	iAmount string,
+	iSignature string,
+) error {
+	return l.submit(func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.Mint(iCtx, iClass, iOwner.GetPublicKey(), iAmount, iSignature)
+		return err
+	})
+}
+
+func (l *testLedger) balanceOf(
+	iClass string,
+	iOwner client.Signer,
+) string {
+	l.t.Helper()
+	var amount string
+	l.mustSubmit("get balance", func(iCtx contractapi.TransactionContextInterface) error {
+		var err error
+		amount, err = l.contract.BalanceOf(iCtx, iClass, iOwner.GetPublicKey())
+		return err
+	})
+
+	return amount
+}
+
+func TestMintRequiresIssuerRole(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	signature := l.signMint("test", alice, "10")
+
+	l.setRole("regulator")
+	err := l.mint("test", alice, "10", signature)
+	if err == nil {
+		t.Fatal("mint submitted without the issuer role succeeded")
+	}
+
+	l.setRole("issuer")
+	err = l.mint("test", alice, "10", signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = l.mint("test", alice, "10", signature)
+	if err == nil {
+		t.Fatal("replayed mint succeeded")
+	}
+
+	if l.balanceOf("test", alice) != "10" {
+		t.Fatalf("balance is %s", l.balanceOf("test", alice))
+	}
+}
+
+func TestFungibleBalances(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	bob := makeTestSigner(t)
+	err := l.mint("test", alice, "10.5", l.signMint("test", alice, "10.5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transfer := token.AmountTransfer{
+		Class:         "test",
+		FromPublicKey: alice.GetPublicKey(),
+		ToPublicKey:   bob.GetPublicKey(),
+		Amount:        "4",
+	}
+	l.mustFail("transfer signed by the receiver", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.Transfer(iCtx, "test", alice.GetPublicKey(), bob.GetPublicKey(), "4", signPayload(t, bob, &transfer))
+		return err
+	})
+
+	signature := signPayload(t, alice, &transfer)
+	l.mustSubmit("transfer", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.Transfer(iCtx, "test", alice.GetPublicKey(), bob.GetPublicKey(), "4", signature)
+		return err
+	})
+
+	l.mustFail("replay transfer", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.Transfer(iCtx, "test", alice.GetPublicKey(), bob.GetPublicKey(), "4", signature)
+		return err
+	})
+
+	overdraft := token.AmountTransfer{
+		Class:         "test",
+		FromPublicKey: bob.GetPublicKey(),
+		ToPublicKey:   alice.GetPublicKey(),
+		Amount:        "5",
+	}
+	l.mustFail("transfer more than the balance", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.Transfer(iCtx, "test", bob.GetPublicKey(), alice.GetPublicKey(), "5", signPayload(t, bob, &overdraft))
+		return err
+	})
+
+	burn := token.Burn{Class: "test", OwnerPublicKey: bob.GetPublicKey(), Amount: "1"}
+	l.mustSubmit("burn", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.Burn(iCtx, "test", bob.GetPublicKey(), "1", signPayload(t, bob, &burn))
+		return err
+	})
+
+	if l.balanceOf("test", alice) != "6.5" || l.balanceOf("test", bob) != "3" {
+		t.Fatalf("balances are %s and %s", l.balanceOf("test", alice), l.balanceOf("test", bob))
+	}
+
+	l.mustSubmit("get supply", func(iCtx contractapi.TransactionContextInterface) error {
+		stats, err := l.contract.GetTokenClassStats(iCtx, "test")
+		if err != nil {
+			return err
+		}
+
+		if stats.Supply != "9.5" || stats.BurnedAmount != "1" {
+			t.Fatalf("supply is %s and burned amount %s", stats.Supply, stats.BurnedAmount)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,65 @@
+package token
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// a version of the token, Token is empty if the token was deleted by the transaction
+type TokenHistoryEntry struct {
+	TxId      string    `json:"TxId"`
+	Timestamp time.Time `json:"Timestamp"`
+	IsDelete  bool      `json:"IsDelete"`
+	Token     *Token    `json:"Token,omitempty" metadata:",optional"`
+}
+
+/// returns every version of the token from its creation, oldest first, so that its transfers and consumptions
+/// can be audited. It requires the history database of the peer to be enabled
+func (c *TokenContract) GetTokenHistory(
+	iCtx contractapi.TransactionContextInterface,
+	iTokenId string,
+) ([]TokenHistoryEntry, error) {
+	key, err := getTokenKey(iCtx, iTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	iterator, err := iCtx.GetStub().GetHistoryForKey(key)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	history := []TokenHistoryEntry{}
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		entry := TokenHistoryEntry{
+			TxId:      modification.TxId,
+			Timestamp: time.Unix(modification.Timestamp.Seconds, int64(modification.Timestamp.Nanos)).UTC(),
+			IsDelete:  modification.IsDelete,
+		}
+
+		if !modification.IsDelete {
+			var token Token
+			err = json.Unmarshal(modification.Value, &token)
+			if err != nil {
+				return nil, err
+			}
+			entry.Token = &token
+		}
+
+		history = append(history, entry)
+	}
+
+	if len(history) == 0 {
+		return nil, makeNotFoundError("token %s does not exist", iTokenId)
+	}
+
+	return history, nil
+}
@@ -0,0 +1,28 @@
+package token
+
+import (
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// the token contract shares the hooks and the transaction context of the graph contract, as the asset
+/// contracts do, since it is registered in the same chaincode
+
+func (c *TokenContract) GetBeforeTransaction() interface{} {
+	return graph.BeforeTransaction
+}
+
+func (c *TokenContract) GetAfterTransaction() interface{} {
+	return graph.AfterTransaction
+}
+
+func (c *TokenContract) GetUnknownTransaction() interface{} {
+	return func(iCtx contractapi.TransactionContextInterface) error {
+		return graph.UnknownTransaction(iCtx, c)
+	}
+}
+
+func (c *TokenContract) GetTransactionContextHandler() contractapi.SettableTransactionContextInterface {
+	return &graph.TransactionContext{}
+}
@@ -0,0 +1,122 @@
+package token
+
+import (
+	"sig_chain/chaincode/asset"
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// material node id -> id of the token which claims it, so that a lot is claimed by a single token
+const tokenMaterialObjectType = "tokenMaterial"
+
+/// Signed by the owner of the token, Sequence is the one of the token before the link
+type TokenMaterialLink struct {
+	TokenId        string `json:"TokenId"`
+	MaterialNodeId string `json:"MaterialNodeId"`
+	Sequence       int    `json:"Sequence"`
+	Signature      string `json:"Signature"`
+}
+
+/// returns an empty id if no token claims iMaterialNodeId
+func getMaterialTokenId(
+	iCtx contractapi.TransactionContextInterface,
+	iMaterialNodeId string,
+) (string, error) {
+	key, err := iCtx.GetStub().CreateCompositeKey(tokenMaterialObjectType, []string{iMaterialNodeId})
+	if err != nil {
+		return "", err
+	}
+
+	tokenId, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return "", makeInternalError("failed to read from ledger: %v", err)
+	}
+
+	return string(tokenId), nil
+}
+
+/// the token becomes a transferable claim on the material, which must be a live node owned by the owner
+/// of the token. iSignature is the token owner's signature of the TokenMaterialLink
+func (c *TokenContract) LinkTokenToMaterial(
+	iCtx contractapi.TransactionContextInterface,
+	iTokenId string,
+	iMaterialNodeId string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	token, err := getToken(iCtx, iTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	link := TokenMaterialLink{
+		TokenId:        iTokenId,
+		MaterialNodeId: iMaterialNodeId,
+		Sequence:       token.Sequence,
+	}
+	err = verifyPayload(token.OwnerPublicKey, &link, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.MaterialNodeId != "" {
+		return nil, makePreconditionFailedError("token %s is already linked to material %s", iTokenId, token.MaterialNodeId)
+	}
+
+	if token.ConsumingTokenId != "" {
+		return nil, makeAlreadyConsumedError(token)
+	}
+
+	material, err := (&asset.MaterialContract{}).GetMaterial(iCtx, iMaterialNodeId)
+	if err != nil {
+		return nil, makeNotFoundError("material %s cannot be read: %v", iMaterialNodeId, err)
+	}
+
+	if material.IsFinalized {
+		return nil, makePreconditionFailedError("material %s is finalized", iMaterialNodeId)
+	}
+
+	if material.OwnerPublicKey != token.OwnerPublicKey {
+		return nil, makeUnauthorizedError("material %s is not owned by the owner of token %s", iMaterialNodeId, iTokenId)
+	}
+
+	claimingTokenId, err := getMaterialTokenId(iCtx, iMaterialNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	if claimingTokenId != "" {
+		return nil, makeAlreadyExistsError("material %s is already claimed by token %s", iMaterialNodeId, claimingTokenId)
+	}
+
+	key, err := iCtx.GetStub().CreateCompositeKey(tokenMaterialObjectType, []string{iMaterialNodeId})
+	if err != nil {
+		return nil, err
+	}
+
+	err = iCtx.GetStub().PutState(key, []byte(iTokenId))
+	if err != nil {
+		return nil, err
+	}
+
+	token.MaterialNodeId = iMaterialNodeId
+	token.Sequence++
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, putToken(iCtx, token))
+}
+
+/// returns the token which claims the material
+func (c *TokenContract) GetMaterialToken(
+	iCtx contractapi.TransactionContextInterface,
+	iMaterialNodeId string,
+) (*Token, error) {
+	tokenId, err := getMaterialTokenId(iCtx, iMaterialNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenId == "" {
+		return nil, makeNotFoundError("material %s is not claimed by a token", iMaterialNodeId)
+	}
+
+	return getToken(iCtx, tokenId)
+}
@@ -0,0 +1,23 @@
+package token
+
+/// Functions listed by GetEvaluateTransactions are tagged as "evaluate" in the contract metadata,
+/// so that client generators query them instead of submitting a transaction
+
+func (c *TokenContract) GetEvaluateTransactions() []string {
+	return []string{
+		"BalanceOf",
+		"GetAllTokens",
+		"GetAllowance",
+		"GetConsumedTokens",
+		"GetConsumptionRequest",
+		"GetMaterialToken",
+		"GetSwap",
+		"GetToken",
+		"GetTokenClass",
+		"GetTokenClassStats",
+		"GetTokenHistory",
+		"GetTokenTombstone",
+		"GetTokensByOwner",
+		"TotalSupply",
+	}
+}
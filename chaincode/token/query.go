@@ -0,0 +1,167 @@
+package token
+
+import (
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	tokenOwnerObjectType    = "tokenOwner"
+	consumedTokenObjectType = "consumed" /// consumed~consumingId~consumedId, so that a token can consume any number of tokens
+)
+
+type TokenPage struct {
+	Tokens              []Token `json:"Tokens"`
+	Bookmark            string  `json:"Bookmark"` /// empty once every token is listed
+	FetchedRecordsCount int32   `json:"FetchedRecordsCount"`
+}
+
+func putOwnerIndex(
+	iCtx contractapi.TransactionContextInterface,
+	iOwnerPublicKey string,
+	iTokenId string,
+) error {
+	key, err := iCtx.GetStub().CreateCompositeKey(tokenOwnerObjectType, []string{ownerFingerprint(iOwnerPublicKey), iTokenId})
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().PutState(key, []byte{0x00})
+}
+
+func deleteOwnerIndex(
+	iCtx contractapi.TransactionContextInterface,
+	iOwnerPublicKey string,
+	iTokenId string,
+) error {
+	key, err := iCtx.GetStub().CreateCompositeKey(tokenOwnerObjectType, []string{ownerFingerprint(iOwnerPublicKey), iTokenId})
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().DelState(key)
+}
+
+func putConsumedIndex(
+	iCtx contractapi.TransactionContextInterface,
+	iConsumingTokenId string,
+	iConsumedTokenId string,
+) error {
+	key, err := iCtx.GetStub().CreateCompositeKey(consumedTokenObjectType, []string{iConsumingTokenId, iConsumedTokenId})
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().PutState(key, []byte{0x00})
+}
+
+/// lists the tokens of an index whose last attribute is the token id
+func getTokenPage(
+	iCtx contractapi.TransactionContextInterface,
+	iObjectType string,
+	iAttributes []string,
+	iPageSize int32,
+	iBookmark string,
+) (*TokenPage, error) {
+	if iPageSize <= 0 {
+		return nil, makeInvalidArgumentError("page size must be positive")
+	}
+
+	iterator, metadata, err := iCtx.GetStub().GetStateByPartialCompositeKeyWithPagination(iObjectType, iAttributes, iPageSize, iBookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	tokens := []Token{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, attributes, err := iCtx.GetStub().SplitCompositeKey(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		token, err := getToken(iCtx, attributes[len(attributes)-1])
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, *token)
+	}
+
+	return &TokenPage{
+		Tokens:              tokens,
+		Bookmark:            metadata.Bookmark,
+		FetchedRecordsCount: metadata.FetchedRecordsCount,
+	}, nil
+}
+
+/// consumed tokens are listed too, iBookmark is empty for the first page
+func (c *TokenContract) GetTokensByOwner(
+	iCtx contractapi.TransactionContextInterface,
+	iOwnerPublicKey string,
+	iPageSize int32,
+	iBookmark string,
+) (*TokenPage, error) {
+	return getTokenPage(iCtx, tokenOwnerObjectType, []string{ownerFingerprint(iOwnerPublicKey)}, iPageSize, iBookmark)
+}
+
+/// lists the tokens consumed by iConsumingTokenId, iBookmark is empty for the first page
+func (c *TokenContract) GetConsumedTokens(
+	iCtx contractapi.TransactionContextInterface,
+	iConsumingTokenId string,
+	iPageSize int32,
+	iBookmark string,
+) (*TokenPage, error) {
+	_, err := getToken(iCtx, iConsumingTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	return getTokenPage(iCtx, consumedTokenObjectType, []string{iConsumingTokenId}, iPageSize, iBookmark)
+}
+
+/// lists every token in the order of their ids, e.g. to reconcile the ledger with an accounting system.
+/// Tokens are stored under composite keys, which GetStateByRangeWithPagination refuses, so the token keyspace
+/// is paged with the partial composite key of the tokens instead. iBookmark is empty for the first page
+func (c *TokenContract) GetAllTokens(
+	iCtx contractapi.TransactionContextInterface,
+	iPageSize int32,
+	iBookmark string,
+) (*TokenPage, error) {
+	if iPageSize <= 0 {
+		return nil, makeInvalidArgumentError("page size must be positive")
+	}
+
+	iterator, metadata, err := iCtx.GetStub().GetStateByPartialCompositeKeyWithPagination(tokenObjectType, []string{}, iPageSize, iBookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	tokens := []Token{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var token Token
+		err = unmarshalRecord(kv.Value, &token)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return &TokenPage{
+		Tokens:              tokens,
+		Bookmark:            metadata.Bookmark,
+		FetchedRecordsCount: metadata.FetchedRecordsCount,
+	}, nil
+}
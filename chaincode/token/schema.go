@@ -0,0 +1,156 @@
+package token
+
+import (
+	"encoding/json"
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// version of the tokens and balances written by this contract, the ones written before versioning have no
+/// SchemaVersion. It is independent of the version of the graph nodes
+const CurrentSchemaVersion = 1
+
+/// upgrades a token or a balance from its version to the next one. Records are handled as generic json objects
+/// so that tokens and balances share the migrations
+var migrations = map[int]func(
+	iRecord map[string]interface{},
+) error{
+	/// version 1 only introduces SchemaVersion
+	0: func(
+		iRecord map[string]interface{},
+	) error {
+		return nil
+	},
+}
+
+/// records migrated by MigrateTokens, in the order of their keys
+var migratedObjectTypes = []string{balanceObjectType, tokenObjectType}
+
+/// Signed by the administrator
+type TokenMigrationRequest struct {
+	StartKey  string `json:"StartKey"`
+	Count     int    `json:"Count"`
+	Signature string `json:"Signature"`
+}
+
+/// returns iRecordJson upgraded to the current version, and whether it was upgraded
+func migrateRecordJson(
+	iRecordJson []byte,
+) ([]byte, bool, error) {
+	var record map[string]interface{}
+	err := json.Unmarshal(iRecordJson, &record)
+	if err != nil {
+		return nil, false, err
+	}
+
+	version := 0
+	if value, ok := record["SchemaVersion"].(float64); ok {
+		version = int(value)
+	}
+
+	if version > CurrentSchemaVersion {
+		return nil, false, makePreconditionFailedError("record has schema version %d, this contract only supports up to %d", version, CurrentSchemaVersion)
+	}
+
+	if version == CurrentSchemaVersion {
+		return iRecordJson, false, nil
+	}
+
+	for ; version < CurrentSchemaVersion; version++ {
+		err = migrations[version](record)
+		if err != nil {
+			return nil, false, makeInternalError("failed to migrate record from schema version %d: %v", version, err)
+		}
+	}
+	record["SchemaVersion"] = CurrentSchemaVersion
+
+	recordJson, err := json.Marshal(record)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return recordJson, true, nil
+}
+
+/// records which are not migrated yet are upgraded when read
+func unmarshalRecord(
+	iRecordJson []byte,
+	oRecord interface{},
+) error {
+	recordJson, _, err := migrateRecordJson(iRecordJson)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(recordJson, oRecord)
+}
+
+/// meant to be run in batches after a chaincode upgrade, like the Migrate transaction of the material contract.
+/// Stores in the current version the tokens and balances of older versions among the iCount ones whose keys
+/// start at iStartKey, which is empty for the first batch and then the NextKey of the previous one.
+/// iSignature is the administrator's signature of the TokenMigrationRequest
+func (c *TokenContract) MigrateTokens(
+	iCtx contractapi.TransactionContextInterface,
+	iStartKey string,
+	iCount int,
+	iSignature string,
+) (*graph.MigrationResult, error) {
+	request := TokenMigrationRequest{
+		StartKey: iStartKey,
+		Count:    iCount,
+	}
+	err := verifyAdministratorSignature(iCtx, &request, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	if iCount <= 0 {
+		return nil, makeInvalidArgumentError("count must be positive")
+	}
+
+	/// range queries cannot start at a composite key, the keys before iStartKey are skipped instead
+	result := graph.MigrationResult{}
+	visited := 0
+	for _, objectType := range migratedObjectTypes {
+		iterator, err := iCtx.GetStub().GetStateByPartialCompositeKey(objectType, []string{})
+		if err != nil {
+			return nil, err
+		}
+		defer iterator.Close()
+
+		for iterator.HasNext() {
+			kv, err := iterator.Next()
+			if err != nil {
+				return nil, err
+			}
+
+			if kv.Key < iStartKey {
+				continue
+			}
+
+			if visited == iCount {
+				result.NextKey = kv.Key
+				return &result, nil
+			}
+			visited++
+
+			recordJson, isMigrated, err := migrateRecordJson(kv.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			if !isMigrated {
+				continue
+			}
+
+			err = iCtx.GetStub().PutState(kv.Key, recordJson)
+			if err != nil {
+				return nil, err
+			}
+			result.MigratedCount++
+		}
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,115 @@
+package token_test
+
+import (
+	"encoding/json"
+	"sig_chain/chaincode/token"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// stores iRecord under the composite key of iObjectType and iAttributes, as an older contract wrote it
+func (l *testLedger) putLegacyRecord(
+	iObjectType string,
+	iAttributes []string,
+	iRecord interface{},
+) {
+	l.t.Helper()
+	recordJson, err := json.Marshal(iRecord)
+	if err != nil {
+		l.t.Fatal(err)
+	}
+
+	l.mustSubmit("seed "+iObjectType, func(iCtx contractapi.TransactionContextInterface) error {
+		key, err := iCtx.GetStub().CreateCompositeKey(iObjectType, iAttributes)
+		if err != nil {
+			return err
+		}
+
+		return iCtx.GetStub().PutState(key, recordJson)
+	})
+}
+
+/// the schema version the record of iObjectType and iAttributes is stored with
+func (l *testLedger) getStoredSchemaVersion(
+	iObjectType string,
+	iAttributes []string,
+) int {
+	l.t.Helper()
+	var record struct {
+		SchemaVersion int `json:"SchemaVersion"`
+	}
+	l.mustSubmit("read "+iObjectType, func(iCtx contractapi.TransactionContextInterface) error {
+		key, err := iCtx.GetStub().CreateCompositeKey(iObjectType, iAttributes)
+		if err != nil {
+			return err
+		}
+
+		recordJson, err := iCtx.GetStub().GetState(key)
+		if err != nil {
+			return err
+		}
+
+		return json.Unmarshal(recordJson, &record)
+	})
+
+	return record.SchemaVersion
+}
+
+func TestMigrateTokens(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	l.createToken("t1", alice, "")
+	if l.getStoredSchemaVersion("token", []string{"t1"}) != token.CurrentSchemaVersion {
+		t.Fatal("created token is not stamped with the current schema version")
+	}
+
+	l.putLegacyRecord("token", []string{"t0"}, map[string]interface{}{"Id": "t0", "OwnerPublicKey": alice.GetPublicKey(), "Class": "test", "Metadata": map[string]string{}})
+	l.putLegacyRecord("balance", []string{"test", "alice"}, map[string]interface{}{"Class": "test", "OwnerPublicKey": alice.GetPublicKey(), "Amount": "3"})
+	l.putLegacyRecord("token", []string{"t9"}, map[string]interface{}{"Id": "t9", "SchemaVersion": token.CurrentSchemaVersion + 1})
+
+	/// records which are not migrated yet are upgraded when read
+	if l.getToken("t0").SchemaVersion != token.CurrentSchemaVersion {
+		t.Fatal("legacy token is not upgraded when read")
+	}
+	l.mustFail("get token of a newer schema version", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.GetToken(iCtx, "t9")
+		return err
+	})
+
+	request := token.TokenMigrationRequest{StartKey: "", Count: 1}
+	l.mustFail("migrate without the administrator's signature", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.MigrateTokens(iCtx, "", 1, signPayload(t, alice, &request))
+		return err
+	})
+
+	/// the balance, t0 then t1 are migrated one at a time, the batch of t9 fails
+	migratedCount := 0
+	startKey := ""
+	for batch := 0; batch < 3; batch++ {
+		request = token.TokenMigrationRequest{StartKey: startKey, Count: 1}
+		l.mustSubmit("migrate", func(iCtx contractapi.TransactionContextInterface) error {
+			result, err := l.contract.MigrateTokens(iCtx, startKey, 1, signPayload(t, l.admin, &request))
+			if err != nil {
+				return err
+			}
+
+			migratedCount += result.MigratedCount
+			startKey = result.NextKey
+			return nil
+		})
+	}
+
+	if migratedCount != 2 {
+		t.Fatalf("migrated %d records", migratedCount)
+	}
+	if l.getStoredSchemaVersion("token", []string{"t0"}) != token.CurrentSchemaVersion || l.getStoredSchemaVersion("balance", []string{"test", "alice"}) != token.CurrentSchemaVersion {
+		t.Fatal("legacy records are not stored in the current schema version")
+	}
+
+	request = token.TokenMigrationRequest{StartKey: startKey, Count: 1}
+	l.mustFail("migrate token of a newer schema version", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.MigrateTokens(iCtx, startKey, 1, signPayload(t, l.admin, &request))
+		return err
+	})
+}
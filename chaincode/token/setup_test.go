@@ -0,0 +1,207 @@
+package token_test
+
+import (
+	"fmt"
+	"sig_chain/chaincode/asset"
+	"sig_chain/chaincode/token"
+	"sig_chain/pkg/client"
+	"sig_chain/pkg/keys"
+	"sig_chain/pkg/testutil"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+var testTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+/// the class of the tokens of the tests, any token of it can be transferred and consumed
+var testPolicy = token.TokenClassPolicy{IsTransferable: true, IsConsumable: true, ConsumingClasses: []string{}}
+
+/// runs the transactions of a test on its own ledger, a transaction is committed before the next one runs.
+/// The submitter may mint and freeze tokens, and is a channel admin once the ledger is bootstrapped
+type testLedger struct {
+	t        *testing.T
+	ledger   *testutil.MockLedger
+	identity *testutil.MockIdentity
+	txCount  int
+	contract token.TokenContract
+	admin    client.Signer
+	issuer   client.Signer
+}
+
+func makeTestLedger(
+	t *testing.T,
+) *testLedger {
+	identity, err := testutil.MakeMockIdentity("admin", "Org1MSP", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := &testLedger{
+		t:        t,
+		ledger:   testutil.MakeMockLedger(),
+		identity: identity,
+		admin:    makeTestSigner(t),
+		issuer:   makeTestSigner(t),
+	}
+	l.setRole("issuer")
+
+	config := asset.BootstrapConfig{
+		AdminPublicKey:      l.admin.GetPublicKey(),
+		ClockDriftTolerance: 60,
+		AdminMspIds:         []string{"Org1MSP"},
+	}
+	signature := signPayload(t, l.admin, &config)
+	l.mustSubmit("bootstrap", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := (&asset.MaterialContract{}).InitLedger(iCtx, config, signature)
+		return err
+	})
+
+	l.registerClass("test")
+	return l
+}
+
+/// sets the role attribute of the submitter, as the CA of its organization would
+func (l *testLedger) setRole(
+	iRole string,
+) {
+	l.identity.Attributes["role"] = iRole
+}
+
+/// returns the error of iTransaction, its writes are only committed if it succeeds
+func (l *testLedger) submit(
+	iTransaction func(iCtx contractapi.TransactionContextInterface) error,
+) error {
+	l.txCount++
+	stub := l.ledger.MakeStub(fmt.Sprintf("tx%d", l.txCount), testTime)
+	ctx, err := testutil.MakeTransactionContext(stub, l.identity)
+	if err != nil {
+		l.t.Fatal(err)
+	}
+
+	err = iTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	return stub.Commit()
+}
+
+/// fails the test if iTransaction fails
+func (l *testLedger) mustSubmit(
+	iName string,
+	iTransaction func(iCtx contractapi.TransactionContextInterface) error,
+) {
+	l.t.Helper()
+	err := l.submit(iTransaction)
+	if err != nil {
+		l.t.Fatalf("%s: %v", iName, err)
+	}
+}
+
+/// fails the test if iTransaction succeeds
+func (l *testLedger) mustFail(
+	iName string,
+	iTransaction func(iCtx contractapi.TransactionContextInterface) error,
+) {
+	l.t.Helper()
+	err := l.submit(iTransaction)
+	if err == nil {
+		l.t.Fatalf("%s: expected an error", iName)
+	}
+}
+
+func (l *testLedger) setConfig(
+	iName string,
+	iValue string,
+) {
+	l.t.Helper()
+	l.mustSubmit("set "+iName, func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := (&asset.MaterialContract{}).SetConfig(iCtx, iName, iValue)
+		return err
+	})
+}
+
+/// registers iName with testPolicy, issued by the issuer of the ledger
+func (l *testLedger) registerClass(
+	iName string,
+) {
+	l.t.Helper()
+	registration := token.TokenClassRegistration{Name: iName, IssuerPublicKey: l.issuer.GetPublicKey(), Policy: testPolicy}
+	signature := signPayload(l.t, l.issuer, &registration)
+	l.mustSubmit("register "+iName, func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.RegisterTokenClass(iCtx, iName, l.issuer.GetPublicKey(), nil, testPolicy, "", signature)
+		return err
+	})
+}
+
+/// creates a token of the test class, whose owner is notified at iUrl
+func (l *testLedger) createToken(
+	iTokenId string,
+	iOwner client.Signer,
+	iUrl string,
+) {
+	l.t.Helper()
+	creation := token.TokenCreation{
+		Id:                 iTokenId,
+		OwnerPublicKey:     iOwner.GetPublicKey(),
+		RequestToSendUrl:   iUrl,
+		RequestToAcceptUrl: iUrl,
+		Class:              "test",
+		Metadata:           map[string]string{},
+	}
+	signature := signPayload(l.t, l.issuer, &creation)
+	l.mustSubmit("create "+iTokenId, func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.CreateToken(iCtx, iTokenId, iOwner.GetPublicKey(), iUrl, iUrl, "test", "", signature, "")
+		return err
+	})
+}
+
+func (l *testLedger) getToken(
+	iTokenId string,
+) *token.Token {
+	l.t.Helper()
+	var result *token.Token
+	l.mustSubmit("get "+iTokenId, func(iCtx contractapi.TransactionContextInterface) error {
+		var err error
+		result, err = l.contract.GetToken(iCtx, iTokenId)
+		return err
+	})
+
+	return result
+}
+
+func makeTestSigner(
+	t *testing.T,
+) client.Signer {
+	privateKey, err := keys.GenerateKey("ecdsa")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey, err := keys.EncodePublicKey(privateKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := client.MakeKeySignerFromKey(privateKey, publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signer
+}
+
+func signPayload(
+	t *testing.T,
+	iSigner client.Signer,
+	iPayload interface{},
+) string {
+	signature, err := client.SignPayload(iSigner, iPayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signature
+}
@@ -0,0 +1,302 @@
+package token
+
+import (
+	"encoding/json"
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/shopspring/decimal"
+)
+
+const swapObjectType = "swap"
+
+/// The proposer offers a token for either a token or an amount of a fungible class of the counterparty.
+/// Id is the id of the transaction which proposed the swap, the swap is deleted once accepted or cancelled
+type Swap struct {
+	Id                    string `json:"Id"`
+	ProposerPublicKey     string `json:"ProposerPublicKey"`
+	CounterpartyPublicKey string `json:"CounterpartyPublicKey"`
+	OfferedTokenId        string `json:"OfferedTokenId"`
+	OfferedTokenSequence  int    `json:"OfferedTokenSequence"` /// the swap fails if the offered token changed since
+	RequestedTokenId      string `json:"RequestedTokenId"`     /// empty if an amount is requested
+	RequestedClass        string `json:"RequestedClass"`       /// empty if a token is requested
+	RequestedAmount       string `json:"RequestedAmount"`
+}
+
+/// Signed by the owner of the offered token, Sequence is the one of the offered token
+type SwapProposal struct {
+	OfferedTokenId        string `json:"OfferedTokenId"`
+	CounterpartyPublicKey string `json:"CounterpartyPublicKey"`
+	RequestedTokenId      string `json:"RequestedTokenId"`
+	RequestedClass        string `json:"RequestedClass"`
+	RequestedAmount       string `json:"RequestedAmount"`
+	Sequence              int    `json:"Sequence"`
+	Signature             string `json:"Signature"`
+}
+
+/// Signed by the counterparty to accept, or by the proposer to cancel
+type SwapDecision struct {
+	SwapId     string `json:"SwapId"`
+	IsAccepted bool   `json:"IsAccepted"`
+	Signature  string `json:"Signature"`
+}
+
+func getSwapKey(
+	iCtx contractapi.TransactionContextInterface,
+	iSwapId string,
+) (string, error) {
+	return iCtx.GetStub().CreateCompositeKey(swapObjectType, []string{iSwapId})
+}
+
+func getSwap(
+	iCtx contractapi.TransactionContextInterface,
+	iSwapId string,
+) (*Swap, error) {
+	key, err := getSwapKey(iCtx, iSwapId)
+	if err != nil {
+		return nil, err
+	}
+
+	swapJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, makeInternalError("failed to read from ledger: %v", err)
+	}
+
+	if swapJson == nil {
+		return nil, makeNotFoundError("swap %s does not exist", iSwapId)
+	}
+
+	var swap Swap
+	err = json.Unmarshal(swapJson, &swap)
+	if err != nil {
+		return nil, err
+	}
+
+	return &swap, nil
+}
+
+func deleteSwap(
+	iCtx contractapi.TransactionContextInterface,
+	iSwapId string,
+) error {
+	key, err := getSwapKey(iCtx, iSwapId)
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().DelState(key)
+}
+
+/// proposes to give iOfferedTokenId to iCounterpartyPublicKey in exchange of either iRequestedTokenId or
+/// iRequestedAmount of iRequestedClass. Nothing moves until the counterparty accepts with AcceptSwap, which
+/// exchanges both sides in the same transaction. iSignature is the signature of the SwapProposal by the owner
+/// of the offered token. The id of the swap is the id of the transaction
+func (c *TokenContract) ProposeSwap(
+	iCtx contractapi.TransactionContextInterface,
+	iOfferedTokenId string,
+	iCounterpartyPublicKey string,
+	iRequestedTokenId string,
+	iRequestedClass string,
+	iRequestedAmount string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	offered, err := getToken(iCtx, iOfferedTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := SwapProposal{
+		OfferedTokenId:        iOfferedTokenId,
+		CounterpartyPublicKey: iCounterpartyPublicKey,
+		RequestedTokenId:      iRequestedTokenId,
+		RequestedClass:        iRequestedClass,
+		RequestedAmount:       iRequestedAmount,
+		Sequence:              offered.Sequence,
+	}
+	err = verifyPayload(offered.OwnerPublicKey, &proposal, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	if iCounterpartyPublicKey == "" {
+		return nil, makeInvalidArgumentError("counterparty public key cannot be empty")
+	}
+
+	if iCounterpartyPublicKey == offered.OwnerPublicKey {
+		return nil, makeInvalidArgumentError("proposer and counterparty must be different")
+	}
+
+	if offered.ConsumingTokenId != "" {
+		return nil, makeAlreadyConsumedError(offered)
+	}
+
+	err = checkNotFrozen(offered)
+	if err != nil {
+		return nil, err
+	}
+
+	if (iRequestedTokenId == "") == (iRequestedClass == "") {
+		return nil, makeInvalidArgumentError("either a token or an amount of a class must be requested")
+	}
+
+	if iRequestedTokenId != "" {
+		requested, err := getToken(iCtx, iRequestedTokenId)
+		if err != nil {
+			return nil, err
+		}
+
+		if requested.OwnerPublicKey != iCounterpartyPublicKey {
+			return nil, makeInvalidArgumentError("token %s is not owned by the counterparty", iRequestedTokenId)
+		}
+	} else {
+		amount, err := parseAmount(iRequestedAmount)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = getTokenClass(iCtx, iRequestedClass)
+		if err != nil {
+			return nil, err
+		}
+
+		iRequestedAmount = amount.String()
+	}
+
+	swap := Swap{
+		Id:                    iCtx.GetStub().GetTxID(),
+		ProposerPublicKey:     offered.OwnerPublicKey,
+		CounterpartyPublicKey: iCounterpartyPublicKey,
+		OfferedTokenId:        iOfferedTokenId,
+		OfferedTokenSequence:  offered.Sequence,
+		RequestedTokenId:      iRequestedTokenId,
+		RequestedClass:        iRequestedClass,
+		RequestedAmount:       iRequestedAmount,
+	}
+	swapJson, err := json.Marshal(swap)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := getSwapKey(iCtx, swap.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, iCtx.GetStub().PutState(key, swapJson))
+}
+
+/// exchanges both sides of the swap. iSignature is the counterparty's signature of the SwapDecision with
+/// IsAccepted set
+func (c *TokenContract) AcceptSwap(
+	iCtx contractapi.TransactionContextInterface,
+	iSwapId string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	swap, err := getSwap(iCtx, iSwapId)
+	if err != nil {
+		return nil, err
+	}
+
+	decision := SwapDecision{
+		SwapId:     iSwapId,
+		IsAccepted: true,
+	}
+	err = verifyPayload(swap.CounterpartyPublicKey, &decision, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	offered, err := getToken(iCtx, swap.OfferedTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	if offered.OwnerPublicKey != swap.ProposerPublicKey || offered.Sequence != swap.OfferedTokenSequence {
+		return nil, makePreconditionFailedError("token %s changed since the swap was proposed", swap.OfferedTokenId)
+	}
+
+	event := TokenEvent{
+		TokenIds:          []string{swap.OfferedTokenId},
+		OwnerFingerprints: []string{ownerFingerprint(swap.ProposerPublicKey), ownerFingerprint(swap.CounterpartyPublicKey)},
+	}
+	if swap.RequestedTokenId != "" {
+		requested, err := getToken(iCtx, swap.RequestedTokenId)
+		if err != nil {
+			return nil, err
+		}
+
+		if requested.OwnerPublicKey != swap.CounterpartyPublicKey {
+			return nil, makePreconditionFailedError("token %s is not owned by the counterparty anymore", swap.RequestedTokenId)
+		}
+
+		err = changeOwner(iCtx, requested, swap.ProposerPublicKey)
+		if err != nil {
+			return nil, err
+		}
+
+		event.TokenIds = append(event.TokenIds, swap.RequestedTokenId)
+	} else {
+		amount, err := decimal.NewFromString(swap.RequestedAmount)
+		if err != nil {
+			return nil, err
+		}
+
+		balance, err := getBalance(iCtx, swap.RequestedClass, swap.CounterpartyPublicKey)
+		if err != nil {
+			return nil, err
+		}
+
+		balance.Sequence++
+		err = transferAmount(iCtx, swap.RequestedClass, balance, swap.ProposerPublicKey, amount)
+		if err != nil {
+			return nil, err
+		}
+
+		event.Class = swap.RequestedClass
+		event.Amount = swap.RequestedAmount
+	}
+
+	err = changeOwner(iCtx, offered, swap.CounterpartyPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	err = deleteSwap(iCtx, iSwapId)
+	if err != nil {
+		return nil, err
+	}
+
+	/// replaces the events of each side, only the last event of a transaction is kept
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, setTokenEvent(iCtx, tokenTransferredEventName, &event))
+}
+
+/// withdraws the swap before it is accepted. iSignature is the proposer's signature of the SwapDecision with
+/// IsAccepted unset
+func (c *TokenContract) CancelSwap(
+	iCtx contractapi.TransactionContextInterface,
+	iSwapId string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	swap, err := getSwap(iCtx, iSwapId)
+	if err != nil {
+		return nil, err
+	}
+
+	decision := SwapDecision{
+		SwapId:     iSwapId,
+		IsAccepted: false,
+	}
+	err = verifyPayload(swap.ProposerPublicKey, &decision, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, deleteSwap(iCtx, iSwapId))
+}
+
+func (c *TokenContract) GetSwap(
+	iCtx contractapi.TransactionContextInterface,
+	iSwapId string,
+) (*Swap, error) {
+	return getSwap(iCtx, iSwapId)
+}
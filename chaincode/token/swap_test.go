@@ -0,0 +1,131 @@
+package token_test
+
+import (
+	"sig_chain/chaincode/token"
+	"sig_chain/pkg/client"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// returns the id of the swap
+func (l *testLedger) proposeSwap(
+	iProposer client.Signer,
+	iProposal token.SwapProposal,
+) string {
+	l.t.Helper()
+	signature := signPayload(l.t, iProposer, &iProposal)
+	swapId := ""
+	l.mustSubmit("propose swap", func(iCtx contractapi.TransactionContextInterface) error {
+		receipt, err := l.contract.ProposeSwap(
+			iCtx,
+			iProposal.OfferedTokenId,
+			iProposal.CounterpartyPublicKey,
+			iProposal.RequestedTokenId,
+			iProposal.RequestedClass,
+			iProposal.RequestedAmount,
+			signature,
+		)
+		if err != nil {
+			return err
+		}
+
+		swapId = receipt.TxId
+		return nil
+	})
+
+	return swapId
+}
+
+func (l *testLedger) acceptSwap(
+	iSwapId string,
+	iCounterparty client.Signer,
+) error {
+	signature := signPayload(l.t, iCounterparty, &token.SwapDecision{SwapId: iSwapId, IsAccepted: true})
+	return l.submit(func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.AcceptSwap(iCtx, iSwapId, signature)
+		return err
+	})
+}
+
+func TestSwapTokens(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	bob := makeTestSigner(t)
+	l.createToken("t1", alice, "")
+	l.createToken("t2", bob, "")
+
+	swapId := l.proposeSwap(alice, token.SwapProposal{
+		OfferedTokenId:        "t1",
+		CounterpartyPublicKey: bob.GetPublicKey(),
+		RequestedTokenId:      "t2",
+	})
+
+	err := l.acceptSwap(swapId, alice)
+	if err == nil {
+		t.Fatal("swap accepted by the proposer")
+	}
+
+	err = l.acceptSwap(swapId, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l.getToken("t1").OwnerPublicKey != bob.GetPublicKey() || l.getToken("t2").OwnerPublicKey != alice.GetPublicKey() {
+		t.Fatal("both sides of the swap are not exchanged")
+	}
+
+	err = l.acceptSwap(swapId, bob)
+	if err == nil {
+		t.Fatal("swap accepted twice")
+	}
+}
+
+func TestSwapTokenForAmount(t *testing.T) {
+	l := makeTestLedger(t)
+	alice := makeTestSigner(t)
+	bob := makeTestSigner(t)
+	carol := makeTestSigner(t)
+	l.createToken("t1", alice, "")
+	err := l.mint("test", bob, "5", l.signMint("test", bob, "5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proposal := token.SwapProposal{
+		OfferedTokenId:        "t1",
+		CounterpartyPublicKey: bob.GetPublicKey(),
+		RequestedClass:        "test",
+		RequestedAmount:       "5",
+	}
+	swapId := l.proposeSwap(alice, proposal)
+
+	/// the token changes hands before the swap is accepted, nothing may move then
+	transfer := token.TokenTransfer{TokenId: "t1", NewOwnerPublicKey: carol.GetPublicKey()}
+	l.mustSubmit("transfer t1", func(iCtx contractapi.TransactionContextInterface) error {
+		_, err := l.contract.TransferToken(iCtx, "t1", carol.GetPublicKey(), signPayload(t, alice, &transfer))
+		return err
+	})
+
+	err = l.acceptSwap(swapId, bob)
+	if err == nil {
+		t.Fatal("swap of a token which changed hands accepted")
+	}
+	if l.balanceOf("test", bob) != "5" {
+		t.Fatal("amount moved by a failed swap")
+	}
+
+	proposal.Sequence = l.getToken("t1").Sequence
+	swapId = l.proposeSwap(carol, proposal)
+	err = l.acceptSwap(swapId, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l.getToken("t1").OwnerPublicKey != bob.GetPublicKey() {
+		t.Fatal("t1 is not given to the counterparty")
+	}
+	if l.balanceOf("test", bob) != "0" || l.balanceOf("test", carol) != "5" {
+		t.Fatalf("balances are %s and %s", l.balanceOf("test", bob), l.balanceOf("test", carol))
+	}
+}
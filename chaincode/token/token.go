@@ -0,0 +1,216 @@
+package token
+
+import (
+	"encoding/json"
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// tokens share the world state of the graph nodes, which are stored under their plain ids
+const tokenObjectType = "token"
+
+/// A token consumes other tokens, e.g. a deposit token consumed by the token of the goods it was paid for.
+/// Consumption is approved by the owners of both tokens, whose wallets are notified at the urls.
+/// The tokens consumed by a token are listed by GetConsumedTokens
+type Token struct {
+	Id                 string `json:"Id"`
+	OwnerPublicKey     string `json:"OwnerPublicKey"`
+	RequestToSendUrl   string `json:"RequestToSendUrl"`   /// notified when the token is to be consumed
+	RequestToAcceptUrl string `json:"RequestToAcceptUrl"` /// notified when the token is to consume another one
+	ConsumingTokenId   string `json:"ConsumingTokenId"`   /// empty until the token is consumed
+	SpenderPublicKey   string `json:"SpenderPublicKey"`   /// may transfer the token for its owner, until the owner changes
+	Sequence           int    `json:"Sequence"`           /// number of signed changes, so that a signed change cannot be replayed
+	MaterialNodeId     string `json:"MaterialNodeId"`     /// material node of the graph the token is a claim on, empty if none
+	IsFrozen           bool   `json:"IsFrozen"`           /// set by the administrator, a frozen token cannot be transferred nor consumed
+
+	Class    string            `json:"Class"`    /// empty for the tokens created before classes were required
+	Metadata map[string]string `json:"Metadata"` /// describes what the token represents, e.g. a voucher
+
+	SchemaVersion int `json:"SchemaVersion,omitempty"` /// stamped when the token is written, see CurrentSchemaVersion
+}
+
+/// Signed by the issuer of the class of the token
+type TokenCreation struct {
+	Id                 string            `json:"Id"`
+	OwnerPublicKey     string            `json:"OwnerPublicKey"`
+	RequestToSendUrl   string            `json:"RequestToSendUrl"`
+	RequestToAcceptUrl string            `json:"RequestToAcceptUrl"`
+	Class              string            `json:"Class"`
+	Metadata           map[string]string `json:"Metadata"`
+	Signature          string            `json:"Signature"`
+}
+
+type TokenContract struct {
+	contractapi.Contract
+}
+
+func getTokenKey(
+	iCtx contractapi.TransactionContextInterface,
+	iTokenId string,
+) (string, error) {
+	return iCtx.GetStub().CreateCompositeKey(tokenObjectType, []string{iTokenId})
+}
+
+func getToken(
+	iCtx contractapi.TransactionContextInterface,
+	iTokenId string,
+) (*Token, error) {
+	key, err := getTokenKey(iCtx, iTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenJson, err := iCtx.GetStub().GetState(key)
+	if err != nil {
+		return nil, makeInternalError("failed to read from ledger: %v", err)
+	}
+
+	if tokenJson == nil {
+		return nil, makeNotFoundError("token %s does not exist", iTokenId)
+	}
+
+	var token Token
+	err = unmarshalRecord(tokenJson, &token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func putToken(
+	iCtx contractapi.TransactionContextInterface,
+	iToken *Token,
+) error {
+	key, err := getTokenKey(iCtx, iToken.Id)
+	if err != nil {
+		return err
+	}
+
+	iToken.SchemaVersion = CurrentSchemaVersion
+	tokenJson, err := json.Marshal(iToken)
+	if err != nil {
+		return err
+	}
+
+	return iCtx.GetStub().PutState(key, tokenJson)
+}
+
+/// iClass is the registered class of the token, its metadata fields check iMetadata which is a json object of
+/// strings. iSignature is the signature of the TokenCreation by the issuer of iClass.
+/// iClientRequestId is optional, a retry with the same id and arguments returns the receipt of the first
+/// request instead of failing because the token exists
+func (c *TokenContract) CreateToken(
+	iCtx contractapi.TransactionContextInterface,
+	iTokenId string,
+	iOwnerPublicKey string,
+	iRequestToSendUrl string,
+	iRequestToAcceptUrl string,
+	iClass string,
+	iMetadata string,
+	iSignature string,
+	iClientRequestId string,
+) (*graph.TransactionReceipt, error) {
+	metadata := map[string]string{}
+	if iMetadata != "" {
+		err := json.Unmarshal([]byte(iMetadata), &metadata)
+		if err != nil {
+			return nil, makeInvalidArgumentError("metadata must be a json object of strings: %v", err)
+		}
+	}
+
+	creation := TokenCreation{
+		Id:                 iTokenId,
+		OwnerPublicKey:     iOwnerPublicKey,
+		RequestToSendUrl:   iRequestToSendUrl,
+		RequestToAcceptUrl: iRequestToAcceptUrl,
+		Class:              iClass,
+		Metadata:           metadata,
+	}
+	/// the signature is part of the request, the signed payload is the creation without it
+	signedCreation := creation
+	signedCreation.Signature = iSignature
+
+	if iClientRequestId != "" {
+		receipt, err := getClientRequestReceipt(iCtx, iClientRequestId, &signedCreation)
+		if err != nil {
+			return nil, err
+		}
+
+		if receipt != nil {
+			return receipt, nil
+		}
+	}
+
+	if iTokenId == "" {
+		return nil, makeInvalidArgumentError("token id cannot be empty")
+	}
+
+	if iOwnerPublicKey == "" {
+		return nil, makeInvalidArgumentError("owner public key cannot be empty")
+	}
+
+	if iClass == "" {
+		return nil, makeInvalidArgumentError("class cannot be empty")
+	}
+
+	_, err := getToken(iCtx, iTokenId)
+	if err == nil {
+		return nil, makeAlreadyExistsError("token %s already exists", iTokenId)
+	}
+	if _, ok := err.(*NotFoundError); !ok {
+		return nil, err
+	}
+
+	_, err = getTombstone(iCtx, iTokenId)
+	if err == nil {
+		return nil, makeAlreadyExistsError("token %s was deleted, its id cannot be reused", iTokenId)
+	}
+	if _, ok := err.(*NotFoundError); !ok {
+		return nil, err
+	}
+
+	err = addTokenToClass(iCtx, &creation, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putOwnerIndex(iCtx, iOwnerPublicKey, iTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setTokenEvent(iCtx, tokenCreatedEventName, &TokenEvent{
+		TokenIds:          []string{iTokenId},
+		OwnerFingerprints: []string{ownerFingerprint(iOwnerPublicKey)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = putToken(iCtx, &Token{
+		Id:                 iTokenId,
+		OwnerPublicKey:     iOwnerPublicKey,
+		RequestToSendUrl:   iRequestToSendUrl,
+		RequestToAcceptUrl: iRequestToAcceptUrl,
+		Class:              iClass,
+		Metadata:           metadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if iClientRequestId == "" {
+		return graph.MakeTransactionReceipt(iCtx)
+	}
+
+	return putClientRequest(iCtx, iClientRequestId, &signedCreation)
+}
+
+func (c *TokenContract) GetToken(
+	iCtx contractapi.TransactionContextInterface,
+	iTokenId string,
+) (*Token, error) {
+	return getToken(iCtx, iTokenId)
+}
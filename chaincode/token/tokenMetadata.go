@@ -0,0 +1,130 @@
+package token
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/shopspring/decimal"
+)
+
+type MetadataType = string
+
+const (
+	eMetadataString  MetadataType = "eMetadataString"
+	eMetadataNumber  MetadataType = "eMetadataNumber"
+	eMetadataBoolean MetadataType = "eMetadataBoolean"
+	eMetadataDate    MetadataType = "eMetadataDate" /// RFC 3339
+)
+
+/// describes an entry of the metadata of the tokens of a class, as claim fields do for certificates
+type MetadataField struct {
+	Name       string       `json:"Name"`
+	Type       MetadataType `json:"Type"`
+	IsRequired bool         `json:"IsRequired"`
+}
+
+func isMetadataType(
+	iType string,
+) bool {
+	switch iType {
+	case eMetadataString, eMetadataNumber, eMetadataBoolean, eMetadataDate:
+		return true
+	default:
+		return false
+	}
+}
+
+func checkMetadataFields(
+	iFields []MetadataField,
+) error {
+	names := map[string]bool{}
+	for _, field := range iFields {
+		if field.Name == "" {
+			return makeInvalidArgumentError("metadata field name cannot be empty")
+		}
+
+		if names[field.Name] {
+			return makeInvalidArgumentError("metadata field %s is defined more than once", field.Name)
+		}
+		names[field.Name] = true
+
+		if !isMetadataType(field.Type) {
+			return makeInvalidArgumentError("unknown metadata type %s", field.Type)
+		}
+	}
+
+	return nil
+}
+
+/// a class without metadata fields accepts any metadata
+func checkMetadata(
+	iClass *TokenClass,
+	iMetadata map[string]string,
+) error {
+	if len(iClass.MetadataFields) == 0 {
+		return nil
+	}
+
+	fields := map[string]MetadataField{}
+	for _, field := range iClass.MetadataFields {
+		fields[field.Name] = field
+
+		if field.IsRequired && iMetadata[field.Name] == "" {
+			return makeInvalidArgumentError("missing required metadata %s", field.Name)
+		}
+	}
+
+	for name, value := range iMetadata {
+		field, ok := fields[name]
+		if !ok {
+			return makeInvalidArgumentError("unknown metadata %s for class %s", name, iClass.Name)
+		}
+
+		var err error
+		switch field.Type {
+		case eMetadataNumber:
+			_, err = decimal.NewFromString(value)
+		case eMetadataBoolean:
+			_, err = strconv.ParseBool(value)
+		case eMetadataDate:
+			_, err = time.Parse(time.RFC3339, value)
+		}
+		if err != nil {
+			return makeInvalidArgumentError("invalid metadata %s: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+/// tokens of a class are approved by its issuer, their metadata follows the fields of the class and they count
+/// towards its supply
+func addTokenToClass(
+	iCtx contractapi.TransactionContextInterface,
+	iCreation *TokenCreation,
+	iSignature string,
+) error {
+	class, err := getTokenClass(iCtx, iCreation.Class)
+	if err != nil {
+		return err
+	}
+
+	err = verifyPayload(class.IssuerPublicKey, iCreation, iSignature)
+	if err != nil {
+		return err
+	}
+
+	err = checkMetadata(class, iCreation.Metadata)
+	if err != nil {
+		return err
+	}
+
+	err = checkSupplyCap(class, decimal.NewFromInt(1))
+	if err != nil {
+		return err
+	}
+
+	class.TokenCount++
+	return putTokenClass(iCtx, class)
+}
@@ -0,0 +1,75 @@
+package token
+
+import "github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+/// how the tokens and amounts of a class may be used, e.g. an access grant is neither transferable nor
+/// consumable while a deposit can only be consumed by the tokens of the goods it pays for
+type TokenClassPolicy struct {
+	IsTransferable   bool     `json:"IsTransferable"` /// tokens change owner and amounts move between balances
+	IsConsumable     bool     `json:"IsConsumable"`
+	ConsumingClasses []string `json:"ConsumingClasses"` /// classes whose tokens may consume the tokens of the class, any if empty
+}
+
+/// tokens created before classes were required belong to no class and follow no policy
+func getTokenPolicy(
+	iCtx contractapi.TransactionContextInterface,
+	iToken *Token,
+) (*TokenClassPolicy, error) {
+	if iToken.Class == "" {
+		return &TokenClassPolicy{
+			IsTransferable:   true,
+			IsConsumable:     true,
+			ConsumingClasses: []string{},
+		}, nil
+	}
+
+	class, err := getTokenClass(iCtx, iToken.Class)
+	if err != nil {
+		return nil, err
+	}
+
+	return &class.Policy, nil
+}
+
+func checkTransferable(
+	iCtx contractapi.TransactionContextInterface,
+	iToken *Token,
+) error {
+	policy, err := getTokenPolicy(iCtx, iToken)
+	if err != nil {
+		return err
+	}
+
+	if !policy.IsTransferable {
+		return makePreconditionFailedError("tokens of class %s are not transferable", iToken.Class)
+	}
+
+	return nil
+}
+
+func checkConsumptionPolicy(
+	iCtx contractapi.TransactionContextInterface,
+	iConsumed *Token,
+	iConsuming *Token,
+) error {
+	policy, err := getTokenPolicy(iCtx, iConsumed)
+	if err != nil {
+		return err
+	}
+
+	if !policy.IsConsumable {
+		return makePreconditionFailedError("tokens of class %s are not consumable", iConsumed.Class)
+	}
+
+	if len(policy.ConsumingClasses) == 0 {
+		return nil
+	}
+
+	for _, class := range policy.ConsumingClasses {
+		if class == iConsuming.Class {
+			return nil
+		}
+	}
+
+	return makePreconditionFailedError("tokens of class %s cannot be consumed by tokens of class %s", iConsumed.Class, iConsuming.Class)
+}
@@ -0,0 +1,88 @@
+package token
+
+import (
+	"sig_chain/chaincode/graph"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// Signed by the current owner, Sequence is the one of the token before the transfer
+type TokenTransfer struct {
+	TokenId           string `json:"TokenId"`
+	NewOwnerPublicKey string `json:"NewOwnerPublicKey"`
+	Sequence          int    `json:"Sequence"`
+	Signature         string `json:"Signature"`
+}
+
+/// pending consumption approvals and the spender of the previous owner are not valid anymore
+func changeOwner(
+	iCtx contractapi.TransactionContextInterface,
+	ioToken *Token,
+	iNewOwnerPublicKey string,
+) error {
+	if iNewOwnerPublicKey == "" {
+		return makeInvalidArgumentError("owner public key cannot be empty")
+	}
+
+	if ioToken.ConsumingTokenId != "" {
+		return makeAlreadyConsumedError(ioToken)
+	}
+
+	err := checkNotFrozen(ioToken)
+	if err != nil {
+		return err
+	}
+
+	err = checkTransferable(iCtx, ioToken)
+	if err != nil {
+		return err
+	}
+
+	err = deleteOwnerIndex(iCtx, ioToken.OwnerPublicKey, ioToken.Id)
+	if err != nil {
+		return err
+	}
+
+	err = putOwnerIndex(iCtx, iNewOwnerPublicKey, ioToken.Id)
+	if err != nil {
+		return err
+	}
+
+	err = setTokenEvent(iCtx, tokenTransferredEventName, &TokenEvent{
+		TokenIds:          []string{ioToken.Id},
+		OwnerFingerprints: []string{ownerFingerprint(ioToken.OwnerPublicKey), ownerFingerprint(iNewOwnerPublicKey)},
+	})
+	if err != nil {
+		return err
+	}
+
+	ioToken.OwnerPublicKey = iNewOwnerPublicKey
+	ioToken.SpenderPublicKey = ""
+	ioToken.Sequence++
+	return putToken(iCtx, ioToken)
+}
+
+/// iSignature is the current owner's signature of the TokenTransfer
+func (c *TokenContract) TransferToken(
+	iCtx contractapi.TransactionContextInterface,
+	iTokenId string,
+	iNewOwnerPublicKey string,
+	iSignature string,
+) (*graph.TransactionReceipt, error) {
+	token, err := getToken(iCtx, iTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer := TokenTransfer{
+		TokenId:           iTokenId,
+		NewOwnerPublicKey: iNewOwnerPublicKey,
+		Sequence:          token.Sequence,
+	}
+	err = verifyPayload(token.OwnerPublicKey, &transfer, iSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.MakeTransactionReceiptIfSucceeded(iCtx, changeOwner(iCtx, token, iNewOwnerPublicKey))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sig_chain/pkg/client"
+	"strings"
+)
+
+type ErrorResponse struct {
+	Error string `json:"Error"`
+}
+
+/// errors of the gateway itself, such as invalid requests
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+func badRequest(
+	iMessage string,
+) error {
+	return &requestError{status: http.StatusBadRequest, message: iMessage}
+}
+
+/// maps chaincode errors to the closest status from their message, errors which are not raised by the chaincode
+/// mean that the network could not be reached
+func getErrorStatus(
+	iErr error,
+) int {
+	var request *requestError
+	if errors.As(iErr, &request) {
+		return request.status
+	}
+
+	message := iErr.Error()
+	switch {
+	case strings.Contains(message, "does not exist"):
+		return http.StatusNotFound
+	case strings.Contains(message, "already exists"), strings.Contains(message, "is already"):
+		return http.StatusConflict
+	}
+
+	var transaction *client.TransactionError
+	if !errors.As(iErr, &transaction) {
+		return http.StatusBadGateway
+	}
+
+	message = transaction.Message
+	switch {
+	case strings.HasPrefix(message, "{") && strings.Contains(message, "unknown function"):
+		return http.StatusNotFound
+	case strings.Contains(message, "verify err"), strings.Contains(message, "not authorized"),
+		strings.Contains(message, "submitter"), strings.Contains(message, "administrator"):
+		return http.StatusForbidden
+	default:
+		return http.StatusUnprocessableEntity
+	}
+}
+
+func writeJson(
+	iWriter http.ResponseWriter,
+	iStatus int,
+	iValue interface{},
+) {
+	iWriter.Header().Set("Content-Type", "application/json")
+	iWriter.WriteHeader(iStatus)
+	json.NewEncoder(iWriter).Encode(iValue)
+}
+
+func writeError(
+	iWriter http.ResponseWriter,
+	iErr error,
+) {
+	/// unknown functions are reported as json, which is passed on as is
+	var transaction *client.TransactionError
+	if errors.As(iErr, &transaction) && json.Valid([]byte(transaction.Message)) {
+		iWriter.Header().Set("Content-Type", "application/json")
+		iWriter.WriteHeader(getErrorStatus(iErr))
+		iWriter.Write([]byte(transaction.Message))
+		return
+	}
+
+	writeJson(iWriter, getErrorStatus(iErr), ErrorResponse{Error: iErr.Error()})
+}
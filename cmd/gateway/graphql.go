@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sig_chain/pkg/client"
+	"sig_chain/pkg/graphql"
+)
+
+/// e.g. { provenance(id: "m1") { material { id name } certificates { certificateType expiryTime }
+/// documents { docType uri } inputs { material { id } certificates { id } } } }
+func getGraphqlSchema(
+	iClient *client.Client,
+) *graphql.Schema {
+	return &graphql.Schema{
+		Queries: map[string]graphql.Resolver{
+			"material": func(iArguments map[string]interface{}) (interface{}, error) {
+				id, err := getIdArgument(iArguments)
+				if err != nil {
+					return nil, err
+				}
+				return iClient.GetMaterial(id)
+			},
+			"provenance": func(iArguments map[string]interface{}) (interface{}, error) {
+				id, err := getIdArgument(iArguments)
+				if err != nil {
+					return nil, err
+				}
+				return iClient.GetProvenance(id)
+			},
+			"certificate": func(iArguments map[string]interface{}) (interface{}, error) {
+				id, err := getIdArgument(iArguments)
+				if err != nil {
+					return nil, err
+				}
+				return iClient.GetCertificate(id)
+			},
+		},
+	}
+}
+
+func getIdArgument(
+	iArguments map[string]interface{},
+) (string, error) {
+	id, ok := iArguments["id"].(string)
+	if !ok || id == "" {
+		return "", fmt.Errorf("id must be a non empty string")
+	}
+
+	return id, nil
+}
+
+/// the whole provenance is read in a single evaluation, the query only selects what is returned. Errors are
+/// reported in the response as GraphQL clients expect
+func executeGraphql(
+	iGateway *gateway,
+	iRequest *http.Request,
+	iParams []string,
+) (interface{}, error) {
+	var body graphql.Request
+	err := readBody(iRequest, &body)
+	if err != nil {
+		return nil, err
+	}
+
+	return getGraphqlSchema(client.MakeClient(iGateway.contract, nil)).Execute(&body), nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sig_chain/pkg/client"
+)
+
+/// HTTP gateway to the chaincode for clients without a Fabric SDK. Nodes are signed with the owner keys of the
+/// wallet, so the gateway must only be reachable by the applications acting for these owners. GET / lists the routes
+type gateway struct {
+	contract  client.Contract
+	wallet    client.Wallet
+	issuer    string /// identity of the wallet signing the proof bundles
+	publicUrl string /// url of the gateway as reached by the consumers scanning the QR codes
+}
+
+func main() {
+	configPath := flag.String("config", "network.json", "network config json, see client.NetworkConfig")
+	walletDirectory := flag.String("wallet", "wallet", "directory of the owner keys")
+	passwordPath := flag.String("password", "", "file holding the password of the wallet, new keys are encrypted with it")
+	address := flag.String("listen", ":8080", "address to listen on")
+	issuer := flag.String("issuer", "", "identity of the wallet signing the proof bundles, proof bundles are disabled if empty")
+	publicUrl := flag.String("url", "", "public url of the gateway, encoded in the QR payloads")
+	flag.Parse()
+
+	var password []byte
+	if *passwordPath != "" {
+		passwordFile, err := ioutil.ReadFile(*passwordPath)
+		if err != nil {
+			log.Panicf("Error reading wallet password: %v", err)
+		}
+		password = bytes.TrimRight(passwordFile, "\r\n")
+	}
+
+	config, err := client.ReadNetworkConfig(*configPath)
+	if err != nil {
+		log.Panicf("Error reading network config: %v", err)
+	}
+
+	contract, err := client.ConnectNetwork(config)
+	if err != nil {
+		log.Panicf("Error connecting to the network: %v", err)
+	}
+	defer contract.Close()
+
+	log.Printf("listening on %s", *address)
+	err = http.ListenAndServe(*address, &gateway{
+		contract:  contract,
+		wallet:    client.MakeFileWallet(*walletDirectory, password),
+		issuer:    *issuer,
+		publicUrl: *publicUrl,
+	})
+	if err != nil {
+		log.Panicf("Error serving: %v", err)
+	}
+}
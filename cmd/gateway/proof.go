@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"sig_chain/pkg/client"
+	"sig_chain/pkg/proof"
+	"strings"
+	"time"
+)
+
+/// the proof routes are only served if the gateway has an issuer identity and knows its public url
+func (g *gateway) getIssuer() (client.Signer, error) {
+	if g.issuer == "" || g.publicUrl == "" {
+		return nil, &requestError{status: http.StatusNotImplemented, message: "proof bundles are not enabled on this gateway"}
+	}
+
+	return g.wallet.GetSigner(g.issuer)
+}
+
+func getQrPayload(
+	iGateway *gateway,
+	iRequest *http.Request,
+	iParams []string,
+) (interface{}, error) {
+	_, err := iGateway.getIssuer()
+	if err != nil {
+		return nil, err
+	}
+
+	/// the material must exist, a QR code printed for a wrong id could not be fixed
+	_, err = client.MakeClient(iGateway.contract, nil).GetMaterial(iParams[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return proof.MakeQrPayload(strings.TrimSuffix(iGateway.publicUrl, "/")+"/proofs", iParams[0])
+}
+
+func getProofBundle(
+	iGateway *gateway,
+	iRequest *http.Request,
+	iParams []string,
+) (interface{}, error) {
+	issuer, err := iGateway.getIssuer()
+	if err != nil {
+		return nil, err
+	}
+
+	nodeId := iRequest.URL.Query().Get("nodeId")
+	if nodeId == "" {
+		return nil, badRequest("nodeId is required")
+	}
+
+	provenance, err := client.MakeClient(iGateway.contract, nil).GetProvenance(nodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	return proof.MakeProofBundle(provenance, issuer, time.Now())
+}
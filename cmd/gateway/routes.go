@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sig_chain/chaincode/asset"
+	"sig_chain/pkg/client"
+	"strings"
+	"time"
+)
+
+/// Path parameters are written {name}, they are passed to the handler in order
+type route struct {
+	Method      string `json:"Method"`
+	Path        string `json:"Path"`
+	Description string `json:"Description"`
+	handler     func(iGateway *gateway, iRequest *http.Request, iParams []string) (interface{}, error)
+	isReadOnly  bool /// POST routes which create nothing are answered with 200 rather than 201
+	pattern     *regexp.Regexp
+}
+
+type CreateIdentityRequest struct {
+	Name string `json:"Name"`
+}
+
+/// CreatedTime defaults to the current time, the owner of the material is Identity
+type CreateMaterialRequest struct {
+	Identity string             `json:"Identity"`
+	Material asset.MaterialSpec `json:"Material"`
+}
+
+/// both identities must be in the wallet of the gateway
+type TransferMaterialRequest struct {
+	Identity      string `json:"Identity"`
+	NewIdentity   string `json:"NewIdentity"`
+	NewOwnerMspId string `json:"NewOwnerMspId"`
+	NewNodeId     string `json:"NewNodeId"`
+}
+
+type SplitRequest struct {
+	NodeId   string `json:"NodeId"`
+	Quantity string `json:"Quantity"`
+	Identity string `json:"Identity"`
+}
+
+type SplitMaterialRequest struct {
+	Identity string         `json:"Identity"`
+	Waste    string         `json:"Waste"`
+	Splits   []SplitRequest `json:"Splits"`
+}
+
+/// the function of the path may be prefixed by the contract name, e.g. CertificateContract:IssueCertificate
+type TransactionRequest struct {
+	Args []string `json:"Args"`
+}
+
+/// wraps results which are not json, such as the strings returned by some functions
+type TransactionResult struct {
+	Result string `json:"Result"`
+}
+
+/// set in init, since the route listing refers to it
+var routes []route
+
+func init() {
+	routes = []route{
+		{Method: "GET", Path: "/", Description: "lists the routes of the gateway", handler: getRoutes},
+		{Method: "GET", Path: "/identities", Description: "lists the identities of the wallet and their public keys", handler: getIdentities},
+		{Method: "POST", Path: "/identities", Description: "generates a key pair for a new identity, body: CreateIdentityRequest", handler: createIdentity},
+		{Method: "POST", Path: "/materials", Description: "creates a material, body: CreateMaterialRequest", handler: createMaterial},
+		{Method: "GET", Path: "/materials/{id}", Description: "returns a material", handler: getMaterial},
+		{Method: "GET", Path: "/materials/{id}/provenance", Description: "returns the full provenance of a material", handler: getProvenance},
+		{Method: "POST", Path: "/materials/{id}/transfer", Description: "transfers a material, body: TransferMaterialRequest", handler: transferMaterial},
+		{Method: "POST", Path: "/materials/{id}/split", Description: "splits a material, body: SplitMaterialRequest", handler: splitMaterial},
+		{Method: "GET", Path: "/materials/{id}/qr", Description: "returns the QR payload pointing to the proof bundle of a material", handler: getQrPayload},
+		{Method: "GET", Path: "/proofs", Description: "returns the proof bundle of the nodeId query parameter, signed by the issuer identity", handler: getProofBundle},
+		{Method: "POST", Path: "/graphql", Description: "resolves a GraphQL query over materials, provenance and certificates, body: graphql.Request", handler: executeGraphql, isReadOnly: true},
+		{Method: "GET", Path: "/transactions/{function}", Description: "evaluates any function, its arguments are the arg query parameters in order", handler: evaluateTransaction},
+		{Method: "POST", Path: "/transactions/{function}", Description: "submits any function, body: TransactionRequest", handler: submitTransaction},
+	}
+
+	for i := range routes {
+		pattern := regexp.MustCompile(`\{[a-z]+\}`).ReplaceAllString(routes[i].Path, `([^/]+)`)
+		routes[i].pattern = regexp.MustCompile("^" + pattern + "$")
+	}
+}
+
+func readBody(
+	iRequest *http.Request,
+	oBody interface{},
+) error {
+	err := json.NewDecoder(iRequest.Body).Decode(oBody)
+	if err != nil {
+		return badRequest("invalid body: " + err.Error())
+	}
+
+	return nil
+}
+
+func toTransactionResult(
+	iResult []byte,
+) interface{} {
+	if json.Valid(iResult) {
+		return json.RawMessage(iResult)
+	}
+
+	return TransactionResult{Result: string(iResult)}
+}
+
+func getRoutes(
+	iGateway *gateway,
+	iRequest *http.Request,
+	iParams []string,
+) (interface{}, error) {
+	return routes, nil
+}
+
+func getIdentities(
+	iGateway *gateway,
+	iRequest *http.Request,
+	iParams []string,
+) (interface{}, error) {
+	return iGateway.wallet.ListIdentities()
+}
+
+func createIdentity(
+	iGateway *gateway,
+	iRequest *http.Request,
+	iParams []string,
+) (interface{}, error) {
+	var body CreateIdentityRequest
+	err := readBody(iRequest, &body)
+	if err != nil {
+		return nil, err
+	}
+
+	return iGateway.wallet.CreateIdentity(body.Name)
+}
+
+func createMaterial(
+	iGateway *gateway,
+	iRequest *http.Request,
+	iParams []string,
+) (interface{}, error) {
+	var body CreateMaterialRequest
+	err := readBody(iRequest, &body)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := iGateway.wallet.GetSigner(body.Identity)
+	if err != nil {
+		return nil, err
+	}
+
+	if body.Material.CreatedTime.IsZero() {
+		body.Material.CreatedTime = time.Now()
+	}
+
+	return client.MakeClient(iGateway.contract, signer).CreateMaterial(body.Material)
+}
+
+func getMaterial(
+	iGateway *gateway,
+	iRequest *http.Request,
+	iParams []string,
+) (interface{}, error) {
+	return client.MakeClient(iGateway.contract, nil).GetMaterial(iParams[0])
+}
+
+func getProvenance(
+	iGateway *gateway,
+	iRequest *http.Request,
+	iParams []string,
+) (interface{}, error) {
+	return client.MakeClient(iGateway.contract, nil).GetProvenance(iParams[0])
+}
+
+func transferMaterial(
+	iGateway *gateway,
+	iRequest *http.Request,
+	iParams []string,
+) (interface{}, error) {
+	var body TransferMaterialRequest
+	err := readBody(iRequest, &body)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := iGateway.wallet.GetSigner(body.Identity)
+	if err != nil {
+		return nil, err
+	}
+
+	newOwner, err := iGateway.wallet.GetSigner(body.NewIdentity)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.MakeClient(iGateway.contract, signer).TransferMaterial(iParams[0], body.NewNodeId, newOwner, body.NewOwnerMspId, time.Now())
+}
+
+func splitMaterial(
+	iGateway *gateway,
+	iRequest *http.Request,
+	iParams []string,
+) (interface{}, error) {
+	var body SplitMaterialRequest
+	err := readBody(iRequest, &body)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := iGateway.wallet.GetSigner(body.Identity)
+	if err != nil {
+		return nil, err
+	}
+
+	splits := []client.Split{}
+	for _, split := range body.Splits {
+		owner, err := iGateway.wallet.GetSigner(split.Identity)
+		if err != nil {
+			return nil, err
+		}
+
+		splits = append(splits, client.Split{NodeId: split.NodeId, Quantity: split.Quantity, Owner: owner})
+	}
+
+	return client.MakeClient(iGateway.contract, signer).SplitMaterial(iParams[0], splits, body.Waste, time.Now())
+}
+
+func evaluateTransaction(
+	iGateway *gateway,
+	iRequest *http.Request,
+	iParams []string,
+) (interface{}, error) {
+	result, err := iGateway.contract.EvaluateTransaction(iParams[0], iRequest.URL.Query()["arg"]...)
+	if err != nil {
+		return nil, err
+	}
+
+	return toTransactionResult(result), nil
+}
+
+func submitTransaction(
+	iGateway *gateway,
+	iRequest *http.Request,
+	iParams []string,
+) (interface{}, error) {
+	var body TransactionRequest
+	err := readBody(iRequest, &body)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := iGateway.contract.SubmitTransaction(iParams[0], body.Args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return toTransactionResult(result), nil
+}
+
+func (g *gateway) ServeHTTP(
+	iWriter http.ResponseWriter,
+	iRequest *http.Request,
+) {
+	path := iRequest.URL.Path
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	isPathFound := false
+	for _, route := range routes {
+		matches := route.pattern.FindStringSubmatch(path)
+		if matches == nil {
+			continue
+		}
+		isPathFound = true
+
+		if route.Method != iRequest.Method {
+			continue
+		}
+
+		result, err := route.handler(g, iRequest, matches[1:])
+		if err != nil {
+			writeError(iWriter, err)
+			return
+		}
+
+		status := http.StatusOK
+		if iRequest.Method == "POST" && !route.isReadOnly {
+			status = http.StatusCreated
+		}
+		writeJson(iWriter, status, result)
+		return
+	}
+
+	if isPathFound {
+		writeJson(iWriter, http.StatusMethodNotAllowed, ErrorResponse{Error: "method not allowed"})
+		return
+	}
+
+	writeJson(iWriter, http.StatusNotFound, ErrorResponse{Error: "no route for " + iRequest.URL.Path})
+}
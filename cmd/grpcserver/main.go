@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"sig_chain/pkg/client"
+	"sig_chain/pkg/rpc"
+
+	"google.golang.org/grpc"
+)
+
+/// gRPC server of pkg/rpc/sigchain.proto, for integrators generating their clients from the proto file. Unlike
+/// the HTTP gateway it holds no owner key, write transactions are signed by the integrators
+func main() {
+	configPath := flag.String("config", "network.json", "network config json, see client.NetworkConfig")
+	address := flag.String("listen", ":9090", "address to listen on")
+	flag.Parse()
+
+	config, err := client.ReadNetworkConfig(*configPath)
+	if err != nil {
+		log.Panicf("Error reading network config: %v", err)
+	}
+
+	contract, err := client.ConnectNetwork(config)
+	if err != nil {
+		log.Panicf("Error connecting to the network: %v", err)
+	}
+	defer contract.Close()
+
+	listener, err := net.Listen("tcp", *address)
+	if err != nil {
+		log.Panicf("Error listening on %s: %v", *address, err)
+	}
+
+	grpcServer := grpc.NewServer()
+	rpc.RegisterSigChainServer(grpcServer, &server{contract: contract})
+
+	log.Printf("listening on %s", *address)
+	err = grpcServer.Serve(listener)
+	if err != nil {
+		log.Panicf("Error serving: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sig_chain/pkg/client"
+	"sig_chain/pkg/rpc"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type server struct {
+	contract client.Contract
+}
+
+/// maps chaincode errors to the closest code from their message, as the HTTP gateway does with statuses
+func toStatusError(
+	iErr error,
+) error {
+	message := iErr.Error()
+	switch {
+	case strings.Contains(message, "does not exist"):
+		return status.Error(codes.NotFound, message)
+	case strings.Contains(message, "already exists"), strings.Contains(message, "is already"):
+		return status.Error(codes.AlreadyExists, message)
+	}
+
+	var transaction *client.TransactionError
+	if !errors.As(iErr, &transaction) {
+		return status.Error(codes.Unavailable, message)
+	}
+
+	switch {
+	case strings.HasPrefix(message, "{") && strings.Contains(message, "unknown function"):
+		return status.Error(codes.Unimplemented, message)
+	case strings.Contains(message, "verify err"), strings.Contains(message, "not authorized"),
+		strings.Contains(message, "submitter"), strings.Contains(message, "administrator"):
+		return status.Error(codes.PermissionDenied, message)
+	default:
+		return status.Error(codes.FailedPrecondition, message)
+	}
+}
+
+func getArgs(
+	iRequest *rpc.TransactionRequest,
+) ([]string, error) {
+	if iRequest.Function == "" {
+		return nil, status.Error(codes.InvalidArgument, "function cannot be empty")
+	}
+
+	args := []string{}
+	for _, arg := range iRequest.Args {
+		args = append(args, string(arg))
+	}
+
+	return args, nil
+}
+
+func (s *server) GetMaterial(
+	iCtx context.Context,
+	iRequest *rpc.NodeRequest,
+) (*rpc.Material, error) {
+	material, err := client.MakeClient(s.contract, nil).GetMaterial(iRequest.Id)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return rpc.MakeMaterial(material), nil
+}
+
+func (s *server) GetCertificate(
+	iCtx context.Context,
+	iRequest *rpc.NodeRequest,
+) (*rpc.Certificate, error) {
+	certificate, err := client.MakeClient(s.contract, nil).GetCertificate(iRequest.Id)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return rpc.MakeCertificate(certificate), nil
+}
+
+func (s *server) GetProvenance(
+	iCtx context.Context,
+	iRequest *rpc.NodeRequest,
+) (*rpc.ProvenanceNode, error) {
+	provenance, err := client.MakeClient(s.contract, nil).GetProvenance(iRequest.Id)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return rpc.MakeProvenanceNode(provenance)
+}
+
+func (s *server) EvaluateTransaction(
+	iCtx context.Context,
+	iRequest *rpc.TransactionRequest,
+) (*rpc.TransactionResponse, error) {
+	args, err := getArgs(iRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.contract.EvaluateTransaction(iRequest.Function, args...)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &rpc.TransactionResponse{Result: result}, nil
+}
+
+func (s *server) SubmitTransaction(
+	iCtx context.Context,
+	iRequest *rpc.TransactionRequest,
+) (*rpc.TransactionResponse, error) {
+	args, err := getArgs(iRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.contract.SubmitTransaction(iRequest.Function, args...)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &rpc.TransactionResponse{Result: result}, nil
+}
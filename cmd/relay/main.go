@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"sig_chain/pkg/client"
+	"time"
+)
+
+/// delay before listening again when the connection to the peer is lost
+const reconnectDelay = 10 * time.Second
+
+/// Relays the chaincode events to webhooks. Events are delivered at least once: the last processed block is only
+/// checkpointed once every webhook received its events, so a restart may deliver them again. Receivers can
+/// deduplicate them with the X-SigChain-Delivery header
+func main() {
+	configPath := flag.String("config", "network.json", "network config json, see client.NetworkConfig")
+	relayConfigPath := flag.String("relay", "relay.json", "relay config json, see RelayConfig")
+	flag.Parse()
+
+	config, err := client.ReadNetworkConfig(*configPath)
+	if err != nil {
+		log.Panicf("Error reading network config: %v", err)
+	}
+
+	relayConfig, err := readRelayConfig(*relayConfigPath)
+	if err != nil {
+		log.Panicf("Error reading relay config: %v", err)
+	}
+
+	contract, err := client.ConnectNetwork(config)
+	if err != nil {
+		log.Panicf("Error connecting to the network: %v", err)
+	}
+	defer contract.Close()
+
+	relay := makeRelay(relayConfig)
+	for {
+		startBlock, err := relay.getStartBlock()
+		if err != nil {
+			log.Panicf("Error reading checkpoint: %v", err)
+		}
+
+		err = contract.ListenEvents(context.Background(), startBlock, relay.handleBlock)
+		log.Printf("stopped listening to events: %v", err)
+		time.Sleep(reconnectDelay)
+	}
+}
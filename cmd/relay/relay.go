@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sig_chain/pkg/client"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxAttempts   = 5
+	firstRetryDelay      = time.Second /// doubled after every failed attempt
+	webhookTimeout       = 10 * time.Second
+	signatureHeader      = "X-SigChain-Signature" /// sha256=<hex hmac of the body>
+	eventNameHeader      = "X-SigChain-Event"
+	deliveryIdHeader     = "X-SigChain-Delivery" /// <tx id>:<event index in the block>, the same for every attempt
+	checkpointFileMode   = 0644
+	checkpointTempSuffix = ".tmp"
+)
+
+type WebhookConfig struct {
+	Url        string   `json:"Url"`
+	Secret     string   `json:"Secret"`     /// key of the HMAC-SHA256 signature of the body
+	EventNames []string `json:"EventNames"` /// empty to receive every event
+}
+
+type RelayConfig struct {
+	Webhooks       []WebhookConfig `json:"Webhooks"`
+	MaxAttempts    int             `json:"MaxAttempts"`    /// per event and webhook, defaults to 5
+	CheckpointPath string          `json:"CheckpointPath"` /// file of the last processed block, starts at the newest block when it does not exist
+}
+
+/// body of the webhook requests, Payload is passed as is if it is json and as a json string otherwise
+type WebhookEvent struct {
+	BlockNumber uint64          `json:"BlockNumber"`
+	TxId        string          `json:"TxId"`
+	EventName   string          `json:"EventName"`
+	Payload     json.RawMessage `json:"Payload"`
+}
+
+type relay struct {
+	config     RelayConfig
+	httpClient *http.Client
+}
+
+func readRelayConfig(
+	iPath string,
+) (*RelayConfig, error) {
+	configJson, err := ioutil.ReadFile(iPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var config RelayConfig
+	err = json.Unmarshal(configJson, &config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid relay config: %v", err)
+	}
+
+	if len(config.Webhooks) == 0 {
+		return nil, fmt.Errorf("relay config has no webhooks")
+	}
+
+	if config.CheckpointPath == "" {
+		return nil, fmt.Errorf("relay config has no checkpoint path")
+	}
+
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+
+	return &config, nil
+}
+
+func makeRelay(
+	iConfig *RelayConfig,
+) *relay {
+	return &relay{
+		config:     *iConfig,
+		httpClient: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+/// the block after the checkpoint, nil if there is no checkpoint yet
+func (r *relay) getStartBlock() (*uint64, error) {
+	checkpoint, err := ioutil.ReadFile(r.config.CheckpointPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	blockNumber, err := strconv.ParseUint(strings.TrimSpace(string(checkpoint)), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %v", r.config.CheckpointPath, err)
+	}
+
+	startBlock := blockNumber + 1
+	return &startBlock, nil
+}
+
+/// written to a temporary file first so that a crash cannot leave a truncated checkpoint
+func (r *relay) saveCheckpoint(
+	iBlockNumber uint64,
+) error {
+	path := r.config.CheckpointPath + checkpointTempSuffix
+	err := ioutil.WriteFile(path, []byte(strconv.FormatUint(iBlockNumber, 10)), checkpointFileMode)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(path, r.config.CheckpointPath)
+}
+
+func isSubscribed(
+	iWebhook *WebhookConfig,
+	iEventName string,
+) bool {
+	if len(iWebhook.EventNames) == 0 {
+		return true
+	}
+
+	for _, name := range iWebhook.EventNames {
+		if name == iEventName {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sign(
+	iSecret string,
+	iBody []byte,
+) string {
+	mac := hmac.New(sha256.New, []byte(iSecret))
+	mac.Write(iBody)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func makeWebhookEvent(
+	iEvent *client.ChaincodeEvent,
+) ([]byte, error) {
+	payload := json.RawMessage(iEvent.Payload)
+	if !json.Valid(iEvent.Payload) {
+		payloadJson, err := json.Marshal(string(iEvent.Payload))
+		if err != nil {
+			return nil, err
+		}
+		payload = payloadJson
+	}
+
+	return json.Marshal(WebhookEvent{
+		BlockNumber: iEvent.BlockNumber,
+		TxId:        iEvent.TxId,
+		EventName:   iEvent.EventName,
+		Payload:     payload,
+	})
+}
+
+/// webhooks must answer with a 2xx status, other answers are retried
+func (r *relay) post(
+	iWebhook *WebhookConfig,
+	iEventName string,
+	iDeliveryId string,
+	iBody []byte,
+) error {
+	request, err := http.NewRequest("POST", iWebhook.Url, bytes.NewReader(iBody))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(eventNameHeader, iEventName)
+	request.Header.Set(deliveryIdHeader, iDeliveryId)
+	if iWebhook.Secret != "" {
+		request.Header.Set(signatureHeader, sign(iWebhook.Secret, iBody))
+	}
+
+	response, err := r.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered with status %d", response.StatusCode)
+	}
+
+	return nil
+}
+
+/// retries with an exponential backoff, the event is dropped once every attempt failed so that one unreachable
+/// webhook does not hold back the others
+func (r *relay) deliver(
+	iWebhook *WebhookConfig,
+	iEventName string,
+	iDeliveryId string,
+	iBody []byte,
+) {
+	delay := firstRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := r.post(iWebhook, iEventName, iDeliveryId, iBody)
+		if err == nil {
+			return
+		}
+
+		if attempt == r.config.MaxAttempts {
+			log.Printf("dropped delivery %s to %s after %d attempts: %v", iDeliveryId, iWebhook.Url, attempt, err)
+			return
+		}
+
+		log.Printf("delivery %s to %s failed, retrying in %v: %v", iDeliveryId, iWebhook.Url, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+/// events are delivered in the order of the ledger
+func (r *relay) handleBlock(
+	iBlockNumber uint64,
+	iEvents []client.ChaincodeEvent,
+) error {
+	for i := range iEvents {
+		body, err := makeWebhookEvent(&iEvents[i])
+		if err != nil {
+			return err
+		}
+
+		deliveryId := fmt.Sprintf("%s:%d", iEvents[i].TxId, i)
+		for j := range r.config.Webhooks {
+			if isSubscribed(&r.config.Webhooks[j], iEvents[i].EventName) {
+				r.deliver(&r.config.Webhooks[j], iEvents[i].EventName, deliveryId, body)
+			}
+		}
+	}
+
+	return r.saveCheckpoint(iBlockNumber)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sig_chain/pkg/keys"
+)
+
+/// password of encrypted private keys, read from the environment to keep it out of the shell history
+const keyPasswordVariable = "SIGCHAIN_KEY_PASSWORD"
+
+func getKeyPassword() ([]byte, error) {
+	password := os.Getenv(keyPasswordVariable)
+	if password == "" {
+		return nil, fmt.Errorf("%s must be set for encrypted private keys", keyPasswordVariable)
+	}
+
+	return []byte(password), nil
+}
+
+/// writes <out>.key with the PKCS8 private key, encrypted with -encrypt, and <out>.pub with the PKIX public key
+func runKeygen(
+	iArgs []string,
+) error {
+	flags := flag.NewFlagSet("keygen", flag.ExitOnError)
+	keyType := flags.String("type", "ecdsa", "ecdsa (P-256) or rsa (3072 bits)")
+	out := flags.String("out", "owner", "prefix of the key files")
+	encrypt := flags.Bool("encrypt", false, "encrypt the private key with the password in "+keyPasswordVariable)
+	flags.Parse(iArgs)
+
+	/// the chaincode does not verify ed25519 signatures, so such keys cannot own nodes
+	if *keyType != "ecdsa" && *keyType != "rsa" {
+		return fmt.Errorf("unknown key type %s", *keyType)
+	}
+
+	privateKey, err := keys.GenerateKey(*keyType)
+	if err != nil {
+		return err
+	}
+
+	var privateKeyPem string
+	if *encrypt {
+		password, err := getKeyPassword()
+		if err != nil {
+			return err
+		}
+		privateKeyPem, err = keys.EncryptPrivateKey(privateKey, password)
+	} else {
+		privateKeyPem, err = keys.EncodePrivateKey(privateKey)
+	}
+	if err != nil {
+		return err
+	}
+
+	publicKey, err := keys.EncodePublicKey(privateKey.Public())
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(*out+".key", []byte(privateKeyPem), 0600)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(*out+".pub", []byte(publicKey), 0644)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sig_chain/pkg/client"
+	"sig_chain/pkg/keys"
+	"sort"
+)
+
+type command struct {
+	description string
+	run         func(iArgs []string) error
+}
+
+var commands = map[string]command{
+	"keygen":     {"generate an owner key pair", runKeygen},
+	"identities": {"list or create the owner identities of a wallet", runIdentities},
+	"create":     {"create a material owned by a key", runCreate},
+	"transfer":   {"transfer a material to another key", runTransfer},
+	"provenance": {"print the provenance of a material from the network or a snapshot", runProvenance},
+	"verify":     {"verify the signature of a node from the network or a file", runVerify},
+	"export":     {"export every record of the world state to a snapshot", runExport},
+	"import":     {"import a snapshot into another channel", runImport},
+	"report":     {"write the provenance of a material as a signed PDF", runReport},
+	"resolve":    {"verify the proof bundle a QR code points to", runResolve},
+	"vectors":    {"check the signing test vectors against this build", runVectors},
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "usage: sigchain <command> [flags], run sigchain <command> -h for the flags of a command")
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", name, commands[name].description)
+	}
+}
+
+/// connects with the network config at iConfigPath, the caller must close the contract
+func connect(
+	iConfigPath string,
+) (*client.NetworkContract, error) {
+	if iConfigPath == "" {
+		return nil, fmt.Errorf("a network config is required")
+	}
+
+	config, err := client.ReadNetworkConfig(iConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.ConnectNetwork(config)
+}
+
+/// reads the owner key pair written by keygen, iPublicKeyPath can also hold a certificate. The private key may be
+/// encrypted with the password in SIGCHAIN_KEY_PASSWORD
+func readSigner(
+	iPrivateKeyPath string,
+	iPublicKeyPath string,
+) (*client.KeySigner, error) {
+	privateKey, err := ioutil.ReadFile(iPrivateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := ioutil.ReadFile(iPublicKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !keys.IsEncrypted(string(privateKey)) {
+		return client.MakeKeySigner(string(privateKey), string(publicKey))
+	}
+
+	password, err := getKeyPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := keys.DecryptPrivateKey(string(privateKey), password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %v", iPrivateKeyPath, err)
+	}
+
+	return client.MakeKeySignerFromKey(signer, string(publicKey))
+}
+
+func printJson(
+	iValue interface{},
+) error {
+	valueJson, err := json.MarshalIndent(iValue, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(valueJson))
+	return nil
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		printUsage()
+		os.Exit(2)
+	}
+
+	err := command.run(os.Args[2:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sig_chain/chaincode/asset"
+	"sig_chain/pkg/client"
+	"time"
+)
+
+func runCreate(
+	iArgs []string,
+) error {
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	configPath := flags.String("config", "", "network config json")
+	privateKeyPath := flags.String("key", "owner.key", "private key of the owner")
+	publicKeyPath := flags.String("pub", "owner.pub", "public key or certificate of the owner")
+	walletDirectory := flags.String("wallet", "wallet", "wallet directory")
+	identity := flags.String("identity", "", "identity of the owner in the wallet, used instead of -key and -pub")
+	nodeId := flags.String("id", "", "id of the new material")
+	name := flags.String("name", "", "name of the material")
+	unit := flags.String("unit", "", "unit of the quantity")
+	quantity := flags.String("quantity", "", "quantity of the material")
+	lotNumber := flags.String("lot", "", "lot number")
+	batchNumber := flags.String("batch", "", "batch number")
+	expiry := flags.String("expiry", "", "RFC 3339 expiry date, the material does not expire if empty")
+	templateId := flags.String("template", "", "product template id")
+	attributes := flags.String("attributes", "{}", "json object of string attributes")
+	gtin := flags.String("gtin", "", "GS1 trade item number")
+	sscc := flags.String("sscc", "", "GS1 serial shipping container code")
+	grade := flags.String("grade", "", "grade of the material")
+	flags.Parse(iArgs)
+
+	if *nodeId == "" {
+		return fmt.Errorf("id is required")
+	}
+
+	spec := asset.MaterialSpec{
+		NodeId:      *nodeId,
+		Name:        *name,
+		Unit:        *unit,
+		Quantity:    *quantity,
+		LotNumber:   *lotNumber,
+		BatchNumber: *batchNumber,
+		TemplateId:  *templateId,
+		Gtin:        *gtin,
+		Sscc:        *sscc,
+		Grade:       *grade,
+		CreatedTime: time.Now(),
+	}
+
+	err := json.Unmarshal([]byte(*attributes), &spec.Attributes)
+	if err != nil {
+		return fmt.Errorf("attributes must be a json object of strings: %v", err)
+	}
+
+	if *expiry != "" {
+		spec.ExpiryDate, err = time.Parse(time.RFC3339, *expiry)
+		if err != nil {
+			return err
+		}
+	}
+
+	signer, err := getOwner(*walletDirectory, *identity, *privateKeyPath, *publicKeyPath)
+	if err != nil {
+		return err
+	}
+
+	contract, err := connect(*configPath)
+	if err != nil {
+		return err
+	}
+	defer contract.Close()
+
+	receipt, err := client.MakeClient(contract, signer).CreateMaterial(spec)
+	if err != nil {
+		return err
+	}
+
+	return printJson(receipt)
+}
+
+func runTransfer(
+	iArgs []string,
+) error {
+	flags := flag.NewFlagSet("transfer", flag.ExitOnError)
+	configPath := flags.String("config", "", "network config json")
+	privateKeyPath := flags.String("key", "owner.key", "private key of the current owner")
+	publicKeyPath := flags.String("pub", "owner.pub", "public key or certificate of the current owner")
+	newPrivateKeyPath := flags.String("new-key", "", "private key of the new owner")
+	newPublicKeyPath := flags.String("new-pub", "", "public key or certificate of the new owner")
+	walletDirectory := flags.String("wallet", "wallet", "wallet directory")
+	identity := flags.String("identity", "", "identity of the current owner in the wallet, used instead of -key and -pub")
+	newIdentity := flags.String("new-identity", "", "identity of the new owner in the wallet, used instead of -new-key and -new-pub")
+	newMspId := flags.String("new-msp", "", "msp id of the new owner, required if the new owner has no certificate")
+	nodeId := flags.String("id", "", "id of the transferred material")
+	newNodeId := flags.String("new-id", "", "id of the material once transferred")
+	flags.Parse(iArgs)
+
+	if *nodeId == "" || *newNodeId == "" {
+		return fmt.Errorf("id and new-id are required")
+	}
+
+	signer, err := getOwner(*walletDirectory, *identity, *privateKeyPath, *publicKeyPath)
+	if err != nil {
+		return err
+	}
+
+	newOwner, err := getOwner(*walletDirectory, *newIdentity, *newPrivateKeyPath, *newPublicKeyPath)
+	if err != nil {
+		return fmt.Errorf("new owner: %v", err)
+	}
+
+	contract, err := connect(*configPath)
+	if err != nil {
+		return err
+	}
+	defer contract.Close()
+
+	receipt, err := client.MakeClient(contract, signer).TransferMaterial(*nodeId, *newNodeId, newOwner, *newMspId, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return printJson(receipt)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"sig_chain/chaincode/asset"
+	"sig_chain/chaincode/graph"
+	"sig_chain/pkg/client"
+	"sig_chain/pkg/proof"
+	"sig_chain/pkg/report"
+	"sig_chain/pkg/signing"
+	"time"
+)
+
+/// GetNodeJson belongs to the graph contract, which is not the default contract of the chaincode
+const getNodeJsonTransaction = "GraphContract:GetNodeJson"
+
+/// reads the provenance of iNodeId from the snapshot at iSnapshotPath if set, from the network otherwise
+func getProvenance(
+	iConfigPath string,
+	iSnapshotPath string,
+	iNodeId string,
+) (*asset.ProvenanceNode, error) {
+	if iNodeId == "" {
+		return nil, fmt.Errorf("id is required")
+	}
+
+	if iSnapshotPath != "" {
+		snapshot, err := client.ReadSnapshot(iSnapshotPath)
+		if err != nil {
+			return nil, err
+		}
+
+		return snapshot.GetProvenance(iNodeId)
+	}
+
+	contract, err := connect(iConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	defer contract.Close()
+
+	return client.MakeClient(contract, nil).GetProvenance(iNodeId)
+}
+
+func runProvenance(
+	iArgs []string,
+) error {
+	flags := flag.NewFlagSet("provenance", flag.ExitOnError)
+	configPath := flags.String("config", "", "network config json")
+	snapshotPath := flags.String("snapshot", "", "exported snapshot, used instead of the network")
+	nodeId := flags.String("id", "", "id of the material")
+	flags.Parse(iArgs)
+
+	provenance, err := getProvenance(*configPath, *snapshotPath, *nodeId)
+	if err != nil {
+		return err
+	}
+
+	return printJson(provenance)
+}
+
+/// the report is signed if a key is given, its public key must then be a certificate
+func runReport(
+	iArgs []string,
+) error {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	configPath := flags.String("config", "", "network config json")
+	snapshotPath := flags.String("snapshot", "", "exported snapshot, used instead of the network")
+	nodeId := flags.String("id", "", "id of the material")
+	outputPath := flags.String("out", "provenance.pdf", "PDF file to write")
+	privateKeyPath := flags.String("key", "", "private key signing the report, the report is not signed if empty")
+	certificatePath := flags.String("cert", "", "certificate of the signing key")
+	flags.Parse(iArgs)
+
+	provenance, err := getProvenance(*configPath, *snapshotPath, *nodeId)
+	if err != nil {
+		return err
+	}
+
+	var signer client.Signer
+	if *privateKeyPath != "" {
+		signer, err = readSigner(*privateKeyPath, *certificatePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	pdf, err := report.RenderProvenance(provenance, signer, time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(*outputPath, pdf, 0644)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("provenance of %s written to %s\n", *nodeId, *outputPath)
+	return nil
+}
+
+/// verifies the signature stored in the node, or the raw signature of a file for nodes stored before signatures
+/// were base64 encoded
+func runVerify(
+	iArgs []string,
+) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	configPath := flags.String("config", "", "network config json, to read the node from the network")
+	nodePath := flags.String("node", "", "node json file, used instead of the network")
+	nodeId := flags.String("id", "", "id of the node to read from the network")
+	signaturePath := flags.String("signature", "", "file holding the raw signature of the node, used instead of the stored one")
+	flags.Parse(iArgs)
+
+	var nodeJson []byte
+	var err error
+	if *nodePath != "" {
+		nodeJson, err = ioutil.ReadFile(*nodePath)
+		if err != nil {
+			return err
+		}
+	} else {
+		if *nodeId == "" {
+			return fmt.Errorf("either node or id is required")
+		}
+
+		contract, err := connect(*configPath)
+		if err != nil {
+			return err
+		}
+		defer contract.Close()
+
+		nodeJson, err = contract.EvaluateTransaction(getNodeJsonTransaction, *nodeId)
+		if err != nil {
+			return err
+		}
+	}
+
+	node, err := client.UnmarshalNode(nodeJson)
+	if err != nil {
+		return err
+	}
+
+	payload, err := signing.GetNodeBytes(node)
+	if err != nil {
+		return err
+	}
+
+	signature := node.GetHeader().Signature
+	if *signaturePath != "" {
+		rawSignature, err := ioutil.ReadFile(*signaturePath)
+		if err != nil {
+			return err
+		}
+		signature = graph.EncodeSignature(rawSignature)
+	}
+
+	err = graph.VerifySignature(node.GetHeader().OwnerPublicKey, payload, signature)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("signature of %s is valid\n", node.GetHeader().Id)
+	return nil
+}
+
+/// verifies the proof bundle a QR code points to, as a consumer application would
+func runResolve(
+	iArgs []string,
+) error {
+	flags := flag.NewFlagSet("resolve", flag.ExitOnError)
+	payload := flags.String("payload", "", "text read from the QR code")
+	issuerPath := flags.String("issuer", "", "public key or certificate of the trusted issuer of the proof bundles")
+	flags.Parse(iArgs)
+
+	issuer, err := ioutil.ReadFile(*issuerPath)
+	if err != nil {
+		return err
+	}
+
+	_, verification, err := proof.MakeResolver([]string{string(issuer)}).Resolve(*payload)
+	if err != nil {
+		return err
+	}
+
+	err = printJson(verification)
+	if err != nil {
+		return err
+	}
+
+	if !verification.IsVerified {
+		return fmt.Errorf("provenance of %s is not verified", verification.NodeId)
+	}
+	return nil
+}
+
+func runVectors(
+	iArgs []string,
+) error {
+	flags := flag.NewFlagSet("vectors", flag.ExitOnError)
+	flags.Parse(iArgs)
+
+	err := signing.CheckVectors()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("signing test vectors are valid")
+	return nil
+}
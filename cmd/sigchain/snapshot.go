@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sig_chain/pkg/client"
+)
+
+/// the identity of the network config must be a channel admin
+func runExport(
+	iArgs []string,
+) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	configPath := flags.String("config", "", "network config json")
+	outputPath := flags.String("out", "snapshot.json", "snapshot file to write")
+	pageSize := flags.Int("page", client.DefaultSnapshotPageSize, "records read per transaction")
+	flags.Parse(iArgs)
+
+	contract, err := connect(*configPath)
+	if err != nil {
+		return err
+	}
+	defer contract.Close()
+
+	snapshot, err := client.MakeClient(contract, nil).ExportSnapshot(*pageSize)
+	if err != nil {
+		return err
+	}
+
+	err = snapshot.Write(*outputPath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d records, %d of them nodes, written to %s\nhash %s\n", len(snapshot.Entries), len(snapshot.Nodes), *outputPath, snapshot.Hash)
+	return nil
+}
+
+/// the key is the one of the administrator of the target channel, which must be bootstrapped with the
+/// administrator of the exported channel
+func runImport(
+	iArgs []string,
+) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	configPath := flags.String("config", "", "network config json of the target channel")
+	snapshotPath := flags.String("snapshot", "snapshot.json", "snapshot written by export")
+	privateKeyPath := flags.String("key", "admin.key", "private key of the administrator")
+	publicKeyPath := flags.String("pub", "admin.pub", "public key of the administrator")
+	pageSize := flags.Int("page", client.DefaultSnapshotPageSize, "records written per transaction")
+	flags.Parse(iArgs)
+
+	snapshot, err := client.ReadSnapshot(*snapshotPath)
+	if err != nil {
+		return err
+	}
+
+	signer, err := readSigner(*privateKeyPath, *publicKeyPath)
+	if err != nil {
+		return err
+	}
+
+	contract, err := connect(*configPath)
+	if err != nil {
+		return err
+	}
+	defer contract.Close()
+
+	err = client.MakeClient(contract, signer).ImportSnapshot(snapshot, *pageSize, func(iImportedCount int) {
+		fmt.Printf("%d of %d records imported\n", iImportedCount, len(snapshot.Entries))
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("snapshot %s imported\n", snapshot.Hash)
+	return nil
+}
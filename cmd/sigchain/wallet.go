@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"sig_chain/pkg/client"
+)
+
+/// keys of the wallet are encrypted with the password in SIGCHAIN_KEY_PASSWORD when it is set
+func openWallet(
+	iDirectory string,
+) client.Wallet {
+	return client.MakeFileWallet(iDirectory, []byte(os.Getenv(keyPasswordVariable)))
+}
+
+/// the owner is iIdentity of the wallet in iWalletDirectory if set, otherwise the key pair read from the key files
+func getOwner(
+	iWalletDirectory string,
+	iIdentity string,
+	iPrivateKeyPath string,
+	iPublicKeyPath string,
+) (client.Signer, error) {
+	if iIdentity != "" {
+		return openWallet(iWalletDirectory).GetSigner(iIdentity)
+	}
+
+	signer, err := readSigner(iPrivateKeyPath, iPublicKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return signer, nil
+}
+
+/// lists the identities of a wallet, or adds one with -create
+func runIdentities(
+	iArgs []string,
+) error {
+	flags := flag.NewFlagSet("identities", flag.ExitOnError)
+	walletDirectory := flags.String("wallet", "wallet", "wallet directory")
+	name := flags.String("create", "", "name of a new identity to generate, e.g. the business unit it signs for")
+	flags.Parse(iArgs)
+
+	wallet := openWallet(*walletDirectory)
+	if *name != "" {
+		identity, err := wallet.CreateIdentity(*name)
+		if err != nil {
+			return err
+		}
+
+		return printJson(identity)
+	}
+
+	identities, err := wallet.ListIdentities()
+	if err != nil {
+		return err
+	}
+
+	return printJson(identities)
+}
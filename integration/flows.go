@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"sig_chain/chaincode/asset"
+	"sig_chain/chaincode/graph"
+	"sig_chain/pkg/client"
+	"time"
+)
+
+/// submitted transactions are not waited for, reads are retried until the transaction is committed
+const pollInterval = 2 * time.Second
+
+/// zero on the mock ledger, which commits on submission
+var commitTimeout = time.Minute
+
+type flow struct {
+	name string
+	run  func(iContract client.Contract) error
+}
+
+var flows = []flow{
+	{"create, split, transfer and recall", runLifecycleFlow},
+}
+
+/// retries iCheck until it succeeds or the commit timeout is reached, errors of the network are not retried
+func waitFor(
+	iCheck func() error,
+) error {
+	deadline := time.Now().Add(commitTimeout)
+	for {
+		err := iCheck()
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+func checkQuantity(
+	iClient *client.Client,
+	iNodeId string,
+	iQuantity string,
+) error {
+	return waitFor(func() error {
+		material, err := iClient.GetMaterial(iNodeId)
+		if err != nil {
+			return err
+		}
+
+		if material.Quantity != iQuantity {
+			return fmt.Errorf("quantity of %s is %s instead of %s", iNodeId, material.Quantity, iQuantity)
+		}
+		return nil
+	})
+}
+
+/// returns whether iNodeId is among the ancestors of iProvenance
+func hasAncestor(
+	iProvenance *asset.ProvenanceNode,
+	iNodeId string,
+) bool {
+	for i := range iProvenance.Inputs {
+		if iProvenance.Inputs[i].Material.Id == iNodeId || hasAncestor(&iProvenance.Inputs[i], iNodeId) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/// the signatures stored in the materials of iProvenance must still verify once read back from the ledger
+func checkNodeSignatures(
+	iProvenance *asset.ProvenanceNode,
+) error {
+	material := iProvenance.Material
+	err := graph.VerifyNodeSignature(&material)
+	if err != nil {
+		return fmt.Errorf("signature of %s: %v", material.Id, err)
+	}
+
+	for i := range iProvenance.Inputs {
+		err = checkNodeSignatures(&iProvenance.Inputs[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func runLifecycleFlow(
+	iContract client.Contract,
+) error {
+	prefix := fmt.Sprintf("it-%d-", time.Now().UnixNano())
+	rawId, keptId, soldId, deliveredId := prefix+"raw", prefix+"kept", prefix+"sold", prefix+"delivered"
+
+	producer, err := makeOwner()
+	if err != nil {
+		return err
+	}
+
+	retailer, err := makeOwner()
+	if err != nil {
+		return err
+	}
+
+	producerClient := client.MakeClient(iContract, producer)
+	_, err = producerClient.CreateMaterial(asset.MaterialSpec{
+		NodeId:      rawId,
+		Name:        "Arabica beans",
+		Unit:        "kg",
+		Quantity:    "100",
+		LotNumber:   prefix + "lot",
+		CreatedTime: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("create: %v", err)
+	}
+
+	err = checkQuantity(producerClient, rawId, "100")
+	if err != nil {
+		return fmt.Errorf("create: %v", err)
+	}
+
+	_, err = producerClient.SplitMaterial(
+		rawId,
+		[]client.Split{
+			{NodeId: keptId, Quantity: "60", Owner: producer},
+			{NodeId: soldId, Quantity: "39", Owner: producer},
+		},
+		"1",
+		time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("split: %v", err)
+	}
+
+	err = checkQuantity(producerClient, soldId, "39")
+	if err != nil {
+		return fmt.Errorf("split: %v", err)
+	}
+
+	_, err = producerClient.TransferMaterial(soldId, deliveredId, retailer, "Org1MSP", time.Now())
+	if err != nil {
+		return fmt.Errorf("transfer: %v", err)
+	}
+
+	retailerClient := client.MakeClient(iContract, retailer)
+	err = waitFor(func() error {
+		material, err := retailerClient.GetMaterial(deliveredId)
+		if err != nil {
+			return err
+		}
+
+		if material.OwnerPublicKey != retailer.GetPublicKey() {
+			return fmt.Errorf("%s is not owned by the retailer", deliveredId)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("transfer: %v", err)
+	}
+
+	provenance, err := retailerClient.GetProvenance(deliveredId)
+	if err != nil {
+		return fmt.Errorf("provenance: %v", err)
+	}
+
+	if !hasAncestor(provenance, rawId) {
+		return fmt.Errorf("provenance: %s is not an ancestor of %s", rawId, deliveredId)
+	}
+
+	err = checkNodeSignatures(provenance)
+	if err != nil {
+		return fmt.Errorf("provenance: %v", err)
+	}
+
+	/// signed by the producer, which owns the recalled material, and submitted by the regulator identity of the
+	/// contract
+	_, err = producerClient.RecallMaterial(rawId, "integration test recall")
+	if err != nil {
+		return fmt.Errorf("recall: %v", err)
+	}
+
+	/// the recall spreads to every material made from the recalled one, whoever owns it
+	for _, nodeId := range []string{keptId, deliveredId} {
+		err = waitFor(func() error {
+			isRecalled, err := retailerClient.IsMaterialRecalled(nodeId)
+			if err != nil {
+				return err
+			}
+
+			if !isRecalled {
+				return fmt.Errorf("%s is not recalled", nodeId)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("recall: %v", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sig_chain/chaincode/asset"
+	"sig_chain/chaincode/graph"
+	"sig_chain/pkg/client"
+	"sig_chain/pkg/keys"
+	"sig_chain/pkg/testutil"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+/// extension in which Fabric CA puts the attributes of the enrolled identity
+var caAttributesOid = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+/// End to end flows of the chaincode, see run.sh to run them on the test network of fabric-samples. With -mock
+/// they run on an in memory ledger instead, which checks the flows but not the network
+func main() {
+	configPath := flag.String("config", "", "network config json, see client.NetworkConfig")
+	isMock := flag.Bool("mock", false, "run on a mock ledger rather than on the network")
+	flag.Parse()
+
+	var contract client.Contract
+	var err error
+	if *isMock {
+		commitTimeout = 0
+		contract, err = makeMockContract()
+	} else {
+		contract, err = connect(*configPath)
+	}
+	if err != nil {
+		log.Panicf("Error setting up the contract: %v", err)
+	}
+
+	failedCount := 0
+	for _, flow := range flows {
+		err = flow.run(contract)
+		if err != nil {
+			fmt.Printf("FAIL %s: %v\n", flow.name, err)
+			failedCount++
+			continue
+		}
+		fmt.Printf("ok   %s\n", flow.name)
+	}
+
+	if failedCount > 0 {
+		fmt.Printf("%d of %d flows failed\n", failedCount, len(flows))
+		os.Exit(1)
+	}
+}
+
+func connect(
+	iConfigPath string,
+) (client.Contract, error) {
+	if iConfigPath == "" {
+		return nil, fmt.Errorf("a network config is required")
+	}
+
+	config, err := client.ReadNetworkConfig(iConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.ConnectNetwork(config)
+}
+
+/// contractapi identifies the submitter by its certificate, a self signed one is enough for the mock. Like the
+/// identity run.sh enrolls, it is a regulator so that it can recall
+func makeMockContract() (client.Contract, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "integration"},
+		ExtraExtensions: []pkix.Extension{
+			{Id: caAttributesOid, Value: []byte(`{"attrs":{"role":"regulator"}}`)},
+		},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  time.Now().Add(time.Hour),
+	}
+	certificateDer, err := x509.CreateCertificate(rand.Reader, &template, &template, privateKey.Public(), privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	certificate, err := x509.ParseCertificate(certificateDer)
+	if err != nil {
+		return nil, err
+	}
+
+	chaincode, err := contractapi.NewChaincode(
+		&asset.MaterialContract{},
+		&asset.ProductContract{},
+		&asset.CertificateContract{},
+		&graph.GraphContract{},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return testutil.MakeMockContract(
+		testutil.MakeMockLedger(),
+		chaincode,
+		&testutil.MockIdentity{
+			Id:          "integration",
+			MspId:       "Org1MSP",
+			Certificate: certificate,
+		},
+	), nil
+}
+
+/// owners are generated for every run so that runs do not depend on each other
+func makeOwner() (*client.KeySigner, error) {
+	privateKey, err := keys.GenerateKey("ecdsa")
+	if err != nil {
+		return nil, err
+	}
+
+	privateKeyPem, err := keys.EncodePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := keys.EncodePublicKey(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	return client.MakeKeySigner(privateKeyPem, publicKey)
+}
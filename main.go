@@ -7,18 +7,31 @@ package main
 import (
 	"log"
 	"sig_chain/chaincode/asset"
+	"sig_chain/chaincode/graph"
+	"sig_chain/chaincode/token"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-contract-api-go/metadata"
 )
 
 func main() {
 	assetChaincode, err := contractapi.NewChaincode(
 		&asset.MaterialContract{},
+		&asset.ProductContract{},
+		&asset.CertificateContract{},
+		&graph.GraphContract{},
+		&token.TokenContract{},
 	)
 	if err != nil {
 		log.Panicf("Error creating asset-transfer-basic chaincode: %v", err)
 	}
 
+	assetChaincode.Info = metadata.InfoMetadata{
+		Title:       "sig_chain",
+		Description: "Signed supply chain graph of materials, their certificates and the tokens claiming them, signatures are base64 encoded",
+		Version:     "1.0",
+	}
+
 	if err := assetChaincode.Start(); err != nil {
 		log.Panicf("Error starting asset-transfer-basic chaincode: %v", err)
 	}
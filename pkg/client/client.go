@@ -0,0 +1,388 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/asset"
+	"sig_chain/chaincode/graph"
+	"sig_chain/pkg/signing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+/// Satisfied by the Contract of the Fabric Gateway client (github.com/hyperledger/fabric-gateway/pkg/client),
+/// which must be the MaterialContract of the chaincode
+type Contract interface {
+	SubmitTransaction(iName string, iArgs ...string) ([]byte, error)
+	EvaluateTransaction(iName string, iArgs ...string) ([]byte, error)
+}
+
+/// Builds the nodes the chaincode verifies and signs them locally, the private keys never leave the client.
+/// Node times are signed as passed so the chaincode must not be configured to use the transaction time,
+/// nodes are then signed without their time, see graph.GetSignedHeader
+type Client struct {
+	contract Contract
+	signer   Signer
+}
+
+/// One of the materials a material is split into, it is signed by its owner
+type Split struct {
+	NodeId   string
+	Quantity string
+	Owner    Signer
+}
+
+func MakeClient(
+	iContract Contract,
+	iSigner Signer,
+) *Client {
+	return &Client{
+		contract: iContract,
+		signer:   iSigner,
+	}
+}
+
+func (c *Client) submit(
+	iName string,
+	iArgs ...string,
+) (*graph.TransactionReceipt, error) {
+	receiptJson, err := c.contract.SubmitTransaction(iName, iArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	var receipt graph.TransactionReceipt
+	err = json.Unmarshal(receiptJson, &receipt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid receipt: %v", err)
+	}
+
+	return &receipt, nil
+}
+
+func (c *Client) evaluate(
+	iName string,
+	oResult interface{},
+	iArgs ...string,
+) error {
+	resultJson, err := c.contract.EvaluateTransaction(iName, iArgs...)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(resultJson, oResult)
+}
+
+/// functions returning a string return it as is rather than as json
+func (c *Client) evaluateString(
+	iName string,
+	iArgs ...string,
+) (string, error) {
+	result, err := c.contract.EvaluateTransaction(iName, iArgs...)
+	if err != nil {
+		return "", err
+	}
+
+	return string(result), nil
+}
+
+func (c *Client) GetMaterial(
+	iNodeId string,
+) (*asset.Material, error) {
+	var material asset.Material
+	err := c.evaluate("GetMaterial", &material, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &material, nil
+}
+
+func (c *Client) GetProvenance(
+	iNodeId string,
+) (*asset.ProvenanceNode, error) {
+	var provenance asset.ProvenanceNode
+	err := c.evaluate("GetFullProvenance", &provenance, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &provenance, nil
+}
+
+func (c *Client) GetCertificate(
+	iNodeId string,
+) (*asset.Certificate, error) {
+	var certificate asset.Certificate
+	err := c.evaluate("CertificateContract:GetCertificate", &certificate, iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &certificate, nil
+}
+
+/// the material is owned by the signer of the client, the owner and signature of iSpec are ignored
+func (c *Client) CreateMaterial(
+	iSpec asset.MaterialSpec,
+) (*graph.TransactionReceipt, error) {
+	quantity, err := decimal.NewFromString(iSpec.Quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := iSpec.Attributes
+	if attributes == nil {
+		attributes = map[string]string{}
+	}
+
+	attributesJson, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	material := asset.MakeMaterial(
+		iSpec.Name,
+		iSpec.Unit,
+		quantity.String(),
+		iSpec.LotNumber,
+		iSpec.BatchNumber,
+		signing.NormalizeTime(iSpec.ExpiryDate),
+		map[string]string{iSpec.Name: "100"},
+		iSpec.TemplateId,
+		attributes,
+		iSpec.Gtin,
+		iSpec.Sscc,
+		iSpec.Grade,
+		"",
+		graph.MakeNodeHeader(
+			iSpec.NodeId,
+			"eMaterial",
+			false,
+			graph.MakeHashSet(),
+			graph.MakeHashSet(),
+			c.signer.GetPublicKey(),
+			signing.NormalizeTime(iSpec.CreatedTime),
+			"",
+		),
+	)
+	signature, err := SignNode(c.signer, &material)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.submit(
+		"CreateMaterial",
+		iSpec.NodeId,
+		iSpec.Name,
+		iSpec.Unit,
+		iSpec.Quantity,
+		iSpec.LotNumber,
+		iSpec.BatchNumber,
+		signing.FormatTime(iSpec.ExpiryDate),
+		iSpec.TemplateId,
+		string(attributesJson),
+		iSpec.Gtin,
+		iSpec.Sscc,
+		iSpec.Grade,
+		c.signer.GetPublicKey(),
+		signing.FormatTime(iSpec.CreatedTime),
+		signature,
+	)
+}
+
+/// the current node is signed by the signer of the client and the new node by iNewOwner.
+/// iNewOwnerMspId is the organization of the new owner, it can be empty if the new owner has a certificate
+func (c *Client) TransferMaterial(
+	iNodeId string,
+	iNewNodeId string,
+	iNewOwner Signer,
+	iNewOwnerMspId string,
+	iTransferTime time.Time,
+) (*graph.TransactionReceipt, error) {
+	material, err := c.GetMaterial(iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	/// the new node carries the quantity and grade of the material once adjusted
+	newMaterial := *material
+	newMaterial.Quantity, err = c.evaluateString("GetMaterialQuantity", iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	newMaterial.Grade, err = c.evaluateString("GetMaterialGrade", iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	header := material.GetHeader()
+	header.IsFinalized = true
+	header.NextNodeHashedIds = header.NextNodeHashedIds.Add(graph.HashId(iNewNodeId))
+	header.NewOwnerPublicKey = iNewOwner.GetPublicKey()
+	material.SetHeader(header)
+
+	signature, err := SignNode(c.signer, material)
+	if err != nil {
+		return nil, err
+	}
+
+	newMaterial.SetHeader(graph.MakeNodeHeader(
+		iNewNodeId,
+		header.Type,
+		false,
+		graph.MakeHashSet(graph.HashId(iNodeId)),
+		graph.MakeHashSet(),
+		iNewOwner.GetPublicKey(),
+		signing.NormalizeTime(iTransferTime),
+		"",
+	))
+
+	newNodeSignature, err := SignNode(iNewOwner, &newMaterial)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.submit(
+		"TransferMaterial",
+		iNodeId,
+		iNewNodeId,
+		iNewOwner.GetPublicKey(),
+		iNewOwnerMspId,
+		signature,
+		newNodeSignature,
+		signing.FormatTime(iTransferTime),
+	)
+}
+
+/// the split node is signed by the signer of the client and every new node by its owner
+func (c *Client) SplitMaterial(
+	iNodeId string,
+	iSplits []Split,
+	iWaste string,
+	iCreatedTime time.Time,
+) (*graph.TransactionReceipt, error) {
+	material, err := c.GetMaterial(iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	grade, err := c.evaluateString("GetMaterialGrade", iNodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	composition := material.Composition
+	if len(composition) == 0 {
+		composition = map[string]string{material.Name: "100"}
+	}
+
+	header := material.GetHeader()
+	quantities := []string{}
+	nodeIds := []string{}
+	ownerPublicKeys := []string{}
+	signatures := []string{}
+	for _, split := range iSplits {
+		quantity, err := decimal.NewFromString(split.Quantity)
+		if err != nil {
+			return nil, err
+		}
+
+		newMaterial := asset.MakeMaterial(
+			material.Name,
+			material.Unit,
+			quantity.String(),
+			material.LotNumber,
+			material.BatchNumber,
+			material.ExpiryDate,
+			composition,
+			material.TemplateId,
+			material.Attributes,
+			material.Gtin,
+			"",
+			grade,
+			"",
+			graph.MakeNodeHeader(
+				split.NodeId,
+				header.Type,
+				false,
+				graph.MakeHashSet(graph.HashId(iNodeId)),
+				graph.MakeHashSet(),
+				split.Owner.GetPublicKey(),
+				signing.NormalizeTime(iCreatedTime),
+				"",
+			),
+		)
+		signature, err := SignNode(split.Owner, &newMaterial)
+		if err != nil {
+			return nil, err
+		}
+
+		quantities = append(quantities, split.Quantity)
+		nodeIds = append(nodeIds, split.NodeId)
+		ownerPublicKeys = append(ownerPublicKeys, split.Owner.GetPublicKey())
+		signatures = append(signatures, signature)
+		header.NextNodeHashedIds = header.NextNodeHashedIds.Add(graph.HashId(split.NodeId))
+	}
+
+	header.IsFinalized = true
+	material.SetHeader(header)
+	signature, err := SignNode(c.signer, material)
+	if err != nil {
+		return nil, err
+	}
+
+	arrays := [][]string{quantities, nodeIds, ownerPublicKeys, signatures}
+	arrayJsons := []string{}
+	for _, array := range arrays {
+		arrayJson, err := json.Marshal(array)
+		if err != nil {
+			return nil, err
+		}
+		arrayJsons = append(arrayJsons, string(arrayJson))
+	}
+
+	return c.submit(
+		"SplitMaterial",
+		iNodeId,
+		arrayJsons[0],
+		iWaste,
+		arrayJsons[1],
+		arrayJsons[2],
+		signing.FormatTime(iCreatedTime),
+		signature,
+		arrayJsons[3],
+	)
+}
+
+/// the recall request is signed by the signer of the client, which must be the recall authority or the owner of
+/// the node, and submitted by the Fabric identity of the contract, which must have the recall role. The recall
+/// spreads to every material made from it
+func (c *Client) RecallMaterial(
+	iNodeId string,
+	iReason string,
+) (*graph.TransactionReceipt, error) {
+	signature, err := SignPayload(c.signer, &asset.RecallRequest{
+		NodeId: iNodeId,
+		Reason: iReason,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c.submit("RecallMaterial", iNodeId, iReason, signature)
+}
+
+func (c *Client) IsMaterialRecalled(
+	iNodeId string,
+) (bool, error) {
+	var isRecalled bool
+	err := c.evaluate("IsMaterialRecalled", &isRecalled, iNodeId)
+	if err != nil {
+		return false, err
+	}
+
+	return isRecalled, nil
+}
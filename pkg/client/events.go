@@ -0,0 +1,272 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+/// Event set by the chaincode in a valid transaction
+type ChaincodeEvent struct {
+	BlockNumber uint64 `json:"BlockNumber"`
+	TxId        string `json:"TxId"`
+	EventName   string `json:"EventName"`
+	Payload     []byte `json:"Payload"`
+}
+
+/// the seek messages of the deliver service, orderer protos are not vendored. Positions are a oneof, which has
+/// the same encoding as optional fields of which only one is set
+type seekNewest struct{}
+
+func (m *seekNewest) Reset()         { *m = seekNewest{} }
+func (m *seekNewest) String() string { return proto.CompactTextString(m) }
+func (*seekNewest) ProtoMessage()    {}
+
+type seekSpecified struct {
+	Number uint64 `protobuf:"varint,1,opt,name=number,proto3"`
+}
+
+func (m *seekSpecified) Reset()         { *m = seekSpecified{} }
+func (m *seekSpecified) String() string { return proto.CompactTextString(m) }
+func (*seekSpecified) ProtoMessage()    {}
+
+type seekPosition struct {
+	Newest    *seekNewest    `protobuf:"bytes,1,opt,name=newest,proto3"`
+	Specified *seekSpecified `protobuf:"bytes,3,opt,name=specified,proto3"`
+}
+
+func (m *seekPosition) Reset()         { *m = seekPosition{} }
+func (m *seekPosition) String() string { return proto.CompactTextString(m) }
+func (*seekPosition) ProtoMessage()    {}
+
+/// Behavior is left to BLOCK_UNTIL_READY
+type seekInfo struct {
+	Start *seekPosition `protobuf:"bytes,1,opt,name=start,proto3"`
+	Stop  *seekPosition `protobuf:"bytes,2,opt,name=stop,proto3"`
+}
+
+func (m *seekInfo) Reset()         { *m = seekInfo{} }
+func (m *seekInfo) String() string { return proto.CompactTextString(m) }
+func (*seekInfo) ProtoMessage()    {}
+
+/// iStartBlock nil starts at the newest block
+func (c *NetworkContract) makeSeekEnvelope(
+	iStartBlock *uint64,
+) (*common.Envelope, error) {
+	start := &seekPosition{Newest: &seekNewest{}}
+	if iStartBlock != nil {
+		start = &seekPosition{Specified: &seekSpecified{Number: *iStartBlock}}
+	}
+
+	seek, err := proto.Marshal(&seekInfo{
+		Start: start,
+		Stop:  &seekPosition{Specified: &seekSpecified{Number: math.MaxUint64}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	channelHeader, err := proto.Marshal(&common.ChannelHeader{
+		Type:      int32(common.HeaderType_DELIVER_SEEK_INFO),
+		ChannelId: c.config.ChannelId,
+		Timestamp: ptypes.TimestampNow(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, 24)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	signatureHeader, err := proto.Marshal(&common.SignatureHeader{
+		Creator: c.creator,
+		Nonce:   nonce,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := proto.Marshal(&common.Payload{
+		Header: &common.Header{
+			ChannelHeader:   channelHeader,
+			SignatureHeader: signatureHeader,
+		},
+		Data: seek,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := c.sign(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &common.Envelope{Payload: payload, Signature: signature}, nil
+}
+
+/// returns the events of this chaincode set by the transaction of iEnvelope
+func (c *NetworkContract) getTransactionEvents(
+	iBlockNumber uint64,
+	iEnvelope []byte,
+) ([]ChaincodeEvent, error) {
+	var envelope common.Envelope
+	err := proto.Unmarshal(iEnvelope, &envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload common.Payload
+	err = proto.Unmarshal(envelope.Payload, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.Header == nil {
+		return nil, fmt.Errorf("transaction has no header")
+	}
+
+	var channelHeader common.ChannelHeader
+	err = proto.Unmarshal(payload.Header.ChannelHeader, &channelHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	if channelHeader.Type != int32(common.HeaderType_ENDORSER_TRANSACTION) {
+		return nil, nil
+	}
+
+	var transaction peer.Transaction
+	err = proto.Unmarshal(payload.Data, &transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []ChaincodeEvent{}
+	for _, transactionAction := range transaction.Actions {
+		var actionPayload peer.ChaincodeActionPayload
+		err = proto.Unmarshal(transactionAction.Payload, &actionPayload)
+		if err != nil {
+			return nil, err
+		}
+
+		if actionPayload.Action == nil {
+			continue
+		}
+
+		var responsePayload peer.ProposalResponsePayload
+		err = proto.Unmarshal(actionPayload.Action.ProposalResponsePayload, &responsePayload)
+		if err != nil {
+			return nil, err
+		}
+
+		var action peer.ChaincodeAction
+		err = proto.Unmarshal(responsePayload.Extension, &action)
+		if err != nil {
+			return nil, err
+		}
+
+		var event peer.ChaincodeEvent
+		err = proto.Unmarshal(action.Events, &event)
+		if err != nil {
+			return nil, err
+		}
+
+		if event.ChaincodeId != c.config.ChaincodeName || event.EventName == "" {
+			continue
+		}
+
+		events = append(events, ChaincodeEvent{
+			BlockNumber: iBlockNumber,
+			TxId:        channelHeader.TxId,
+			EventName:   event.EventName,
+			Payload:     event.Payload,
+		})
+	}
+
+	return events, nil
+}
+
+/// returns the events of this chaincode set by the valid transactions of iBlock
+func (c *NetworkContract) getBlockEvents(
+	iBlock *common.Block,
+) ([]ChaincodeEvent, error) {
+	if iBlock.Header == nil || iBlock.Data == nil || iBlock.Metadata == nil {
+		return nil, fmt.Errorf("block is incomplete")
+	}
+
+	validationCodes := []byte{}
+	if len(iBlock.Metadata.Metadata) > int(common.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		validationCodes = iBlock.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER]
+	}
+
+	events := []ChaincodeEvent{}
+	for i, envelope := range iBlock.Data.Data {
+		if i >= len(validationCodes) || peer.TxValidationCode(validationCodes[i]) != peer.TxValidationCode_VALID {
+			continue
+		}
+
+		transactionEvents, err := c.getTransactionEvents(iBlock.Header.Number, envelope)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d of block %d: %v", i, iBlock.Header.Number, err)
+		}
+		events = append(events, transactionEvents...)
+	}
+
+	return events, nil
+}
+
+/// Streams the blocks of the channel from the first peer from iStartBlock on, or from the newest block if it is
+/// nil, until iCtx is cancelled or iHandler fails. iHandler is called once per block, with the events of the block
+/// which may be empty, so that callers can keep track of the blocks they processed
+func (c *NetworkContract) ListenEvents(
+	iCtx context.Context,
+	iStartBlock *uint64,
+	iHandler func(iBlockNumber uint64, iEvents []ChaincodeEvent) error,
+) error {
+	envelope, err := c.makeSeekEnvelope(iStartBlock)
+	if err != nil {
+		return err
+	}
+
+	stream, err := peer.NewDeliverClient(c.peers[0]).Deliver(iCtx)
+	if err != nil {
+		return err
+	}
+
+	err = stream.Send(envelope)
+	if err != nil {
+		return err
+	}
+
+	for {
+		response, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		switch content := response.Type.(type) {
+		case *peer.DeliverResponse_Status:
+			return fmt.Errorf("deliver ended with status %s", content.Status)
+		case *peer.DeliverResponse_Block:
+			events, err := c.getBlockEvents(content.Block)
+			if err != nil {
+				return err
+			}
+
+			err = iHandler(content.Block.Header.Number, events)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"sig_chain/pkg/keys"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	networkTimeout = 30 * time.Second
+
+	/// Fabric treats chaincode responses from this status on as errors
+	errorThreshold = 400
+
+	broadcastMethod = "/orderer.AtomicBroadcast/Broadcast"
+)
+
+type EndpointConfig struct {
+	Address            string `json:"Address"`
+	TlsRootCertPath    string `json:"TlsRootCertPath"`
+	ServerNameOverride string `json:"ServerNameOverride"` /// e.g. peer0.org1.example.com when connecting through localhost
+}
+
+/// The Fabric identity submitting the transactions, which is not the owner of the nodes. The private key must be an
+/// ECDSA key as issued by the Fabric CAs
+type NetworkConfig struct {
+	ChannelId       string           `json:"ChannelId"`
+	ChaincodeName   string           `json:"ChaincodeName"`
+	MspId           string           `json:"MspId"`
+	CertificatePath string           `json:"CertificatePath"`
+	PrivateKeyPath  string           `json:"PrivateKeyPath"`
+	Peers           []EndpointConfig `json:"Peers"` /// transactions are endorsed by all of them and evaluated by the first one
+	Orderer         EndpointConfig   `json:"Orderer"`
+}
+
+/// Contract which endorses transactions on the peers and submits them to the orderer, it does not wait for the
+/// transactions to be committed
+type NetworkContract struct {
+	config     NetworkConfig
+	creator    []byte
+	privateKey *ecdsa.PrivateKey
+	peers      []*grpc.ClientConn
+	orderer    *grpc.ClientConn
+}
+
+/// Returned when the chaincode rejects a transaction, as opposed to failing to reach the network
+type TransactionError struct {
+	Message string
+}
+
+func (e *TransactionError) Error() string {
+	return e.Message
+}
+
+/// the response of the orderer, orderer protos are not vendored
+type broadcastResponse struct {
+	Status common.Status `protobuf:"varint,1,opt,name=status,proto3,enum=common.Status"`
+	Info   string        `protobuf:"bytes,2,opt,name=info,proto3"`
+}
+
+func (m *broadcastResponse) Reset()         { *m = broadcastResponse{} }
+func (m *broadcastResponse) String() string { return proto.CompactTextString(m) }
+func (*broadcastResponse) ProtoMessage()    {}
+
+func ReadNetworkConfig(
+	iPath string,
+) (*NetworkConfig, error) {
+	configJson, err := ioutil.ReadFile(iPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var config NetworkConfig
+	err = json.Unmarshal(configJson, &config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid network config: %v", err)
+	}
+
+	if len(config.Peers) == 0 {
+		return nil, fmt.Errorf("network config has no peers")
+	}
+
+	return &config, nil
+}
+
+func dialEndpoint(
+	iEndpoint EndpointConfig,
+) (*grpc.ClientConn, error) {
+	rootCert, err := ioutil.ReadFile(iEndpoint.TlsRootCertPath)
+	if err != nil {
+		return nil, err
+	}
+
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(rootCert) {
+		return nil, fmt.Errorf("invalid tls root certificate %s", iEndpoint.TlsRootCertPath)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), networkTimeout)
+	defer cancel()
+
+	return grpc.DialContext(
+		ctx,
+		iEndpoint.Address,
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+			RootCAs:    rootCAs,
+			ServerName: iEndpoint.ServerNameOverride,
+		})),
+		grpc.WithBlock(),
+	)
+}
+
+func ConnectNetwork(
+	iConfig *NetworkConfig,
+) (*NetworkContract, error) {
+	certificate, err := ioutil.ReadFile(iConfig.CertificatePath)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKeyPem, err := ioutil.ReadFile(iConfig.PrivateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := keys.ParsePrivateKey(string(privateKeyPem))
+	if err != nil {
+		return nil, err
+	}
+
+	ecdsaKey, ok := privateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("fabric identities must have an ECDSA key")
+	}
+
+	creator, err := proto.Marshal(&msp.SerializedIdentity{
+		Mspid:   iConfig.MspId,
+		IdBytes: certificate,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	contract := NetworkContract{
+		config:     *iConfig,
+		creator:    creator,
+		privateKey: ecdsaKey,
+	}
+
+	for _, endpoint := range iConfig.Peers {
+		conn, err := dialEndpoint(endpoint)
+		if err != nil {
+			contract.Close()
+			return nil, fmt.Errorf("failed to connect to peer %s: %v", endpoint.Address, err)
+		}
+		contract.peers = append(contract.peers, conn)
+	}
+
+	contract.orderer, err = dialEndpoint(iConfig.Orderer)
+	if err != nil {
+		contract.Close()
+		return nil, fmt.Errorf("failed to connect to orderer %s: %v", iConfig.Orderer.Address, err)
+	}
+
+	return &contract, nil
+}
+
+func (c *NetworkContract) Close() {
+	for _, conn := range c.peers {
+		conn.Close()
+	}
+
+	if c.orderer != nil {
+		c.orderer.Close()
+	}
+}
+
+/// Fabric only accepts ECDSA signatures with a low S
+func (c *NetworkContract) sign(
+	iMessage []byte,
+) ([]byte, error) {
+	hash := sha256.Sum256(iMessage)
+	r, s, err := ecdsa.Sign(rand.Reader, c.privateKey, hash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	order := c.privateKey.Curve.Params().N
+	if s.Cmp(new(big.Int).Rsh(order, 1)) > 0 {
+		s.Sub(order, s)
+	}
+
+	return asn1.Marshal(struct {
+		R *big.Int
+		S *big.Int
+	}{r, s})
+}
+
+/// returns the signed proposal along with its header and payload, which the transaction reuses
+func (c *NetworkContract) makeProposal(
+	iName string,
+	iArgs []string,
+) (*peer.SignedProposal, *common.Header, []byte, error) {
+	nonce := make([]byte, 24)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	txIdHash := sha256.Sum256(append(append([]byte{}, nonce...), c.creator...))
+
+	chaincodeId := &peer.ChaincodeID{Name: c.config.ChaincodeName}
+	extension, err := proto.Marshal(&peer.ChaincodeHeaderExtension{ChaincodeId: chaincodeId})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	channelHeader, err := proto.Marshal(&common.ChannelHeader{
+		Type:      int32(common.HeaderType_ENDORSER_TRANSACTION),
+		ChannelId: c.config.ChannelId,
+		TxId:      hex.EncodeToString(txIdHash[:]),
+		Timestamp: ptypes.TimestampNow(),
+		Extension: extension,
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	signatureHeader, err := proto.Marshal(&common.SignatureHeader{
+		Creator: c.creator,
+		Nonce:   nonce,
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	args := [][]byte{[]byte(iName)}
+	for _, arg := range iArgs {
+		args = append(args, []byte(arg))
+	}
+
+	invocation, err := proto.Marshal(&peer.ChaincodeInvocationSpec{
+		ChaincodeSpec: &peer.ChaincodeSpec{
+			Type:        peer.ChaincodeSpec_GOLANG,
+			ChaincodeId: chaincodeId,
+			Input:       &peer.ChaincodeInput{Args: args},
+		},
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	payload, err := proto.Marshal(&peer.ChaincodeProposalPayload{Input: invocation})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	header := &common.Header{
+		ChannelHeader:   channelHeader,
+		SignatureHeader: signatureHeader,
+	}
+	headerBytes, err := proto.Marshal(header)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	proposal, err := proto.Marshal(&peer.Proposal{
+		Header:  headerBytes,
+		Payload: payload,
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	signature, err := c.sign(proposal)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return &peer.SignedProposal{ProposalBytes: proposal, Signature: signature}, header, payload, nil
+}
+
+func endorse(
+	iConn *grpc.ClientConn,
+	iProposal *peer.SignedProposal,
+) (*peer.ProposalResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), networkTimeout)
+	defer cancel()
+
+	response, err := peer.NewEndorserClient(iConn).ProcessProposal(ctx, iProposal)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Response.Status >= errorThreshold {
+		return nil, &TransactionError{Message: response.Response.Message}
+	}
+
+	return response, nil
+}
+
+func (c *NetworkContract) EvaluateTransaction(
+	iName string,
+	iArgs ...string,
+) ([]byte, error) {
+	proposal, _, _, err := c.makeProposal(iName, iArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := endorse(c.peers[0], proposal)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Response.Payload, nil
+}
+
+func (c *NetworkContract) SubmitTransaction(
+	iName string,
+	iArgs ...string,
+) ([]byte, error) {
+	proposal, header, proposalPayload, err := c.makeProposal(iName, iArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []*peer.ProposalResponse{}
+	endorsements := []*peer.Endorsement{}
+	for i, conn := range c.peers {
+		response, err := endorse(conn, proposal)
+		if err != nil {
+			return nil, fmt.Errorf("endorsement by %s failed: %w", c.config.Peers[i].Address, err)
+		}
+
+		if len(responses) > 0 && !bytes.Equal(response.Payload, responses[0].Payload) {
+			return nil, fmt.Errorf("endorsement by %s does not match the other ones", c.config.Peers[i].Address)
+		}
+
+		responses = append(responses, response)
+		endorsements = append(endorsements, response.Endorsement)
+	}
+
+	actionPayload, err := proto.Marshal(&peer.ChaincodeActionPayload{
+		ChaincodeProposalPayload: proposalPayload,
+		Action: &peer.ChaincodeEndorsedAction{
+			ProposalResponsePayload: responses[0].Payload,
+			Endorsements:            endorsements,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, err := proto.Marshal(&peer.Transaction{
+		Actions: []*peer.TransactionAction{{
+			Header:  header.SignatureHeader,
+			Payload: actionPayload,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := proto.Marshal(&common.Payload{
+		Header: header,
+		Data:   transaction,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := c.sign(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.broadcast(&common.Envelope{Payload: payload, Signature: signature})
+	if err != nil {
+		return nil, err
+	}
+
+	return responses[0].Response.Payload, nil
+}
+
+func (c *NetworkContract) broadcast(
+	iEnvelope *common.Envelope,
+) error {
+	ctx, cancel := context.WithTimeout(context.Background(), networkTimeout)
+	defer cancel()
+
+	stream, err := c.orderer.NewStream(
+		ctx,
+		&grpc.StreamDesc{StreamName: "Broadcast", ServerStreams: true, ClientStreams: true},
+		broadcastMethod,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = stream.SendMsg(iEnvelope)
+	if err != nil {
+		return err
+	}
+
+	err = stream.CloseSend()
+	if err != nil {
+		return err
+	}
+
+	var response broadcastResponse
+	err = stream.RecvMsg(&response)
+	if err != nil {
+		return err
+	}
+
+	if response.Status != common.Status_SUCCESS {
+		return fmt.Errorf("orderer rejected the transaction: %s %s", response.Status, response.Info)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"encoding/json"
+	"sig_chain/chaincode/asset"
+	"sig_chain/chaincode/graph"
+	"sig_chain/pkg/signing"
+)
+
+/// returns the signature as passed to the chaincode, i.e. base64 encoded
+func SignNode(
+	iSigner Signer,
+	iNode graph.NodeI,
+) (string, error) {
+	payload, err := signing.GetNodeBytes(iNode)
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := iSigner.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return graph.EncodeSignature(signature), nil
+}
+
+/// signs the canonical bytes of a request of the chaincode, the signature is base64 encoded
+func SignPayload(
+	iSigner Signer,
+	iPayload interface{},
+) (string, error) {
+	payload, err := signing.GetPayloadBytes(iPayload)
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := iSigner.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return graph.EncodeSignature(signature), nil
+}
+
+/// unmarshals iNodeJson into the type of node given by its Type
+func UnmarshalNode(
+	iNodeJson []byte,
+) (graph.NodeI, error) {
+	var header graph.NodeHeader
+	err := json.Unmarshal(iNodeJson, &header)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := asset.MakeNode(header.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(iNodeJson, node)
+	if err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"crypto"
+	"fmt"
+	"sig_chain/pkg/keys"
+	"sort"
+)
+
+/// Session on a PKCS#11 token, the private keys never leave the token. This module does not vendor a PKCS#11
+/// binding, since it needs cgo and the vendor library of the HSM, so applications adapt theirs, e.g. the
+/// FindKeyPair and GenerateECDSAKeyPairWithLabel of github.com/ThalesIgnite/crypto11
+type Pkcs11Token interface {
+	ListKeyLabels() ([]string, error)
+	FindKeyPair(iLabel string) (crypto.Signer, error)          /// nil if the token has no key pair with iLabel
+	GenerateEcdsaKeyPair(iLabel string) (crypto.Signer, error) /// on the P-256 curve
+}
+
+/// Keys of a PKCS#11 token, identities are the labels of the key pairs. The chaincode only verifies RSA and
+/// ECDSA signatures, other key pairs of the token cannot sign nodes
+type Pkcs11Wallet struct {
+	token Pkcs11Token
+}
+
+func MakePkcs11Wallet(
+	iToken Pkcs11Token,
+) *Pkcs11Wallet {
+	return &Pkcs11Wallet{
+		token: iToken,
+	}
+}
+
+func makePkcs11Identity(
+	iName string,
+	iKeyPair crypto.Signer,
+) (*Identity, error) {
+	publicKey, err := keys.EncodePublicKey(iKeyPair.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the public key of identity %s: %v", iName, err)
+	}
+
+	return &Identity{Name: iName, PublicKey: publicKey}, nil
+}
+
+func (w *Pkcs11Wallet) ListIdentities() ([]Identity, error) {
+	labels, err := w.token.ListKeyLabels()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(labels)
+
+	identities := []Identity{}
+	for _, label := range labels {
+		if !identityNamePattern.MatchString(label) {
+			continue
+		}
+
+		keyPair, err := w.token.FindKeyPair(label)
+		if err != nil {
+			return nil, err
+		}
+
+		/// labels of secret keys or lone public keys
+		if keyPair == nil {
+			continue
+		}
+
+		identity, err := makePkcs11Identity(label, keyPair)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, *identity)
+	}
+
+	return identities, nil
+}
+
+/// the public key is encoded from the token, so it matches the OwnerPublicKey of nodes created with this wallet
+/// but not of nodes owned by a certificate of the same key
+func (w *Pkcs11Wallet) GetSigner(
+	iName string,
+) (Signer, error) {
+	err := checkIdentityName(iName)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPair, err := w.token.FindKeyPair(iName)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyPair == nil {
+		return nil, fmt.Errorf("identity %s does not exist", iName)
+	}
+
+	identity, err := makePkcs11Identity(iName, keyPair)
+	if err != nil {
+		return nil, err
+	}
+
+	return MakeKeySignerFromKey(keyPair, identity.PublicKey)
+}
+
+func (w *Pkcs11Wallet) CreateIdentity(
+	iName string,
+) (*Identity, error) {
+	err := checkIdentityName(iName)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPair, err := w.token.FindKeyPair(iName)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyPair != nil {
+		return nil, fmt.Errorf("identity %s already exists", iName)
+	}
+
+	keyPair, err = w.token.GenerateEcdsaKeyPair(iName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the key pair of identity %s: %v", iName, err)
+	}
+
+	return makePkcs11Identity(iName, keyPair)
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"crypto"
+	"fmt"
+	"sig_chain/chaincode/graph"
+	"sig_chain/pkg/keys"
+	"sig_chain/pkg/signing"
+)
+
+/// Signs payloads on behalf of the owner of a node, implementations may keep the key in an HSM or ask another party
+type Signer interface {
+	GetPublicKey() string /// as stored in the OwnerPublicKey of the nodes
+	Sign(iMessage []byte) ([]byte, error)
+}
+
+/// Signs with a private key held in memory
+type KeySigner struct {
+	privateKey crypto.Signer
+	publicKey  string
+}
+
+/// iPrivateKeyPem is a PKCS8, PKCS1 RSA or SEC1 EC private key. iPublicKey is either the matching public key or
+/// the certificate of the owner, such as a Fabric enrollment certificate
+func MakeKeySigner(
+	iPrivateKeyPem string,
+	iPublicKey string,
+) (*KeySigner, error) {
+	privateKey, err := keys.ParsePrivateKey(iPrivateKeyPem)
+	if err != nil {
+		return nil, err
+	}
+
+	return MakeKeySignerFromKey(privateKey, iPublicKey)
+}
+
+/// same as MakeKeySigner for a key which is already parsed, e.g. decrypted with keys.DecryptPrivateKey
+func MakeKeySignerFromKey(
+	iPrivateKey crypto.Signer,
+	iPublicKey string,
+) (*KeySigner, error) {
+	publicKey, err := graph.ParsePublicKey(iPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if !keys.IsMatching(iPrivateKey, publicKey) {
+		return nil, fmt.Errorf("public key does not match the private key")
+	}
+
+	return &KeySigner{
+		privateKey: iPrivateKey,
+		publicKey:  iPublicKey,
+	}, nil
+}
+
+func (s *KeySigner) GetPublicKey() string {
+	return s.publicKey
+}
+
+func (s *KeySigner) Sign(
+	iMessage []byte,
+) ([]byte, error) {
+	return signing.Sign(s.privateKey, iMessage)
+}
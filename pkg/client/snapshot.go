@@ -0,0 +1,200 @@
+package client
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sig_chain/chaincode/asset"
+	"sig_chain/chaincode/graph"
+	"strconv"
+	"strings"
+)
+
+/// Nodes exported from the world state, as returned by GetNodeJson. Snapshots written by ExportSnapshot also hold
+/// every record of the world state, so that they can be imported into another channel
+type Snapshot struct {
+	Nodes   []json.RawMessage  `json:"Nodes"`
+	Entries []graph.StateEntry `json:"Entries,omitempty"`
+	Hash    string             `json:"Hash,omitempty"` /// graph.GetStatePageHash of the entries
+}
+
+/// entries of a snapshot are submitted in pages of this size by default
+const DefaultSnapshotPageSize = 100
+
+func ReadSnapshot(
+	iPath string,
+) (*Snapshot, error) {
+	snapshotJson, err := ioutil.ReadFile(iPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot Snapshot
+	err = json.Unmarshal(snapshotJson, &snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %v", err)
+	}
+
+	return &snapshot, nil
+}
+
+func (s *Snapshot) Write(
+	iPath string,
+) error {
+	snapshotJson, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(iPath, snapshotJson, 0600)
+}
+
+/// returns the materials of the snapshot by hashed id, other nodes are skipped
+func (s *Snapshot) getMaterials() (map[string]*asset.Material, error) {
+	materials := map[string]*asset.Material{}
+	for i, nodeJson := range s.Nodes {
+		node, err := UnmarshalNode(nodeJson)
+		if err != nil {
+			return nil, fmt.Errorf("node %d: %v", i, err)
+		}
+
+		if material, ok := node.(*asset.Material); ok {
+			materials[graph.HashId(material.Id)] = material
+		}
+	}
+
+	return materials, nil
+}
+
+func getSnapshotProvenanceNode(
+	iMaterials map[string]*asset.Material,
+	iHashedId string,
+	iVisited map[string]bool,
+) *asset.ProvenanceNode {
+	material := iMaterials[iHashedId]
+	node := asset.ProvenanceNode{
+		Material: *material,
+		Inputs:   []asset.ProvenanceNode{},
+	}
+
+	iVisited[iHashedId] = true
+	for _, previousHashedId := range material.PreviousNodeHashedIds {
+		if _, ok := iMaterials[previousHashedId]; !ok || iVisited[previousHashedId] {
+			continue
+		}
+		node.Inputs = append(node.Inputs, *getSnapshotProvenanceNode(iMaterials, previousHashedId, iVisited))
+	}
+
+	return &node
+}
+
+/// offline counterpart of Client.GetProvenance, only the materials the node derives from are known from a snapshot
+func (s *Snapshot) GetProvenance(
+	iNodeId string,
+) (*asset.ProvenanceNode, error) {
+	materials, err := s.getMaterials()
+	if err != nil {
+		return nil, err
+	}
+
+	hashedId := graph.HashId(iNodeId)
+	if _, ok := materials[hashedId]; !ok {
+		return nil, fmt.Errorf("material %s is not in the snapshot", iNodeId)
+	}
+
+	return getSnapshotProvenanceNode(materials, hashedId, map[string]bool{}), nil
+}
+
+/// exports the world state in pages of iPageSize records, the identity of the contract must be a channel admin.
+/// Records written while exporting may be missed, the network should not be used meanwhile
+func (c *Client) ExportSnapshot(
+	iPageSize int,
+) (*Snapshot, error) {
+	snapshot := Snapshot{
+		Nodes:   []json.RawMessage{},
+		Entries: []graph.StateEntry{},
+	}
+
+	bookmark := ""
+	for {
+		var page graph.StatePage
+		err := c.evaluate("ExportState", &page, bookmark, strconv.Itoa(iPageSize))
+		if err != nil {
+			return nil, err
+		}
+
+		if graph.GetStatePageHash(page.Entries) != page.Hash {
+			return nil, fmt.Errorf("page at %q does not match its hash", bookmark)
+		}
+
+		for _, entry := range page.Entries {
+			snapshot.Entries = append(snapshot.Entries, entry)
+
+			/// composite keys start with U+0000, the other records are nodes
+			if strings.HasPrefix(entry.Key, "\x00") {
+				continue
+			}
+
+			nodeJson, err := base64.StdEncoding.DecodeString(entry.Value)
+			if err != nil {
+				return nil, fmt.Errorf("node %s: %v", entry.Key, err)
+			}
+			snapshot.Nodes = append(snapshot.Nodes, nodeJson)
+		}
+
+		if page.Bookmark == "" {
+			break
+		}
+		bookmark = page.Bookmark
+	}
+
+	snapshot.Hash = graph.GetStatePageHash(snapshot.Entries)
+	return &snapshot, nil
+}
+
+/// imports iSnapshot in pages of iPageSize records, each signed by the signer of the client, who must be the
+/// administrator of the channel. iProgress is called with the number of records imported after each page
+func (c *Client) ImportSnapshot(
+	iSnapshot *Snapshot,
+	iPageSize int,
+	iProgress func(iImportedCount int),
+) error {
+	if len(iSnapshot.Entries) == 0 {
+		return fmt.Errorf("snapshot has no entries, it was not written by ExportSnapshot")
+	}
+
+	if graph.GetStatePageHash(iSnapshot.Entries) != iSnapshot.Hash {
+		return fmt.Errorf("snapshot entries do not match the snapshot hash")
+	}
+
+	for start := 0; start < len(iSnapshot.Entries); start += iPageSize {
+		end := start + iPageSize
+		if end > len(iSnapshot.Entries) {
+			end = len(iSnapshot.Entries)
+		}
+
+		entries := iSnapshot.Entries[start:end]
+		entriesJson, err := json.Marshal(entries)
+		if err != nil {
+			return err
+		}
+
+		pageHash := graph.GetStatePageHash(entries)
+		signature, err := SignPayload(c.signer, &asset.ImportRequest{PageHash: pageHash})
+		if err != nil {
+			return err
+		}
+
+		_, err = c.submit("ImportState", string(entriesJson), pageHash, signature)
+		if err != nil {
+			return fmt.Errorf("failed to import records %d to %d: %v", start, end, err)
+		}
+
+		if iProgress != nil {
+			iProgress(end)
+		}
+	}
+
+	return nil
+}
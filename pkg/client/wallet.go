@@ -0,0 +1,185 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sig_chain/pkg/keys"
+	"sort"
+	"strings"
+)
+
+/// identity names are used as file names and key labels
+var identityNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+/// A named owner key of a wallet, e.g. one per business unit
+type Identity struct {
+	Name      string `json:"Name"`
+	PublicKey string `json:"PublicKey"` /// as stored in the OwnerPublicKey of the nodes
+}
+
+/// Owner keys an application signs with, so that one application can act for several owners
+type Wallet interface {
+	ListIdentities() ([]Identity, error)
+	GetSigner(iName string) (Signer, error)
+	CreateIdentity(iName string) (*Identity, error) /// generates a P-256 key pair, existing identities are never overwritten
+}
+
+func checkIdentityName(
+	iName string,
+) error {
+	if !identityNamePattern.MatchString(iName) {
+		return fmt.Errorf("invalid identity name %s", iName)
+	}
+
+	return nil
+}
+
+/// Keys stored as <name>.key and <name>.pub in a directory, as written by sigchain keygen
+type FileWallet struct {
+	directory string
+	password  []byte
+}
+
+/// private keys are encrypted with iPassword when it is not empty, unencrypted keys can still be read
+func MakeFileWallet(
+	iDirectory string,
+	iPassword []byte,
+) *FileWallet {
+	return &FileWallet{
+		directory: iDirectory,
+		password:  iPassword,
+	}
+}
+
+func (w *FileWallet) getPaths(
+	iName string,
+) (string, string, error) {
+	err := checkIdentityName(iName)
+	if err != nil {
+		return "", "", err
+	}
+
+	return filepath.Join(w.directory, iName+".key"), filepath.Join(w.directory, iName+".pub"), nil
+}
+
+func (w *FileWallet) ListIdentities() ([]Identity, error) {
+	files, err := ioutil.ReadDir(w.directory)
+	if err != nil {
+		return nil, err
+	}
+
+	identities := []Identity{}
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), ".pub")
+		if name == file.Name() || !identityNamePattern.MatchString(name) {
+			continue
+		}
+
+		publicKey, err := ioutil.ReadFile(filepath.Join(w.directory, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		identities = append(identities, Identity{Name: name, PublicKey: string(publicKey)})
+	}
+
+	sort.Slice(identities, func(i, j int) bool {
+		return identities[i].Name < identities[j].Name
+	})
+	return identities, nil
+}
+
+func (w *FileWallet) GetSigner(
+	iName string,
+) (Signer, error) {
+	privateKeyPath, publicKeyPath, err := w.getPaths(iName)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKeyPem, err := ioutil.ReadFile(privateKeyPath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("identity %s does not exist", iName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := ioutil.ReadFile(publicKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !keys.IsEncrypted(string(privateKeyPem)) {
+		return MakeKeySigner(string(privateKeyPem), string(publicKey))
+	}
+
+	if len(w.password) == 0 {
+		return nil, fmt.Errorf("the key of identity %s is encrypted but the wallet has no password", iName)
+	}
+
+	privateKey, err := keys.DecryptPrivateKey(string(privateKeyPem), w.password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the key of identity %s: %v", iName, err)
+	}
+
+	return MakeKeySignerFromKey(privateKey, string(publicKey))
+}
+
+func (w *FileWallet) CreateIdentity(
+	iName string,
+) (*Identity, error) {
+	privateKeyPath, publicKeyPath, err := w.getPaths(iName)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := keys.GenerateKey("ecdsa")
+	if err != nil {
+		return nil, err
+	}
+
+	var privateKeyPem string
+	if len(w.password) > 0 {
+		privateKeyPem, err = keys.EncryptPrivateKey(privateKey, w.password)
+	} else {
+		privateKeyPem, err = keys.EncodePrivateKey(privateKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := keys.EncodePublicKey(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(w.directory, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKeyFile, err := os.OpenFile(privateKeyPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return nil, fmt.Errorf("identity %s already exists", iName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer privateKeyFile.Close()
+
+	_, err = privateKeyFile.WriteString(privateKeyPem)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ioutil.WriteFile(publicKeyPath, []byte(publicKey), 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Identity{Name: iName, PublicKey: publicKey}, nil
+}
@@ -0,0 +1,445 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+/// Resolves a root field from its arguments. The returned Go value is projected on the selection set of the field,
+/// its fields are named after their json names starting with a lower case letter, e.g. PreviousNodeHashedIds is
+/// previousNodeHashedIds
+type Resolver func(iArguments map[string]interface{}) (interface{}, error)
+
+/// Only queries are supported, without introspection. Types are checked against the Go values when they are
+/// projected rather than against a schema
+type Schema struct {
+	Queries map[string]Resolver
+}
+
+type Request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+type Error struct {
+	Message string   `json:"message"`
+	Path    []string `json:"path,omitempty"`
+}
+
+type Response struct {
+	Data   *orderedObject `json:"data"`
+	Errors []Error        `json:"errors,omitempty"`
+}
+
+/// json object keeping the order of the selection set
+type orderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *orderedObject) set(
+	iKey string,
+	iValue interface{},
+) {
+	if _, ok := o.values[iKey]; !ok {
+		o.keys = append(o.keys, iKey)
+	}
+	o.values[iKey] = iValue
+}
+
+func (o *orderedObject) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+
+		keyJson, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+
+		valueJson, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+
+		buffer.Write(keyJson)
+		buffer.WriteByte(':')
+		buffer.Write(valueJson)
+	}
+	buffer.WriteByte('}')
+
+	return buffer.Bytes(), nil
+}
+
+type executor struct {
+	fragments map[string][]selection
+	variables map[string]interface{}
+}
+
+/// returns iValue with its variables replaced by their values
+func (e *executor) substitute(
+	iValue interface{},
+) (interface{}, error) {
+	switch value := iValue.(type) {
+	case variable:
+		if variableValue, ok := e.variables[string(value)]; ok {
+			return variableValue, nil
+		}
+		return nil, fmt.Errorf("variable $%s is not defined", value)
+	case []interface{}:
+		list := []interface{}{}
+		for _, element := range value {
+			substituted, err := e.substitute(element)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, substituted)
+		}
+		return list, nil
+	case map[string]interface{}:
+		object := map[string]interface{}{}
+		for key, element := range value {
+			substituted, err := e.substitute(element)
+			if err != nil {
+				return nil, err
+			}
+			object[key] = substituted
+		}
+		return object, nil
+	default:
+		return iValue, nil
+	}
+}
+
+func (e *executor) getArguments(
+	iArguments map[string]interface{},
+) (map[string]interface{}, error) {
+	arguments := map[string]interface{}{}
+	for name, value := range iArguments {
+		substituted, err := e.substitute(value)
+		if err != nil {
+			return nil, err
+		}
+		arguments[name] = substituted
+	}
+
+	return arguments, nil
+}
+
+/// applies @skip and @include
+func (e *executor) isIncluded(
+	iDirectives []directive,
+) (bool, error) {
+	for _, directive := range iDirectives {
+		if directive.name != "skip" && directive.name != "include" {
+			return false, fmt.Errorf("unknown directive @%s", directive.name)
+		}
+
+		arguments, err := e.getArguments(directive.arguments)
+		if err != nil {
+			return false, err
+		}
+
+		condition, ok := arguments["if"].(bool)
+		if !ok {
+			return false, fmt.Errorf("@%s requires a boolean if argument", directive.name)
+		}
+
+		if condition == (directive.name == "skip") {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+/// flattens the fragments of iSelections and merges the fields with the same response key
+func (e *executor) collectFields(
+	iSelections []selection,
+	iVisitedFragments map[string]bool,
+	oFields *[]selection,
+) error {
+	for _, selection := range iSelections {
+		isIncluded, err := e.isIncluded(selection.directives)
+		if err != nil {
+			return err
+		}
+
+		if !isIncluded {
+			continue
+		}
+
+		if selection.isInline {
+			err = e.collectFields(selection.selections, iVisitedFragments, oFields)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if selection.fragmentName != "" {
+			if iVisitedFragments[selection.fragmentName] {
+				continue
+			}
+
+			fragment, ok := e.fragments[selection.fragmentName]
+			if !ok {
+				return fmt.Errorf("unknown fragment %s", selection.fragmentName)
+			}
+
+			iVisitedFragments[selection.fragmentName] = true
+			err = e.collectFields(fragment, iVisitedFragments, oFields)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		isMerged := false
+		for i := range *oFields {
+			if getResponseKey(&(*oFields)[i]) == getResponseKey(&selection) {
+				(*oFields)[i].selections = append((*oFields)[i].selections, selection.selections...)
+				isMerged = true
+				break
+			}
+		}
+
+		if !isMerged {
+			*oFields = append(*oFields, selection)
+		}
+	}
+
+	return nil
+}
+
+func getResponseKey(
+	iField *selection,
+) string {
+	if iField.alias != "" {
+		return iField.alias
+	}
+	return iField.name
+}
+
+func lowerFirst(
+	iName string,
+) string {
+	if iName == "" {
+		return iName
+	}
+
+	runes := []rune(iName)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+/// the index of every field of iType by name, fields of embedded structs are promoted as json does
+func getFieldIndexes(
+	iType reflect.Type,
+	iPrefix []int,
+	oIndexes map[string][]int,
+) {
+	for i := 0; i < iType.NumField(); i++ {
+		field := iType.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if tag == "-" {
+			continue
+		}
+
+		index := append(append([]int{}, iPrefix...), i)
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			getFieldIndexes(field.Type, index, oIndexes)
+			continue
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := field.Name
+		if tag != "" {
+			name = tag
+		}
+
+		if _, ok := oIndexes[lowerFirst(name)]; !ok {
+			oIndexes[lowerFirst(name)] = index
+		}
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+/// projects iValue on iSelections, iPath is used in errors
+func (e *executor) project(
+	iValue reflect.Value,
+	iSelections []selection,
+	iPath string,
+) (interface{}, error) {
+	for iValue.Kind() == reflect.Ptr || iValue.Kind() == reflect.Interface {
+		if iValue.IsNil() {
+			return nil, nil
+		}
+		iValue = iValue.Elem()
+	}
+
+	isObject := iValue.Kind() == reflect.Struct && iValue.Type() != timeType
+	isList := iValue.Kind() == reflect.Slice || iValue.Kind() == reflect.Array
+
+	if isList {
+		list := []interface{}{}
+		for i := 0; i < iValue.Len(); i++ {
+			element, err := e.project(iValue.Index(i), iSelections, fmt.Sprintf("%s.%d", iPath, i))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, element)
+		}
+		return list, nil
+	}
+
+	if !isObject {
+		if len(iSelections) > 0 {
+			return nil, fmt.Errorf("%s is a scalar and cannot have a selection set", iPath)
+		}
+		return iValue.Interface(), nil
+	}
+
+	if len(iSelections) == 0 {
+		return nil, fmt.Errorf("%s is an object and must have a selection set", iPath)
+	}
+
+	fields := []selection{}
+	err := e.collectFields(iSelections, map[string]bool{}, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := map[string][]int{}
+	getFieldIndexes(iValue.Type(), nil, indexes)
+
+	object := orderedObject{values: map[string]interface{}{}}
+	for _, field := range fields {
+		if field.name == "__typename" {
+			object.set(getResponseKey(&field), iValue.Type().Name())
+			continue
+		}
+
+		if len(field.arguments) > 0 {
+			return nil, fmt.Errorf("%s.%s does not take arguments", iPath, field.name)
+		}
+
+		index, ok := indexes[field.name]
+		if !ok {
+			return nil, fmt.Errorf("%s has no field %s", iPath, field.name)
+		}
+
+		value, err := e.project(iValue.FieldByIndex(index), field.selections, iPath+"."+field.name)
+		if err != nil {
+			return nil, err
+		}
+		object.set(getResponseKey(&field), value)
+	}
+
+	return &object, nil
+}
+
+func (s *Schema) getOperation(
+	iDocument *document,
+	iOperationName string,
+) (*operation, error) {
+	if iOperationName == "" {
+		if len(iDocument.operations) > 1 {
+			return nil, fmt.Errorf("operationName is required when the document has several operations")
+		}
+		return iDocument.operations[0], nil
+	}
+
+	for _, operation := range iDocument.operations {
+		if operation.name == iOperationName {
+			return operation, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown operation %s", iOperationName)
+}
+
+/// the root fields are resolved one after another, a field which fails is null and reported in the errors
+func (s *Schema) Execute(
+	iRequest *Request,
+) *Response {
+	document, err := parseDocument(iRequest.Query)
+	if err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	operation, err := s.getOperation(document, iRequest.OperationName)
+	if err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	if operation.kind != "query" {
+		return &Response{Errors: []Error{{Message: fmt.Sprintf("%s operations are not supported", operation.kind)}}}
+	}
+
+	variables := map[string]interface{}{}
+	for name, value := range operation.defaults {
+		variables[name] = value
+	}
+	for name, value := range iRequest.Variables {
+		variables[name] = value
+	}
+
+	e := executor{fragments: document.fragments, variables: variables}
+	fields := []selection{}
+	err = e.collectFields(operation.selections, map[string]bool{}, &fields)
+	if err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	response := Response{Data: &orderedObject{values: map[string]interface{}{}}}
+	for _, field := range fields {
+		key := getResponseKey(&field)
+		value, err := s.resolveRootField(&e, &field)
+		if err != nil {
+			response.Errors = append(response.Errors, Error{Message: err.Error(), Path: []string{key}})
+		}
+		response.Data.set(key, value)
+	}
+
+	return &response
+}
+
+func (s *Schema) resolveRootField(
+	iExecutor *executor,
+	iField *selection,
+) (interface{}, error) {
+	if iField.name == "__typename" {
+		return "Query", nil
+	}
+
+	resolver, ok := s.Queries[iField.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown query %s", iField.name)
+	}
+
+	arguments, err := iExecutor.getArguments(iField.arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := resolver(arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	return iExecutor.project(reflect.ValueOf(value), iField.selections, iField.name)
+}
@@ -0,0 +1,140 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type TokenKind = string
+
+const (
+	eNameToken        TokenKind = "eNameToken"
+	eStringToken      TokenKind = "eStringToken"
+	eIntToken         TokenKind = "eIntToken"
+	eFloatToken       TokenKind = "eFloatToken"
+	ePunctuationToken TokenKind = "ePunctuationToken"
+	eEndToken         TokenKind = "eEndToken"
+)
+
+type token struct {
+	kind     TokenKind
+	value    string
+	position int
+}
+
+func isNameStart(
+	iChar byte,
+) bool {
+	return iChar == '_' || (iChar >= 'a' && iChar <= 'z') || (iChar >= 'A' && iChar <= 'Z')
+}
+
+func isDigit(
+	iChar byte,
+) bool {
+	return iChar >= '0' && iChar <= '9'
+}
+
+/// returns the string starting with the quote at iPosition and the position following it, block strings are
+/// not supported
+func readString(
+	iSource string,
+	iPosition int,
+) (string, int, error) {
+	var builder strings.Builder
+	for i := iPosition + 1; i < len(iSource); i++ {
+		switch iSource[i] {
+		case '"':
+			return builder.String(), i + 1, nil
+		case '\n':
+			return "", 0, fmt.Errorf("unterminated string at %d", iPosition)
+		case '\\':
+			i++
+			if i >= len(iSource) {
+				return "", 0, fmt.Errorf("unterminated string at %d", iPosition)
+			}
+
+			switch iSource[i] {
+			case 'n':
+				builder.WriteByte('\n')
+			case 't':
+				builder.WriteByte('\t')
+			case 'r':
+				builder.WriteByte('\r')
+			case 'b':
+				builder.WriteByte('\b')
+			case 'f':
+				builder.WriteByte('\f')
+			case 'u':
+				if i+4 >= len(iSource) {
+					return "", 0, fmt.Errorf("invalid unicode escape at %d", i)
+				}
+				code, err := strconv.ParseUint(iSource[i+1:i+5], 16, 32)
+				if err != nil {
+					return "", 0, fmt.Errorf("invalid unicode escape at %d", i)
+				}
+				builder.WriteRune(rune(code))
+				i += 4
+			default:
+				builder.WriteByte(iSource[i])
+			}
+		default:
+			builder.WriteByte(iSource[i])
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated string at %d", iPosition)
+}
+
+func tokenize(
+	iSource string,
+) ([]token, error) {
+	tokens := []token{}
+	for i := 0; i < len(iSource); {
+		char := iSource[i]
+		switch {
+		case char == ' ' || char == '\t' || char == '\n' || char == '\r' || char == ',':
+			i++
+		case char == '#':
+			for i < len(iSource) && iSource[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(iSource[i:], "..."):
+			tokens = append(tokens, token{ePunctuationToken, "...", i})
+			i += 3
+		case strings.IndexByte("{}()[]:!$=@", char) != -1:
+			tokens = append(tokens, token{ePunctuationToken, string(char), i})
+			i++
+		case char == '"':
+			value, next, err := readString(iSource, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{eStringToken, value, i})
+			i = next
+		case isNameStart(char):
+			start := i
+			for i < len(iSource) && (isNameStart(iSource[i]) || isDigit(iSource[i])) {
+				i++
+			}
+			tokens = append(tokens, token{eNameToken, iSource[start:i], start})
+		case isDigit(char) || char == '-':
+			start := i
+			kind := eIntToken
+			i++
+			for i < len(iSource) && (isDigit(iSource[i]) || strings.IndexByte(".eE+-", iSource[i]) != -1) {
+				if !isDigit(iSource[i]) {
+					kind = eFloatToken
+				}
+				i++
+			}
+			tokens = append(tokens, token{kind, iSource[start:i], start})
+		default:
+			unexpected, _ := utf8.DecodeRuneInString(iSource[i:])
+			return nil, fmt.Errorf("unexpected character %q at %d", unexpected, i)
+		}
+	}
+
+	return append(tokens, token{eEndToken, "", len(iSource)}), nil
+}
@@ -0,0 +1,409 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+)
+
+/// a value referring to a variable of the operation
+type variable string
+
+type directive struct {
+	name      string
+	arguments map[string]interface{}
+}
+
+/// either a field, or a fragment spread when fragmentName is set, or an inline fragment
+type selection struct {
+	alias        string
+	name         string
+	arguments    map[string]interface{}
+	directives   []directive
+	selections   []selection
+	fragmentName string
+	isInline     bool
+}
+
+type operation struct {
+	kind       string
+	name       string
+	defaults   map[string]interface{} /// default values of the variables
+	selections []selection
+}
+
+type document struct {
+	operations []*operation
+	fragments  map[string][]selection
+}
+
+type parser struct {
+	tokens   []token
+	position int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.position]
+}
+
+func (p *parser) next() token {
+	token := p.tokens[p.position]
+	if token.kind != eEndToken {
+		p.position++
+	}
+	return token
+}
+
+func (p *parser) isPunctuation(
+	iValue string,
+) bool {
+	token := p.peek()
+	return token.kind == ePunctuationToken && token.value == iValue
+}
+
+func (p *parser) expectPunctuation(
+	iValue string,
+) error {
+	token := p.next()
+	if token.kind != ePunctuationToken || token.value != iValue {
+		return fmt.Errorf("expected %s at %d", iValue, token.position)
+	}
+	return nil
+}
+
+func (p *parser) expectName() (string, error) {
+	token := p.next()
+	if token.kind != eNameToken {
+		return "", fmt.Errorf("expected a name at %d", token.position)
+	}
+	return token.value, nil
+}
+
+func (p *parser) parseValue() (interface{}, error) {
+	token := p.next()
+	switch token.kind {
+	case eStringToken:
+		return token.value, nil
+	case eIntToken:
+		return strconv.ParseInt(token.value, 10, 64)
+	case eFloatToken:
+		return strconv.ParseFloat(token.value, 64)
+	case eNameToken:
+		switch token.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		default:
+			return token.value, nil /// enum values are passed as strings
+		}
+	case ePunctuationToken:
+		switch token.value {
+		case "$":
+			name, err := p.expectName()
+			return variable(name), err
+		case "[":
+			list := []interface{}{}
+			for !p.isPunctuation("]") {
+				if p.peek().kind == eEndToken {
+					return nil, fmt.Errorf("unterminated list at %d", token.position)
+				}
+				value, err := p.parseValue()
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			}
+			p.next()
+			return list, nil
+		case "{":
+			object := map[string]interface{}{}
+			for !p.isPunctuation("}") {
+				name, err := p.expectName()
+				if err != nil {
+					return nil, err
+				}
+				err = p.expectPunctuation(":")
+				if err != nil {
+					return nil, err
+				}
+				object[name], err = p.parseValue()
+				if err != nil {
+					return nil, err
+				}
+			}
+			p.next()
+			return object, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected value at %d", token.position)
+}
+
+func (p *parser) parseArguments() (map[string]interface{}, error) {
+	arguments := map[string]interface{}{}
+	if !p.isPunctuation("(") {
+		return arguments, nil
+	}
+	p.next()
+
+	for !p.isPunctuation(")") {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.expectPunctuation(":")
+		if err != nil {
+			return nil, err
+		}
+
+		arguments[name], err = p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.next()
+
+	return arguments, nil
+}
+
+func (p *parser) parseDirectives() ([]directive, error) {
+	directives := []directive{}
+	for p.isPunctuation("@") {
+		p.next()
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+
+		arguments, err := p.parseArguments()
+		if err != nil {
+			return nil, err
+		}
+
+		directives = append(directives, directive{name: name, arguments: arguments})
+	}
+
+	return directives, nil
+}
+
+/// skips a type reference such as [ID!]!, types are not checked
+func (p *parser) skipType() error {
+	if p.isPunctuation("[") {
+		p.next()
+		err := p.skipType()
+		if err != nil {
+			return err
+		}
+
+		err = p.expectPunctuation("]")
+		if err != nil {
+			return err
+		}
+	} else {
+		_, err := p.expectName()
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.isPunctuation("!") {
+		p.next()
+	}
+	return nil
+}
+
+func (p *parser) parseSelectionSet() ([]selection, error) {
+	err := p.expectPunctuation("{")
+	if err != nil {
+		return nil, err
+	}
+
+	selections := []selection{}
+	for !p.isPunctuation("}") {
+		if p.peek().kind == eEndToken {
+			return nil, fmt.Errorf("unterminated selection set")
+		}
+
+		if p.isPunctuation("...") {
+			p.next()
+			var fragment selection
+			if p.peek().kind == eNameToken && p.peek().value != "on" {
+				fragment.fragmentName = p.next().value
+			} else {
+				fragment.isInline = true
+				if p.peek().kind == eNameToken {
+					p.next()
+					_, err = p.expectName()
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+
+			fragment.directives, err = p.parseDirectives()
+			if err != nil {
+				return nil, err
+			}
+
+			if fragment.isInline {
+				fragment.selections, err = p.parseSelectionSet()
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			selections = append(selections, fragment)
+			continue
+		}
+
+		var field selection
+		field.name, err = p.expectName()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.isPunctuation(":") {
+			p.next()
+			field.alias = field.name
+			field.name, err = p.expectName()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		field.arguments, err = p.parseArguments()
+		if err != nil {
+			return nil, err
+		}
+
+		field.directives, err = p.parseDirectives()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.isPunctuation("{") {
+			field.selections, err = p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		selections = append(selections, field)
+	}
+	p.next()
+
+	return selections, nil
+}
+
+func (p *parser) parseOperation() (*operation, error) {
+	operation := operation{kind: "query", defaults: map[string]interface{}{}}
+	if p.peek().kind == eNameToken {
+		operation.kind = p.next().value
+		if p.peek().kind == eNameToken {
+			operation.name = p.next().value
+		}
+
+		if p.isPunctuation("(") {
+			p.next()
+			for !p.isPunctuation(")") {
+				err := p.expectPunctuation("$")
+				if err != nil {
+					return nil, err
+				}
+
+				name, err := p.expectName()
+				if err != nil {
+					return nil, err
+				}
+
+				err = p.expectPunctuation(":")
+				if err != nil {
+					return nil, err
+				}
+
+				err = p.skipType()
+				if err != nil {
+					return nil, err
+				}
+
+				if p.isPunctuation("=") {
+					p.next()
+					operation.defaults[name], err = p.parseValue()
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+			p.next()
+		}
+
+		_, err := p.parseDirectives()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	operation.selections, err = p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+
+	return &operation, nil
+}
+
+func parseDocument(
+	iSource string,
+) (*document, error) {
+	tokens, err := tokenize(iSource)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser{tokens: tokens}
+	document := document{fragments: map[string][]selection{}}
+	for p.peek().kind != eEndToken {
+		if p.peek().kind == eNameToken && p.peek().value == "fragment" {
+			p.next()
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+
+			if on, err := p.expectName(); err != nil || on != "on" {
+				return nil, fmt.Errorf("expected on after fragment %s", name)
+			}
+
+			_, err = p.expectName()
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = p.parseDirectives()
+			if err != nil {
+				return nil, err
+			}
+
+			document.fragments[name], err = p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		operation, err := p.parseOperation()
+		if err != nil {
+			return nil, err
+		}
+		document.operations = append(document.operations, operation)
+	}
+
+	if len(document.operations) == 0 {
+		return nil, fmt.Errorf("document has no operation")
+	}
+
+	return &document, nil
+}
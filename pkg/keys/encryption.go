@@ -0,0 +1,226 @@
+package keys
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+)
+
+/// Private keys are encrypted at rest as PKCS8 EncryptedPrivateKeyInfo with PBES2, PBKDF2 with HMAC-SHA256 and
+/// AES-256-CBC, so that they can also be read by openssl pkcs8
+const (
+	encryptedPrivateKeyType = "ENCRYPTED PRIVATE KEY"
+
+	pbkdf2Iterations = 600000
+	pbkdf2SaltSize   = 16
+	aesKeySize       = 32
+)
+
+var (
+	oidPbes2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPbkdf2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHmacWithSha256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAes256Cbc      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+/// structures of RFC 8018 and RFC 5958
+type pbkdf2Parameters struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int `asn1:"optional"`
+	Prf            pkix.AlgorithmIdentifier
+}
+
+type pbes2Parameters struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type encryptedPrivateKeyInfo struct {
+	EncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedData       []byte
+}
+
+/// PBKDF2 of RFC 8018 with HMAC-SHA256
+func deriveKey(
+	iPassword []byte,
+	iSalt []byte,
+	iIterations int,
+	iKeyLength int,
+) []byte {
+	key := []byte{}
+	for block := uint32(1); len(key) < iKeyLength; block++ {
+		prf := hmac.New(sha256.New, iPassword)
+		prf.Write(iSalt)
+		binary.Write(prf, binary.BigEndian, block)
+		u := prf.Sum(nil)
+
+		t := append([]byte{}, u...)
+		for i := 1; i < iIterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+
+	return key[:iKeyLength]
+}
+
+/// returns an ENCRYPTED PRIVATE KEY PEM, iPassword should come from the user or a secret store rather than
+/// from the command line
+func EncryptPrivateKey(
+	iPrivateKey crypto.Signer,
+	iPassword []byte,
+) (string, error) {
+	if len(iPassword) == 0 {
+		return "", fmt.Errorf("password cannot be empty")
+	}
+
+	privateKeyDer, err := x509.MarshalPKCS8PrivateKey(iPrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	salt := make([]byte, pbkdf2SaltSize)
+	iv := make([]byte, aes.BlockSize)
+	for _, random := range [][]byte{salt, iv} {
+		_, err = rand.Read(random)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	block, err := aes.NewCipher(deriveKey(iPassword, salt, pbkdf2Iterations, aesKeySize))
+	if err != nil {
+		return "", err
+	}
+
+	/// PKCS #7 padding, always at least one byte
+	padding := aes.BlockSize - len(privateKeyDer)%aes.BlockSize
+	encrypted := append(privateKeyDer, bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+
+	kdfParameters, err := asn1.Marshal(pbkdf2Parameters{
+		Salt:           salt,
+		IterationCount: pbkdf2Iterations,
+		KeyLength:      aesKeySize,
+		Prf:            pkix.AlgorithmIdentifier{Algorithm: oidHmacWithSha256, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	ivParameter, err := asn1.Marshal(iv)
+	if err != nil {
+		return "", err
+	}
+
+	pbes2, err := asn1.Marshal(pbes2Parameters{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPbkdf2, Parameters: asn1.RawValue{FullBytes: kdfParameters}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAes256Cbc, Parameters: asn1.RawValue{FullBytes: ivParameter}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	infoDer, err := asn1.Marshal(encryptedPrivateKeyInfo{
+		EncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidPbes2, Parameters: asn1.RawValue{FullBytes: pbes2}},
+		EncryptedData:       encrypted,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: encryptedPrivateKeyType, Bytes: infoDer})), nil
+}
+
+func IsEncrypted(
+	iPrivateKeyPem string,
+) bool {
+	block, _ := pem.Decode([]byte(iPrivateKeyPem))
+	return block != nil && block.Type == encryptedPrivateKeyType
+}
+
+/// decrypts a key written by EncryptPrivateKey, or by openssl with PBKDF2, HMAC-SHA256 and AES-256-CBC
+func DecryptPrivateKey(
+	iPrivateKeyPem string,
+	iPassword []byte,
+) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(iPrivateKeyPem))
+	if block == nil || block.Type != encryptedPrivateKeyType {
+		return nil, fmt.Errorf("not an encrypted private key")
+	}
+
+	var info encryptedPrivateKeyInfo
+	_, err := asn1.Unmarshal(block.Bytes, &info)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted private key: %v", err)
+	}
+
+	var pbes2 pbes2Parameters
+	var kdfParameters pbkdf2Parameters
+	var iv []byte
+	if !info.EncryptionAlgorithm.Algorithm.Equal(oidPbes2) {
+		return nil, fmt.Errorf("unsupported encryption %v", info.EncryptionAlgorithm.Algorithm)
+	}
+
+	_, err = asn1.Unmarshal(info.EncryptionAlgorithm.Parameters.FullBytes, &pbes2)
+	if err == nil {
+		_, err = asn1.Unmarshal(pbes2.KeyDerivationFunc.Parameters.FullBytes, &kdfParameters)
+	}
+	if err == nil {
+		_, err = asn1.Unmarshal(pbes2.EncryptionScheme.Parameters.FullBytes, &iv)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption parameters: %v", err)
+	}
+
+	if !pbes2.KeyDerivationFunc.Algorithm.Equal(oidPbkdf2) || !kdfParameters.Prf.Algorithm.Equal(oidHmacWithSha256) ||
+		!pbes2.EncryptionScheme.Algorithm.Equal(oidAes256Cbc) {
+		return nil, fmt.Errorf("unsupported encryption, only PBKDF2 with HMAC-SHA256 and AES-256-CBC are supported")
+	}
+
+	if len(iv) != aes.BlockSize || len(info.EncryptedData) == 0 || len(info.EncryptedData)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("invalid encrypted private key")
+	}
+
+	aesBlock, err := aes.NewCipher(deriveKey(iPassword, kdfParameters.Salt, kdfParameters.IterationCount, aesKeySize))
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(aesBlock, iv).CryptBlocks(decrypted, info.EncryptedData)
+
+	/// a wrong password almost always gives invalid padding, otherwise the key does not parse
+	padding := int(decrypted[len(decrypted)-1])
+	if padding == 0 || padding > aes.BlockSize || !bytes.Equal(decrypted[len(decrypted)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("wrong password")
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(decrypted[:len(decrypted)-padding])
+	if err != nil {
+		return nil, fmt.Errorf("wrong password")
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key format")
+	}
+
+	return signer, nil
+}
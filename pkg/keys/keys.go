@@ -0,0 +1,108 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+)
+
+/// iKeyType is ecdsa (P-256), rsa (3072 bits) or ed25519. The chaincode only verifies RSA and ECDSA signatures,
+/// ed25519 keys cannot own nodes
+func GenerateKey(
+	iKeyType string,
+) (crypto.Signer, error) {
+	switch iKeyType {
+	case "ecdsa":
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "rsa":
+		return rsa.GenerateKey(rand.Reader, 3072)
+	case "ed25519":
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		return privateKey, err
+	default:
+		return nil, fmt.Errorf("unknown key type %s", iKeyType)
+	}
+}
+
+/// PKCS8 PEM, as written by sigchain keygen
+func EncodePrivateKey(
+	iPrivateKey crypto.Signer,
+) (string, error) {
+	privateKeyDer, err := x509.MarshalPKCS8PrivateKey(iPrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyDer})), nil
+}
+
+/// PKIX PEM, the form of the OwnerPublicKey of the nodes
+func EncodePublicKey(
+	iPublicKey crypto.PublicKey,
+) (string, error) {
+	publicKeyDer, err := x509.MarshalPKIXPublicKey(iPublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDer})), nil
+}
+
+/// iPrivateKeyPem is a PKCS8, PKCS1 RSA or SEC1 EC private key, see DecryptPrivateKey for encrypted keys
+func ParsePrivateKey(
+	iPrivateKeyPem string,
+) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(iPrivateKeyPem))
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key")
+	}
+
+	var privateKey interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	case encryptedPrivateKeyType:
+		return nil, fmt.Errorf("private key is encrypted")
+	default:
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key format")
+	}
+
+	return signer, nil
+}
+
+/// whether iPublicKey is the public key of iPrivateKey
+func IsMatching(
+	iPrivateKey crypto.Signer,
+	iPublicKey crypto.PublicKey,
+) bool {
+	publicKey, ok := iPrivateKey.Public().(interface{ Equal(crypto.PublicKey) bool })
+	return ok && publicKey.Equal(iPublicKey)
+}
+
+/// the fingerprint the chaincode indexes owners by, i.e. the hex SHA-256 of the public key exactly as stored
+/// in the nodes. Two encodings of the same key, or a key and its certificate, have different fingerprints
+func GetFingerprint(
+	iPublicKey string,
+) string {
+	hash := sha256.Sum256([]byte(iPublicKey))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,53 @@
+package proof
+
+import (
+	"encoding/json"
+	"fmt"
+	"sig_chain/chaincode/asset"
+	"sig_chain/pkg/client"
+	"time"
+)
+
+/// Provenance of a node as read from the ledger by the issuer, a service the verifiers trust. The issuer
+/// signature vouches for the bundle as a whole, and every node of it must still verify against its owner's
+/// signature
+type ProofBundle struct {
+	NodeId          string               `json:"NodeId"`
+	Provenance      asset.ProvenanceNode `json:"Provenance"`
+	IssuedTime      time.Time            `json:"IssuedTime"`
+	IssuerPublicKey string               `json:"IssuerPublicKey"`
+	Signature       []byte               `json:"Signature"` /// of the bundle without its signature, base64 in json as node signatures
+}
+
+/// the bytes the issuer signs, iBundle is left unchanged
+func (b *ProofBundle) getSignedBytes() ([]byte, error) {
+	bundle := *b
+	bundle.Signature = nil
+	return json.Marshal(bundle)
+}
+
+/// iProvenance is typically the result of client.Client.GetProvenance
+func MakeProofBundle(
+	iProvenance *asset.ProvenanceNode,
+	iIssuer client.Signer,
+	iIssuedTime time.Time,
+) (*ProofBundle, error) {
+	bundle := ProofBundle{
+		NodeId:          iProvenance.Material.Id,
+		Provenance:      *iProvenance,
+		IssuedTime:      iIssuedTime.UTC(),
+		IssuerPublicKey: iIssuer.GetPublicKey(),
+	}
+
+	payload, err := bundle.getSignedBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	bundle.Signature, err = iIssuer.Sign(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign the proof bundle: %v", err)
+	}
+
+	return &bundle, nil
+}
@@ -0,0 +1,67 @@
+package proof
+
+import (
+	"fmt"
+	"net/url"
+)
+
+/// query parameter carrying the node id, as in the digital links of the chaincode
+const nodeIdParameter = "nodeId"
+
+/// Url is encoded in the QR code as is, so that any phone camera opens the proof bundle of NodeId
+type QrPayload struct {
+	NodeId string `json:"NodeId"`
+	Url    string `json:"Url"`
+}
+
+/// iBundleUrl is where the proof bundles are served, such as the /proofs route of the gateway
+func MakeQrPayload(
+	iBundleUrl string,
+	iNodeId string,
+) (*QrPayload, error) {
+	if iNodeId == "" {
+		return nil, fmt.Errorf("node id cannot be empty")
+	}
+
+	bundleUrl, err := url.Parse(iBundleUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if (bundleUrl.Scheme != "https" && bundleUrl.Scheme != "http") || bundleUrl.Host == "" {
+		return nil, fmt.Errorf("bundle url must be an http or https url")
+	}
+
+	query := bundleUrl.Query()
+	query.Set(nodeIdParameter, iNodeId)
+	bundleUrl.RawQuery = query.Encode()
+
+	return &QrPayload{
+		NodeId: iNodeId,
+		Url:    bundleUrl.String(),
+	}, nil
+}
+
+/// iPayload is the text read from the QR code
+func ParseQrPayload(
+	iPayload string,
+) (*QrPayload, error) {
+	payloadUrl, err := url.Parse(iPayload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid QR payload: %v", err)
+	}
+
+	if (payloadUrl.Scheme != "https" && payloadUrl.Scheme != "http") || payloadUrl.Host == "" {
+		return nil, fmt.Errorf("invalid QR payload: not an http or https url")
+	}
+
+	nodeId := payloadUrl.Query().Get(nodeIdParameter)
+	if nodeId == "" {
+		return nil, fmt.Errorf("invalid QR payload: no %s parameter", nodeIdParameter)
+	}
+
+	return &QrPayload{
+		NodeId: nodeId,
+		Url:    iPayload,
+	}, nil
+}
@@ -0,0 +1,216 @@
+package proof
+
+import (
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sig_chain/chaincode/asset"
+	"sig_chain/chaincode/graph"
+	"time"
+)
+
+type NodeStatus = string
+
+const (
+	eValidNode    NodeStatus = "eValidNode"
+	eInvalidNode  NodeStatus = "eInvalidNode"  /// the signature does not match the node
+	eUnlinkedNode NodeStatus = "eUnlinkedNode" /// an input is not among the previous nodes of the material
+)
+
+/// bundles larger than this are rejected rather than read
+const maxBundleSize = 16 << 20
+
+type NodeVerification struct {
+	NodeId string     `json:"NodeId"`
+	Type   string     `json:"Type"`
+	Status NodeStatus `json:"Status"`
+	Error  string     `json:"Error"` /// empty for valid nodes
+}
+
+/// IsVerified is set if no node of the bundle is invalid or unlinked
+type Verification struct {
+	NodeId          string             `json:"NodeId"`
+	IssuerPublicKey string             `json:"IssuerPublicKey"`
+	IssuedTime      time.Time          `json:"IssuedTime"`
+	IsVerified      bool               `json:"IsVerified"`
+	Nodes           []NodeVerification `json:"Nodes"`
+}
+
+/// compares parsed keys so that the PEM encoding and a certificate holding the key do not matter
+func isSameKey(
+	iPublicKey string,
+	iOtherPublicKey string,
+) bool {
+	publicKey, err := graph.ParsePublicKey(iPublicKey)
+	if err != nil {
+		return false
+	}
+
+	otherPublicKey, err := graph.ParsePublicKey(iOtherPublicKey)
+	if err != nil {
+		return false
+	}
+
+	comparable, ok := publicKey.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && comparable.Equal(otherPublicKey)
+}
+
+func verifyNode(
+	iNode graph.NodeI,
+) NodeVerification {
+	header := iNode.GetHeader()
+	verification := NodeVerification{
+		NodeId: header.Id,
+		Type:   header.Type,
+		Status: eValidNode,
+	}
+
+	err := graph.VerifyNodeSignature(iNode)
+	if err != nil {
+		verification.Status = eInvalidNode
+		verification.Error = err.Error()
+	}
+
+	return verification
+}
+
+/// appends the verifications of the materials and certificates of iProvenance to oNodes, shared ancestors and
+/// certificates are only verified once
+func verifyProvenanceNode(
+	iProvenance *asset.ProvenanceNode,
+	ioVisited map[string]bool,
+	oNodes *[]NodeVerification,
+) {
+	material := iProvenance.Material
+	if !ioVisited[material.Id] {
+		ioVisited[material.Id] = true
+		verification := verifyNode(&material)
+
+		/// the hashes of the inputs are covered by the signature of the material, which links it to them
+		for i := range iProvenance.Inputs {
+			inputId := iProvenance.Inputs[i].Material.Id
+			if verification.Status != eInvalidNode && !material.PreviousNodeHashedIds.Contains(graph.HashId(inputId)) {
+				verification.Status = eUnlinkedNode
+				verification.Error = fmt.Sprintf("%s is not an input of the material", inputId)
+			}
+		}
+		*oNodes = append(*oNodes, verification)
+	}
+
+	for i := range iProvenance.Certificates {
+		certificate := iProvenance.Certificates[i]
+		if !ioVisited[certificate.Id] {
+			ioVisited[certificate.Id] = true
+			*oNodes = append(*oNodes, verifyNode(&certificate))
+		}
+	}
+
+	for i := range iProvenance.Inputs {
+		verifyProvenanceNode(&iProvenance.Inputs[i], ioVisited, oNodes)
+	}
+}
+
+/// returns an error if iBundle is not signed by one of iTrustedIssuers, which are PEM public keys or
+/// certificates. The nodes of the bundle are verified one by one in the returned Verification
+func VerifyProofBundle(
+	iBundle *ProofBundle,
+	iTrustedIssuers []string,
+) (*Verification, error) {
+	isTrusted := false
+	for _, issuer := range iTrustedIssuers {
+		if isSameKey(issuer, iBundle.IssuerPublicKey) {
+			isTrusted = true
+			break
+		}
+	}
+
+	if !isTrusted {
+		return nil, fmt.Errorf("issuer of the proof bundle is not trusted")
+	}
+
+	payload, err := iBundle.getSignedBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = graph.VerifySignature(iBundle.IssuerPublicKey, payload, graph.EncodeSignature(iBundle.Signature))
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature of the proof bundle: %v", err)
+	}
+
+	if iBundle.Provenance.Material.Id != iBundle.NodeId {
+		return nil, fmt.Errorf("proof bundle of %s holds the provenance of %s", iBundle.NodeId, iBundle.Provenance.Material.Id)
+	}
+
+	verification := Verification{
+		NodeId:          iBundle.NodeId,
+		IssuerPublicKey: iBundle.IssuerPublicKey,
+		IssuedTime:      iBundle.IssuedTime,
+		IsVerified:      true,
+		Nodes:           []NodeVerification{},
+	}
+	verifyProvenanceNode(&iBundle.Provenance, map[string]bool{}, &verification.Nodes)
+
+	for _, node := range verification.Nodes {
+		if node.Status == eInvalidNode || node.Status == eUnlinkedNode {
+			verification.IsVerified = false
+		}
+	}
+
+	return &verification, nil
+}
+
+/// Fetches and verifies the proof bundles QR codes point to, for scan to verify applications
+type Resolver struct {
+	httpClient     *http.Client
+	trustedIssuers []string
+}
+
+/// iTrustedIssuers are the PEM public keys or certificates of the services whose bundles are accepted
+func MakeResolver(
+	iTrustedIssuers []string,
+) *Resolver {
+	return &Resolver{
+		httpClient:     &http.Client{Timeout: 30 * time.Second},
+		trustedIssuers: iTrustedIssuers,
+	}
+}
+
+/// iPayload is the text read from the QR code
+func (r *Resolver) Resolve(
+	iPayload string,
+) (*ProofBundle, *Verification, error) {
+	payload, err := ParseQrPayload(iPayload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	response, err := r.httpClient.Get(payload.Url)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch the proof bundle: %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("failed to fetch the proof bundle: %s", response.Status)
+	}
+
+	var bundle ProofBundle
+	err = json.NewDecoder(io.LimitReader(response.Body, maxBundleSize)).Decode(&bundle)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid proof bundle: %v", err)
+	}
+
+	if bundle.NodeId != payload.NodeId {
+		return nil, nil, fmt.Errorf("proof bundle is for %s rather than %s", bundle.NodeId, payload.NodeId)
+	}
+
+	verification, err := VerifyProofBundle(&bundle, r.trustedIssuers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &bundle, verification, nil
+}
@@ -0,0 +1,219 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+/// A4 in points, the unit of PDF
+const (
+	pageWidth  = 595
+	pageHeight = 842
+	margin     = 56
+)
+
+type textStyle = string
+
+const (
+	eTitleStyle   textStyle = "eTitleStyle"
+	eHeadingStyle textStyle = "eHeadingStyle"
+	eBodyStyle    textStyle = "eBodyStyle"
+	eSmallStyle   textStyle = "eSmallStyle"
+)
+
+type font struct {
+	name     string /// resource name in the content streams
+	size     float64
+	leading  float64 /// space taken by a line
+	isSpaced bool    /// lines of the style are preceded by a blank
+}
+
+var fonts = map[textStyle]font{
+	eTitleStyle:   {name: "F2", size: 18, leading: 26},
+	eHeadingStyle: {name: "F2", size: 12, leading: 18, isSpaced: true},
+	eBodyStyle:    {name: "F1", size: 10, leading: 14},
+	eSmallStyle:   {name: "F1", size: 8, leading: 11},
+}
+
+/// Text only PDF with the standard Helvetica fonts, which every reader has so that no font is embedded
+type document struct {
+	pages []*bytes.Buffer /// content streams
+	y     float64         /// baseline of the next line on the last page
+}
+
+func makeDocument() *document {
+	d := document{}
+	d.addPage()
+	return &d
+}
+
+func (d *document) addPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pageHeight - margin
+}
+
+/// the standard fonts use WinAnsiEncoding, characters outside of Latin-1 are replaced
+func escapeText(
+	iText string,
+) string {
+	var builder strings.Builder
+	for _, character := range iText {
+		switch {
+		case character == '(' || character == ')' || character == '\\':
+			builder.WriteByte('\\')
+			builder.WriteRune(character)
+		case character < ' ' || character > 0xff || (character >= 0x7f && character < 0xa0):
+			builder.WriteByte('?')
+		default:
+			builder.WriteByte(byte(character))
+		}
+	}
+
+	return builder.String()
+}
+
+/// splits iText at spaces so that lines are at most iMaxLength characters, longer words such as hashes are cut
+func wrapText(
+	iText string,
+	iMaxLength int,
+) []string {
+	lines := []string{}
+	line := ""
+	for _, word := range strings.Fields(iText) {
+		for len([]rune(word)) > iMaxLength {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			lines = append(lines, string([]rune(word)[:iMaxLength]))
+			word = string([]rune(word)[iMaxLength:])
+		}
+
+		if line == "" {
+			line = word
+		} else if len([]rune(line))+1+len([]rune(word)) <= iMaxLength {
+			line += " " + word
+		} else {
+			lines = append(lines, line)
+			line = word
+		}
+	}
+
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+/// iIndent is in levels of 12 points
+func (d *document) addText(
+	iText string,
+	iStyle textStyle,
+	iIndent int,
+) {
+	font := fonts[iStyle]
+	x := float64(margin + 12*iIndent)
+
+	/// Helvetica characters are at most 0.56 em wide but for a few capitals, which is close enough for wrapping
+	maxLength := int((pageWidth - margin - x) / (font.size * 0.56))
+
+	if font.isSpaced && d.y < pageHeight-margin {
+		d.y -= font.leading / 2
+	}
+
+	for _, line := range wrapText(iText, maxLength) {
+		if d.y-font.leading < margin {
+			d.addPage()
+		}
+		d.y -= font.leading
+
+		fmt.Fprintf(d.pages[len(d.pages)-1], "BT /%s %g Tf %g %g Td (%s) Tj ET\n", font.name, font.size, x, d.y, escapeText(line))
+	}
+}
+
+/// Signature field of the document, reserved when the document is written and filled once its bytes are known
+type signatureField struct {
+	name        string
+	signingTime string /// PDF date
+	size        int    /// of the reserved signature, in bytes
+}
+
+const byteRangePlaceholder = "/ByteRange [0 0000000000 0000000000 0000000000]"
+
+/// returns the PDF bytes, the signature of iSignature is left as zeros to be filled by signDocument
+func (d *document) write(
+	iTitle string,
+	iSignature *signatureField,
+) []byte {
+	pageCount := len(d.pages)
+
+	/// 1 catalog, 2 page tree, 3 and 4 fonts, 5 info, then a page and its content for each page, then the
+	/// signature and its widget
+	objects := make([]string, 5+2*pageCount)
+	getPageId := func(iIndex int) int { return 6 + 2*iIndex }
+	signatureId, widgetId := 6+2*pageCount, 7+2*pageCount
+
+	catalog := "<< /Type /Catalog /Pages 2 0 R"
+	if iSignature != nil {
+		catalog += fmt.Sprintf(" /AcroForm << /Fields [%d 0 R] /SigFlags 3 >>", widgetId)
+	}
+	objects[0] = catalog + " >>"
+
+	kids := []string{}
+	for i := 0; i < pageCount; i++ {
+		kids = append(kids, fmt.Sprintf("%d 0 R", getPageId(i)))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pageCount)
+	objects[2] = "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>"
+	objects[3] = "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>"
+	objects[4] = fmt.Sprintf("<< /Title (%s) /Producer (sig_chain) >>", escapeText(iTitle))
+
+	for i, content := range d.pages {
+		/// page numbers are only known once every page is laid out
+		footer := fmt.Sprintf("Page %d of %d", i+1, pageCount)
+		fmt.Fprintf(content, "BT /F1 8 Tf %d %d Td (%s) Tj ET\n", margin, margin/2, footer)
+
+		annotations := ""
+		if iSignature != nil && i == 0 {
+			annotations = fmt.Sprintf(" /Annots [%d 0 R]", widgetId)
+		}
+
+		objects[getPageId(i)-1] = fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R%s >>",
+			pageWidth, pageHeight, getPageId(i)+1, annotations,
+		)
+		objects[getPageId(i)] = fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String())
+	}
+
+	if iSignature != nil {
+		objects = append(objects,
+			fmt.Sprintf(
+				"<< /Type /Sig /Filter /Adobe.PPKLite /SubFilter /adbe.pkcs7.detached %s /Contents <%s> /M (%s) /Name (%s) >>",
+				byteRangePlaceholder, strings.Repeat("0", 2*iSignature.size), iSignature.signingTime, escapeText(iSignature.name),
+			),
+			fmt.Sprintf(
+				"<< /Type /Annot /Subtype /Widget /FT /Sig /T (Signature) /Rect [0 0 0 0] /F 132 /V %d 0 R /P %d 0 R >>",
+				signatureId, getPageId(0),
+			),
+		)
+	}
+
+	/// the binary comment tells transfer tools that the file is not text
+	var output bytes.Buffer
+	output.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	offsets := []int{}
+	for i, object := range objects {
+		offsets = append(offsets, output.Len())
+		fmt.Fprintf(&output, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xrefOffset := output.Len()
+	fmt.Fprintf(&output, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&output, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&output, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xrefOffset)
+
+	return output.Bytes()
+}
@@ -0,0 +1,241 @@
+package report
+
+import (
+	"fmt"
+	"sig_chain/chaincode/asset"
+	"sig_chain/pkg/client"
+	"sig_chain/pkg/keys"
+	"sort"
+	"strings"
+	"time"
+)
+
+const dateFormat = "2006-01-02 15:04 MST"
+
+/// same as the chaincode indexes, shortened since it is only read by people
+func getOwnerFingerprint(
+	iPublicKey string,
+) string {
+	return keys.GetFingerprint(iPublicKey)[:16]
+}
+
+/// enum values are stored as e.g. eShipped
+func formatEnum(
+	iValue string,
+) string {
+	return strings.TrimPrefix(iValue, "e")
+}
+
+func formatDate(
+	iTime time.Time,
+) string {
+	if iTime.IsZero() {
+		return "-"
+	}
+
+	return iTime.UTC().Format(dateFormat)
+}
+
+func addField(
+	ioDocument *document,
+	iName string,
+	iValue string,
+) {
+	if iValue != "" {
+		ioDocument.addText(iName+": "+iValue, eBodyStyle, 1)
+	}
+}
+
+func addMaterial(
+	ioDocument *document,
+	iMaterial *asset.Material,
+) {
+	addField(ioDocument, "Id", iMaterial.Id)
+	addField(ioDocument, "Name", iMaterial.Name)
+	addField(ioDocument, "Quantity", iMaterial.Quantity+" "+iMaterial.Unit)
+	addField(ioDocument, "Lot number", iMaterial.LotNumber)
+	addField(ioDocument, "Batch number", iMaterial.BatchNumber)
+	addField(ioDocument, "GTIN", iMaterial.Gtin)
+	addField(ioDocument, "SSCC", iMaterial.Sscc)
+	addField(ioDocument, "Serial number", iMaterial.SerialNumber)
+	addField(ioDocument, "Grade", iMaterial.Grade)
+	if !iMaterial.ExpiryDate.IsZero() {
+		addField(ioDocument, "Expiry date", iMaterial.ExpiryDate.UTC().Format("2006-01-02"))
+	}
+	addField(ioDocument, "Created", formatDate(iMaterial.CreatedTime))
+	addField(ioDocument, "Owner", getOwnerFingerprint(iMaterial.OwnerPublicKey))
+
+	names := []string{}
+	for name := range iMaterial.Composition {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		addField(ioDocument, "Composition", fmt.Sprintf("%s %s%%", name, iMaterial.Composition[name]))
+	}
+}
+
+/// one line per material from iProvenance to its origins, with the custody events in between. Materials reached
+/// through several inputs are only detailed once
+func addCustodyChain(
+	ioDocument *document,
+	iProvenance *asset.ProvenanceNode,
+	iDepth int,
+	ioVisited map[string]bool,
+) {
+	material := iProvenance.Material
+	line := fmt.Sprintf("%s: %s, %s %s, owner %s, created %s", material.Id, material.Name, material.Quantity, material.Unit,
+		getOwnerFingerprint(material.OwnerPublicKey), formatDate(material.CreatedTime))
+	if ioVisited[material.Id] {
+		ioDocument.addText(line+", see above", eBodyStyle, iDepth)
+		return
+	}
+	ioVisited[material.Id] = true
+	ioDocument.addText(line, eBodyStyle, iDepth)
+
+	for _, event := range iProvenance.CustodyEvents {
+		location := event.LocationGln
+		if location == "" && event.Latitude != "" {
+			location = event.Latitude + ", " + event.Longitude
+		}
+		ioDocument.addText(fmt.Sprintf("%s %s at %s", formatDate(event.EventTime), formatEnum(event.EventType), location), eSmallStyle, iDepth+1)
+	}
+
+	for i := range iProvenance.Inputs {
+		addCustodyChain(ioDocument, &iProvenance.Inputs[i], iDepth+1, ioVisited)
+	}
+}
+
+func formatCertificateStatus(
+	iStatuses []asset.CertificateStatus,
+	iIndex int,
+) string {
+	if iIndex >= len(iStatuses) {
+		return "unknown"
+	}
+
+	if iStatuses[iIndex].IsValid {
+		return "valid"
+	}
+
+	return "not valid (" + iStatuses[iIndex].Reason + ")"
+}
+
+func addCertificates(
+	ioDocument *document,
+	iProvenance *asset.ProvenanceNode,
+	ioVisited map[string]bool,
+	ioCount *int,
+) {
+	for i, certificate := range iProvenance.Certificates {
+		key := iProvenance.Material.Id + "/" + certificate.Id
+		if ioVisited[key] {
+			continue
+		}
+		ioVisited[key] = true
+		*ioCount++
+
+		ioDocument.addText(fmt.Sprintf("%s certificate %s of %s", certificate.CertificateType, certificate.Id, iProvenance.Material.Id), eBodyStyle, 1)
+		ioDocument.addText(fmt.Sprintf("Issued by %s on %s, expires %s", certificate.IssuerId, formatDate(certificate.IssueTime), formatDate(certificate.ExpiryTime)), eSmallStyle, 2)
+		ioDocument.addText(fmt.Sprintf("Status: %s, at the creation of the material: %s",
+			formatCertificateStatus(iProvenance.CertificateStatuses, i), formatCertificateStatus(iProvenance.CertificateStatusesAtCreation, i)), eSmallStyle, 2)
+	}
+
+	for i := range iProvenance.Inputs {
+		addCertificates(ioDocument, &iProvenance.Inputs[i], ioVisited, ioCount)
+	}
+}
+
+func hasDocuments(
+	iProvenance *asset.ProvenanceNode,
+) bool {
+	if len(iProvenance.Documents) > 0 {
+		return true
+	}
+
+	for i := range iProvenance.Inputs {
+		if hasDocuments(&iProvenance.Inputs[i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func addDocuments(
+	ioDocument *document,
+	iProvenance *asset.ProvenanceNode,
+	ioVisited map[string]bool,
+) {
+	for _, attachment := range iProvenance.Documents {
+		if ioVisited[attachment.DocHash] {
+			continue
+		}
+		ioVisited[attachment.DocHash] = true
+
+		ioDocument.addText(fmt.Sprintf("%s of %s, attached %s", formatEnum(attachment.DocType), iProvenance.Material.Id, formatDate(attachment.AttachedTime)), eBodyStyle, 1)
+		ioDocument.addText("SHA-256 "+attachment.DocHash, eSmallStyle, 2)
+		if attachment.Uri != "" {
+			ioDocument.addText(attachment.Uri, eSmallStyle, 2)
+		}
+	}
+
+	for i := range iProvenance.Inputs {
+		addDocuments(ioDocument, &iProvenance.Inputs[i], ioVisited)
+	}
+}
+
+/// Renders iProvenance into a PDF, signed by iSigner unless it is nil. The public key of iSigner must be a
+/// certificate, such as a Fabric enrollment certificate, so that PDF readers can show who signed the document
+func RenderProvenance(
+	iProvenance *asset.ProvenanceNode,
+	iSigner client.Signer,
+	iTime time.Time,
+) ([]byte, error) {
+	title := "Provenance certificate of " + iProvenance.Material.Id
+
+	document := makeDocument()
+	document.addText("Provenance certificate", eTitleStyle, 0)
+	document.addText(fmt.Sprintf("Issued %s from the sig_chain ledger. Owners are identified by the fingerprint of their public key.", formatDate(iTime)), eSmallStyle, 0)
+
+	document.addText("Material", eHeadingStyle, 0)
+	addMaterial(document, &iProvenance.Material)
+
+	document.addText("Chain of custody", eHeadingStyle, 0)
+	addCustodyChain(document, iProvenance, 1, map[string]bool{})
+
+	document.addText("Certificates", eHeadingStyle, 0)
+	certificateCount := 0
+	addCertificates(document, iProvenance, map[string]bool{}, &certificateCount)
+	if certificateCount == 0 {
+		document.addText("No certificate is attached to the material or its inputs", eBodyStyle, 1)
+	}
+
+	if hasDocuments(iProvenance) {
+		document.addText("Documents", eHeadingStyle, 0)
+		addDocuments(document, iProvenance, map[string]bool{})
+	}
+
+	if iSigner == nil {
+		return document.write(title, nil), nil
+	}
+
+	certificate, err := getSignerCertificate(iSigner)
+	if err != nil {
+		return nil, err
+	}
+
+	pdf := document.write(title, &signatureField{
+		name:        certificate.Subject.CommonName,
+		signingTime: formatPdfTime(iTime),
+		size:        signatureSize,
+	})
+
+	err = signDocument(pdf, iSigner, certificate, iTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return pdf, nil
+}
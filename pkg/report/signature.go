@@ -0,0 +1,241 @@
+package report
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"sig_chain/pkg/client"
+	"sig_chain/pkg/signing"
+	"sort"
+	"time"
+)
+
+/// reserved for the CMS signature, which holds the certificate of the signer
+const signatureSize = 16384
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidSha256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSha512        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	oidEcdsaSha256   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidRsaSha512     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+)
+
+/// CMS structures of RFC 5652, limited to a detached signature by a single signer
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue /// [0] EXPLICIT
+}
+
+type encapsulatedContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+type signerInfo struct {
+	Version            int
+	Sid                issuerAndSerialNumber
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttributes   asn1.RawValue /// [0] IMPLICIT
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     asn1.RawValue /// [0] IMPLICIT
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+/// the algorithms matching signing.GetHash, which the signers of the client use
+func getAlgorithms(
+	iPublicKey crypto.PublicKey,
+) (crypto.Hash, pkix.AlgorithmIdentifier, pkix.AlgorithmIdentifier, error) {
+	hash, err := signing.GetHash(iPublicKey)
+	if err != nil {
+		return 0, pkix.AlgorithmIdentifier{}, pkix.AlgorithmIdentifier{}, err
+	}
+
+	switch iPublicKey.(type) {
+	case *rsa.PublicKey:
+		return hash, pkix.AlgorithmIdentifier{Algorithm: oidSha512, Parameters: asn1.NullRawValue},
+			pkix.AlgorithmIdentifier{Algorithm: oidRsaSha512, Parameters: asn1.NullRawValue}, nil
+	case *ecdsa.PublicKey:
+		return hash, pkix.AlgorithmIdentifier{Algorithm: oidSha256}, pkix.AlgorithmIdentifier{Algorithm: oidEcdsaSha256}, nil
+	default:
+		return 0, pkix.AlgorithmIdentifier{}, pkix.AlgorithmIdentifier{}, fmt.Errorf("unsupported key format")
+	}
+}
+
+func makeAttribute(
+	iType asn1.ObjectIdentifier,
+	iValue interface{},
+) ([]byte, error) {
+	value, err := asn1.Marshal(iValue)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(attribute{Type: iType, Values: []asn1.RawValue{{FullBytes: value}}})
+}
+
+/// returns the DER SET of the attributes, which is what is signed. DER sorts the elements of a set
+func makeSignedAttributes(
+	iDigest []byte,
+	iSigningTime time.Time,
+) ([]byte, error) {
+	attributes := [][]byte{}
+	for _, value := range []struct {
+		oid   asn1.ObjectIdentifier
+		value interface{}
+	}{
+		{oidContentType, oidData},
+		{oidSigningTime, iSigningTime.UTC()},
+		{oidMessageDigest, iDigest},
+	} {
+		attribute, err := makeAttribute(value.oid, value.value)
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, attribute)
+	}
+
+	sort.Slice(attributes, func(i, j int) bool {
+		return bytes.Compare(attributes[i], attributes[j]) < 0
+	})
+
+	return asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: bytes.Join(attributes, nil)})
+}
+
+/// the public key of the signer must be a certificate, PDF readers need it to show who signed
+func getSignerCertificate(
+	iSigner client.Signer,
+) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(iSigner.GetPublicKey()))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("signing a PDF requires the certificate of the signer")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+/// detached CMS signature of iContent, as the adbe.pkcs7.detached sub filter expects
+func makeCmsSignature(
+	iContent []byte,
+	iSigner client.Signer,
+	iCertificate *x509.Certificate,
+	iSigningTime time.Time,
+) ([]byte, error) {
+	hash, digestAlgorithm, signatureAlgorithm, err := getAlgorithms(iCertificate.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	hasher := hash.New()
+	hasher.Write(iContent)
+	signedAttributes, err := makeSignedAttributes(hasher.Sum(nil), iSigningTime)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := iSigner.Sign(signedAttributes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign the document: %v", err)
+	}
+
+	/// the signed attributes are signed as a SET but stored with the [0] IMPLICIT tag
+	storedAttributes := append([]byte{0xa0}, signedAttributes[1:]...)
+
+	signedDataDer, err := asn1.Marshal(signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{digestAlgorithm},
+		EncapContentInfo: encapsulatedContentInfo{ContentType: oidData},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: iCertificate.Raw},
+		SignerInfos: []signerInfo{{
+			Version: 1,
+			Sid: issuerAndSerialNumber{
+				Issuer:       asn1.RawValue{FullBytes: iCertificate.RawIssuer},
+				SerialNumber: iCertificate.SerialNumber,
+			},
+			DigestAlgorithm:    digestAlgorithm,
+			SignedAttributes:   asn1.RawValue{FullBytes: storedAttributes},
+			SignatureAlgorithm: signatureAlgorithm,
+			Signature:          signature,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signedDataDer},
+	})
+}
+
+/// PDF dates are D:YYYYMMDDHHmmSS followed by the offset
+func formatPdfTime(
+	iTime time.Time,
+) string {
+	return iTime.UTC().Format("D:20060102150405Z")
+}
+
+/// fills the byte range and the signature reserved by document.write, the signature covers the whole file
+/// but the signature value itself
+func signDocument(
+	ioPdf []byte,
+	iSigner client.Signer,
+	iCertificate *x509.Certificate,
+	iSigningTime time.Time,
+) error {
+	byteRangeStart := bytes.Index(ioPdf, []byte(byteRangePlaceholder))
+	if byteRangeStart == -1 {
+		return fmt.Errorf("document has no signature field")
+	}
+
+	contentsStart := bytes.Index(ioPdf[byteRangeStart:], []byte("/Contents <"))
+	if contentsStart == -1 {
+		return fmt.Errorf("document has no signature field")
+	}
+	contentsStart += byteRangeStart + len("/Contents ")
+	contentsEnd := contentsStart + 2*signatureSize + 2
+
+	byteRange := fmt.Sprintf("/ByteRange [0 %010d %010d %010d]", contentsStart, contentsEnd, len(ioPdf)-contentsEnd)
+	copy(ioPdf[byteRangeStart:], byteRange)
+
+	content := append(append([]byte{}, ioPdf[:contentsStart]...), ioPdf[contentsEnd:]...)
+	signature, err := makeCmsSignature(content, iSigner, iCertificate, iSigningTime)
+	if err != nil {
+		return err
+	}
+
+	if len(signature) > signatureSize {
+		return fmt.Errorf("signature of %d bytes does not fit in the %d reserved", len(signature), signatureSize)
+	}
+
+	copy(ioPdf[contentsStart+1:], hex.EncodeToString(signature))
+	return nil
+}
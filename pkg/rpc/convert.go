@@ -0,0 +1,149 @@
+package rpc
+
+import (
+	"encoding/json"
+	"sig_chain/chaincode/asset"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+)
+
+/// zero times are left unset
+func makeTimestamp(
+	iTime time.Time,
+) *timestamp.Timestamp {
+	if iTime.IsZero() {
+		return nil
+	}
+
+	return &timestamp.Timestamp{
+		Seconds: iTime.Unix(),
+		Nanos:   int32(iTime.Nanosecond()),
+	}
+}
+
+/// messages carry the raw signature bytes, signatures stored before they were base64 encoded are passed as they are
+func makeSignature(
+	iSignature string,
+) []byte {
+	signature, err := graph.DecodeSignature(iSignature)
+	if err != nil {
+		return []byte(iSignature)
+	}
+
+	return signature
+}
+
+func MakeNodeHeader(
+	iHeader *graph.NodeHeader,
+) *NodeHeader {
+	return &NodeHeader{
+		Id:                    iHeader.Id,
+		Type:                  iHeader.Type,
+		IsFinalized:           iHeader.IsFinalized,
+		PreviousNodeHashedIds: iHeader.PreviousNodeHashedIds,
+		NextNodeHashedIds:     iHeader.NextNodeHashedIds,
+		OwnerPublicKey:        iHeader.OwnerPublicKey,
+		CreatedTime:           makeTimestamp(iHeader.CreatedTime),
+		Signature:             makeSignature(iHeader.Signature),
+		SchemaVersion:         int32(iHeader.SchemaVersion),
+		NewOwnerPublicKey:     iHeader.NewOwnerPublicKey,
+	}
+}
+
+func MakeMaterial(
+	iMaterial *asset.Material,
+) *Material {
+	return &Material{
+		Header:            MakeNodeHeader(&iMaterial.NodeHeader),
+		Name:              iMaterial.Name,
+		Unit:              iMaterial.Unit,
+		Quantity:          iMaterial.Quantity,
+		LotNumber:         iMaterial.LotNumber,
+		BatchNumber:       iMaterial.BatchNumber,
+		ExpiryDate:        makeTimestamp(iMaterial.ExpiryDate),
+		Composition:       iMaterial.Composition,
+		TemplateId:        iMaterial.TemplateId,
+		Attributes:        iMaterial.Attributes,
+		Gtin:              iMaterial.Gtin,
+		Sscc:              iMaterial.Sscc,
+		Grade:             iMaterial.Grade,
+		SerialNumber:      iMaterial.SerialNumber,
+		PrivateCollection: iMaterial.PrivateCollection,
+		PrivateDataHash:   iMaterial.PrivateDataHash,
+	}
+}
+
+func MakeCertificate(
+	iCertificate *asset.Certificate,
+) *Certificate {
+	return &Certificate{
+		Header:                MakeNodeHeader(&iCertificate.NodeHeader),
+		CertificateType:       iCertificate.CertificateType,
+		SubjectId:             iCertificate.SubjectId,
+		IssueTime:             makeTimestamp(iCertificate.IssueTime),
+		ExpiryTime:            makeTimestamp(iCertificate.ExpiryTime),
+		IssuerId:              iCertificate.IssuerId,
+		PreviousCertificateId: iCertificate.PreviousCertificateId,
+		Scope: &CertificateScope{
+			MaterialNames: iCertificate.Scope.MaterialNames,
+			Origins:       iCertificate.Scope.Origins,
+			MaxQuantity:   iCertificate.Scope.MaxQuantity,
+			Unit:          iCertificate.Scope.Unit,
+		},
+		CoIssuerIds: iCertificate.CoIssuerIds,
+		Claims:      iCertificate.Claims,
+		Credential:  iCertificate.Credential,
+	}
+}
+
+func MakeDocument(
+	iDocument *asset.MaterialDocument,
+) *Document {
+	return &Document{
+		NodeId:       iDocument.NodeId,
+		DocHash:      iDocument.DocHash,
+		DocType:      iDocument.DocType,
+		Uri:          iDocument.Uri,
+		Signature:    makeSignature(iDocument.Signature),
+		AttachedTime: makeTimestamp(iDocument.AttachedTime),
+		TxId:         iDocument.TxId,
+	}
+}
+
+func makeProvenanceNode(
+	iProvenance *asset.ProvenanceNode,
+) *ProvenanceNode {
+	provenance := ProvenanceNode{
+		Material: MakeMaterial(&iProvenance.Material),
+	}
+
+	for i := range iProvenance.Certificates {
+		provenance.Certificates = append(provenance.Certificates, MakeCertificate(&iProvenance.Certificates[i]))
+	}
+
+	for i := range iProvenance.Documents {
+		provenance.Documents = append(provenance.Documents, MakeDocument(&iProvenance.Documents[i]))
+	}
+
+	for i := range iProvenance.Inputs {
+		provenance.Inputs = append(provenance.Inputs, makeProvenanceNode(&iProvenance.Inputs[i]))
+	}
+
+	return &provenance
+}
+
+/// only the root carries the json of the whole provenance
+func MakeProvenanceNode(
+	iProvenance *asset.ProvenanceNode,
+) (*ProvenanceNode, error) {
+	provenanceJson, err := json.Marshal(iProvenance)
+	if err != nil {
+		return nil, err
+	}
+
+	provenance := makeProvenanceNode(iProvenance)
+	provenance.Json = string(provenanceJson)
+	return provenance, nil
+}
@@ -0,0 +1,129 @@
+package rpc
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+)
+
+/// Messages of sigchain.proto. There is no protoc in the build so they are written by hand, the golang/protobuf
+/// runtime only needs the struct tags
+
+type NodeRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (m *NodeRequest) Reset()         { *m = NodeRequest{} }
+func (m *NodeRequest) String() string { return proto.CompactTextString(m) }
+func (*NodeRequest) ProtoMessage()    {}
+
+type NodeHeader struct {
+	Id                    string               `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type                  string               `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	IsFinalized           bool                 `protobuf:"varint,3,opt,name=is_finalized,json=isFinalized,proto3" json:"is_finalized,omitempty"`
+	PreviousNodeHashedIds []string             `protobuf:"bytes,4,rep,name=previous_node_hashed_ids,json=previousNodeHashedIds,proto3" json:"previous_node_hashed_ids,omitempty"`
+	NextNodeHashedIds     []string             `protobuf:"bytes,5,rep,name=next_node_hashed_ids,json=nextNodeHashedIds,proto3" json:"next_node_hashed_ids,omitempty"`
+	OwnerPublicKey        string               `protobuf:"bytes,6,opt,name=owner_public_key,json=ownerPublicKey,proto3" json:"owner_public_key,omitempty"`
+	CreatedTime           *timestamp.Timestamp `protobuf:"bytes,7,opt,name=created_time,json=createdTime,proto3" json:"created_time,omitempty"`
+	Signature             []byte               `protobuf:"bytes,8,opt,name=signature,proto3" json:"signature,omitempty"`
+	SchemaVersion         int32                `protobuf:"varint,9,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	NewOwnerPublicKey     string               `protobuf:"bytes,10,opt,name=new_owner_public_key,json=newOwnerPublicKey,proto3" json:"new_owner_public_key,omitempty"`
+}
+
+func (m *NodeHeader) Reset()         { *m = NodeHeader{} }
+func (m *NodeHeader) String() string { return proto.CompactTextString(m) }
+func (*NodeHeader) ProtoMessage()    {}
+
+type Material struct {
+	Header            *NodeHeader          `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	Name              string               `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Unit              string               `protobuf:"bytes,3,opt,name=unit,proto3" json:"unit,omitempty"`
+	Quantity          string               `protobuf:"bytes,4,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	LotNumber         string               `protobuf:"bytes,5,opt,name=lot_number,json=lotNumber,proto3" json:"lot_number,omitempty"`
+	BatchNumber       string               `protobuf:"bytes,6,opt,name=batch_number,json=batchNumber,proto3" json:"batch_number,omitempty"`
+	ExpiryDate        *timestamp.Timestamp `protobuf:"bytes,7,opt,name=expiry_date,json=expiryDate,proto3" json:"expiry_date,omitempty"`
+	Composition       map[string]string    `protobuf:"bytes,8,rep,name=composition,proto3" json:"composition,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	TemplateId        string               `protobuf:"bytes,9,opt,name=template_id,json=templateId,proto3" json:"template_id,omitempty"`
+	Attributes        map[string]string    `protobuf:"bytes,10,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Gtin              string               `protobuf:"bytes,11,opt,name=gtin,proto3" json:"gtin,omitempty"`
+	Sscc              string               `protobuf:"bytes,12,opt,name=sscc,proto3" json:"sscc,omitempty"`
+	Grade             string               `protobuf:"bytes,13,opt,name=grade,proto3" json:"grade,omitempty"`
+	SerialNumber      string               `protobuf:"bytes,14,opt,name=serial_number,json=serialNumber,proto3" json:"serial_number,omitempty"`
+	PrivateCollection string               `protobuf:"bytes,15,opt,name=private_collection,json=privateCollection,proto3" json:"private_collection,omitempty"`
+	PrivateDataHash   string               `protobuf:"bytes,16,opt,name=private_data_hash,json=privateDataHash,proto3" json:"private_data_hash,omitempty"`
+}
+
+func (m *Material) Reset()         { *m = Material{} }
+func (m *Material) String() string { return proto.CompactTextString(m) }
+func (*Material) ProtoMessage()    {}
+
+type CertificateScope struct {
+	MaterialNames []string `protobuf:"bytes,1,rep,name=material_names,json=materialNames,proto3" json:"material_names,omitempty"`
+	Origins       []string `protobuf:"bytes,2,rep,name=origins,proto3" json:"origins,omitempty"`
+	MaxQuantity   string   `protobuf:"bytes,3,opt,name=max_quantity,json=maxQuantity,proto3" json:"max_quantity,omitempty"`
+	Unit          string   `protobuf:"bytes,4,opt,name=unit,proto3" json:"unit,omitempty"`
+}
+
+func (m *CertificateScope) Reset()         { *m = CertificateScope{} }
+func (m *CertificateScope) String() string { return proto.CompactTextString(m) }
+func (*CertificateScope) ProtoMessage()    {}
+
+type Certificate struct {
+	Header                *NodeHeader          `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	CertificateType       string               `protobuf:"bytes,2,opt,name=certificate_type,json=certificateType,proto3" json:"certificate_type,omitempty"`
+	SubjectId             string               `protobuf:"bytes,3,opt,name=subject_id,json=subjectId,proto3" json:"subject_id,omitempty"`
+	IssueTime             *timestamp.Timestamp `protobuf:"bytes,4,opt,name=issue_time,json=issueTime,proto3" json:"issue_time,omitempty"`
+	ExpiryTime            *timestamp.Timestamp `protobuf:"bytes,5,opt,name=expiry_time,json=expiryTime,proto3" json:"expiry_time,omitempty"`
+	IssuerId              string               `protobuf:"bytes,6,opt,name=issuer_id,json=issuerId,proto3" json:"issuer_id,omitempty"`
+	PreviousCertificateId string               `protobuf:"bytes,7,opt,name=previous_certificate_id,json=previousCertificateId,proto3" json:"previous_certificate_id,omitempty"`
+	Scope                 *CertificateScope    `protobuf:"bytes,8,opt,name=scope,proto3" json:"scope,omitempty"`
+	CoIssuerIds           []string             `protobuf:"bytes,9,rep,name=co_issuer_ids,json=coIssuerIds,proto3" json:"co_issuer_ids,omitempty"`
+	Claims                map[string]string    `protobuf:"bytes,10,rep,name=claims,proto3" json:"claims,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Credential            string               `protobuf:"bytes,11,opt,name=credential,proto3" json:"credential,omitempty"`
+}
+
+func (m *Certificate) Reset()         { *m = Certificate{} }
+func (m *Certificate) String() string { return proto.CompactTextString(m) }
+func (*Certificate) ProtoMessage()    {}
+
+type Document struct {
+	NodeId       string               `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	DocHash      string               `protobuf:"bytes,2,opt,name=doc_hash,json=docHash,proto3" json:"doc_hash,omitempty"`
+	DocType      string               `protobuf:"bytes,3,opt,name=doc_type,json=docType,proto3" json:"doc_type,omitempty"`
+	Uri          string               `protobuf:"bytes,4,opt,name=uri,proto3" json:"uri,omitempty"`
+	Signature    []byte               `protobuf:"bytes,5,opt,name=signature,proto3" json:"signature,omitempty"`
+	AttachedTime *timestamp.Timestamp `protobuf:"bytes,6,opt,name=attached_time,json=attachedTime,proto3" json:"attached_time,omitempty"`
+	TxId         string               `protobuf:"bytes,7,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+}
+
+func (m *Document) Reset()         { *m = Document{} }
+func (m *Document) String() string { return proto.CompactTextString(m) }
+func (*Document) ProtoMessage()    {}
+
+type ProvenanceNode struct {
+	Material     *Material         `protobuf:"bytes,1,opt,name=material,proto3" json:"material,omitempty"`
+	Certificates []*Certificate    `protobuf:"bytes,2,rep,name=certificates,proto3" json:"certificates,omitempty"`
+	Documents    []*Document       `protobuf:"bytes,3,rep,name=documents,proto3" json:"documents,omitempty"`
+	Inputs       []*ProvenanceNode `protobuf:"bytes,4,rep,name=inputs,proto3" json:"inputs,omitempty"`
+	Json         string            `protobuf:"bytes,5,opt,name=json,proto3" json:"json,omitempty"`
+}
+
+func (m *ProvenanceNode) Reset()         { *m = ProvenanceNode{} }
+func (m *ProvenanceNode) String() string { return proto.CompactTextString(m) }
+func (*ProvenanceNode) ProtoMessage()    {}
+
+type TransactionRequest struct {
+	Function string   `protobuf:"bytes,1,opt,name=function,proto3" json:"function,omitempty"`
+	Args     [][]byte `protobuf:"bytes,2,rep,name=args,proto3" json:"args,omitempty"`
+}
+
+func (m *TransactionRequest) Reset()         { *m = TransactionRequest{} }
+func (m *TransactionRequest) String() string { return proto.CompactTextString(m) }
+func (*TransactionRequest) ProtoMessage()    {}
+
+type TransactionResponse struct {
+	Result []byte `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+}
+
+func (m *TransactionResponse) Reset()         { *m = TransactionResponse{} }
+func (m *TransactionResponse) String() string { return proto.CompactTextString(m) }
+func (*TransactionResponse) ProtoMessage()    {}
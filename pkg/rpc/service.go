@@ -0,0 +1,189 @@
+package rpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+const serviceName = "sigchain.SigChain"
+
+/// SigChain service of sigchain.proto
+type SigChainServer interface {
+	GetMaterial(iCtx context.Context, iRequest *NodeRequest) (*Material, error)
+	GetCertificate(iCtx context.Context, iRequest *NodeRequest) (*Certificate, error)
+	GetProvenance(iCtx context.Context, iRequest *NodeRequest) (*ProvenanceNode, error)
+	EvaluateTransaction(iCtx context.Context, iRequest *TransactionRequest) (*TransactionResponse, error)
+	SubmitTransaction(iCtx context.Context, iRequest *TransactionRequest) (*TransactionResponse, error)
+}
+
+/// iCall invokes the method of the server with the decoded request
+func makeUnaryHandler(
+	iMethod string,
+	iNewRequest func() interface{},
+	iCall func(iServer SigChainServer, iCtx context.Context, iRequest interface{}) (interface{}, error),
+) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: iMethod,
+		Handler: func(
+			iServer interface{},
+			iCtx context.Context,
+			iDecode func(interface{}) error,
+			iInterceptor grpc.UnaryServerInterceptor,
+		) (interface{}, error) {
+			request := iNewRequest()
+			err := iDecode(request)
+			if err != nil {
+				return nil, err
+			}
+
+			if iInterceptor == nil {
+				return iCall(iServer.(SigChainServer), iCtx, request)
+			}
+
+			info := grpc.UnaryServerInfo{
+				Server:     iServer,
+				FullMethod: "/" + serviceName + "/" + iMethod,
+			}
+			return iInterceptor(iCtx, request, &info, func(iCtx context.Context, iRequest interface{}) (interface{}, error) {
+				return iCall(iServer.(SigChainServer), iCtx, iRequest)
+			})
+		},
+	}
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*SigChainServer)(nil),
+	Methods: []grpc.MethodDesc{
+		makeUnaryHandler(
+			"GetMaterial",
+			func() interface{} { return &NodeRequest{} },
+			func(iServer SigChainServer, iCtx context.Context, iRequest interface{}) (interface{}, error) {
+				return iServer.GetMaterial(iCtx, iRequest.(*NodeRequest))
+			},
+		),
+		makeUnaryHandler(
+			"GetCertificate",
+			func() interface{} { return &NodeRequest{} },
+			func(iServer SigChainServer, iCtx context.Context, iRequest interface{}) (interface{}, error) {
+				return iServer.GetCertificate(iCtx, iRequest.(*NodeRequest))
+			},
+		),
+		makeUnaryHandler(
+			"GetProvenance",
+			func() interface{} { return &NodeRequest{} },
+			func(iServer SigChainServer, iCtx context.Context, iRequest interface{}) (interface{}, error) {
+				return iServer.GetProvenance(iCtx, iRequest.(*NodeRequest))
+			},
+		),
+		makeUnaryHandler(
+			"EvaluateTransaction",
+			func() interface{} { return &TransactionRequest{} },
+			func(iServer SigChainServer, iCtx context.Context, iRequest interface{}) (interface{}, error) {
+				return iServer.EvaluateTransaction(iCtx, iRequest.(*TransactionRequest))
+			},
+		),
+		makeUnaryHandler(
+			"SubmitTransaction",
+			func() interface{} { return &TransactionRequest{} },
+			func(iServer SigChainServer, iCtx context.Context, iRequest interface{}) (interface{}, error) {
+				return iServer.SubmitTransaction(iCtx, iRequest.(*TransactionRequest))
+			},
+		),
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "sigchain.proto",
+}
+
+func RegisterSigChainServer(
+	iServer *grpc.Server,
+	iImplementation SigChainServer,
+) {
+	iServer.RegisterService(&serviceDesc, iImplementation)
+}
+
+/// Go client of the service, clients of other languages are generated from sigchain.proto
+type SigChainClient struct {
+	connection *grpc.ClientConn
+}
+
+func MakeSigChainClient(
+	iConnection *grpc.ClientConn,
+) *SigChainClient {
+	return &SigChainClient{connection: iConnection}
+}
+
+func (c *SigChainClient) invoke(
+	iCtx context.Context,
+	iMethod string,
+	iRequest interface{},
+	oResponse interface{},
+) error {
+	return c.connection.Invoke(iCtx, "/"+serviceName+"/"+iMethod, iRequest, oResponse)
+}
+
+func (c *SigChainClient) GetMaterial(
+	iCtx context.Context,
+	iRequest *NodeRequest,
+) (*Material, error) {
+	var material Material
+	err := c.invoke(iCtx, "GetMaterial", iRequest, &material)
+	if err != nil {
+		return nil, err
+	}
+
+	return &material, nil
+}
+
+func (c *SigChainClient) GetCertificate(
+	iCtx context.Context,
+	iRequest *NodeRequest,
+) (*Certificate, error) {
+	var certificate Certificate
+	err := c.invoke(iCtx, "GetCertificate", iRequest, &certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &certificate, nil
+}
+
+func (c *SigChainClient) GetProvenance(
+	iCtx context.Context,
+	iRequest *NodeRequest,
+) (*ProvenanceNode, error) {
+	var provenance ProvenanceNode
+	err := c.invoke(iCtx, "GetProvenance", iRequest, &provenance)
+	if err != nil {
+		return nil, err
+	}
+
+	return &provenance, nil
+}
+
+func (c *SigChainClient) EvaluateTransaction(
+	iCtx context.Context,
+	iRequest *TransactionRequest,
+) (*TransactionResponse, error) {
+	var response TransactionResponse
+	err := c.invoke(iCtx, "EvaluateTransaction", iRequest, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+func (c *SigChainClient) SubmitTransaction(
+	iCtx context.Context,
+	iRequest *TransactionRequest,
+) (*TransactionResponse, error) {
+	var response TransactionResponse
+	err := c.invoke(iCtx, "SubmitTransaction", iRequest, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
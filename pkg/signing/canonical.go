@@ -0,0 +1,56 @@
+package signing
+
+import (
+	"encoding/json"
+	"sig_chain/chaincode/graph"
+	"time"
+)
+
+/// The chaincode verifies signatures against the output of Go's encoding/json, so other implementations must
+/// reproduce it byte for byte rather than produce any equivalent json:
+///   - fields are in the order of the struct declarations, not sorted, and embedded headers come first
+///   - map keys are sorted, e.g. Attributes and Composition
+///   - no whitespace, <, > and & are escaped as \u003c, \u003e and \u0026, other characters are kept as UTF-8
+///   - times are RFC 3339 with nanoseconds and without trailing zeros, in the zone they were passed in
+///   - empty hash sets are [], nil maps and slices are null
+///   - nodes created at the time of their transaction, with IsTransactionTime set, are signed with the zero time
+/// The test vectors of vectors.json show each of these cases
+
+/// the bytes the owner of iNode signs, i.e. the node with the header of graph.GetSignedHeader.
+/// iNode is left unchanged
+func GetNodeBytes(
+	iNode graph.NodeI,
+) ([]byte, error) {
+	originalHeader := iNode.GetHeader()
+	defer func() {
+		iNode.SetHeader(originalHeader)
+	}()
+
+	iNode.SetHeader(graph.GetSignedHeader(originalHeader))
+
+	return json.Marshal(iNode)
+}
+
+/// the bytes signed for the payloads which are not nodes, such as the administrator payloads.
+/// The signature field of iPayload must be cleared by the caller, as graph.VerifyPayload expects
+func GetPayloadBytes(
+	iPayload interface{},
+) ([]byte, error) {
+	return json.Marshal(iPayload)
+}
+
+/// the chaincode only accepts the times it queries in UTC and at a whole second, the nodes must be signed with
+/// their times in this form
+func NormalizeTime(
+	iTime time.Time,
+) time.Time {
+	return iTime.UTC().Truncate(time.Second)
+}
+
+/// the chaincode parses times as RFC 3339 and marshals them back in the nodes, times are normalized so that
+/// the signed json is the one the chaincode rebuilds
+func FormatTime(
+	iTime time.Time,
+) string {
+	return NormalizeTime(iTime).Format(time.RFC3339Nano)
+}
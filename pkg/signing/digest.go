@@ -0,0 +1,64 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"sig_chain/chaincode/graph"
+
+	/// registers the hashes for crypto.Hash.New
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+/// the hash graph.VerifySignature uses for the key type: SHA-512 for RSA with PKCS #1 v1.5 signatures,
+/// SHA-256 for ECDSA with ASN.1 signatures as Fabric identities sign
+func GetHash(
+	iPublicKey crypto.PublicKey,
+) (crypto.Hash, error) {
+	switch iPublicKey.(type) {
+	case *rsa.PublicKey:
+		return crypto.SHA512, nil
+	case *ecdsa.PublicKey:
+		return crypto.SHA256, nil
+	default:
+		return 0, fmt.Errorf("unsupported key format")
+	}
+}
+
+/// the digest of iMessage which is signed, iPublicKey is a PEM public key or certificate as stored in the nodes
+func GetDigest(
+	iPublicKey string,
+	iMessage []byte,
+) ([]byte, error) {
+	publicKey, err := graph.ParsePublicKey(iPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := GetHash(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	hasher := hash.New()
+	hasher.Write(iMessage)
+	return hasher.Sum(nil), nil
+}
+
+/// signs iMessage as graph.VerifySignature expects, iPrivateKey may be held in memory or by an HSM
+func Sign(
+	iPrivateKey crypto.Signer,
+	iMessage []byte,
+) ([]byte, error) {
+	hash, err := GetHash(iPrivateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	hasher := hash.New()
+	hasher.Write(iMessage)
+	return iPrivateKey.Sign(rand.Reader, hasher.Sum(nil), hash)
+}
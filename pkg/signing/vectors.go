@@ -0,0 +1,215 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"sig_chain/chaincode/asset"
+	"sig_chain/chaincode/graph"
+
+	_ "embed"
+)
+
+type VectorKind = string
+
+const (
+	eNodeVector    VectorKind = "eNodeVector"    /// Input is the node as stored on the ledger, with its signature
+	ePayloadVector VectorKind = "ePayloadVector" /// Input is the payload of type PayloadType with its signature field cleared
+)
+
+/// payloads which are not nodes, by the name of their type in the asset package
+var payloadTypes = map[string]func() interface{}{
+	"SettlementChaincode": func() interface{} { return &asset.SettlementChaincode{} },
+}
+
+/// The keys of the test vectors, they are public and must never own real nodes
+type VectorKey struct {
+	Name       string `json:"Name"`
+	PrivateKey string `json:"PrivateKey"` /// PKCS #8 PEM
+	PublicKey  string `json:"PublicKey"`
+}
+
+/// RSA signatures are deterministic and can be compared as is, ECDSA ones can only be verified
+type Vector struct {
+	Name           string          `json:"Name"`
+	Kind           VectorKind      `json:"Kind"`
+	PayloadType    string          `json:"PayloadType,omitempty"` /// only set for payload vectors
+	Input          json.RawMessage `json:"Input"`
+	CanonicalBytes string          `json:"CanonicalBytes"`
+	Digest         string          `json:"Digest"` /// hex
+	KeyName        string          `json:"KeyName"`
+	Signature      string          `json:"Signature"` /// hex of the raw bytes, the chaincode takes them base64 encoded
+}
+
+type VectorSet struct {
+	Keys    []VectorKey `json:"Keys"`
+	Vectors []Vector    `json:"Vectors"`
+}
+
+//go:embed vectors.json
+var vectorsJson []byte
+
+/// the vectors published in vectors.json, integrators in other languages should read the file instead
+func GetVectors() (*VectorSet, error) {
+	var vectors VectorSet
+	err := json.Unmarshal(vectorsJson, &vectors)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vectors, nil
+}
+
+func (s *VectorSet) getKey(
+	iName string,
+) (*VectorKey, error) {
+	for i := range s.Keys {
+		if s.Keys[i].Name == iName {
+			return &s.Keys[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown key %s", iName)
+}
+
+/// the canonical bytes of the input of a vector, nodes are unmarshalled into the type given by their Type
+func getVectorBytes(
+	iKind VectorKind,
+	iPayloadType string,
+	iInput []byte,
+) ([]byte, error) {
+	switch iKind {
+	case eNodeVector:
+		var header graph.NodeHeader
+		err := json.Unmarshal(iInput, &header)
+		if err != nil {
+			return nil, err
+		}
+
+		node, err := asset.MakeNode(header.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(iInput, node)
+		if err != nil {
+			return nil, err
+		}
+
+		return GetNodeBytes(node)
+	case ePayloadVector:
+		makePayload, ok := payloadTypes[iPayloadType]
+		if !ok {
+			return nil, fmt.Errorf("unknown payload type %s", iPayloadType)
+		}
+
+		payload := makePayload()
+		err := json.Unmarshal(iInput, payload)
+		if err != nil {
+			return nil, err
+		}
+
+		return GetPayloadBytes(payload)
+	default:
+		return nil, fmt.Errorf("invalid vector kind %s", iKind)
+	}
+}
+
+/// builds and signs a vector, used to extend vectors.json. iPayloadType is only used for payload vectors
+func MakeVector(
+	iName string,
+	iKind VectorKind,
+	iPayloadType string,
+	iInput []byte,
+	iKey *VectorKey,
+) (*Vector, error) {
+	canonicalBytes, err := getVectorBytes(iKind, iPayloadType, iInput)
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := GetDigest(iKey.PublicKey, canonicalBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode([]byte(iKey.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key of %s", iKey.Name)
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key format")
+	}
+
+	signature, err := Sign(signer, canonicalBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Vector{
+		Name:           iName,
+		Kind:           iKind,
+		PayloadType:    iPayloadType,
+		Input:          iInput,
+		CanonicalBytes: string(canonicalBytes),
+		Digest:         hex.EncodeToString(digest),
+		KeyName:        iKey.Name,
+		Signature:      hex.EncodeToString(signature),
+	}, nil
+}
+
+/// checks that this package still produces the published bytes and digests, and that the published signatures
+/// are accepted by graph.VerifySignature
+func CheckVectors() error {
+	vectors, err := GetVectors()
+	if err != nil {
+		return err
+	}
+
+	for _, vector := range vectors.Vectors {
+		key, err := vectors.getKey(vector.KeyName)
+		if err != nil {
+			return fmt.Errorf("vector %s: %v", vector.Name, err)
+		}
+
+		canonicalBytes, err := getVectorBytes(vector.Kind, vector.PayloadType, vector.Input)
+		if err != nil {
+			return fmt.Errorf("vector %s: %v", vector.Name, err)
+		}
+
+		if string(canonicalBytes) != vector.CanonicalBytes {
+			return fmt.Errorf("vector %s: canonical bytes are %s", vector.Name, canonicalBytes)
+		}
+
+		digest, err := GetDigest(key.PublicKey, canonicalBytes)
+		if err != nil {
+			return fmt.Errorf("vector %s: %v", vector.Name, err)
+		}
+
+		if hex.EncodeToString(digest) != vector.Digest {
+			return fmt.Errorf("vector %s: digest is %x", vector.Name, digest)
+		}
+
+		signature, err := hex.DecodeString(vector.Signature)
+		if err != nil {
+			return fmt.Errorf("vector %s: %v", vector.Name, err)
+		}
+
+		err = graph.VerifySignature(key.PublicKey, canonicalBytes, graph.EncodeSignature(signature))
+		if err != nil {
+			return fmt.Errorf("vector %s: %v", vector.Name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,14 @@
+package signing_test
+
+import (
+	"sig_chain/pkg/signing"
+	"testing"
+)
+
+/// the published vectors must keep matching the chaincode, integrators in other languages rely on them
+func TestVectors(t *testing.T) {
+	err := signing.CheckVectors()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,76 @@
+package testutil
+
+import (
+	"fmt"
+	"sig_chain/pkg/client"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+/// client.Contract running a chaincode on a MockLedger, so that the client and the tools built on it can be
+/// tested without a network. Submitted transactions are committed at once, evaluated ones are not
+type MockContract struct {
+	ledger    *MockLedger
+	chaincode shim.Chaincode
+	identity  *MockIdentity
+	txCount   int
+}
+
+var _ client.Contract = (*MockContract)(nil)
+
+/// iChaincode is typically the result of contractapi.NewChaincode, which requires iIdentity to have a certificate
+func MakeMockContract(
+	iLedger *MockLedger,
+	iChaincode shim.Chaincode,
+	iIdentity *MockIdentity,
+) *MockContract {
+	return &MockContract{
+		ledger:    iLedger,
+		chaincode: iChaincode,
+		identity:  iIdentity,
+	}
+}
+
+func (c *MockContract) invoke(
+	iName string,
+	iArgs []string,
+	iIsCommitted bool,
+) ([]byte, error) {
+	c.txCount++
+	stub := c.ledger.MakeStub(fmt.Sprintf("tx%d", c.txCount), time.Now(), append([]string{iName}, iArgs...)...)
+
+	creator, err := c.identity.GetCreator()
+	if err != nil {
+		return nil, err
+	}
+	stub.SetCreator(creator)
+
+	response := c.chaincode.Invoke(stub)
+	if response.Status >= shim.ERRORTHRESHOLD {
+		return nil, &client.TransactionError{Message: response.Message}
+	}
+
+	if iIsCommitted {
+		err = stub.Commit()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return response.Payload, nil
+}
+
+func (c *MockContract) SubmitTransaction(
+	iName string,
+	iArgs ...string,
+) ([]byte, error) {
+	return c.invoke(iName, iArgs, true)
+}
+
+func (c *MockContract) EvaluateTransaction(
+	iName string,
+	iArgs ...string,
+) ([]byte, error) {
+	return c.invoke(iName, iArgs, false)
+}
@@ -0,0 +1,128 @@
+package testutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"sig_chain/chaincode/graph"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-protos-go/msp"
+)
+
+/// Submitter of the transactions, Certificate can be nil for contracts which do not read it
+type MockIdentity struct {
+	Id          string
+	MspId       string
+	Attributes  map[string]string
+	Certificate *x509.Certificate
+}
+
+var _ cid.ClientIdentity = (*MockIdentity)(nil)
+
+/// MockIdentity with a self-signed certificate, which the contracts read to record the submitter of the
+/// transactions which write. iOrganizationalUnits are the OUs of its subject, e.g. "admin" for channel admins
+func MakeMockIdentity(
+	iId string,
+	iMspId string,
+	iOrganizationalUnits ...string,
+) (*MockIdentity, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	/// valid whatever the time of the stubs, which the tests choose
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: iId, OrganizationalUnit: iOrganizationalUnits},
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	certificateDer, err := x509.CreateCertificate(rand.Reader, &template, &template, privateKey.Public(), privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	certificate, err := x509.ParseCertificate(certificateDer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MockIdentity{
+		Id:          iId,
+		MspId:       iMspId,
+		Attributes:  map[string]string{},
+		Certificate: certificate,
+	}, nil
+}
+
+func (i *MockIdentity) GetID() (string, error) {
+	return i.Id, nil
+}
+
+func (i *MockIdentity) GetMSPID() (string, error) {
+	return i.MspId, nil
+}
+
+func (i *MockIdentity) GetAttributeValue(
+	iName string,
+) (string, bool, error) {
+	value, ok := i.Attributes[iName]
+	return value, ok, nil
+}
+
+func (i *MockIdentity) AssertAttributeValue(
+	iName string,
+	iValue string,
+) error {
+	value, ok := i.Attributes[iName]
+	if !ok {
+		return fmt.Errorf("attribute '%s' was not found", iName)
+	}
+
+	if value != iValue {
+		return fmt.Errorf("attribute '%s' equals '%s', not '%s'", iName, value, iValue)
+	}
+
+	return nil
+}
+
+func (i *MockIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return i.Certificate, nil
+}
+
+/// the creator of the transactions of iIdentity, as returned by GetCreator
+func (i *MockIdentity) GetCreator() ([]byte, error) {
+	serializedIdentity := msp.SerializedIdentity{Mspid: i.MspId}
+	if i.Certificate != nil {
+		serializedIdentity.IdBytes = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: i.Certificate.Raw})
+	}
+
+	return proto.Marshal(&serializedIdentity)
+}
+
+/// the context the contracts get from contractapi, so that the receipts list the written keys.
+/// The before and after transaction hooks are not called
+func MakeTransactionContext(
+	iStub *MockStub,
+	iIdentity *MockIdentity,
+) (*graph.TransactionContext, error) {
+	creator, err := iIdentity.GetCreator()
+	if err != nil {
+		return nil, err
+	}
+	iStub.SetCreator(creator)
+
+	ctx := graph.TransactionContext{}
+	ctx.SetStub(iStub)
+	ctx.SetClientIdentity(iIdentity)
+	return &ctx, nil
+}
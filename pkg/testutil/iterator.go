@@ -0,0 +1,51 @@
+package testutil
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+)
+
+type mockStateIterator struct {
+	kvs   []*queryresult.KV
+	index int
+}
+
+func (i *mockStateIterator) HasNext() bool {
+	return i.index < len(i.kvs)
+}
+
+func (i *mockStateIterator) Next() (*queryresult.KV, error) {
+	if !i.HasNext() {
+		return nil, fmt.Errorf("iterator is exhausted")
+	}
+
+	i.index++
+	return i.kvs[i.index-1], nil
+}
+
+func (i *mockStateIterator) Close() error {
+	return nil
+}
+
+type mockHistoryIterator struct {
+	modifications []*queryresult.KeyModification
+	index         int
+}
+
+func (i *mockHistoryIterator) HasNext() bool {
+	return i.index < len(i.modifications)
+}
+
+func (i *mockHistoryIterator) Next() (*queryresult.KeyModification, error) {
+	if !i.HasNext() {
+		return nil, fmt.Errorf("iterator is exhausted")
+	}
+
+	i.index++
+	return i.modifications[i.index-1], nil
+}
+
+func (i *mockHistoryIterator) Close() error {
+	return nil
+}
@@ -0,0 +1,138 @@
+package testutil
+
+import (
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+)
+
+/// In memory ledger for the tests of the contracts. Every transaction works on its own MockStub and its writes
+/// are only visible to the next transactions once committed, as on a peer
+type MockLedger struct {
+	state                map[string][]byte
+	privateData          map[string]map[string][]byte /// by collection
+	validationParameters map[string][]byte
+	history              map[string][]*queryresult.KeyModification /// oldest first
+	chaincodes           map[string]shim.Chaincode                 /// reachable through InvokeChaincode
+}
+
+func MakeMockLedger() *MockLedger {
+	return &MockLedger{
+		state:                map[string][]byte{},
+		privateData:          map[string]map[string][]byte{},
+		validationParameters: map[string][]byte{},
+		history:              map[string][]*queryresult.KeyModification{},
+		chaincodes:           map[string]shim.Chaincode{},
+	}
+}
+
+/// iChaincode shares the ledger, as chaincodes of the same channel do
+func (l *MockLedger) RegisterChaincode(
+	iName string,
+	iChaincode shim.Chaincode,
+) {
+	l.chaincodes[iName] = iChaincode
+}
+
+/// starts a transaction, iArgs are the function name followed by its arguments
+func (l *MockLedger) MakeStub(
+	iTxId string,
+	iTime time.Time,
+	iArgs ...string,
+) *MockStub {
+	args := [][]byte{}
+	for _, arg := range iArgs {
+		args = append(args, []byte(arg))
+	}
+
+	return &MockStub{
+		ledger:        l,
+		txId:          iTxId,
+		channelId:     MockChannelId,
+		args:          args,
+		timestamp:     &timestamp.Timestamp{Seconds: iTime.Unix(), Nanos: int32(iTime.Nanosecond())},
+		transient:     map[string][]byte{},
+		writes:        map[string][]byte{},
+		privateWrites: map[string]map[string][]byte{},
+
+		validationParameters: map[string][]byte{},
+	}
+}
+
+/// the committed value of iKey, nil if it does not exist
+func (l *MockLedger) GetState(
+	iKey string,
+) []byte {
+	return l.state[iKey]
+}
+
+/// the committed value of iKey in iCollection, nil if it does not exist
+func (l *MockLedger) GetPrivateData(
+	iCollection string,
+	iKey string,
+) []byte {
+	return l.privateData[iCollection][iKey]
+}
+
+/// the committed keys of the world state in order, composite keys included
+func (l *MockLedger) GetKeys() []string {
+	return getSortedKeys(l.state)
+}
+
+func getSortedKeys(
+	iValues map[string][]byte,
+) []string {
+	keys := []string{}
+	for key := range iValues {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+/// a nil value deletes the key
+func (l *MockLedger) commit(
+	iTxId string,
+	iTimestamp *timestamp.Timestamp,
+	iWrites map[string][]byte,
+	iPrivateWrites map[string]map[string][]byte,
+	iValidationParameters map[string][]byte,
+) {
+	for _, key := range getSortedKeys(iWrites) {
+		value := iWrites[key]
+		if value == nil {
+			delete(l.state, key)
+		} else {
+			l.state[key] = value
+		}
+
+		l.history[key] = append(l.history[key], &queryresult.KeyModification{
+			TxId:      iTxId,
+			Value:     value,
+			Timestamp: iTimestamp,
+			IsDelete:  value == nil,
+		})
+	}
+
+	for collection, writes := range iPrivateWrites {
+		if l.privateData[collection] == nil {
+			l.privateData[collection] = map[string][]byte{}
+		}
+
+		for key, value := range writes {
+			if value == nil {
+				delete(l.privateData[collection], key)
+			} else {
+				l.privateData[collection][key] = value
+			}
+		}
+	}
+
+	for key, parameter := range iValidationParameters {
+		l.validationParameters[key] = parameter
+	}
+}
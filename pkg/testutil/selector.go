@@ -0,0 +1,207 @@
+package testutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+/// the value of the dotted iField in iDocument, e.g. Scope.Unit
+func getField(
+	iDocument interface{},
+	iField string,
+) (interface{}, bool) {
+	value := iDocument
+	for _, name := range strings.Split(iField, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		value, ok = object[name]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+/// -1, 0 or 1 as CouchDB orders values of the same type, ok is false for values which cannot be ordered
+func compare(
+	iValue interface{},
+	iOther interface{},
+) (int, bool) {
+	switch value := iValue.(type) {
+	case float64:
+		other, ok := iOther.(float64)
+		if !ok {
+			return 0, false
+		}
+
+		switch {
+		case value < other:
+			return -1, true
+		case value > other:
+			return 1, true
+		default:
+			return 0, true
+		}
+	case string:
+		other, ok := iOther.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(value, other), true
+	case bool:
+		other, ok := iOther.(bool)
+		if !ok || value != other {
+			return 0, false
+		}
+		return 0, true
+	case nil:
+		return 0, iOther == nil
+	default:
+		return 0, false
+	}
+}
+
+/// applies the condition operator iOperator to the field value iValue, which may not exist
+func matchOperator(
+	iOperator string,
+	iArgument interface{},
+	iValue interface{},
+	iExists bool,
+) (bool, error) {
+	if iOperator == "$exists" {
+		exists, ok := iArgument.(bool)
+		if !ok {
+			return false, fmt.Errorf("$exists takes a boolean")
+		}
+		return exists == iExists, nil
+	}
+
+	if !iExists {
+		return iOperator == "$ne", nil
+	}
+
+	if iOperator == "$in" || iOperator == "$nin" {
+		candidates, ok := iArgument.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("%s takes an array", iOperator)
+		}
+
+		isFound := false
+		for _, candidate := range candidates {
+			if comparison, ok := compare(iValue, candidate); ok && comparison == 0 {
+				isFound = true
+				break
+			}
+		}
+		return isFound == (iOperator == "$in"), nil
+	}
+
+	comparison, ok := compare(iValue, iArgument)
+	switch iOperator {
+	case "$eq":
+		return ok && comparison == 0, nil
+	case "$ne":
+		return !ok || comparison != 0, nil
+	case "$gt":
+		return ok && comparison > 0, nil
+	case "$gte":
+		return ok && comparison >= 0, nil
+	case "$lt":
+		return ok && comparison < 0, nil
+	case "$lte":
+		return ok && comparison <= 0, nil
+	default:
+		return false, fmt.Errorf("operator %s is not supported by the mock", iOperator)
+	}
+}
+
+/// iCondition is either a value the field must equal or an object of operators
+func matchCondition(
+	iCondition interface{},
+	iValue interface{},
+	iExists bool,
+) (bool, error) {
+	operators, ok := iCondition.(map[string]interface{})
+	if !ok {
+		return matchOperator("$eq", iCondition, iValue, iExists)
+	}
+
+	for operator, argument := range operators {
+		if !strings.HasPrefix(operator, "$") {
+			return false, fmt.Errorf("nested field selectors are not supported by the mock, use dotted fields")
+		}
+
+		isMatching, err := matchOperator(operator, argument, iValue, iExists)
+		if err != nil || !isMatching {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+/// Subset of the CouchDB selectors: $and, $or, $nor and $not combinations of $eq, $ne, $gt, $gte, $lt, $lte,
+/// $in, $nin and $exists conditions on dotted fields. Arrays are compared as a whole
+func matchSelector(
+	iSelector map[string]interface{},
+	iDocument interface{},
+) (bool, error) {
+	for field, condition := range iSelector {
+		isMatching := false
+		var err error
+		switch field {
+		case "$and", "$or", "$nor":
+			selectors, ok := condition.([]interface{})
+			if !ok {
+				return false, fmt.Errorf("%s takes an array of selectors", field)
+			}
+
+			matchCount := 0
+			for _, selector := range selectors {
+				object, ok := selector.(map[string]interface{})
+				if !ok {
+					return false, fmt.Errorf("%s takes an array of selectors", field)
+				}
+
+				isSelectorMatching, err := matchSelector(object, iDocument)
+				if err != nil {
+					return false, err
+				}
+
+				if isSelectorMatching {
+					matchCount++
+				}
+			}
+
+			switch field {
+			case "$and":
+				isMatching = matchCount == len(selectors)
+			case "$or":
+				isMatching = matchCount > 0
+			default:
+				isMatching = matchCount == 0
+			}
+		case "$not":
+			selector, ok := condition.(map[string]interface{})
+			if !ok {
+				return false, fmt.Errorf("$not takes a selector")
+			}
+
+			isMatching, err = matchSelector(selector, iDocument)
+			isMatching = !isMatching
+		default:
+			value, exists := getField(iDocument, field)
+			isMatching, err = matchCondition(condition, value, exists)
+		}
+
+		if err != nil || !isMatching {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
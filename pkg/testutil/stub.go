@@ -0,0 +1,637 @@
+package testutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+const (
+	MockChannelId = "mockchannel"
+
+	compositeKeyNamespace = "\x00"
+	maxUnicodeRune        = "\U0010FFFF" /// ends the range of a partial composite key
+)
+
+/// Stub of a single transaction of a MockLedger. Reads return the committed state and do not see the writes of
+/// the transaction, which are applied by Commit
+type MockStub struct {
+	ledger               *MockLedger
+	txId                 string
+	channelId            string
+	args                 [][]byte
+	timestamp            *timestamp.Timestamp
+	creator              []byte
+	transient            map[string][]byte
+	writes               map[string][]byte            /// nil for deletions
+	privateWrites        map[string]map[string][]byte /// by collection, nil for deletions
+	validationParameters map[string][]byte
+	event                *pb.ChaincodeEvent
+	isCommitted          bool
+	hasWritten           bool
+	hasPaginatedQuery    bool
+}
+
+var _ shim.ChaincodeStubInterface = (*MockStub)(nil)
+
+/// iCreator is a serialized msp.SerializedIdentity, see MakeMockIdentity
+func (s *MockStub) SetCreator(
+	iCreator []byte,
+) {
+	s.creator = iCreator
+}
+
+func (s *MockStub) SetTransient(
+	iTransient map[string][]byte,
+) {
+	s.transient = iTransient
+}
+
+/// the last event set by the transaction, nil if there is none
+func (s *MockStub) GetEvent() *pb.ChaincodeEvent {
+	return s.event
+}
+
+/// as the peer, writes are refused in transactions which ran paginated queries
+func (s *MockStub) checkWrite() error {
+	if s.hasPaginatedQuery {
+		return fmt.Errorf("txid [%s]: unsupported transaction. Queries with pagination are not supported in a transaction that writes", s.txId)
+	}
+
+	s.hasWritten = true
+	return nil
+}
+
+/// as the peer, paginated queries are refused in transactions which wrote
+func (s *MockStub) checkPaginatedQuery() error {
+	if s.hasWritten {
+		return fmt.Errorf("txid [%s]: unsupported transaction. Paginated queries are supported only in a read-only transaction", s.txId)
+	}
+
+	s.hasPaginatedQuery = true
+	return nil
+}
+
+/// applies the writes of the transaction to the ledger, a stub can only be committed once
+func (s *MockStub) Commit() error {
+	if s.isCommitted {
+		return fmt.Errorf("transaction %s is already committed", s.txId)
+	}
+
+	s.ledger.commit(s.txId, s.timestamp, s.writes, s.privateWrites, s.validationParameters)
+	s.isCommitted = true
+	return nil
+}
+
+func (s *MockStub) GetArgs() [][]byte {
+	return s.args
+}
+
+func (s *MockStub) GetStringArgs() []string {
+	args := []string{}
+	for _, arg := range s.args {
+		args = append(args, string(arg))
+	}
+
+	return args
+}
+
+func (s *MockStub) GetFunctionAndParameters() (string, []string) {
+	args := s.GetStringArgs()
+	if len(args) == 0 {
+		return "", []string{}
+	}
+
+	return args[0], args[1:]
+}
+
+func (s *MockStub) GetArgsSlice() ([]byte, error) {
+	return bytes.Join(s.args, nil), nil
+}
+
+func (s *MockStub) GetTxID() string {
+	return s.txId
+}
+
+func (s *MockStub) GetChannelID() string {
+	return s.channelId
+}
+
+/// the invoked chaincode runs in the same transaction, its writes are committed with the ones of this stub
+func (s *MockStub) InvokeChaincode(
+	iChaincodeName string,
+	iArgs [][]byte,
+	iChannel string,
+) pb.Response {
+	if iChannel != "" && iChannel != s.channelId {
+		return shim.Error(fmt.Sprintf("channel %s is not mocked", iChannel))
+	}
+
+	chaincode, ok := s.ledger.chaincodes[iChaincodeName]
+	if !ok {
+		return shim.Error(fmt.Sprintf("chaincode %s is not registered", iChaincodeName))
+	}
+
+	stub := *s
+	stub.args = iArgs
+	return chaincode.Invoke(&stub)
+}
+
+func (s *MockStub) GetState(
+	iKey string,
+) ([]byte, error) {
+	return s.ledger.state[iKey], nil
+}
+
+func (s *MockStub) PutState(
+	iKey string,
+	iValue []byte,
+) error {
+	if iKey == "" {
+		return fmt.Errorf("key must not be an empty string")
+	}
+
+	if !utf8.ValidString(iKey) {
+		return fmt.Errorf("key %x is not valid UTF-8", iKey)
+	}
+
+	/// Fabric stores empty values as deletions
+	if len(iValue) == 0 {
+		return s.DelState(iKey)
+	}
+
+	err := s.checkWrite()
+	if err != nil {
+		return err
+	}
+
+	s.writes[iKey] = append([]byte{}, iValue...)
+	return nil
+}
+
+func (s *MockStub) DelState(
+	iKey string,
+) error {
+	err := s.checkWrite()
+	if err != nil {
+		return err
+	}
+
+	s.writes[iKey] = nil
+	return nil
+}
+
+func (s *MockStub) SetStateValidationParameter(
+	iKey string,
+	iParameter []byte,
+) error {
+	err := s.checkWrite()
+	if err != nil {
+		return err
+	}
+
+	s.validationParameters[iKey] = iParameter
+	return nil
+}
+
+func (s *MockStub) GetStateValidationParameter(
+	iKey string,
+) ([]byte, error) {
+	return s.ledger.validationParameters[iKey], nil
+}
+
+/// the committed kvs with a key in [iStartKey, iEndKey), an empty iEndKey has no upper bound
+func getRange(
+	iValues map[string][]byte,
+	iStartKey string,
+	iEndKey string,
+) []*queryresult.KV {
+	kvs := []*queryresult.KV{}
+	for _, key := range getSortedKeys(iValues) {
+		if key < iStartKey || (iEndKey != "" && key >= iEndKey) {
+			continue
+		}
+
+		kvs = append(kvs, &queryresult.KV{
+			Namespace: "mock",
+			Key:       key,
+			Value:     iValues[key],
+		})
+	}
+
+	return kvs
+}
+
+/// the bookmark is the key of the first kv of the next page
+func getPage(
+	iKvs []*queryresult.KV,
+	iPageSize int32,
+	iBookmark string,
+) ([]*queryresult.KV, *pb.QueryResponseMetadata) {
+	start := 0
+	if iBookmark != "" {
+		for start < len(iKvs) && iKvs[start].Key < iBookmark {
+			start++
+		}
+	}
+
+	end := len(iKvs)
+	bookmark := ""
+	if iPageSize > 0 && start+int(iPageSize) < end {
+		end = start + int(iPageSize)
+		bookmark = iKvs[end].Key
+	}
+
+	return iKvs[start:end], &pb.QueryResponseMetadata{
+		FetchedRecordsCount: int32(end - start),
+		Bookmark:            bookmark,
+	}
+}
+
+func isCompositeKey(
+	iKey string,
+) bool {
+	return strings.HasPrefix(iKey, compositeKeyNamespace)
+}
+
+/// range queries skip composite keys as they do on a peer
+func (s *MockStub) getSimpleKeyRange(
+	iStartKey string,
+	iEndKey string,
+) ([]*queryresult.KV, error) {
+	if isCompositeKey(iStartKey) || isCompositeKey(iEndKey) {
+		return nil, fmt.Errorf("range queries do not accept composite keys")
+	}
+
+	kvs := []*queryresult.KV{}
+	for _, kv := range getRange(s.ledger.state, iStartKey, iEndKey) {
+		if !isCompositeKey(kv.Key) {
+			kvs = append(kvs, kv)
+		}
+	}
+
+	return kvs, nil
+}
+
+func (s *MockStub) GetStateByRange(
+	iStartKey string,
+	iEndKey string,
+) (shim.StateQueryIteratorInterface, error) {
+	kvs, err := s.getSimpleKeyRange(iStartKey, iEndKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mockStateIterator{kvs: kvs}, nil
+}
+
+func (s *MockStub) GetStateByRangeWithPagination(
+	iStartKey string,
+	iEndKey string,
+	iPageSize int32,
+	iBookmark string,
+) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	err := s.checkPaginatedQuery()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kvs, err := s.getSimpleKeyRange(iStartKey, iEndKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page, metadata := getPage(kvs, iPageSize, iBookmark)
+	return &mockStateIterator{kvs: page}, metadata, nil
+}
+
+func (s *MockStub) getCompositeKeyRange(
+	iValues map[string][]byte,
+	iObjectType string,
+	iAttributes []string,
+) ([]*queryresult.KV, error) {
+	prefix, err := s.CreateCompositeKey(iObjectType, iAttributes)
+	if err != nil {
+		return nil, err
+	}
+
+	return getRange(iValues, prefix, prefix+maxUnicodeRune), nil
+}
+
+func (s *MockStub) GetStateByPartialCompositeKey(
+	iObjectType string,
+	iAttributes []string,
+) (shim.StateQueryIteratorInterface, error) {
+	kvs, err := s.getCompositeKeyRange(s.ledger.state, iObjectType, iAttributes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mockStateIterator{kvs: kvs}, nil
+}
+
+func (s *MockStub) GetStateByPartialCompositeKeyWithPagination(
+	iObjectType string,
+	iAttributes []string,
+	iPageSize int32,
+	iBookmark string,
+) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	err := s.checkPaginatedQuery()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kvs, err := s.getCompositeKeyRange(s.ledger.state, iObjectType, iAttributes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page, metadata := getPage(kvs, iPageSize, iBookmark)
+	return &mockStateIterator{kvs: page}, metadata, nil
+}
+
+func validateCompositeKeyAttribute(
+	iAttribute string,
+) error {
+	if !utf8.ValidString(iAttribute) {
+		return fmt.Errorf("attribute %x is not valid UTF-8", iAttribute)
+	}
+
+	if strings.Contains(iAttribute, compositeKeyNamespace) || strings.Contains(iAttribute, maxUnicodeRune) {
+		return fmt.Errorf("attribute %s contains a reserved character", iAttribute)
+	}
+
+	return nil
+}
+
+func (s *MockStub) CreateCompositeKey(
+	iObjectType string,
+	iAttributes []string,
+) (string, error) {
+	err := validateCompositeKeyAttribute(iObjectType)
+	if err != nil {
+		return "", err
+	}
+
+	key := compositeKeyNamespace + iObjectType + compositeKeyNamespace
+	for _, attribute := range iAttributes {
+		err = validateCompositeKeyAttribute(attribute)
+		if err != nil {
+			return "", err
+		}
+		key += attribute + compositeKeyNamespace
+	}
+
+	return key, nil
+}
+
+func (s *MockStub) SplitCompositeKey(
+	iCompositeKey string,
+) (string, []string, error) {
+	if !isCompositeKey(iCompositeKey) {
+		return "", nil, fmt.Errorf("%s is not a composite key", iCompositeKey)
+	}
+
+	components := strings.Split(strings.TrimSuffix(iCompositeKey[1:], compositeKeyNamespace), compositeKeyNamespace)
+	return components[0], components[1:], nil
+}
+
+/// the kvs of iValues whose json value matches the selector of iQuery, see matchSelector for the operators
+func getQueryResult(
+	iValues map[string][]byte,
+	iQuery string,
+) ([]*queryresult.KV, error) {
+	var query struct {
+		Selector map[string]interface{} `json:"selector"`
+	}
+	err := json.Unmarshal([]byte(iQuery), &query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %v", err)
+	}
+
+	kvs := []*queryresult.KV{}
+	for _, kv := range getRange(iValues, "", "") {
+		if isCompositeKey(kv.Key) {
+			continue
+		}
+
+		var document interface{}
+		if json.Unmarshal(kv.Value, &document) != nil {
+			continue
+		}
+
+		isMatching, err := matchSelector(query.Selector, document)
+		if err != nil {
+			return nil, err
+		}
+
+		if isMatching {
+			kvs = append(kvs, kv)
+		}
+	}
+
+	return kvs, nil
+}
+
+func (s *MockStub) GetQueryResult(
+	iQuery string,
+) (shim.StateQueryIteratorInterface, error) {
+	kvs, err := getQueryResult(s.ledger.state, iQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mockStateIterator{kvs: kvs}, nil
+}
+
+func (s *MockStub) GetQueryResultWithPagination(
+	iQuery string,
+	iPageSize int32,
+	iBookmark string,
+) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	err := s.checkPaginatedQuery()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kvs, err := getQueryResult(s.ledger.state, iQuery)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page, metadata := getPage(kvs, iPageSize, iBookmark)
+	return &mockStateIterator{kvs: page}, metadata, nil
+}
+
+/// oldest first
+func (s *MockStub) GetHistoryForKey(
+	iKey string,
+) (shim.HistoryQueryIteratorInterface, error) {
+	return &mockHistoryIterator{modifications: s.ledger.history[iKey]}, nil
+}
+
+func (s *MockStub) GetPrivateData(
+	iCollection string,
+	iKey string,
+) ([]byte, error) {
+	return s.ledger.privateData[iCollection][iKey], nil
+}
+
+func (s *MockStub) GetPrivateDataHash(
+	iCollection string,
+	iKey string,
+) ([]byte, error) {
+	value := s.ledger.privateData[iCollection][iKey]
+	if value == nil {
+		return nil, nil
+	}
+
+	hash := sha256.Sum256(value)
+	return hash[:], nil
+}
+
+func (s *MockStub) PutPrivateData(
+	iCollection string,
+	iKey string,
+	iValue []byte,
+) error {
+	if iKey == "" {
+		return fmt.Errorf("key must not be an empty string")
+	}
+
+	if len(iValue) == 0 {
+		return s.DelPrivateData(iCollection, iKey)
+	}
+
+	err := s.checkWrite()
+	if err != nil {
+		return err
+	}
+
+	if s.privateWrites[iCollection] == nil {
+		s.privateWrites[iCollection] = map[string][]byte{}
+	}
+
+	s.privateWrites[iCollection][iKey] = append([]byte{}, iValue...)
+	return nil
+}
+
+func (s *MockStub) DelPrivateData(
+	iCollection string,
+	iKey string,
+) error {
+	err := s.checkWrite()
+	if err != nil {
+		return err
+	}
+
+	if s.privateWrites[iCollection] == nil {
+		s.privateWrites[iCollection] = map[string][]byte{}
+	}
+
+	s.privateWrites[iCollection][iKey] = nil
+	return nil
+}
+
+func (s *MockStub) SetPrivateDataValidationParameter(
+	iCollection string,
+	iKey string,
+	iParameter []byte,
+) error {
+	return s.SetStateValidationParameter(iCollection+compositeKeyNamespace+iKey, iParameter)
+}
+
+func (s *MockStub) GetPrivateDataValidationParameter(
+	iCollection string,
+	iKey string,
+) ([]byte, error) {
+	return s.GetStateValidationParameter(iCollection + compositeKeyNamespace + iKey)
+}
+
+func (s *MockStub) GetPrivateDataByRange(
+	iCollection string,
+	iStartKey string,
+	iEndKey string,
+) (shim.StateQueryIteratorInterface, error) {
+	kvs := []*queryresult.KV{}
+	for _, kv := range getRange(s.ledger.privateData[iCollection], iStartKey, iEndKey) {
+		if !isCompositeKey(kv.Key) {
+			kvs = append(kvs, kv)
+		}
+	}
+
+	return &mockStateIterator{kvs: kvs}, nil
+}
+
+func (s *MockStub) GetPrivateDataByPartialCompositeKey(
+	iCollection string,
+	iObjectType string,
+	iAttributes []string,
+) (shim.StateQueryIteratorInterface, error) {
+	kvs, err := s.getCompositeKeyRange(s.ledger.privateData[iCollection], iObjectType, iAttributes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mockStateIterator{kvs: kvs}, nil
+}
+
+func (s *MockStub) GetPrivateDataQueryResult(
+	iCollection string,
+	iQuery string,
+) (shim.StateQueryIteratorInterface, error) {
+	kvs, err := getQueryResult(s.ledger.privateData[iCollection], iQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mockStateIterator{kvs: kvs}, nil
+}
+
+func (s *MockStub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+func (s *MockStub) GetTransient() (map[string][]byte, error) {
+	return s.transient, nil
+}
+
+func (s *MockStub) GetBinding() ([]byte, error) {
+	return nil, nil
+}
+
+func (s *MockStub) GetDecorations() map[string][]byte {
+	return map[string][]byte{}
+}
+
+func (s *MockStub) GetSignedProposal() (*pb.SignedProposal, error) {
+	return nil, fmt.Errorf("signed proposals are not mocked")
+}
+
+func (s *MockStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return s.timestamp, nil
+}
+
+func (s *MockStub) SetEvent(
+	iName string,
+	iPayload []byte,
+) error {
+	if iName == "" {
+		return fmt.Errorf("event name cannot be empty")
+	}
+
+	s.event = &pb.ChaincodeEvent{
+		TxId:      s.txId,
+		EventName: iName,
+		Payload:   iPayload,
+	}
+	return nil
+}